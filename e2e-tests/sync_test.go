@@ -17,6 +17,19 @@ import (
 	"time"
 )
 
+// testDSN is the database of the test server, DATABASE_URI overrides it.
+const testDSN = "host=localhost port=5433 user=postgres password=postgres dbname=keeper sslmode=disable"
+
+func databaseURI() string {
+	if dsn := os.Getenv("DATABASE_URI"); dsn != "" {
+		return dsn
+	}
+	return testDSN
+}
+
+// testJWTKey signs the tokens of the test server.
+const testJWTKey = "e2e-test-jwt-key-of-at-least-32-bytes"
+
 type SyncSuite struct {
 	suite.Suite
 	serverProcess *process.Process
@@ -31,7 +44,8 @@ func (suite *SyncSuite) SetupSuite() {
 	fmt.Println(string(out))
 	suite.Require().NoError(err, "ServerBuildCmd command")
 
-	p := process.NewProcess(ctx, "../cmd/server/server")
+	p := process.NewProcess(ctx, "../cmd/server/server",
+		process.WithArgs("-d", databaseURI(), "-jwt-keys", testJWTKey))
 	suite.serverProcess = p
 
 	ctx, cancel := context.WithTimeout(ctx, 20*time.Second)
@@ -63,6 +77,12 @@ func (suite *SyncSuite) TestSync() {
 	fmt.Println(string(out))
 	suite.Require().NoError(err, "ClientBuildCmd command")
 
+	registerCmd := exec.CommandContext(ctx, "../cmd/client/client", "register",
+		"-ul=Denis", "-up=Denis", "-wd=saved")
+	out, err = registerCmd.CombinedOutput()
+	suite.Require().NoError(err, "Register command")
+	fmt.Println(string(out))
+
 	fileCmd := exec.CommandContext(ctx, "../cmd/client/client", "file",
 		"-ul=Denis", "-up=Denis", "-wd=saved", "-a=save", "-f=./test_data/bom.json", "-in=true")
 	out, err = fileCmd.CombinedOutput()
@@ -107,7 +127,7 @@ func (suite *SyncSuite) TestSync() {
 
 	cardCmd := exec.CommandContext(ctx, "../cmd/client/client", "card",
 		"-ul=Denis", "-up=Denis", "-wd=saved", "-a=save", "-hn=\"Denis Denis\"", "-c=111",
-		"-n=\"1111 1111 1111 1111\"", "-in=true")
+		"-n=4111 1111 1111 1111", "-em=12", "-ey=2030", "-in=true")
 	out, err = cardCmd.CombinedOutput()
 	suite.Assert().NoError(err, "Card command")
 
@@ -128,8 +148,8 @@ func (suite *SyncSuite) TestSync() {
 	fmt.Println(string(out))
 
 	credCmd := exec.CommandContext(ctx, "../cmd/client/client", "cred",
-		"-ul=Denis", "-up=Denis", "-wd=saved", "-a=save", "-l=Denis", "-p=Denis",
-		"-in=true")
+		"-ul=Denis", "-wd=saved", "-a=save", "-l=Denis", "-in=true", "-stdin")
+	credCmd.Stdin = strings.NewReader("Denis\nDenis\n")
 	out, err = credCmd.CombinedOutput()
 	suite.Require().NoError(err, "Credentials command")
 
@@ -159,6 +179,12 @@ func (suite *SyncSuite) TestSync() {
 	})
 
 	suite.Run("test sync from db", func() {
+		loginCmd := exec.CommandContext(ctx, "../cmd/client/client", "login",
+			"-ul=Denis", "-up=Denis", "-wd=saved2")
+		out, err = loginCmd.CombinedOutput()
+		fmt.Println(string(out))
+		suite.Require().NoError(err, "Login command with new folder")
+
 		client2Cmd := exec.CommandContext(ctx, "../cmd/client/client", "sync",
 			"-ul=Denis", "-up=Denis", "-wd=saved2")
 		out, err = client2Cmd.CombinedOutput()
@@ -184,7 +210,7 @@ func (suite *SyncSuite) TestSync() {
 		suite.Assert().True(fileRes)
 
 		checkTextCmd := exec.CommandContext(ctx, "../cmd/client/client", "text",
-			"-ul=Denis", "-up=Denis", "-wd=saved2", "-a=get", "-id="+textID)
+			"-ul=Denis", "-up=Denis", "-wd=saved2", "-a=get", "-id="+textID, "-show")
 		out, err = checkTextCmd.CombinedOutput()
 		fmt.Println("TEXT")
 		suite.Assert().NoError(err, "check get text")
@@ -201,7 +227,7 @@ func (suite *SyncSuite) TestSync() {
 		suite.Assert().True(textRes)
 
 		checkCardCmd := exec.CommandContext(ctx, "../cmd/client/client", "card",
-			"-ul=Denis", "-up=Denis", "-wd=saved2", "-a=get", "-id="+cardID)
+			"-ul=Denis", "-up=Denis", "-wd=saved2", "-a=get", "-id="+cardID, "-show")
 		out, err = checkCardCmd.CombinedOutput()
 		fmt.Println("CARD")
 		suite.Assert().NoError(err, "check get card")
@@ -211,14 +237,14 @@ func (suite *SyncSuite) TestSync() {
 		var cardRes bool
 		for checkCardScan.Scan() {
 			text := checkCardScan.Text()
-			if strings.Contains(text, "1111 1111 1111 1111") {
+			if strings.Contains(text, "4111 1111 1111 1111") {
 				cardRes = true
 			}
 		}
 		suite.Assert().True(cardRes)
 
 		checkCredCmd := exec.CommandContext(ctx, "../cmd/client/client", "cred",
-			"-ul=Denis", "-up=Denis", "-wd=saved2", "-a=get", "-id="+credID)
+			"-ul=Denis", "-up=Denis", "-wd=saved2", "-a=get", "-id="+credID, "-show")
 		out, err = checkCredCmd.CombinedOutput()
 		fmt.Println("CRED")
 		suite.Assert().NoError(err, "check get cred")
@@ -265,8 +291,7 @@ func (suite *SyncSuite) TearDownSuite() {
 	}
 
 	{
-		pool, err := pgxpool.New(ctx, "host=localhost port=5433 user=postgres "+
-			"password=postgres dbname=keeper sslmode=disable")
+		pool, err := pgxpool.New(ctx, databaseURI())
 		suite.Assert().NoError(err)
 		defer pool.Close()
 
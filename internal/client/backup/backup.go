@@ -0,0 +1,265 @@
+// Package backup writes the whole vault into a single archive encrypted
+// with an export passphrase, independent of the key and the storage format
+// of the vault itself.
+//
+// An archive is the magic line, a JSON header line and the AES-GCM sealed,
+// gzipped JSON payload. The key is derived from the passphrase with
+// argon2id, the header is authenticated along with the payload.
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/transfer"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"golang.org/x/crypto/argon2"
+	"io"
+	"time"
+)
+
+// Version is the archive format version, archives of newer versions are
+// refused.
+const Version = 1
+
+const (
+	magic = "GOPHKEEPER-BACKUP"
+
+	kdfArgon2id = "argon2id"
+
+	MinPassphraseLength = 8
+)
+
+var (
+	ErrNotArchive       = errors.New("not a gophkeeper backup")
+	ErrWrongPassphrase  = errors.New("wrong passphrase or corrupted archive")
+	ErrUnsupportedKDF   = errors.New("unsupported key derivation function")
+	ErrPassphraseLength = fmt.Errorf("passphrase must be at least %d characters", MinPassphraseLength)
+)
+
+type Header struct {
+	Version int       `json:"version"`
+	Created time.Time `json:"created"`
+	KDF     string    `json:"kdf"`
+	Time    uint32    `json:"time"`
+	Memory  uint32    `json:"memory"`
+	Threads uint8     `json:"threads"`
+	Salt    []byte    `json:"salt"`
+	Nonce   []byte    `json:"nonce"`
+}
+
+type payload struct {
+	Version int      `json:"version"`
+	Records []record `json:"records"`
+}
+
+type record struct {
+	Kind     transfer.Kind `json:"kind"`
+	Title    string        `json:"title,omitempty"`
+	Login    string        `json:"login,omitempty"`
+	Password string        `json:"password,omitempty"`
+	Card     *card         `json:"card,omitempty"`
+	Text     string        `json:"text,omitempty"`
+	FileName string        `json:"file_name,omitempty"`
+	Data     []byte        `json:"data,omitempty"`
+}
+
+type card struct {
+	Num            string `json:"num"`
+	CVC            string `json:"cvc"`
+	HolderName     string `json:"holder_name"`
+	ExpMonth       string `json:"exp_month"`
+	ExpYear        string `json:"exp_year"`
+	PIN            string `json:"pin,omitempty"`
+	BillingLine1   string `json:"billing_line1,omitempty"`
+	BillingLine2   string `json:"billing_line2,omitempty"`
+	BillingCity    string `json:"billing_city,omitempty"`
+	BillingRegion  string `json:"billing_region,omitempty"`
+	BillingZip     string `json:"billing_zip,omitempty"`
+	BillingCountry string `json:"billing_country,omitempty"`
+}
+
+// Write encrypts items into w.
+func Write(w io.Writer, passphrase string, items []transfer.Item) error {
+	if len(passphrase) < MinPassphraseLength {
+		return ErrPassphraseLength
+	}
+	p := payload{Version: Version, Records: make([]record, 0, len(items))}
+	for _, it := range items {
+		p.Records = append(p.Records, newRecord(it))
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(p); err != nil {
+		return fmt.Errorf("json.Encode: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("zw.Close: %w", err)
+	}
+
+	h := Header{
+		Version: Version,
+		Created: time.Now().UTC(),
+		KDF:     kdfArgon2id,
+		Time:    3,
+		Memory:  64 * 1024,
+		Threads: 4,
+		Salt:    make([]byte, 16),
+		Nonce:   make([]byte, 12),
+	}
+	if _, err := rand.Read(h.Salt); err != nil {
+		return fmt.Errorf("rand.Read: %w", err)
+	}
+	if _, err := rand.Read(h.Nonce); err != nil {
+		return fmt.Errorf("rand.Read: %w", err)
+	}
+	headerLine, err := json.Marshal(h)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+	aead, err := h.aead(passphrase)
+	if err != nil {
+		return err
+	}
+	sealed := aead.Seal(nil, h.Nonce, buf.Bytes(), headerLine)
+
+	for _, b := range [][]byte{[]byte(magic + "\n"), headerLine, []byte("\n"), sealed} {
+		if _, err := w.Write(b); err != nil {
+			return fmt.Errorf("w.Write: %w", err)
+		}
+	}
+	return nil
+}
+
+// Read decrypts an archive written by Write.
+func Read(r io.Reader, passphrase string) ([]transfer.Item, Header, error) {
+	br := bufio.NewReader(r)
+	line, err := br.ReadString('\n')
+	if err != nil || line != magic+"\n" {
+		return nil, Header{}, ErrNotArchive
+	}
+	headerLine, err := br.ReadBytes('\n')
+	if err != nil {
+		return nil, Header{}, ErrNotArchive
+	}
+	headerLine = bytes.TrimSuffix(headerLine, []byte("\n"))
+	var h Header
+	if err := json.Unmarshal(headerLine, &h); err != nil {
+		return nil, Header{}, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+	if h.Version > Version {
+		return nil, h, fmt.Errorf("archive version %d is newer than supported %d", h.Version, Version)
+	}
+	sealed, err := io.ReadAll(br)
+	if err != nil {
+		return nil, h, fmt.Errorf("io.ReadAll: %w", err)
+	}
+	aead, err := h.aead(passphrase)
+	if err != nil {
+		return nil, h, err
+	}
+	plain, err := aead.Open(nil, h.Nonce, sealed, headerLine)
+	if err != nil {
+		return nil, h, ErrWrongPassphrase
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(plain))
+	if err != nil {
+		return nil, h, fmt.Errorf("gzip.NewReader: %w", err)
+	}
+	defer zr.Close()
+	var p payload
+	if err := json.NewDecoder(zr).Decode(&p); err != nil {
+		return nil, h, fmt.Errorf("json.Decode: %w", err)
+	}
+	items := make([]transfer.Item, 0, len(p.Records))
+	for _, rec := range p.Records {
+		items = append(items, rec.item())
+	}
+	return items, h, nil
+}
+
+func (h Header) aead(passphrase string) (cipher.AEAD, error) {
+	if h.KDF != kdfArgon2id {
+		return nil, ErrUnsupportedKDF
+	}
+	key := argon2.IDKey([]byte(passphrase), h.Salt, h.Time, h.Memory, h.Threads, 32)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("aes.NewCipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("cipher.NewGCM: %w", err)
+	}
+	if len(h.Nonce) != aead.NonceSize() {
+		return nil, ErrNotArchive
+	}
+	return aead, nil
+}
+
+func newRecord(it transfer.Item) record {
+	rec := record{
+		Kind:     it.Kind,
+		Title:    it.Title,
+		Login:    it.Login,
+		Password: it.Password,
+		Text:     it.Text,
+		FileName: it.FileName,
+		Data:     it.Data,
+	}
+	if it.Kind == transfer.KindCard {
+		c, a := it.Card, it.Card.BillingAddress
+		rec.Card = &card{
+			Num:            c.Num,
+			CVC:            c.CVC,
+			HolderName:     c.HolderName,
+			ExpMonth:       c.ExpMonth,
+			ExpYear:        c.ExpYear,
+			PIN:            c.PIN,
+			BillingLine1:   a.Line1,
+			BillingLine2:   a.Line2,
+			BillingCity:    a.City,
+			BillingRegion:  a.Region,
+			BillingZip:     a.PostalCode,
+			BillingCountry: a.Country,
+		}
+	}
+	return rec
+}
+
+func (rec record) item() transfer.Item {
+	it := transfer.Item{
+		Kind:     rec.Kind,
+		Title:    rec.Title,
+		Login:    rec.Login,
+		Password: rec.Password,
+		Text:     rec.Text,
+		FileName: rec.FileName,
+		Data:     rec.Data,
+	}
+	if c := rec.Card; c != nil {
+		it.Card = model.CardInput{
+			Num:        c.Num,
+			CVC:        c.CVC,
+			HolderName: c.HolderName,
+			ExpMonth:   c.ExpMonth,
+			ExpYear:    c.ExpYear,
+			PIN:        c.PIN,
+			BillingAddress: model.BillingAddress{
+				Line1:      c.BillingLine1,
+				Line2:      c.BillingLine2,
+				City:       c.BillingCity,
+				Region:     c.BillingRegion,
+				PostalCode: c.BillingZip,
+				Country:    c.BillingCountry,
+			},
+		}
+	}
+	return it
+}
@@ -0,0 +1,107 @@
+package backup
+
+import (
+	"bytes"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/transfer"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+const testPassphrase = "correct horse battery"
+
+var testItems = []transfer.Item{
+	{Kind: transfer.KindCredentials, Title: "Mail", Login: "alice", Password: "s3cret"},
+	{Kind: transfer.KindCard, Title: "Visa", Card: model.CardInput{Num: "4111111111111111", CVC: "123",
+		HolderName: "Alice", ExpMonth: "03", ExpYear: "2027", PIN: "0000",
+		BillingAddress: model.BillingAddress{Line1: "1 Main St", Line2: "Apt 2", City: "Springfield",
+			Region: "IL", PostalCode: "62701", Country: "US"}}},
+	{Kind: transfer.KindText, Title: "Wifi", Text: "password is hunter2"},
+	{Kind: transfer.KindFile, Title: "Key", FileName: "id_ed25519", Data: []byte{0, 1, 2, 0xff}},
+}
+
+func writeArchive(t *testing.T, items []transfer.Item) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, testPassphrase, items))
+	return buf.Bytes()
+}
+
+func TestRoundTrip(t *testing.T) {
+	archive := writeArchive(t, testItems)
+	assert.False(t, bytes.Contains(archive, []byte("hunter2")), "the payload is encrypted")
+
+	items, h, err := Read(bytes.NewReader(archive), testPassphrase)
+	require.NoError(t, err)
+	assert.Equal(t, testItems, items)
+	assert.Equal(t, Version, h.Version)
+	assert.Equal(t, kdfArgon2id, h.KDF)
+	assert.False(t, h.Created.IsZero())
+
+	items, _, err = Read(bytes.NewReader(writeArchive(t, nil)), testPassphrase)
+	require.NoError(t, err)
+	assert.Empty(t, items)
+}
+
+func TestWriteShortPassphrase(t *testing.T) {
+	var buf bytes.Buffer
+	err := Write(&buf, "short", testItems)
+	assert.ErrorIs(t, err, ErrPassphraseLength)
+	assert.Zero(t, buf.Len())
+}
+
+func TestReadRejects(t *testing.T) {
+	archive := writeArchive(t, testItems)
+	headerEnd := len(magic) + 1 + bytes.IndexByte(archive[len(magic)+1:], '\n')
+
+	replaceHeader := func(old, new string) []byte {
+		header := bytes.Replace(archive[:headerEnd], []byte(old), []byte(new), 1)
+		require.NotEqual(t, archive[:headerEnd], header, "%s is not in the header", old)
+		return append(header, archive[headerEnd:]...)
+	}
+	flip := func(i int) []byte {
+		b := bytes.Clone(archive)
+		b[i] ^= 1
+		return b
+	}
+
+	tests := []struct {
+		name       string
+		archive    []byte
+		passphrase string
+		wantErr    error
+	}{
+		{name: "wrong passphrase", archive: archive, passphrase: "wrong passphrase",
+			wantErr: ErrWrongPassphrase},
+		{name: "payload changed", archive: flip(len(archive) - 20), wantErr: ErrWrongPassphrase},
+		{name: "tag changed", archive: flip(len(archive) - 1), wantErr: ErrWrongPassphrase},
+		{name: "payload truncated", archive: archive[:len(archive)-1], wantErr: ErrWrongPassphrase},
+		{name: "header changed", archive: replaceHeader(`"version":1`, `"version":0`),
+			wantErr: ErrWrongPassphrase},
+		{name: "unknown kdf", archive: replaceHeader(`"argon2id"`, `"scrypt"`), wantErr: ErrUnsupportedKDF},
+		{name: "wrong magic", archive: append([]byte("GOPHKEEPER-BACKUQ"), archive[len(magic):]...),
+			wantErr: ErrNotArchive},
+		{name: "no header", archive: []byte(magic + "\n"), wantErr: ErrNotArchive},
+		{name: "empty", archive: nil, wantErr: ErrNotArchive},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			passphrase := tt.passphrase
+			if passphrase == "" {
+				passphrase = testPassphrase
+			}
+			items, _, err := Read(bytes.NewReader(tt.archive), passphrase)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Nil(t, items)
+		})
+	}
+}
+
+func TestReadNewerVersion(t *testing.T) {
+	archive := writeArchive(t, testItems)
+	archive = bytes.Replace(archive, []byte(`"version":1`), []byte(`"version":2`), 1)
+	_, h, err := Read(bytes.NewReader(archive), testPassphrase)
+	assert.ErrorContains(t, err, "newer than supported")
+	assert.Equal(t, 2, h.Version)
+}
@@ -0,0 +1,155 @@
+package clipboard
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ClearCommand is the hidden client subcommand run in the background to
+// clear the clipboard after a timeout.
+const ClearCommand = "clipboard-clear"
+
+var ErrUnavailable = errors.New("no clipboard tool or terminal found")
+
+type tool struct {
+	copy  []string
+	paste []string
+}
+
+// tools are tried in order, the first one installed is used.
+var tools = []tool{
+	{copy: []string{"wl-copy"}, paste: []string{"wl-paste", "--no-newline"}},
+	{copy: []string{"xclip", "-selection", "clipboard"},
+		paste: []string{"xclip", "-selection", "clipboard", "-o"}},
+	{copy: []string{"xsel", "--clipboard", "--input"},
+		paste: []string{"xsel", "--clipboard", "--output"}},
+	{copy: []string{"pbcopy"}, paste: []string{"pbpaste"}},
+	{copy: []string{"clip.exe"},
+		paste: []string{"powershell.exe", "-NoProfile", "-Command", "Get-Clipboard"}},
+}
+
+func findTool() (tool, bool) {
+	for _, t := range tools {
+		if _, err := exec.LookPath(t.copy[0]); err == nil {
+			return t, true
+		}
+	}
+	return tool{}, false
+}
+
+// Write puts text into the system clipboard. Without a clipboard tool the
+// OSC 52 escape sequence asks the terminal to do it, which also works over
+// ssh.
+func Write(text string) error {
+	t, ok := findTool()
+	if !ok {
+		return writeOSC52(base64.StdEncoding.EncodeToString([]byte(text)))
+	}
+	cmd := exec.Command(t.copy[0], t.copy[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w", t.copy[0], err)
+	}
+	return nil
+}
+
+func read() (string, error) {
+	t, ok := findTool()
+	if !ok {
+		return "", ErrUnavailable
+	}
+	out, err := exec.Command(t.paste[0], t.paste[1:]...).Output()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", t.paste[0], err)
+	}
+	return string(out), nil
+}
+
+func writeOSC52(payload string) error {
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		return ErrUnavailable
+	}
+	defer tty.Close()
+	_, err = fmt.Fprintf(tty, "\x1b]52;c;%s\a", payload)
+	if err != nil {
+		return fmt.Errorf("tty.Write: %w", err)
+	}
+	return nil
+}
+
+// clearIfUnchanged empties the clipboard if it still holds the value with
+// the given hash, so anything copied later by the user is left alone. The
+// terminal clipboard can't be read back and is cleared unconditionally.
+func clearIfUnchanged(sum string) error {
+	if _, ok := findTool(); !ok {
+		// invalid base64 makes the terminal drop the selection
+		return writeOSC52("!")
+	}
+	current, err := read()
+	if err != nil {
+		return fmt.Errorf("read: %w", err)
+	}
+	if hash(current) != sum && hash(strings.TrimRight(current, "\r\n")) != sum {
+		return nil
+	}
+	return Write("")
+}
+
+func hash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// ClearAfter starts a background process that clears text from the
+// clipboard after d. The hash of the value is passed through a pipe, so it
+// never shows up in the process list.
+func ClearAfter(text string, d time.Duration) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("os.Executable: %w", err)
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("os.Pipe: %w", err)
+	}
+	defer r.Close()
+
+	cmd := exec.Command(exe, ClearCommand, "-after="+d.String())
+	cmd.Stdin = r
+	if err := cmd.Start(); err != nil {
+		w.Close()
+		return fmt.Errorf("cmd.Start: %w", err)
+	}
+	_, err = fmt.Fprintln(w, hash(text))
+	w.Close()
+	if err != nil {
+		return fmt.Errorf("w.Write: %w", err)
+	}
+	return cmd.Process.Release()
+}
+
+// RunClear is the entry point of ClearCommand.
+func RunClear(args []string) error {
+	set := flag.NewFlagSet(ClearCommand, flag.ContinueOnError)
+	after := set.Duration("after", 30*time.Second, "Clear the clipboard after")
+	if err := set.Parse(args); err != nil {
+		return fmt.Errorf("set.Parse: %w", err)
+	}
+	sum, err := bufio.NewReader(os.Stdin).ReadBytes('\n')
+	if err != nil {
+		return fmt.Errorf("read hash: %w", err)
+	}
+	time.Sleep(*after)
+	return clearIfUnchanged(string(bytes.TrimSpace(sum)))
+}
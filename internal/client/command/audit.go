@@ -0,0 +1,36 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/config"
+	"time"
+)
+
+// DoAudit prints the security events of the account, newest first.
+func DoAudit(ctx context.Context, conf *config.Config, s *session) error {
+	if conf.AuditLimit <= 0 {
+		return errors.New("number of events must be positive, set -n")
+	}
+	page, err := s.clientService.FindAuditEvents(ctx, conf.AuditBefore, conf.AuditLimit)
+	if err != nil {
+		return fmt.Errorf("clientService.FindAuditEvents: %w", err)
+	}
+	if len(page.Events) == 0 {
+		fmt.Println("no events")
+		return nil
+	}
+	for _, e := range page.Events {
+		client := e.ClientID
+		if client != "" && client == s.client.ID {
+			client = "this client"
+		}
+		fmt.Printf("%d\t%s\t%s\t%s\t%s\t%s\n", e.ID,
+			e.CreatedTms.Local().Format(time.DateTime), e.Event, e.IP, client, e.Details)
+	}
+	if page.Next != 0 {
+		fmt.Printf("more with -before %d\n", page.Next)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/backup"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/config"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/prompt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/transfer"
+	"os"
+	"strings"
+)
+
+// DoExport writes every item of the vault into an encrypted archive.
+func DoExport(ctx context.Context, conf *config.Config, s *session) error {
+	if len(conf.ExportPassphrase) < backup.MinPassphraseLength {
+		return backup.ErrPassphraseLength
+	}
+	items, err := s.loadItems(ctx)
+	if err != nil {
+		return fmt.Errorf("s.loadItems: %w", err)
+	}
+	f, err := createExportFile(conf)
+	if err != nil {
+		return err
+	}
+	err = backup.Write(f, conf.ExportPassphrase, items)
+	if cErr := f.Close(); err == nil && cErr != nil {
+		err = fmt.Errorf("f.Close: %w", cErr)
+	}
+	if err != nil {
+		os.Remove(conf.Filename)
+		return fmt.Errorf("backup.Write: %w", err)
+	}
+	fmt.Printf("exported %d item(s) to %s\n", len(items), conf.Filename)
+	return nil
+}
+
+// DoExportPlain writes the vault unencrypted in the format of another
+// password manager, after the user confirmed it.
+func DoExportPlain(ctx context.Context, conf *config.Config, s *session, p *prompt.Prompter) error {
+	w, err := transfer.NewWriter(conf.ExportFormat)
+	if err != nil {
+		return fmt.Errorf("transfer.NewWriter: %w", err)
+	}
+	all, err := s.loadItems(ctx)
+	if err != nil {
+		return fmt.Errorf("s.loadItems: %w", err)
+	}
+	items := make([]transfer.Item, 0, len(all))
+	skipped := make(map[transfer.Kind]int)
+	for _, it := range all {
+		if !w.Supports(it.Kind) {
+			skipped[it.Kind]++
+			continue
+		}
+		items = append(items, it)
+	}
+
+	question := fmt.Sprintf("write %d item(s) UNENCRYPTED to %s?", len(items), conf.Filename)
+	ok, err := confirm(p, conf, question)
+	if err != nil {
+		return fmt.Errorf("confirm: %w", err)
+	}
+	if !ok {
+		return errors.New("export cancelled")
+	}
+
+	f, err := createExportFile(conf)
+	if err != nil {
+		return err
+	}
+	err = w.Write(f, items)
+	if cErr := f.Close(); err == nil && cErr != nil {
+		err = fmt.Errorf("f.Close: %w", cErr)
+	}
+	if err != nil {
+		os.Remove(conf.Filename)
+		return fmt.Errorf("w.Write: %w", err)
+	}
+	fmt.Printf("exported %d item(s) to %s unencrypted, delete the file once it is imported\n",
+		len(items), conf.Filename)
+	for k, n := range skipped {
+		fmt.Printf("skipped %d %s item(s), %s can't hold them\n", n, k, conf.ExportFormat)
+	}
+	return nil
+}
+
+// createExportFile creates the file readable by the owner only, an existing
+// file is overwritten only with -force.
+func createExportFile(conf *config.Config) (*os.File, error) {
+	if conf.Filename == "" {
+		return nil, errors.New("output file is required, set -f")
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if conf.Force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(conf.Filename, flags, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("os.OpenFile: %w", err)
+	}
+	// O_TRUNC keeps the mode of an existing file
+	if err := f.Chmod(0600); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("f.Chmod: %w", err)
+	}
+	return f, nil
+}
+
+// DoRestore imports an archive written by DoExport. Items already in the
+// vault are skipped when merging, replacing deletes the vault items first.
+func DoRestore(ctx context.Context, conf *config.Config, s *session, p *prompt.Prompter) error {
+	f, err := os.Open(conf.Filename)
+	if err != nil {
+		return fmt.Errorf("os.Open: %w", err)
+	}
+	items, h, err := backup.Read(f, conf.ExportPassphrase)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("backup.Read: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	if conf.RestoreReplace {
+		entries, err := s.loadEntries(ctx)
+		if err != nil {
+			return fmt.Errorf("s.loadEntries: %w", err)
+		}
+		question := fmt.Sprintf("replace %d vault item(s) with %d item(s) of the backup from %s?",
+			len(entries), len(items), h.Created.Local().Format("2006-01-02 15:04"))
+		ok, err := confirm(p, conf, question)
+		if err != nil {
+			return fmt.Errorf("confirm: %w", err)
+		}
+		if !ok {
+			return errors.New("restore cancelled")
+		}
+		for _, e := range entries {
+			if err := s.deleteEntry(ctx, e); err != nil {
+				return fmt.Errorf("s.deleteEntry: %w", err)
+			}
+		}
+	} else {
+		existing, err := s.loadItems(ctx)
+		if err != nil {
+			return fmt.Errorf("s.loadItems: %w", err)
+		}
+		for _, it := range existing {
+			seen[it.Key()] = true
+		}
+	}
+
+	report := transferReport{saved: make(map[transfer.Kind]int)}
+	for _, it := range items {
+		key := it.Key()
+		if seen[key] {
+			report.duplicates++
+			continue
+		}
+		seen[key] = true
+		if err := s.saveItem(ctx, it); err != nil {
+			return fmt.Errorf("s.saveItem: %w", err)
+		}
+		report.saved[it.Kind]++
+	}
+	report.print(os.Stdout, "restored", false)
+	return nil
+}
+
+// confirm asks a yes/no question, -yes answers it up front.
+func confirm(p *prompt.Prompter, conf *config.Config, question string) (bool, error) {
+	if conf.AssumeYes {
+		return true, nil
+	}
+	if !p.Interactive() {
+		return false, errors.New("confirmation required, pass -yes")
+	}
+	answer, err := p.Line(question + " [y/N]")
+	if err != nil {
+		return false, fmt.Errorf("p.Line: %w", err)
+	}
+	return strings.EqualFold(strings.TrimSpace(answer), "y"), nil
+}
@@ -3,19 +3,21 @@ package command
 import (
 	"bufio"
 	"context"
-	"encoding/base64"
 	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/config"
 	"github.com/denis-oreshkevich/gophkeeper/internal/client/service"
-	"github.com/denis-oreshkevich/gophkeeper/internal/shared/config"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/vault"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/crypto"
 	"github.com/denis-oreshkevich/gophkeeper/internal/shared/logger"
 	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
 	"github.com/google/uuid"
 	"io"
 	"os"
+	"time"
 )
 
 func DoFile(ctx context.Context, conf *config.Config, clientService *service.ClientService,
-	user model.User) error {
+	dealer *crypto.Dealer, user model.User) error {
 	switch conf.Action {
 	case config.ActionGet:
 		byID, fErr := clientService.FindBinaryByID(ctx, conf.ID)
@@ -23,9 +25,9 @@ func DoFile(ctx context.Context, conf *config.Config, clientService *service.Cli
 			return fmt.Errorf("clientService.FindBinaryByID: %w", fErr)
 		}
 
-		dec, bsErr := base64.StdEncoding.DecodeString(byID.Data)
+		dec, bsErr := vault.OpenBinary(dealer, byID.Data)
 		if bsErr != nil {
-			return fmt.Errorf("base64.StdEncoding.DecodeString: %w", bsErr)
+			return fmt.Errorf("vault.OpenBinary: %w", bsErr)
 		}
 
 		f, osErr := os.Create(byID.Name)
@@ -42,32 +44,23 @@ func DoFile(ctx context.Context, conf *config.Config, clientService *service.Cli
 		}
 		logger.Log.Info("Success")
 	case config.ActionSave:
-		file, err := os.Open(conf.Filename)
+		data, modTms, err := readFile(conf.Filename)
 		if err != nil {
-			return fmt.Errorf("os.Open: %w", err)
+			return fmt.Errorf("readFile: %w", err)
 		}
-		defer file.Close()
-
-		stat, err := file.Stat()
+		enc, err := vault.SealBinary(dealer, data)
 		if err != nil {
-			return fmt.Errorf("file.Stat: %w", err)
-		}
-
-		bf := make([]byte, stat.Size())
-		_, err = bufio.NewReader(file).Read(bf)
-		if err != nil && err != io.EOF {
-			return fmt.Errorf("bufio.NewReader(file).Read: %w", err)
+			return fmt.Errorf("vault.SealBinary: %w", err)
 		}
-		bStr := base64.StdEncoding.EncodeToString(bf)
 		id := uuid.New()
 		binary := model.Binary{
 			ID:          id.String(),
 			Name:        conf.Filename,
-			Data:        bStr,
+			Data:        enc,
 			New:         conf.IsNew,
 			UserID:      user.ID,
 			Status:      model.StatusActive,
-			ModifiedTms: stat.ModTime().UTC(),
+			ModifiedTms: modTms,
 		}
 		err = clientService.SaveBinary(ctx, &binary)
 		if err != nil {
@@ -85,3 +78,24 @@ func DoFile(ctx context.Context, conf *config.Config, clientService *service.Cli
 	}
 	return nil
 }
+
+// readFile returns file content and its modification time.
+func readFile(filename string) ([]byte, time.Time, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("os.Open: %w", err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("file.Stat: %w", err)
+	}
+
+	bf := make([]byte, stat.Size())
+	_, err = io.ReadFull(bufio.NewReader(file), bf)
+	if err != nil && err != io.EOF {
+		return nil, time.Time{}, fmt.Errorf("io.ReadFull: %w", err)
+	}
+	return bf, stat.ModTime().UTC(), nil
+}
@@ -3,12 +3,15 @@ package command
 import (
 	"context"
 	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/config"
 	"github.com/denis-oreshkevich/gophkeeper/internal/client/service"
-	"github.com/denis-oreshkevich/gophkeeper/internal/shared/config"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/vault"
 	"github.com/denis-oreshkevich/gophkeeper/internal/shared/crypto"
 	"github.com/denis-oreshkevich/gophkeeper/internal/shared/logger"
 	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
 	"github.com/google/uuid"
+	"os"
+	"strings"
 	"time"
 )
 
@@ -20,44 +23,49 @@ func DoCard(ctx context.Context, conf *config.Config, clientService *service.Cli
 		if err != nil {
 			return fmt.Errorf("clientService.FindCardByID: %w", err)
 		}
-		num, err := dealer.Decrypt(byID.Num)
-		if err != nil {
-			return fmt.Errorf("dealer.Decrypt(byID.Num): %w", err)
-		}
-		cvc, err := dealer.Decrypt(byID.CVC)
-		if err != nil {
-			return fmt.Errorf("dealer.Decrypt(byID.CVC): %w", err)
+		if conf.CopyField != "" {
+			e, err := cardEntry(dealer, &byID)
+			if err != nil {
+				return fmt.Errorf("cardEntry: %w", err)
+			}
+			return copyField(os.Stdout, e, conf.CopyField, conf.ClipTimeout)
 		}
-		name, err := dealer.Decrypt(byID.HolderName)
+		in, brand, err := vault.OpenCard(dealer, byID)
 		if err != nil {
-			return fmt.Errorf("dealer.Decrypt(byID.HolderName): %w", err)
+			return fmt.Errorf("vault.OpenCard: %w", err)
 		}
-		logger.Log.Info(fmt.Sprintf("num: %s, cvc: %s, name %s",
-			num, cvc, name))
+		fmt.Println(formatCard(in, brand, conf.ShowSecrets))
 	case config.ActionSave:
-		id := uuid.New()
-		num, err := dealer.Encrypt(conf.CardNum)
-		if err != nil {
-			return fmt.Errorf("dealer.Encrypt(conf.CardNum): %w", err)
+		in := model.CardInput{
+			Num:        conf.CardNum,
+			CVC:        conf.CardCVC,
+			HolderName: conf.CardHolderName,
+			ExpMonth:   conf.CardExpMonth,
+			ExpYear:    conf.CardExpYear,
+			PIN:        conf.CardPIN,
+			BillingAddress: model.BillingAddress{
+				Line1:      conf.CardBillingLine1,
+				Line2:      conf.CardBillingLine2,
+				City:       conf.CardBillingCity,
+				Region:     conf.CardBillingRegion,
+				PostalCode: conf.CardBillingZip,
+				Country:    strings.ToUpper(conf.CardBillingCountry),
+			},
 		}
-		cvc, err := dealer.Encrypt(conf.CardCVC)
+		err := model.ValidateCardInput(in, time.Now().UTC())
 		if err != nil {
-			return fmt.Errorf("dealer.Encrypt(conf.CardCVC): %w", err)
+			return fmt.Errorf("model.ValidateCardInput: %w", err)
 		}
-		name, err := dealer.Encrypt(conf.CardHolderName)
+		card, err := vault.SealCard(dealer, in)
 		if err != nil {
-			return fmt.Errorf("dealer.Encrypt(conf.CardHolderName): %w", err)
-		}
-		card := model.Card{
-			ID:          id.String(),
-			Num:         num,
-			CVC:         cvc,
-			HolderName:  name,
-			New:         conf.IsNew,
-			UserID:      user.ID,
-			Status:      model.StatusActive,
-			ModifiedTms: time.Now().UTC(),
+			return fmt.Errorf("vault.SealCard: %w", err)
 		}
+		id := uuid.New()
+		card.ID = id.String()
+		card.New = conf.IsNew
+		card.UserID = user.ID
+		card.Status = model.StatusActive
+		card.ModifiedTms = time.Now().UTC()
 		err = clientService.SaveCard(ctx, card)
 		if err != nil {
 			return fmt.Errorf("clientService.SaveCard: %w", err)
@@ -74,3 +82,35 @@ func DoCard(ctx context.Context, conf *config.Config, clientService *service.Cli
 	}
 	return nil
 }
+
+func formatCard(in model.CardInput, brand model.Brand, show bool) string {
+	num := model.MaskCardNum(in.Num)
+	cvc := strings.Repeat("*", len(in.CVC))
+	pin := strings.Repeat("*", len(in.PIN))
+	if show {
+		num = model.FormatCardNum(in.Num)
+		cvc = in.CVC
+		pin = in.PIN
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "brand: %s, num: %s, exp: %s/%s, cvc: %s, name: %s",
+		brand, num, in.ExpMonth, in.ExpYear, cvc, in.HolderName)
+	if pin != "" {
+		fmt.Fprintf(&sb, ", pin: %s", pin)
+	}
+	if !in.BillingAddress.IsEmpty() {
+		fmt.Fprintf(&sb, ", billing address: %s", formatAddress(in.BillingAddress))
+	}
+	return sb.String()
+}
+
+func formatAddress(addr model.BillingAddress) string {
+	parts := make([]string, 0, 6)
+	for _, p := range []string{addr.Line1, addr.Line2, addr.City, addr.Region,
+		addr.PostalCode, addr.Country} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
@@ -2,161 +2,452 @@ package command
 
 import (
 	"context"
-	"crypto/tls"
 	"errors"
 	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/config"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/prompt"
 	"github.com/denis-oreshkevich/gophkeeper/internal/client/repo"
 	"github.com/denis-oreshkevich/gophkeeper/internal/client/repo/fs"
 	"github.com/denis-oreshkevich/gophkeeper/internal/client/repo/rest"
 	"github.com/denis-oreshkevich/gophkeeper/internal/client/service"
 	"github.com/denis-oreshkevich/gophkeeper/internal/shared/auth"
-	"github.com/denis-oreshkevich/gophkeeper/internal/shared/config"
 	"github.com/denis-oreshkevich/gophkeeper/internal/shared/crypto"
 	"github.com/denis-oreshkevich/gophkeeper/internal/shared/logger"
 	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/tracing"
 	"github.com/go-resty/resty/v2"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.uber.org/zap"
+	"net/http"
 	"os"
-	"strings"
 )
 
-func Do(ctx context.Context, conf *config.Config) error {
+var tracer = tracing.Tracer("client/command")
+
+// session is an opened vault: local and remote repositories are wired,
+// the user is logged in and the client is registered.
+type session struct {
+	repository    *fs.Repository
+	clientService *service.ClientService
+	user          model.User
+	client        model.Client
+	dealer        *crypto.Dealer
+	wd            string
+
+	// confirm asks the user, keys of other users are trusted with it
+	confirm func(question string) (bool, error)
+}
+
+func openSession(ctx context.Context, conf *config.Config) (*session, error) {
 	err := os.MkdirAll(conf.WorkingDir, 0755)
 	if err != nil {
 		if !errors.Is(err, os.ErrExist) {
-			return fmt.Errorf("os.MkdirAll: %w", err)
+			return nil, fmt.Errorf("os.MkdirAll: %w", err)
+		}
+	}
+
+	repository, clientRepo := newRepository(conf.WorkingDir)
+
+	dev, err := loadDevice(conf.WorkingDir)
+	if err != nil {
+		return nil, fmt.Errorf("loadDevice: %w", err)
+	}
+	r, err := newRESTClient(conf, dev)
+	if err != nil {
+		return nil, err
+	}
+	restRepo := rest.NewRESTRepositoryImpl(r)
+
+	clientService := service.NewClientService(repository, restRepo)
+
+	// a registered client names itself from the login on, for the audit log
+	findClient, err := clientRepo.FindClient(ctx)
+	registered := err == nil
+	if err != nil && !errors.Is(err, repo.ErrItemNotFound) {
+		return nil, fmt.Errorf("clientRepo.FindClient: %w", err)
+	}
+	if registered {
+		r.SetHeader(rest.ClientIDHeaderName, findClient.ID)
+	}
+
+	user, err := repository.FindUser(ctx)
+	if errors.Is(err, repo.ErrItemNotFound) {
+		return nil, ErrNotLoggedIn
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repository.FindUser: %w", err)
+	}
+	if conf.UserLogin != "" && conf.UserLogin != user.Login {
+		return nil, fmt.Errorf("logged in as %s, not %s", user.Login, conf.UserLogin)
+	}
+	// the vault is opened before the server is asked, a wrong password is
+	// caught offline. A password changed on another device needs a login.
+	proof := loginPassword(user, conf.UserPassword)
+	err = auth.ComparePasswords(user.HashedPassword, proof)
+	if err != nil {
+		return nil, fmt.Errorf("wrong password, run login if it was changed on another device: %w", err)
+	}
+
+	// a device with a certificate authenticates with it instead of a token
+	if !dev.has() {
+		token, err := sessionToken(ctx, conf, user, proof, clientService)
+		if err != nil {
+			return nil, fmt.Errorf("sessionToken: %w", err)
+		}
+		r.SetAuthToken(token)
+	}
+
+	if !registered {
+		csr, key, err := dev.newRequest()
+		if err != nil {
+			return nil, fmt.Errorf("dev.newRequest: %w", err)
+		}
+		findClient, err = clientService.RegisterClient(ctx, user.ID, csr)
+		if err != nil {
+			return nil, fmt.Errorf("clientService.RegisterClient: %w", err)
+		}
+		r.SetHeader(rest.ClientIDHeaderName, findClient.ID)
+		if findClient.Certificate != "" {
+			err = dev.save(findClient.Certificate, key)
+			if err != nil {
+				return nil, fmt.Errorf("dev.save: %w", err)
+			}
+			// connections made before have no certificate
+			r.GetClient().CloseIdleConnections()
+			r.SetAuthToken("")
+		}
+	}
+
+	s := &session{
+		repository:    repository,
+		clientService: clientService,
+		user:          user,
+		client:        findClient,
+		wd:            conf.WorkingDir,
+	}
+	if err := s.openDealer(conf.UserPassword); err != nil {
+		return nil, fmt.Errorf("s.openDealer: %w", err)
+	}
+	if err := s.migrateVault(ctx); err != nil {
+		return nil, fmt.Errorf("s.migrateVault: %w", err)
+	}
+	// a password change that moved the vault to a random key was interrupted
+	pending, err := s.rekeyPending()
+	if err != nil {
+		return nil, fmt.Errorf("s.rekeyPending: %w", err)
+	}
+	if pending && s.user.Vault != nil {
+		if err := s.finishRekey(ctx); err != nil {
+			return nil, fmt.Errorf("s.finishRekey: %w", err)
 		}
 	}
+	return s, nil
+}
 
-	userRepo := fs.NewUserRepository(conf.WorkingDir +
+func newRepository(wd string) (*fs.Repository, *fs.ClientRepository) {
+	userRepo := fs.NewUserRepository(wd +
 		string(os.PathSeparator) + "user.json")
 
-	clientRepo := fs.NewClientRepository(conf.WorkingDir+
+	clientRepo := fs.NewClientRepository(wd+
 		string(os.PathSeparator)+"client.json",
 		"client-tm-*")
 
-	binaryRepo := fs.NewBaseRepository(conf.WorkingDir+
+	binaryRepo := fs.NewBaseRepository(wd+
 		string(os.PathSeparator)+"binary.json",
 		"binary-tm-*", &model.Binary{})
 
-	cardRepo := fs.NewBaseRepository(conf.WorkingDir+
+	cardRepo := fs.NewBaseRepository(wd+
 		string(os.PathSeparator)+"card.json",
 		"card-tm-*", &model.Card{})
 
-	credentialsRepo := fs.NewBaseRepository(conf.WorkingDir+
+	credentialsRepo := fs.NewBaseRepository(wd+
 		string(os.PathSeparator)+"credentials.json",
 		"credentials-tm-*", &model.Credentials{})
 
-	textRepo := fs.NewBaseRepository(conf.WorkingDir+
+	textRepo := fs.NewBaseRepository(wd+
 		string(os.PathSeparator)+"text.json",
 		"text-tm-*", &model.Text{})
 
-	repository := fs.NewRepository(userRepo, clientRepo, binaryRepo,
-		cardRepo, credentialsRepo, textRepo)
+	return fs.NewRepository(userRepo, clientRepo, binaryRepo,
+		cardRepo, credentialsRepo, textRepo), clientRepo
+}
 
-	cert, err := tls.LoadX509KeyPair("certs/cert.pem", "certs/key.pem")
+// newRESTClient connects to the server, presenting the certificate of dev
+// when there is one. dev may be nil.
+func newRESTClient(conf *config.Config, dev *device) (*resty.Client, error) {
+	tlsConf, err := tlsConfig(conf, dev)
 	if err != nil {
-		return fmt.Errorf("tls.LoadX509KeyPair: %w", err)
+		return nil, fmt.Errorf("tlsConfig: %w", err)
 	}
-	r := resty.New().SetCertificates(cert).SetTLSClientConfig(&tls.Config{
-		// на маке не доверяет
-		InsecureSkipVerify: true,
-	}).SetBaseURL("https://" + conf.ServerAddress)
+	c := resty.New().SetTLSClientConfig(tlsConf).SetBaseURL("https://" + conf.ServerAddress)
+	// wrapped after the TLS setup, resty configures only *http.Transport
+	c.SetTransport(otelhttp.NewTransport(c.GetClient().Transport,
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return "HTTP " + r.Method
+		})))
+	return c, nil
+}
 
-	restRepo := rest.NewRESTRepositoryImpl(r)
+// openDealer unwraps the vault key with the password. Users registered
+// before vault keys were wrapped keep the key derived from id and login
+// until they change the password.
+func (s *session) openDealer(password string) error {
+	if s.user.Vault == nil {
+		dealer, err := s.legacyDealer()
+		if err != nil {
+			return err
+		}
+		s.dealer = dealer
+		return nil
+	}
+	key, err := unwrapVaultKey(password, *s.user.Vault)
+	if err != nil {
+		return err
+	}
+	dealer, err := crypto.NewDealerFromKey(key)
+	if err != nil {
+		return fmt.Errorf("crypto.NewDealerFromKey: %w", err)
+	}
+	s.dealer = dealer
+	return nil
+}
 
-	clientService := service.NewClientService(repository, restRepo)
+// lock drops the vault key, unlock has to be called before secrets can be
+// read or written again.
+func (s *session) lock() {
+	s.dealer = nil
+}
 
-	user, err := login(ctx, conf, repository, clientService)
+func (s *session) locked() bool {
+	return s.dealer == nil
+}
+
+func (s *session) unlock(password string) error {
+	err := auth.ComparePasswords(s.user.HashedPassword, loginPassword(s.user, password))
+	if err != nil {
+		return fmt.Errorf("auth.ComparePasswords: %w", err)
+	}
+	return s.openDealer(password)
+}
+
+// sync exchanges changes with the server and refreshes the client sync time.
+func (s *session) sync(ctx context.Context) (err error) {
+	ctx, span := tracer.Start(ctx, "session.sync")
+	defer func() { tracing.End(span, err) }()
+
+	err = DoSync(ctx, s.client, s.clientService, s.user.ID)
+	if err != nil {
+		return fmt.Errorf("DoSync: %w", err)
+	}
+	err = s.syncShares(ctx)
+	if err != nil {
+		return fmt.Errorf("s.syncShares: %w", err)
+	}
+	err = s.syncOrgs(ctx)
 	if err != nil {
-		return fmt.Errorf("login: %w", err)
+		return fmt.Errorf("s.syncOrgs: %w", err)
 	}
-	token, err := auth.GenerateToken(user.ID)
+	client, err := s.clientService.CheckClient(ctx, s.client.ID)
 	if err != nil {
-		return fmt.Errorf("auth.GenerateToken: %w", err)
+		return fmt.Errorf("clientService.CheckClient: %w", err)
+	}
+	s.client = client
+	return nil
+}
+
+func Do(ctx context.Context, conf *config.Config) error {
+	if conf.IsGenerate {
+		return DoGenerate(conf)
 	}
 
-	r.SetAuthToken(token)
+	if conf.IsProfile {
+		return DoProfile(conf)
+	}
 
-	findClient, err := clientRepo.FindClient(ctx)
-	if err != nil {
-		if !errors.Is(err, repo.ErrItemNotFound) {
-			return fmt.Errorf("clientRepo.FindClient: %w", err)
+	// status and logout work on the local files only
+	if conf.IsStatus {
+		return DoStatus(ctx, conf)
+	}
+	if conf.IsLogout {
+		return DoLogout(ctx, conf)
+	}
+
+	// the interactive session reads its commands from stdin anyway
+	p := prompt.New(os.Stdin, os.Stderr, conf.SecretsFromStdin || conf.IsRepl)
+	if err := readSecrets(conf, p); err != nil {
+		return fmt.Errorf("readSecrets: %w", err)
+	}
+
+	if conf.IsWipe && !conf.WipeAccount {
+		err := DoWipe(conf, p)
+		if err != nil {
+			return fmt.Errorf("DoWipe: %w", err)
 		}
-		findClient, err = clientService.RegisterClient(ctx, user.ID)
+		return nil
+	}
+
+	if conf.IsRegister {
+		err := DoRegister(ctx, conf)
 		if err != nil {
-			return fmt.Errorf("clientService.RegisterClient: %w", err)
+			return fmt.Errorf("DoRegister: %w", err)
 		}
+		return nil
 	}
 
-	if !conf.IsSync && conf.Action == "" {
-		return errors.New("action is empty and")
+	if conf.IsLogin {
+		err := DoLogin(ctx, conf)
+		if err != nil {
+			return fmt.Errorf("DoLogin: %w", err)
+		}
+		return nil
 	}
-	k := user.ID + user.Login
 
-	dealer, err := crypto.NewDealer(k)
+	if conf.IsRecover {
+		err := DoRecover(ctx, conf)
+		if err != nil {
+			return fmt.Errorf("DoRecover: %w", err)
+		}
+		return nil
+	}
+
+	s, err := openSession(ctx, conf)
 	if err != nil {
-		return fmt.Errorf("crypto.NewDealer %w", err)
+		return fmt.Errorf("openSession: %w", err)
+	}
+	s.confirm = func(question string) (bool, error) {
+		return confirm(p, conf, question)
+	}
+
+	if conf.IsRepl {
+		err := DoRepl(ctx, conf, s, p)
+		if err != nil {
+			return fmt.Errorf("DoRepl: %w", err)
+		}
+		return nil
+	}
+
+	if conf.IsImport {
+		err := DoImport(ctx, conf, s)
+		if err != nil {
+			return fmt.Errorf("DoImport: %w", err)
+		}
+		return nil
+	}
+
+	if conf.IsExport && conf.ExportFormat != "" {
+		err := DoExportPlain(ctx, conf, s, p)
+		if err != nil {
+			return fmt.Errorf("DoExportPlain: %w", err)
+		}
+		return nil
+	}
+
+	if conf.IsExport {
+		err := DoExport(ctx, conf, s)
+		if err != nil {
+			return fmt.Errorf("DoExport: %w", err)
+		}
+		return nil
+	}
+
+	if conf.IsRestore {
+		err := DoRestore(ctx, conf, s, p)
+		if err != nil {
+			return fmt.Errorf("DoRestore: %w", err)
+		}
+		return nil
+	}
+
+	if conf.IsShare {
+		err := DoShare(ctx, conf, s)
+		if err != nil {
+			return fmt.Errorf("DoShare: %w", err)
+		}
+		return nil
+	}
+
+	if conf.IsOrg {
+		err := DoOrg(ctx, conf, s)
+		if err != nil {
+			return fmt.Errorf("DoOrg: %w", err)
+		}
+		return nil
+	}
+
+	if conf.IsPasswd {
+		err := DoPasswd(ctx, conf, s)
+		if err != nil {
+			return fmt.Errorf("DoPasswd: %w", err)
+		}
+		return nil
+	}
+
+	if conf.IsWipe {
+		err := DoDeleteAccount(ctx, conf, s, p)
+		if err != nil {
+			return fmt.Errorf("DoDeleteAccount: %w", err)
+		}
+		return nil
+	}
+
+	if conf.IsEmergency {
+		err := DoEmergency(ctx, conf, s)
+		if err != nil {
+			return fmt.Errorf("DoEmergency: %w", err)
+		}
+		return nil
+	}
+
+	if conf.IsAudit {
+		err := DoAudit(ctx, conf, s)
+		if err != nil {
+			return fmt.Errorf("DoAudit: %w", err)
+		}
+		return nil
+	}
+
+	if conf.IsDevice {
+		err := DoDevice(ctx, conf, s)
+		if err != nil {
+			return fmt.Errorf("DoDevice: %w", err)
+		}
+		return nil
+	}
+
+	if !conf.IsSync && conf.Action == "" {
+		return errors.New("action is empty and")
 	}
 
 	logger.Log.Debug(fmt.Sprintf("id is %s", conf.ID))
 
 	if conf.IsFileFlagsParsed {
-		err := DoFile(ctx, conf, clientService, user)
+		err := DoFile(ctx, conf, s.clientService, s.dealer, s.user)
 		if err != nil {
 			return fmt.Errorf("DoFile: %w", err)
 		}
 	} else if conf.IsTextFlagsParsed {
-		err := DoText(ctx, conf, clientService, user)
+		err := DoText(ctx, conf, s.clientService, s.dealer, s.user)
 		if err != nil {
 			return fmt.Errorf("DoText: %w", err)
 		}
 	} else if conf.IsCardFlagsParsed {
-		err := DoCard(ctx, conf, clientService, dealer, user)
+		err := DoCard(ctx, conf, s.clientService, s.dealer, s.user)
 		if err != nil {
 			return fmt.Errorf("DoCard: %w", err)
 		}
 
 	} else if conf.IsCredentialsFlagsParsed {
-		err := DoCredentials(ctx, conf, clientService, dealer, user)
+		err := DoCredentials(ctx, conf, s.clientService, s.dealer, s.user)
 		if err != nil {
 			return fmt.Errorf("DoCredentials: %w", err)
 		}
 	} else if conf.IsSync {
-		err = DoSync(ctx, findClient, clientService, user.ID)
+		err = s.sync(ctx)
 		if err != nil {
-			return fmt.Errorf("DoSync: %w", err)
+			return fmt.Errorf("s.sync: %w", err)
 		}
 	} else {
 		logger.Log.Error("nothing to do", zap.Error(err))
 	}
 	return nil
 }
-
-func login(ctx context.Context, conf *config.Config, repository *fs.Repository,
-	clientService *service.ClientService) (model.User, error) {
-	user, err := repository.FindUser(ctx)
-
-	if err != nil {
-		if !errors.Is(err, repo.ErrItemNotFound) {
-			return model.User{}, fmt.Errorf("repository.FindUser: %w", err)
-		}
-		l := conf.UserLogin
-		password := conf.UserPassword
-		if strings.TrimSpace(l) == "" || strings.TrimSpace(password) == "" {
-			return model.User{}, errors.New("invalid credentials")
-		}
-		user, err = clientService.Login(ctx, l, password)
-		if err != nil {
-			if !errors.Is(err, repo.ErrItemNotFound) {
-				return model.User{}, fmt.Errorf("clientService.Login: %w", err)
-			}
-			user, err = clientService.RegisterUser(ctx, l, password)
-			if err != nil {
-				return model.User{}, fmt.Errorf("clientService.RegisterUser: %w", err)
-			}
-		}
-
-	}
-	return user, nil
-}
@@ -0,0 +1,108 @@
+package command
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/crypto"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// knownContactsFile keeps the fingerprints of the keys of other users that
+// were trusted, one "login fingerprint" per line.
+const knownContactsFile = "known_contacts"
+
+var ErrContactKeyChanged = errors.New("contact key changed")
+
+// contactKey decodes the key the server published for login before a vault
+// or item key is wrapped for it. A key seen for the first time is shown by
+// its fingerprint and kept once the user trusts it, a key other than the
+// kept one is refused.
+func (s *session) contactKey(ctx context.Context, login, published string) ([]byte, error) {
+	pub, err := base64.StdEncoding.DecodeString(published)
+	if err != nil {
+		return nil, fmt.Errorf("base64.StdEncoding.DecodeString: %w", err)
+	}
+	got := crypto.Fingerprint(pub)
+	if login == s.user.Login {
+		own, _, err := s.userKeys(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("s.userKeys: %w", err)
+		}
+		if got != crypto.Fingerprint(own) {
+			return nil, fmt.Errorf("%w: the server has key %s for you", ErrContactKeyChanged, got)
+		}
+		return pub, nil
+	}
+
+	path := filepath.Join(s.wd, knownContactsFile)
+	known, err := loadKnownContacts(path)
+	if err != nil {
+		return nil, fmt.Errorf("loadKnownContacts: %w", err)
+	}
+	if want, ok := known[login]; ok {
+		if got != want {
+			return nil, fmt.Errorf("%w: %s has key %s, %s is trusted, remove it from %s if the change is expected",
+				ErrContactKeyChanged, login, got, want, path)
+		}
+		return pub, nil
+	}
+
+	if s.confirm == nil {
+		return nil, fmt.Errorf("the key of %s is not trusted yet", login)
+	}
+	ok, err := s.confirm(fmt.Sprintf("%s has key %s, compare it with the one share -fingerprint "+
+		"shows them. Trust it?", login, got))
+	if err != nil {
+		return nil, fmt.Errorf("confirm: %w", err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("the key of %s is not trusted", login)
+	}
+	err = addKnownContact(path, login, got)
+	if err != nil {
+		return nil, fmt.Errorf("addKnownContact: %w", err)
+	}
+	return pub, nil
+}
+
+func loadKnownContacts(path string) (map[string]string, error) {
+	known := make(map[string]string)
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return known, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("os.Open: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 {
+			known[fields[0]] = fields[1]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scanner.Err: %w", err)
+	}
+	return known, nil
+}
+
+func addKnownContact(path, login, fingerprint string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("os.OpenFile: %w", err)
+	}
+	_, err = fmt.Fprintf(f, "%s %s\n", login, fingerprint)
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("fmt.Fprintf: %w", err)
+	}
+	return f.Close()
+}
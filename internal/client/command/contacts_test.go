@@ -0,0 +1,119 @@
+package command
+
+import (
+	"context"
+	"encoding/base64"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/crypto"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newPublicKey(t *testing.T) ([]byte, string) {
+	t.Helper()
+	pub, _, err := crypto.GenerateKeyPair()
+	require.NoError(t, err)
+	return pub, base64.StdEncoding.EncodeToString(pub)
+}
+
+func TestContactKey(t *testing.T) {
+	pub, published := newPublicKey(t)
+	_, other := newPublicKey(t)
+
+	tests := []struct {
+		name      string
+		known     string
+		published string
+		confirm   func(string) (bool, error)
+		wantAsked bool
+		wantKnown string
+		wantErr   string
+	}{
+		{
+			name:      "first seen and trusted",
+			published: published,
+			confirm:   func(string) (bool, error) { return true, nil },
+			wantAsked: true,
+			wantKnown: "bob " + crypto.Fingerprint(pub) + "\n",
+		},
+		{
+			name:      "trusted before",
+			known:     "bob " + crypto.Fingerprint(pub) + "\n",
+			published: published,
+			wantKnown: "bob " + crypto.Fingerprint(pub) + "\n",
+		},
+		{
+			name:      "changed",
+			known:     "bob " + crypto.Fingerprint(pub) + "\n",
+			published: other,
+			confirm:   func(string) (bool, error) { return true, nil },
+			wantKnown: "bob " + crypto.Fingerprint(pub) + "\n",
+			wantErr:   ErrContactKeyChanged.Error(),
+		},
+		{
+			name:      "first seen and declined",
+			published: published,
+			confirm:   func(string) (bool, error) { return false, nil },
+			wantAsked: true,
+			wantErr:   "the key of bob is not trusted",
+		},
+		{
+			name:      "first seen without a terminal",
+			published: published,
+			wantErr:   "the key of bob is not trusted yet",
+		},
+		{
+			name:      "other contact trusted before",
+			known:     "carol " + crypto.Fingerprint(pub) + "\n",
+			published: published,
+			confirm:   func(string) (bool, error) { return true, nil },
+			wantAsked: true,
+			wantKnown: "carol " + crypto.Fingerprint(pub) + "\nbob " + crypto.Fingerprint(pub) + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wd := t.TempDir()
+			path := filepath.Join(wd, knownContactsFile)
+			if tt.known != "" {
+				require.NoError(t, os.WriteFile(path, []byte(tt.known), 0600))
+			}
+			asked := false
+			s := &session{user: model.User{Login: "alice"}, wd: wd}
+			if tt.confirm != nil {
+				s.confirm = func(q string) (bool, error) {
+					asked = true
+					assert.Contains(t, q, crypto.Fingerprint(pub))
+					return tt.confirm(q)
+				}
+			}
+
+			got, err := s.contactKey(context.Background(), "bob", tt.published)
+			assert.Equal(t, tt.wantAsked, asked)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, pub, got)
+			}
+
+			data, err := os.ReadFile(path)
+			if tt.wantKnown == "" {
+				assert.ErrorIs(t, err, os.ErrNotExist)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantKnown, string(data))
+		})
+	}
+}
+
+func TestContactKeyMalformed(t *testing.T) {
+	s := &session{user: model.User{Login: "alice"}, wd: t.TempDir()}
+	_, err := s.contactKey(context.Background(), "bob", "not base64!")
+	assert.Error(t, err)
+}
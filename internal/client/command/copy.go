@@ -0,0 +1,32 @@
+package command
+
+import (
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/clipboard"
+	"io"
+	"time"
+)
+
+// copyField puts a field of e on the clipboard, the default one when name
+// is empty, and clears it again after timeout.
+func copyField(w io.Writer, e entry, name string, timeout time.Duration) error {
+	f, ok := e.defaultField()
+	if name != "" {
+		f, ok = e.field(name)
+	}
+	if !ok {
+		return fmt.Errorf("%s has no field %q", e.kind, name)
+	}
+	if err := clipboard.Write(f.value); err != nil {
+		return fmt.Errorf("clipboard.Write: %w", err)
+	}
+	if timeout <= 0 {
+		fmt.Fprintf(w, "copied %s to clipboard\n", f.name)
+		return nil
+	}
+	if err := clipboard.ClearAfter(f.value, timeout); err != nil {
+		return fmt.Errorf("clipboard.ClearAfter: %w", err)
+	}
+	fmt.Fprintf(w, "copied %s to clipboard, clearing in %s\n", f.name, timeout)
+	return nil
+}
@@ -3,12 +3,15 @@ package command
 import (
 	"context"
 	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/config"
 	"github.com/denis-oreshkevich/gophkeeper/internal/client/service"
-	"github.com/denis-oreshkevich/gophkeeper/internal/shared/config"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/vault"
 	"github.com/denis-oreshkevich/gophkeeper/internal/shared/crypto"
 	"github.com/denis-oreshkevich/gophkeeper/internal/shared/logger"
 	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
 	"github.com/google/uuid"
+	"os"
+	"strings"
 	"time"
 )
 
@@ -21,37 +24,45 @@ func DoCredentials(ctx context.Context, conf *config.Config,
 		if err != nil {
 			return fmt.Errorf("clientService.FindCredentialsByID: %w", err)
 		}
-		login, err := dealer.Decrypt(byID.Login)
-		if err != nil {
-			return fmt.Errorf("dealer.Decrypt(byID.Login): %w", err)
+		if conf.CopyField != "" {
+			e, err := credEntry(dealer, &byID)
+			if err != nil {
+				return fmt.Errorf("credEntry: %w", err)
+			}
+			return copyField(os.Stdout, e, conf.CopyField, conf.ClipTimeout)
 		}
-		password, err := dealer.Decrypt(byID.Password)
+		login, password, err := vault.OpenCredentials(dealer, byID)
 		if err != nil {
-			return fmt.Errorf("dealer.Decrypt(byID.Password): %w", err)
+			return fmt.Errorf("vault.OpenCredentials: %w", err)
 		}
-
-		logger.Log.Info(fmt.Sprintf("Login: %s, password: %s",
-			login, password))
+		if !conf.ShowSecrets {
+			password = strings.Repeat("*", len(password))
+		}
+		// secrets go to stdout only, never to the logger
+		fmt.Printf("Login: %s, password: %s\n", login, password)
 	case config.ActionSave:
-		id := uuid.New()
-		crLogin, err := dealer.Encrypt(conf.CredentialsLogin)
-		if err != nil {
-			return fmt.Errorf("dealer.Encrypt(conf.CredentialsLogin): %w", err)
+		if conf.Generate {
+			res, err := generate(conf)
+			if err != nil {
+				return fmt.Errorf("generate: %w", err)
+			}
+			conf.CredentialsPassword = res.Value
+			fmt.Printf("generated password, entropy: %.1f bits\n", res.Entropy)
 		}
-
-		crPwd, err := dealer.Encrypt(conf.CredentialsPassword)
+		err := model.ValidateCredentialsInput(conf.CredentialsLogin, conf.CredentialsPassword)
 		if err != nil {
-			return fmt.Errorf("dealer.Encrypt(conf.CredentialsPassword): %w", err)
+			return fmt.Errorf("model.ValidateCredentialsInput: %w", err)
 		}
-		cred := model.Credentials{
-			ID:          id.String(),
-			Login:       crLogin,
-			Password:    crPwd,
-			New:         conf.IsNew,
-			UserID:      user.ID,
-			Status:      model.StatusActive,
-			ModifiedTms: time.Now().UTC(),
+		cred, err := vault.SealCredentials(dealer, conf.CredentialsLogin, conf.CredentialsPassword)
+		if err != nil {
+			return fmt.Errorf("vault.SealCredentials: %w", err)
 		}
+		id := uuid.New()
+		cred.ID = id.String()
+		cred.New = conf.IsNew
+		cred.UserID = user.ID
+		cred.Status = model.StatusActive
+		cred.ModifiedTms = time.Now().UTC()
 		err = clientService.SaveCredentials(ctx, cred)
 		if err != nil {
 			return fmt.Errorf("clientService.SaveCredentials: %w", err)
@@ -0,0 +1,49 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/config"
+	"time"
+)
+
+// DoDevice lists the clients of the account or revokes one. A revoked
+// client with a certificate can't reach the server any more.
+func DoDevice(ctx context.Context, conf *config.Config, s *session) error {
+	switch conf.DeviceAction {
+	case "revoke":
+		if conf.ID == "" {
+			return errors.New("client id is required, set -id")
+		}
+		if conf.ID == s.client.ID && !conf.AssumeYes {
+			return errors.New("this is the current client, set -yes to revoke it anyway")
+		}
+		err := s.clientService.RevokeClient(ctx, conf.ID)
+		if err != nil {
+			return fmt.Errorf("clientService.RevokeClient: %w", err)
+		}
+		fmt.Printf("revoked client %s\n", conf.ID)
+		return nil
+	default:
+		clients, err := s.clientService.FindClients(ctx)
+		if err != nil {
+			return fmt.Errorf("clientService.FindClients: %w", err)
+		}
+		for _, c := range clients {
+			auth := "token"
+			if c.CertFingerprint != "" {
+				auth = "cert " + c.CertFingerprint[:16]
+			}
+			state := "active"
+			if c.RevokedTms != nil {
+				state = "revoked " + c.RevokedTms.Local().Format(time.DateTime)
+			}
+			if c.ID == s.client.ID {
+				state += ", this client"
+			}
+			fmt.Printf("%s\t%s\t%s\t%s\n", c.ID, c.SyncTms.Local().Format(time.DateTime), auth, state)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,253 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/config"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/repo"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/transfer"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/crypto"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"github.com/google/uuid"
+	"os"
+	"strings"
+	"time"
+)
+
+// DoEmergency manages the trusted contacts of the user and the vaults the
+// user is a trusted contact for.
+func DoEmergency(ctx context.Context, conf *config.Config, s *session) error {
+	if conf.EmergencyAction == "list" {
+		return listEmergencyAccess(ctx, s)
+	}
+	if strings.TrimSpace(conf.ShareWith) == "" {
+		return errors.New("the other user is required, set -with")
+	}
+	if conf.ShareWith == s.user.Login {
+		return errors.New("you can't be your own trusted contact")
+	}
+	if conf.EmergencyAction == "grant" {
+		return grantEmergencyAccess(ctx, conf, s)
+	}
+
+	// the owner acts on the access of the contact, the contact on the one
+	// of the owner
+	asOwner := conf.EmergencyAction != "request" && conf.EmergencyAction != "open"
+	e, err := findEmergencyAccess(ctx, s, conf.ShareWith, asOwner,
+		conf.EmergencyAction == "revoke")
+	if err != nil {
+		return err
+	}
+	switch conf.EmergencyAction {
+	case "revoke":
+		err = s.clientService.RevokeEmergencyAccess(ctx, e.ID)
+		if err != nil {
+			return fmt.Errorf("clientService.RevokeEmergencyAccess: %w", err)
+		}
+		fmt.Printf("revoked the emergency access between %s and %s\n", e.OwnerLogin, e.ContactLogin)
+	case "open":
+		return openEmergencyVault(ctx, s, e)
+	default:
+		err = s.clientService.MoveEmergencyAccess(ctx, e.ID, conf.EmergencyAction)
+		if err != nil {
+			return fmt.Errorf("clientService.MoveEmergencyAccess: %w", err)
+		}
+		switch conf.EmergencyAction {
+		case "request":
+			fmt.Printf("requested access to the vault of %s, approved at %s unless declined\n",
+				e.OwnerLogin, time.Now().Add(time.Duration(e.WaitHours)*time.Hour).Format(time.DateTime))
+		case "approve":
+			fmt.Printf("approved the request of %s\n", e.ContactLogin)
+		case "decline":
+			fmt.Printf("declined the request of %s\n", e.ContactLogin)
+		}
+	}
+	return nil
+}
+
+// grantEmergencyAccess wraps the vault key for the contact, the server
+// hands it out once a request is approved.
+func grantEmergencyAccess(ctx context.Context, conf *config.Config, s *session) error {
+	if conf.EmergencyWait < time.Hour || conf.EmergencyWait%time.Hour != 0 {
+		return errors.New("waiting period must be whole hours, e.g. -wait 72h")
+	}
+	err := s.grantEmergencyKey(ctx, conf.ShareWith, int(conf.EmergencyWait/time.Hour))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s is your trusted contact, waiting period %s\n", conf.ShareWith, conf.EmergencyWait)
+	return nil
+}
+
+// grantEmergencyKey wraps the current vault key for the contact. An
+// access the contact has already is granted again.
+func (s *session) grantEmergencyKey(ctx context.Context, login string, waitHours int) error {
+	contact, err := s.clientService.FindPublicKey(ctx, login)
+	if err != nil {
+		if errors.Is(err, repo.ErrItemNotFound) {
+			return fmt.Errorf("%s has no key yet, they have to sync once", login)
+		}
+		return fmt.Errorf("clientService.FindPublicKey: %w", err)
+	}
+	pub, err := s.contactKey(ctx, login, contact.PublicKey)
+	if err != nil {
+		return fmt.Errorf("s.contactKey: %w", err)
+	}
+	wrapped, err := wrapKey(pub, s.dealer.Key())
+	if err != nil {
+		return err
+	}
+	err = s.clientService.GrantEmergencyAccess(ctx, model.EmergencyAccess{
+		ID:           uuid.New().String(),
+		ContactLogin: login,
+		WaitHours:    waitHours,
+		WrappedKey:   wrapped,
+	})
+	if err != nil {
+		return fmt.Errorf("clientService.GrantEmergencyAccess: %w", err)
+	}
+	return nil
+}
+
+func listEmergencyAccess(ctx context.Context, s *session) error {
+	res, err := s.clientService.FindEmergencyAccess(ctx)
+	if err != nil {
+		return fmt.Errorf("clientService.FindEmergencyAccess: %w", err)
+	}
+	if len(res) == 0 {
+		fmt.Println("no emergency access")
+		return nil
+	}
+	for _, e := range res {
+		line := fmt.Sprintf("contact of %s", e.OwnerLogin)
+		if e.OwnerLogin == s.user.Login {
+			line = fmt.Sprintf("trusted contact %s", e.ContactLogin)
+		}
+		line = fmt.Sprintf("%s\t%s\twait %dh", line, strings.ToLower(string(e.Status)), e.WaitHours)
+		if e.Status == model.EmergencyRequested {
+			line += "\tapproved at " + e.ApprovesAt().Local().Format(time.DateTime)
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
+
+// findEmergencyAccess looks up the access between the user and login, the
+// user is the owner when asOwner is set. With either both sides match.
+func findEmergencyAccess(ctx context.Context, s *session, login string,
+	asOwner, either bool) (model.EmergencyAccess, error) {
+	res, err := s.clientService.FindEmergencyAccess(ctx)
+	if err != nil {
+		return model.EmergencyAccess{}, fmt.Errorf("clientService.FindEmergencyAccess: %w", err)
+	}
+	for _, e := range res {
+		owned := e.OwnerLogin == s.user.Login && e.ContactLogin == login
+		granted := e.ContactLogin == s.user.Login && e.OwnerLogin == login
+		if (owned && (asOwner || either)) || (granted && (!asOwner || either)) {
+			return e, nil
+		}
+	}
+	if asOwner {
+		return model.EmergencyAccess{}, fmt.Errorf("%s is not your trusted contact", login)
+	}
+	return model.EmergencyAccess{}, fmt.Errorf("you are not a trusted contact of %s", login)
+}
+
+// openEmergencyVault copies the vault of the owner into the vault of the
+// user, items the user already has are skipped.
+func openEmergencyVault(ctx context.Context, s *session, e model.EmergencyAccess) error {
+	if e.Status != model.EmergencyApproved {
+		return fmt.Errorf("access to the vault of %s is %s", e.OwnerLogin,
+			strings.ToLower(string(e.Status)))
+	}
+	_, priv, err := s.userKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("s.userKeys: %w", err)
+	}
+	key, err := unwrapKey(priv, e.WrappedKey)
+	if err != nil {
+		return err
+	}
+	dealer, err := crypto.NewDealerFromKey(key)
+	if err != nil {
+		return fmt.Errorf("crypto.NewDealerFromKey: %w", err)
+	}
+	v, err := s.clientService.FindEmergencyVault(ctx, e.ID)
+	if err != nil {
+		return fmt.Errorf("clientService.FindEmergencyVault: %w", err)
+	}
+	items, err := openEmergencyItems(dealer, v)
+	if err != nil {
+		return err
+	}
+
+	existing, err := s.loadItems(ctx)
+	if err != nil {
+		return fmt.Errorf("s.loadItems: %w", err)
+	}
+	seen := make(map[string]bool)
+	for _, it := range existing {
+		seen[it.Key()] = true
+	}
+	report := transferReport{saved: make(map[transfer.Kind]int)}
+	for _, it := range items {
+		if seen[it.Key()] {
+			report.duplicates++
+			continue
+		}
+		seen[it.Key()] = true
+		if err := s.saveItem(ctx, it); err != nil {
+			return fmt.Errorf("s.saveItem: %w", err)
+		}
+		report.saved[it.Kind]++
+	}
+	fmt.Printf("vault of %s: ", e.OwnerLogin)
+	report.print(os.Stdout, "copied", false)
+	return nil
+}
+
+func openEmergencyItems(dealer *crypto.Dealer, v model.EmergencyVault) ([]transfer.Item, error) {
+	var res []transfer.Item
+	for i := range v.Credentials {
+		if v.Credentials[i].Status == model.StatusDeleted {
+			continue
+		}
+		it, err := credItem(dealer, &v.Credentials[i])
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, it)
+	}
+	for i := range v.Cards {
+		if v.Cards[i].Status == model.StatusDeleted {
+			continue
+		}
+		it, err := cardItem(dealer, &v.Cards[i])
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, it)
+	}
+	for _, t := range v.Texts {
+		if t.Status == model.StatusDeleted {
+			continue
+		}
+		it, err := textItem(dealer, t)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, it)
+	}
+	for _, b := range v.Binaries {
+		if b.Status == model.StatusDeleted {
+			continue
+		}
+		it, err := fileItem(dealer, b)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, it)
+	}
+	return res, nil
+}
@@ -0,0 +1,270 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/repo"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/vault"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/crypto"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	kindCred = "cred"
+	kindCard = "card"
+	kindText = "text"
+	kindFile = "file"
+)
+
+const titleLen = 40
+
+// field is a single displayable value of an entry. Secret fields have
+// masked set and are shown only on request.
+type field struct {
+	name   string
+	value  string
+	masked string
+}
+
+func (f field) secret() bool {
+	return f.masked != ""
+}
+
+func (f field) show(reveal bool) string {
+	if f.secret() && !reveal {
+		return f.masked
+	}
+	return f.value
+}
+
+// entry is a decrypted vault item as the interactive session sees it.
+type entry struct {
+	id     string
+	kind   string
+	title  string
+	fields []field
+}
+
+func (e entry) field(name string) (field, bool) {
+	for _, f := range e.fields {
+		if f.name == name {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+// defaultField is copied when no field is named: the first secret one,
+// otherwise the first field.
+func (e entry) defaultField() (field, bool) {
+	for _, f := range e.fields {
+		if f.secret() {
+			return f, true
+		}
+	}
+	if len(e.fields) == 0 {
+		return field{}, false
+	}
+	return e.fields[0], true
+}
+
+func (e entry) matches(term string) bool {
+	term = strings.ToLower(term)
+	if strings.Contains(strings.ToLower(e.title), term) {
+		return true
+	}
+	for _, f := range e.fields {
+		if !f.secret() && strings.Contains(strings.ToLower(f.value), term) {
+			return true
+		}
+	}
+	return false
+}
+
+func secretField(name, value string) field {
+	return field{name: name, value: value, masked: strings.Repeat("*", len(value))}
+}
+
+func (s *session) loadEntries(ctx context.Context) ([]entry, error) {
+	var res []entry
+
+	creds, err := s.clientService.FindCredentialsByUserID(ctx, s.user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("clientService.FindCredentialsByUserID: %w", err)
+	}
+	for _, c := range creds {
+		e, err := credEntry(s.dealer, c)
+		if err != nil {
+			return nil, fmt.Errorf("credEntry: %w", err)
+		}
+		res = append(res, e)
+	}
+
+	cards, err := s.clientService.FindCardsByUserID(ctx, s.user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("clientService.FindCardsByUserID: %w", err)
+	}
+	for _, c := range cards {
+		e, err := cardEntry(s.dealer, c)
+		if err != nil {
+			return nil, fmt.Errorf("cardEntry: %w", err)
+		}
+		res = append(res, e)
+	}
+
+	texts, err := s.clientService.FindTextsByUserID(ctx, s.user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("clientService.FindTextsByUserID: %w", err)
+	}
+	for _, t := range texts {
+		e, err := textEntry(s.dealer, t)
+		if err != nil {
+			return nil, fmt.Errorf("textEntry: %w", err)
+		}
+		res = append(res, e)
+	}
+
+	binaries, err := s.clientService.FindBinariesByUserID(ctx, s.user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("clientService.FindBinariesByUserID: %w", err)
+	}
+	for _, b := range binaries {
+		e, err := fileEntry(s.dealer, b)
+		if err != nil {
+			return nil, fmt.Errorf("fileEntry: %w", err)
+		}
+		res = append(res, e)
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].kind != res[j].kind {
+			return res[i].kind < res[j].kind
+		}
+		return strings.ToLower(res[i].title) < strings.ToLower(res[j].title)
+	})
+	return res, nil
+}
+
+// findEntry looks an entry up by its id or by a unique id prefix.
+func (s *session) findEntry(ctx context.Context, id string) (entry, error) {
+	entries, err := s.loadEntries(ctx)
+	if err != nil {
+		return entry{}, fmt.Errorf("s.loadEntries: %w", err)
+	}
+	var found []entry
+	for _, e := range entries {
+		if e.id == id {
+			return e, nil
+		}
+		if strings.HasPrefix(e.id, id) {
+			found = append(found, e)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return entry{}, repo.ErrItemNotFound
+	case 1:
+		return found[0], nil
+	default:
+		return entry{}, errors.New("id prefix is ambiguous")
+	}
+}
+
+func (s *session) deleteEntry(ctx context.Context, e entry) error {
+	var err error
+	svc := s.clientService
+	switch e.kind {
+	case kindCred:
+		err = svc.DeleteCredentialsByID(ctx, e.id)
+	case kindCard:
+		err = svc.DeleteCardByID(ctx, e.id)
+	case kindText:
+		err = svc.DeleteTextByID(ctx, e.id)
+	case kindFile:
+		err = svc.DeleteBinaryByID(ctx, e.id)
+	}
+	if err != nil {
+		return fmt.Errorf("delete %s: %w", e.kind, err)
+	}
+	return nil
+}
+
+func credEntry(dealer *crypto.Dealer, c *model.Credentials) (entry, error) {
+	login, password, err := vault.OpenCredentials(dealer, *c)
+	if err != nil {
+		return entry{}, fmt.Errorf("vault.OpenCredentials: %w", err)
+	}
+	return entry{
+		id:    c.ID,
+		kind:  kindCred,
+		title: login,
+		fields: []field{
+			{name: "login", value: login},
+			secretField("password", password),
+		},
+	}, nil
+}
+
+func cardEntry(dealer *crypto.Dealer, c *model.Card) (entry, error) {
+	in, brand, err := vault.OpenCard(dealer, *c)
+	if err != nil {
+		return entry{}, fmt.Errorf("vault.OpenCard: %w", err)
+	}
+	fields := []field{
+		{name: "num", value: model.FormatCardNum(in.Num), masked: model.MaskCardNum(in.Num)},
+		{name: "exp", value: in.ExpMonth + "/" + in.ExpYear},
+		secretField("cvc", in.CVC),
+		{name: "holder", value: in.HolderName},
+		{name: "brand", value: string(brand)},
+	}
+	if in.PIN != "" {
+		fields = append(fields, secretField("pin", in.PIN))
+	}
+	if !in.BillingAddress.IsEmpty() {
+		fields = append(fields, field{name: "billing", value: formatAddress(in.BillingAddress)})
+	}
+	return entry{
+		id:     c.ID,
+		kind:   kindCard,
+		title:  string(brand) + " " + model.MaskCardNum(in.Num),
+		fields: fields,
+	}, nil
+}
+
+func textEntry(dealer *crypto.Dealer, t *model.Text) (entry, error) {
+	txt, err := vault.OpenText(dealer, t.Txt)
+	if err != nil {
+		return entry{}, fmt.Errorf("vault.OpenText: %w", err)
+	}
+	title, _, _ := strings.Cut(txt, "\n")
+	if r := []rune(title); len(r) > titleLen {
+		title = string(r[:titleLen]) + "..."
+	}
+	return entry{
+		id:     t.ID,
+		kind:   kindText,
+		title:  title,
+		fields: []field{{name: "text", value: txt}},
+	}, nil
+}
+
+func fileEntry(dealer *crypto.Dealer, b *model.Binary) (entry, error) {
+	data, err := vault.OpenBinary(dealer, b.Data)
+	if err != nil {
+		return entry{}, fmt.Errorf("vault.OpenBinary: %w", err)
+	}
+	size := strconv.Itoa(len(data)) + " bytes"
+	return entry{
+		id:    b.ID,
+		kind:  kindFile,
+		title: b.Name,
+		fields: []field{
+			{name: "name", value: b.Name},
+			{name: "size", value: size},
+		},
+	}, nil
+}
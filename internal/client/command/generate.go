@@ -0,0 +1,64 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/config"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/generator"
+	"os"
+	"path/filepath"
+)
+
+func DoGenerate(conf *config.Config) error {
+	res, err := generate(conf)
+	if err != nil {
+		return fmt.Errorf("generate: %w", err)
+	}
+	if conf.GenCopy {
+		e := entry{kind: "generated", fields: []field{secretField("password", res.Value)}}
+		if err := copyField(os.Stdout, e, "", conf.ClipTimeout); err != nil {
+			return fmt.Errorf("copyField: %w", err)
+		}
+	} else {
+		fmt.Println(res.Value)
+	}
+	// entropy goes to stderr, so stdout holds only the password
+	fmt.Fprintf(os.Stderr, "entropy: %.1f bits\n", res.Entropy)
+	return nil
+}
+
+// generate builds a password or, when words are requested, a passphrase.
+// Site rules narrow the password options.
+func generate(conf *config.Config) (generator.Result, error) {
+	if conf.GenWords > 0 {
+		return generator.Passphrase(generator.PassphraseOptions{
+			Words:      conf.GenWords,
+			Separator:  conf.GenSeparator,
+			Capitalize: conf.GenCapitalize,
+			Number:     conf.GenNumber,
+		})
+	}
+	o := generator.Options{
+		Length:      conf.GenLength,
+		Lower:       conf.GenLower,
+		Upper:       conf.GenUpper,
+		Digits:      conf.GenDigits,
+		Symbols:     conf.GenSymbols,
+		NoAmbiguous: conf.GenNoAmbiguous,
+		Exclude:     conf.GenExclude,
+	}
+	if conf.GenSite != "" {
+		path := conf.GenRulesFile
+		if path == "" {
+			path = filepath.Join(conf.WorkingDir, "rules.json")
+		}
+		rules, err := generator.LoadRules(path)
+		if err != nil && (conf.GenRulesFile != "" || !errors.Is(err, os.ErrNotExist)) {
+			return generator.Result{}, fmt.Errorf("generator.LoadRules: %w", err)
+		}
+		if r, ok := generator.FindRule(rules, conf.GenSite); ok {
+			o = r.Apply(o)
+		}
+	}
+	return generator.Password(o)
+}
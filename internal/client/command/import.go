@@ -0,0 +1,130 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/config"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/transfer"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// importFormats picks a format by file extension when -format is not set.
+var importFormats = map[string]string{
+	".json": transfer.FormatBitwarden,
+	".xml":  transfer.FormatKeePass,
+	".1pux": transfer.FormatOnePassword,
+	".csv":  transfer.FormatCSV,
+}
+
+type transferReport struct {
+	saved      map[transfer.Kind]int
+	duplicates int
+	errors     []string
+	warnings   []string
+}
+
+func DoImport(ctx context.Context, conf *config.Config, s *session) error {
+	format := conf.ImportFormat
+	if format == "" {
+		format = importFormats[strings.ToLower(filepath.Ext(conf.Filename))]
+		if format == "" {
+			return fmt.Errorf("can't detect format of %q, set -format", conf.Filename)
+		}
+	}
+	parser, err := transfer.NewParser(format, transfer.Options{Mapping: conf.ImportMapping})
+	if err != nil {
+		return fmt.Errorf("transfer.NewParser: %w", err)
+	}
+	data, err := os.ReadFile(conf.Filename)
+	if err != nil {
+		return fmt.Errorf("os.ReadFile: %w", err)
+	}
+	entries, err := parser.Parse(data)
+	if err != nil {
+		return fmt.Errorf("parser.Parse: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	if !conf.ImportKeepDuplicates {
+		existing, err := s.loadItems(ctx)
+		if err != nil {
+			return fmt.Errorf("s.loadItems: %w", err)
+		}
+		for _, it := range existing {
+			seen[it.Key()] = true
+		}
+	}
+
+	report := transferReport{saved: make(map[transfer.Kind]int)}
+	now := time.Now()
+	for _, e := range entries {
+		if err := importEntry(ctx, s, e, now, seen, conf, &report); err != nil {
+			report.errors = append(report.errors, fmt.Sprintf("%s: %v", e.Ref, err))
+		}
+	}
+	report.print(os.Stdout, "imported", conf.DryRun)
+	return nil
+}
+
+// importEntry validates all items of the entry first, so an entry is
+// imported either whole or not at all.
+func importEntry(ctx context.Context, s *session, e transfer.Entry, now time.Time,
+	seen map[string]bool, conf *config.Config, report *transferReport) error {
+	if e.Err != nil {
+		return e.Err
+	}
+	var warnings []string
+	for _, it := range e.Items {
+		warning, err := validateItem(it, now)
+		if err != nil {
+			return fmt.Errorf("%s: %w", it.Kind, err)
+		}
+		if warning != nil {
+			warnings = append(warnings, fmt.Sprintf("%s: %s: %v", e.Ref, it.Kind, warning))
+		}
+	}
+	report.warnings = append(report.warnings, warnings...)
+	for _, it := range e.Items {
+		key := it.Key()
+		if !conf.ImportKeepDuplicates && seen[key] {
+			report.duplicates++
+			continue
+		}
+		seen[key] = true
+		if !conf.DryRun {
+			if err := s.saveItem(ctx, it); err != nil {
+				return fmt.Errorf("s.saveItem: %w", err)
+			}
+		}
+		report.saved[it.Kind]++
+	}
+	return nil
+}
+
+func (r transferReport) print(w io.Writer, verb string, dryRun bool) {
+	if dryRun {
+		fmt.Fprintln(w, "dry run, nothing was saved")
+		verb = "would be " + verb
+	}
+	fmt.Fprintf(w, "%s: %d cred, %d card, %d text, %d file\n", verb,
+		r.saved[transfer.KindCredentials], r.saved[transfer.KindCard],
+		r.saved[transfer.KindText], r.saved[transfer.KindFile])
+	fmt.Fprintf(w, "duplicates skipped: %d\n", r.duplicates)
+	if len(r.warnings) > 0 {
+		fmt.Fprintf(w, "kept with warnings: %d\n", len(r.warnings))
+		for _, e := range r.warnings {
+			fmt.Fprintf(w, "  %s\n", e)
+		}
+	}
+	if len(r.errors) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "failed entries: %d\n", len(r.errors))
+	for _, e := range r.errors {
+		fmt.Fprintf(w, "  %s\n", e)
+	}
+}
@@ -0,0 +1,286 @@
+package command
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/transfer"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/vault"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/crypto"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"github.com/google/uuid"
+	"strings"
+	"time"
+)
+
+// loadItems opens all active items of the user.
+func (s *session) loadItems(ctx context.Context) ([]transfer.Item, error) {
+	var res []transfer.Item
+
+	creds, err := s.clientService.FindCredentialsByUserID(ctx, s.user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("clientService.FindCredentialsByUserID: %w", err)
+	}
+	for _, c := range creds {
+		it, err := credItem(s.dealer, c)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, it)
+	}
+
+	cards, err := s.clientService.FindCardsByUserID(ctx, s.user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("clientService.FindCardsByUserID: %w", err)
+	}
+	for _, c := range cards {
+		it, err := cardItem(s.dealer, c)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, it)
+	}
+
+	texts, err := s.clientService.FindTextsByUserID(ctx, s.user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("clientService.FindTextsByUserID: %w", err)
+	}
+	for _, t := range texts {
+		it, err := textItem(s.dealer, t)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, it)
+	}
+
+	binaries, err := s.clientService.FindBinariesByUserID(ctx, s.user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("clientService.FindBinariesByUserID: %w", err)
+	}
+	for _, b := range binaries {
+		it, err := fileItem(s.dealer, b)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, it)
+	}
+	return res, nil
+}
+
+// itemState is the bookkeeping of a vault item that is not part of its
+// value.
+type itemState struct {
+	modified time.Time
+	deleted  bool
+}
+
+func newItemState(tms time.Time, status model.Status) itemState {
+	return itemState{modified: tms.Truncate(time.Microsecond), deleted: status == model.StatusDeleted}
+}
+
+// findItem opens a single item, deleted ones included.
+func (s *session) findItem(ctx context.Context, kind transfer.Kind,
+	id string) (transfer.Item, itemState, error) {
+	svc := s.clientService
+	switch kind {
+	case transfer.KindCredentials:
+		c, err := svc.FindCredentialsByID(ctx, id)
+		if err != nil {
+			return transfer.Item{}, itemState{}, fmt.Errorf("clientService.FindCredentialsByID: %w", err)
+		}
+		it, err := credItem(s.dealer, &c)
+		return it, newItemState(c.ModifiedTms, c.Status), err
+	case transfer.KindCard:
+		c, err := svc.FindCardByID(ctx, id)
+		if err != nil {
+			return transfer.Item{}, itemState{}, fmt.Errorf("clientService.FindCardByID: %w", err)
+		}
+		it, err := cardItem(s.dealer, &c)
+		return it, newItemState(c.ModifiedTms, c.Status), err
+	case transfer.KindText:
+		t, err := svc.FindTextByID(ctx, id)
+		if err != nil {
+			return transfer.Item{}, itemState{}, fmt.Errorf("clientService.FindTextByID: %w", err)
+		}
+		it, err := textItem(s.dealer, t)
+		return it, newItemState(t.ModifiedTms, t.Status), err
+	case transfer.KindFile:
+		b, err := svc.FindBinaryByID(ctx, id)
+		if err != nil {
+			return transfer.Item{}, itemState{}, fmt.Errorf("clientService.FindBinaryByID: %w", err)
+		}
+		it, err := fileItem(s.dealer, b)
+		return it, newItemState(b.ModifiedTms, b.Status), err
+	}
+	return transfer.Item{}, itemState{}, fmt.Errorf("unknown item kind %q", kind)
+}
+
+func credItem(dealer *crypto.Dealer, c *model.Credentials) (transfer.Item, error) {
+	login, password, err := vault.OpenCredentials(dealer, *c)
+	if err != nil {
+		return transfer.Item{}, fmt.Errorf("vault.OpenCredentials: %w", err)
+	}
+	return transfer.Item{Kind: transfer.KindCredentials,
+		Title: login, Login: login, Password: password}, nil
+}
+
+func cardItem(dealer *crypto.Dealer, c *model.Card) (transfer.Item, error) {
+	in, brand, err := vault.OpenCard(dealer, *c)
+	if err != nil {
+		return transfer.Item{}, fmt.Errorf("vault.OpenCard: %w", err)
+	}
+	return transfer.Item{Kind: transfer.KindCard,
+		Title: string(brand) + " " + model.MaskCardNum(in.Num), Card: in}, nil
+}
+
+func textItem(dealer *crypto.Dealer, t *model.Text) (transfer.Item, error) {
+	txt, err := vault.OpenText(dealer, t.Txt)
+	if err != nil {
+		return transfer.Item{}, fmt.Errorf("vault.OpenText: %w", err)
+	}
+	title, _, _ := strings.Cut(txt, "\n")
+	return transfer.Item{Kind: transfer.KindText, Title: title, Text: txt}, nil
+}
+
+func fileItem(dealer *crypto.Dealer, b *model.Binary) (transfer.Item, error) {
+	data, err := vault.OpenBinary(dealer, b.Data)
+	if err != nil {
+		return transfer.Item{}, fmt.Errorf("vault.OpenBinary: %w", err)
+	}
+	return transfer.Item{Kind: transfer.KindFile,
+		Title: b.Name, FileName: b.Name, Data: data}, nil
+}
+
+// validateItem checks an imported item. A card only has to be well formed,
+// what a card entered by hand would fail on more is returned as a warning.
+func validateItem(it transfer.Item, now time.Time) (warning, err error) {
+	switch it.Kind {
+	case transfer.KindCredentials:
+		return nil, model.ValidateCredentialsInput(it.Login, it.Password)
+	case transfer.KindCard:
+		err = model.ValidateCardStructure(it.Card)
+		if err != nil {
+			return nil, err
+		}
+		return model.ValidateCardInput(it.Card, now), nil
+	case transfer.KindText:
+		return nil, model.ValidateTextInput(it.Text)
+	case transfer.KindFile:
+		if it.FileName == "" {
+			return nil, errors.New("file name is required")
+		}
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown item kind %q", it.Kind)
+}
+
+// saveItem encrypts a plain item and stores it as a new vault item.
+func (s *session) saveItem(ctx context.Context, it transfer.Item) error {
+	return s.putItem(ctx, uuid.New().String(), it, time.Now().UTC(), true)
+}
+
+// putItem encrypts a plain item and stores it under id, isNew tells whether
+// the id is already in the vault.
+func (s *session) putItem(ctx context.Context, id string, it transfer.Item,
+	now time.Time, isNew bool) error {
+	switch it.Kind {
+	case transfer.KindCredentials:
+		cred, err := vault.SealCredentials(s.dealer, it.Login, it.Password)
+		if err != nil {
+			return fmt.Errorf("vault.SealCredentials: %w", err)
+		}
+		cred.ID, cred.New, cred.UserID = id, isNew, s.user.ID
+		cred.Status, cred.ModifiedTms = model.StatusActive, now
+		if err := s.clientService.SaveCredentials(ctx, cred); err != nil {
+			return fmt.Errorf("clientService.SaveCredentials: %w", err)
+		}
+	case transfer.KindCard:
+		card, err := vault.SealCard(s.dealer, it.Card)
+		if err != nil {
+			return fmt.Errorf("vault.SealCard: %w", err)
+		}
+		card.ID, card.New, card.UserID = id, isNew, s.user.ID
+		card.Status, card.ModifiedTms = model.StatusActive, now
+		if err := s.clientService.SaveCard(ctx, card); err != nil {
+			return fmt.Errorf("clientService.SaveCard: %w", err)
+		}
+	case transfer.KindText:
+		enc, err := vault.SealText(s.dealer, it.Text)
+		if err != nil {
+			return fmt.Errorf("vault.SealText: %w", err)
+		}
+		t := model.Text{ID: id, Txt: enc, New: isNew, UserID: s.user.ID,
+			Status: model.StatusActive, ModifiedTms: now}
+		if err := s.clientService.SaveText(ctx, &t); err != nil {
+			return fmt.Errorf("clientService.SaveText: %w", err)
+		}
+	case transfer.KindFile:
+		data, err := vault.SealBinary(s.dealer, it.Data)
+		if err != nil {
+			return fmt.Errorf("vault.SealBinary: %w", err)
+		}
+		b := model.Binary{ID: id, Name: it.FileName, Data: data,
+			New: isNew, UserID: s.user.ID, Status: model.StatusActive, ModifiedTms: now}
+		if err := s.clientService.SaveBinary(ctx, &b); err != nil {
+			return fmt.Errorf("clientService.SaveBinary: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown item kind %q", it.Kind)
+	}
+	return nil
+}
+
+// wrapKey wraps a share or collection key for the owner of pub.
+func wrapKey(pub []byte, key []byte) (string, error) {
+	wrapped, err := crypto.WrapKey(pub, key)
+	if err != nil {
+		return "", fmt.Errorf("crypto.WrapKey: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(wrapped), nil
+}
+
+// unwrapKey opens a key wrapped by wrapKey.
+func unwrapKey(priv []byte, wrapped string) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("base64.StdEncoding.DecodeString: %w", err)
+	}
+	key, err := crypto.UnwrapKey(priv, raw)
+	if err != nil {
+		return nil, fmt.Errorf("crypto.UnwrapKey: %w", err)
+	}
+	return key, nil
+}
+
+// sealItem encrypts an item with a share or collection key.
+func sealItem(key []byte, it transfer.Item) (string, error) {
+	data, err := json.Marshal(it)
+	if err != nil {
+		return "", fmt.Errorf("json.Marshal: %w", err)
+	}
+	sealed, err := crypto.Seal(key, data)
+	if err != nil {
+		return "", fmt.Errorf("crypto.Seal: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// openItem decrypts an item sealed by sealItem.
+func openItem(key []byte, payload string) (transfer.Item, error) {
+	raw, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return transfer.Item{}, fmt.Errorf("base64.StdEncoding.DecodeString: %w", err)
+	}
+	data, err := crypto.Open(key, raw)
+	if err != nil {
+		return transfer.Item{}, fmt.Errorf("crypto.Open: %w", err)
+	}
+	var it transfer.Item
+	err = json.Unmarshal(data, &it)
+	if err != nil {
+		return transfer.Item{}, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+	return it, nil
+}
@@ -0,0 +1,317 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/config"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/repo"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/repo/rest"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/service"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/auth"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/crypto"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// sessionFile keeps the token the server issued to the logged in user.
+const sessionFile = "session.json"
+
+// tokenRefreshMargin is how long before its expiry a token is replaced, so
+// it does not run out during a command.
+const tokenRefreshMargin = time.Minute
+
+// keepOnLogout are the files of a working directory that belong to no
+// account.
+var keepOnLogout = map[string]bool{
+	knownServersFile: true,
+	"rules.json":     true,
+}
+
+var ErrNotLoggedIn = errors.New("not logged in, run login or register")
+
+// savedSession is the login of a working directory.
+type savedSession struct {
+	Login  string `json:"login"`
+	UserID string `json:"user_id"`
+	Server string `json:"server"`
+	Token  string `json:"token"`
+}
+
+// loadSavedSession reads the session of wd, there may be none.
+func loadSavedSession(wd string) (savedSession, error) {
+	var ss savedSession
+	data, err := os.ReadFile(filepath.Join(wd, sessionFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return ss, nil
+	}
+	if err != nil {
+		return ss, fmt.Errorf("os.ReadFile: %w", err)
+	}
+	err = json.Unmarshal(data, &ss)
+	if err != nil {
+		return ss, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+	return ss, nil
+}
+
+func (ss savedSession) save(wd string) error {
+	data, err := json.Marshal(ss)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+	err = os.WriteFile(filepath.Join(wd, sessionFile), data, 0600)
+	if err != nil {
+		return fmt.Errorf("os.WriteFile: %w", err)
+	}
+	return nil
+}
+
+// expires returns when the token runs out, the zero time when there is no
+// readable token.
+func (ss savedSession) expires() time.Time {
+	if ss.Token == "" {
+		return time.Time{}
+	}
+	exp, err := auth.TokenExpiry(ss.Token)
+	if err != nil {
+		return time.Time{}
+	}
+	return exp
+}
+
+func (ss savedSession) valid(user model.User, server string) bool {
+	return ss.Login == user.Login && ss.Server == server &&
+		time.Until(ss.expires()) > tokenRefreshMargin
+}
+
+// sessionToken returns the token of the saved session. An expired one is
+// replaced by logging in again with the proof of the password the vault
+// was unlocked with.
+func sessionToken(ctx context.Context, conf *config.Config, user model.User, proof string,
+	clientService *service.ClientService) (string, error) {
+	ss, err := loadSavedSession(conf.WorkingDir)
+	if err != nil {
+		return "", fmt.Errorf("loadSavedSession: %w", err)
+	}
+	if ss.valid(user, conf.ServerAddress) {
+		return ss.Token, nil
+	}
+	_, token, err := clientService.Login(ctx, user.Login, proof)
+	if err != nil {
+		return "", fmt.Errorf("clientService.Login: %w", err)
+	}
+	err = saveSession(conf, user, token)
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func saveSession(conf *config.Config, user model.User, token string) error {
+	ss := savedSession{
+		Login:  user.Login,
+		UserID: user.ID,
+		Server: conf.ServerAddress,
+		Token:  token,
+	}
+	err := ss.save(conf.WorkingDir)
+	if err != nil {
+		return fmt.Errorf("ss.save: %w", err)
+	}
+	return nil
+}
+
+// loginService connects to the server without a token, for the commands
+// that get one.
+func loginService(ctx context.Context, conf *config.Config) (*service.ClientService, error) {
+	if conf.UserLogin == "" {
+		return nil, errors.New("user login is required, set -ul")
+	}
+	err := os.MkdirAll(conf.WorkingDir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("os.MkdirAll: %w", err)
+	}
+	repository, _ := newRepository(conf.WorkingDir)
+	stored, err := repository.FindUser(ctx)
+	if err != nil && !errors.Is(err, repo.ErrItemNotFound) {
+		return nil, fmt.Errorf("repository.FindUser: %w", err)
+	}
+	if err == nil && stored.Login != conf.UserLogin {
+		return nil, fmt.Errorf("logged in as %s, logout first", stored.Login)
+	}
+	r, err := newRESTClient(conf, nil)
+	if err != nil {
+		return nil, err
+	}
+	return service.NewClientService(repository, rest.NewRESTRepositoryImpl(r)), nil
+}
+
+// DoRegister creates the account on the server and logs in. The device
+// is registered by the first command that needs it.
+func DoRegister(ctx context.Context, conf *config.Config) error {
+	clientService, err := loginService(ctx, conf)
+	if err != nil {
+		return fmt.Errorf("loginService: %w", err)
+	}
+	user, token, err := register(ctx, clientService, conf.UserLogin, conf.UserPassword)
+	if errors.Is(err, repo.ErrUserAlreadyExist) {
+		return fmt.Errorf("login %s is taken, or this directory already has the account", conf.UserLogin)
+	}
+	if err != nil {
+		return fmt.Errorf("register: %w", err)
+	}
+	err = saveSession(conf, user, token)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("registered and logged in as %s\n", user.Login)
+	return nil
+}
+
+// DoLogin checks the password on the server and keeps the user and the
+// token in the working directory. It never creates an account.
+func DoLogin(ctx context.Context, conf *config.Config) error {
+	clientService, err := loginService(ctx, conf)
+	if err != nil {
+		return fmt.Errorf("loginService: %w", err)
+	}
+	pre, err := clientService.Prelogin(ctx, conf.UserLogin)
+	if err != nil {
+		return fmt.Errorf("clientService.Prelogin: %w", err)
+	}
+	proof := conf.UserPassword
+	if pre.LoginSecret {
+		proof = crypto.LoginSecret(conf.UserLogin, conf.UserPassword)
+	}
+	user, token, err := clientService.Login(ctx, conf.UserLogin, proof)
+	if errors.Is(err, repo.ErrItemNotFound) {
+		return errors.New("invalid login or password")
+	}
+	if err != nil {
+		return fmt.Errorf("clientService.Login: %w", err)
+	}
+	err = saveSession(conf, user, token)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("logged in as %s\n", user.Login)
+	return nil
+}
+
+// DoLogout removes the session, the vault key, the device certificate and
+// the cached items. Items not synced yet would be lost, so it refuses
+// unless forced.
+func DoLogout(ctx context.Context, conf *config.Config) error {
+	repository, clientRepo := newRepository(conf.WorkingDir)
+	user, err := repository.FindUser(ctx)
+	if errors.Is(err, repo.ErrItemNotFound) {
+		return ErrNotLoggedIn
+	}
+	if err != nil {
+		return fmt.Errorf("repository.FindUser: %w", err)
+	}
+
+	var since time.Time
+	client, err := clientRepo.FindClient(ctx)
+	if err != nil && !errors.Is(err, repo.ErrItemNotFound) {
+		return fmt.Errorf("clientRepo.FindClient: %w", err)
+	}
+	if err == nil {
+		since = client.SyncTms
+	}
+	pending, err := service.NewClientService(repository, nil).PendingChanges(ctx, user.ID, since)
+	if err != nil {
+		return fmt.Errorf("PendingChanges: %w", err)
+	}
+	if pending > 0 && !conf.Force {
+		return fmt.Errorf("%d local changes are not synced, run sync first or set -force", pending)
+	}
+
+	entries, err := os.ReadDir(conf.WorkingDir)
+	if err != nil {
+		return fmt.Errorf("os.ReadDir: %w", err)
+	}
+	for _, e := range entries {
+		if !e.Type().IsRegular() || keepOnLogout[e.Name()] {
+			continue
+		}
+		path := filepath.Join(conf.WorkingDir, e.Name())
+		err = shredFile(path)
+		if err != nil {
+			return fmt.Errorf("shredFile: %w", err)
+		}
+		err = os.Remove(path)
+		if err != nil {
+			return fmt.Errorf("os.Remove: %w", err)
+		}
+	}
+	fmt.Printf("logged out %s\n", user.Login)
+	return nil
+}
+
+// DoStatus shows who is logged in where, without the password or the
+// server.
+func DoStatus(ctx context.Context, conf *config.Config) error {
+	if conf.Profile != "" {
+		fmt.Printf("profile:\t%s\n", conf.Profile)
+	}
+	fmt.Printf("server:\t\t%s\n", conf.ServerAddress)
+	fmt.Printf("directory:\t%s\n", conf.WorkingDir)
+
+	repository, clientRepo := newRepository(conf.WorkingDir)
+	user, err := repository.FindUser(ctx)
+	if errors.Is(err, repo.ErrItemNotFound) {
+		fmt.Println("user:\t\tnot logged in")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("repository.FindUser: %w", err)
+	}
+	fmt.Printf("user:\t\t%s\n", user.Login)
+
+	dev, err := loadDevice(conf.WorkingDir)
+	if err != nil {
+		return fmt.Errorf("loadDevice: %w", err)
+	}
+	ss, err := loadSavedSession(conf.WorkingDir)
+	if err != nil {
+		return fmt.Errorf("loadSavedSession: %w", err)
+	}
+	switch exp := ss.expires(); {
+	case dev.has():
+		fmt.Println("session:\tdevice certificate")
+	case exp.IsZero():
+		fmt.Println("session:\tnone, the next command logs in")
+	case ss.Server != conf.ServerAddress:
+		fmt.Printf("session:\tfor %s, the next command logs in\n", ss.Server)
+	case time.Until(exp) <= tokenRefreshMargin:
+		fmt.Println("session:\texpired, the next command logs in")
+	default:
+		fmt.Printf("session:\tvalid until %s\n", exp.Local().Format(time.DateTime))
+	}
+
+	client, err := clientRepo.FindClient(ctx)
+	if errors.Is(err, repo.ErrItemNotFound) {
+		fmt.Println("device:\t\tnot registered yet")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("clientRepo.FindClient: %w", err)
+	}
+	fmt.Printf("device:\t\t%s\n", client.ID)
+	if client.SyncTms.Year() <= 1900 {
+		fmt.Println("last sync:\tnever")
+	} else {
+		fmt.Printf("last sync:\t%s\n", client.SyncTms.Local().Format(time.DateTime))
+	}
+	pending, err := service.NewClientService(repository, nil).PendingChanges(ctx, user.ID, client.SyncTms)
+	if err != nil {
+		return fmt.Errorf("PendingChanges: %w", err)
+	}
+	fmt.Printf("pending:\t%d local changes\n", pending)
+	return nil
+}
@@ -0,0 +1,121 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/vault"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/logger"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// migratedFile marks a working directory whose values were sealed with a
+// random nonce. From then on a note or file that does not decrypt is an
+// error, not plain text of an older client.
+const migratedFile = "vault-v1"
+
+// migrateVault seals the values of older clients once, the changed items
+// are pushed by the next sync.
+func (s *session) migrateVault(ctx context.Context) error {
+	marker := filepath.Join(s.wd, migratedFile)
+	_, err := os.Stat(marker)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("os.Stat: %w", err)
+	}
+
+	now := time.Now().UTC()
+	migrated := 0
+	texts, err := s.clientService.FindTextsByUserID(ctx, s.user.ID)
+	if err != nil {
+		return fmt.Errorf("clientService.FindTextsByUserID: %w", err)
+	}
+	for _, t := range texts {
+		enc, changed, err := vault.MigrateText(s.dealer, t.Txt)
+		if err != nil {
+			return fmt.Errorf("vault.MigrateText: %w", err)
+		}
+		if !changed {
+			continue
+		}
+		t.Txt, t.ModifiedTms = enc, now
+		err = s.clientService.SaveText(ctx, t)
+		if err != nil {
+			return fmt.Errorf("clientService.SaveText: %w", err)
+		}
+		migrated++
+	}
+
+	binaries, err := s.clientService.FindBinariesByUserID(ctx, s.user.ID)
+	if err != nil {
+		return fmt.Errorf("clientService.FindBinariesByUserID: %w", err)
+	}
+	for _, b := range binaries {
+		enc, changed, err := vault.MigrateBinary(s.dealer, b.Data)
+		if err != nil {
+			return fmt.Errorf("vault.MigrateBinary: %w", err)
+		}
+		if !changed {
+			continue
+		}
+		b.Data, b.ModifiedTms = enc, now
+		err = s.clientService.SaveBinary(ctx, b)
+		if err != nil {
+			return fmt.Errorf("clientService.SaveBinary: %w", err)
+		}
+		migrated++
+	}
+
+	creds, err := s.clientService.FindCredentialsByUserID(ctx, s.user.ID)
+	if err != nil {
+		return fmt.Errorf("clientService.FindCredentialsByUserID: %w", err)
+	}
+	for _, c := range creds {
+		if vault.CredentialsSealed(*c) {
+			continue
+		}
+		enc, err := vault.ResealCredentials(s.dealer, s.dealer, *c)
+		if err != nil {
+			return fmt.Errorf("vault.ResealCredentials: %w", err)
+		}
+		enc.ModifiedTms = now
+		err = s.clientService.SaveCredentials(ctx, enc)
+		if err != nil {
+			return fmt.Errorf("clientService.SaveCredentials: %w", err)
+		}
+		migrated++
+	}
+
+	cards, err := s.clientService.FindCardsByUserID(ctx, s.user.ID)
+	if err != nil {
+		return fmt.Errorf("clientService.FindCardsByUserID: %w", err)
+	}
+	for _, c := range cards {
+		if vault.CardSealed(*c) {
+			continue
+		}
+		enc, err := vault.ResealCard(s.dealer, s.dealer, *c)
+		if err != nil {
+			return fmt.Errorf("vault.ResealCard: %w", err)
+		}
+		enc.ModifiedTms = now
+		err = s.clientService.SaveCard(ctx, enc)
+		if err != nil {
+			return fmt.Errorf("clientService.SaveCard: %w", err)
+		}
+		migrated++
+	}
+
+	err = os.WriteFile(marker, nil, 0600)
+	if err != nil {
+		return fmt.Errorf("os.WriteFile: %w", err)
+	}
+	if migrated > 0 {
+		logger.Log.Info(fmt.Sprintf("sealed %d items of an older client", migrated))
+	}
+	return nil
+}
@@ -0,0 +1,509 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/config"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/repo"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/transfer"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/crypto"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/logger"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// orgsFile keeps the collection of every organization item in the vault,
+// so the local copies can be removed once the access is lost.
+const orgsFile = "orgs.json"
+
+type orgItemRef struct {
+	OrgID        string        `json:"org_id"`
+	CollectionID string        `json:"collection_id"`
+	Kind         transfer.Kind `json:"kind"`
+}
+
+// DoOrg manages organizations, their members and collections.
+func DoOrg(ctx context.Context, conf *config.Config, s *session) error {
+	if conf.OrgAction == "create" {
+		return s.createOrg(ctx, conf.OrgName)
+	}
+	if conf.OrgAction == "list" {
+		return s.listOrgs(ctx)
+	}
+
+	org, err := s.findOrg(ctx, conf.OrgID)
+	if err != nil {
+		return err
+	}
+	switch conf.OrgAction {
+	case "collection":
+		return s.createCollection(ctx, org, conf.OrgName)
+	case "add-member":
+		return s.addOrgMember(ctx, org, conf.ShareWith, conf.OrgRole)
+	case "remove-member":
+		return s.removeOrgMember(ctx, org, conf.ShareWith)
+	case "add":
+		c, err := s.findCollection(ctx, org, conf.CollectionID)
+		if err != nil {
+			return err
+		}
+		return s.moveToCollection(ctx, org, c, conf.ID)
+	}
+	return fmt.Errorf("unknown org action %q", conf.OrgAction)
+}
+
+func (s *session) createOrg(ctx context.Context, name string) error {
+	org := model.Org{ID: uuid.New().String(), Name: strings.TrimSpace(name)}
+	if org.Name == "" {
+		return errors.New("name is required, set -name")
+	}
+	// members need a published key before collections are wrapped for them
+	if _, _, err := s.userKeys(ctx); err != nil {
+		return fmt.Errorf("s.userKeys: %w", err)
+	}
+	if err := s.clientService.CreateOrg(ctx, org); err != nil {
+		return fmt.Errorf("clientService.CreateOrg: %w", err)
+	}
+	fmt.Printf("created organization %s %s\n", org.ID, org.Name)
+	return nil
+}
+
+func (s *session) listOrgs(ctx context.Context) error {
+	orgs, err := s.clientService.FindOrgs(ctx)
+	if err != nil {
+		return fmt.Errorf("clientService.FindOrgs: %w", err)
+	}
+	if len(orgs) == 0 {
+		fmt.Println("no organizations")
+		return nil
+	}
+	for _, org := range orgs {
+		fmt.Printf("%s %s (%s)\n", org.ID, org.Name, roleName(org.Role))
+		members, err := s.clientService.FindOrgMembers(ctx, org.ID)
+		if err != nil {
+			return fmt.Errorf("clientService.FindOrgMembers: %w", err)
+		}
+		for _, m := range members {
+			fmt.Printf("  member %s (%s)\n", m.Login, roleName(m.Role))
+		}
+		collections, err := s.clientService.FindCollections(ctx, org.ID)
+		if err != nil {
+			return fmt.Errorf("clientService.FindCollections: %w", err)
+		}
+		for _, c := range collections {
+			fmt.Printf("  collection %s %s\n", c.ID, c.Name)
+		}
+	}
+	return nil
+}
+
+// createCollection creates a collection with a new key wrapped for every
+// member.
+func (s *session) createCollection(ctx context.Context, org model.Org, name string) error {
+	c := model.Collection{ID: uuid.New().String(), Name: strings.TrimSpace(name)}
+	if c.Name == "" {
+		return errors.New("name is required, set -name")
+	}
+	key, err := crypto.NewItemKey()
+	if err != nil {
+		return err
+	}
+	c.Keys, err = s.wrapForMembers(ctx, org, key)
+	if err != nil {
+		return err
+	}
+	if err := s.clientService.CreateCollection(ctx, org.ID, c); err != nil {
+		return fmt.Errorf("clientService.CreateCollection: %w", err)
+	}
+	fmt.Printf("created collection %s %s in %s\n", c.ID, c.Name, org.Name)
+	return nil
+}
+
+// addOrgMember adds a user or changes its role. A new member gets the key
+// of every collection.
+func (s *session) addOrgMember(ctx context.Context, org model.Org, login string,
+	role model.Role) error {
+	if strings.TrimSpace(login) == "" {
+		return errors.New("member is required, set -with")
+	}
+	member := model.OrgMember{Login: login, Role: role}
+	members, err := s.clientService.FindOrgMembers(ctx, org.ID)
+	if err != nil {
+		return fmt.Errorf("clientService.FindOrgMembers: %w", err)
+	}
+	isNew := true
+	for _, m := range members {
+		isNew = isNew && m.Login != login
+	}
+
+	if isNew {
+		recipient, err := s.clientService.FindPublicKey(ctx, login)
+		if err != nil {
+			if errors.Is(err, repo.ErrItemNotFound) {
+				return fmt.Errorf("%s has no key yet, they have to sync once", login)
+			}
+			return fmt.Errorf("clientService.FindPublicKey: %w", err)
+		}
+		pub, err := s.contactKey(ctx, login, recipient.PublicKey)
+		if err != nil {
+			return fmt.Errorf("s.contactKey: %w", err)
+		}
+		_, priv, err := s.userKeys(ctx)
+		if err != nil {
+			return fmt.Errorf("s.userKeys: %w", err)
+		}
+		collections, err := s.clientService.FindCollections(ctx, org.ID)
+		if err != nil {
+			return fmt.Errorf("clientService.FindCollections: %w", err)
+		}
+		for _, c := range collections {
+			key, err := unwrapKey(priv, c.WrappedKey)
+			if err != nil {
+				return fmt.Errorf("collection %s: %w", c.Name, err)
+			}
+			wrapped, err := wrapKey(pub, key)
+			if err != nil {
+				return err
+			}
+			member.Keys = append(member.Keys,
+				model.CollectionKey{CollectionID: c.ID, WrappedKey: wrapped})
+		}
+	}
+
+	if err := s.clientService.SaveOrgMember(ctx, org.ID, member); err != nil {
+		return fmt.Errorf("clientService.SaveOrgMember: %w", err)
+	}
+	fmt.Printf("%s is %s of %s\n", login, roleName(role), org.Name)
+	return nil
+}
+
+// removeOrgMember takes the member out and re-keys every collection, so
+// the keys it knows open nothing written afterwards.
+func (s *session) removeOrgMember(ctx context.Context, org model.Org, login string) error {
+	if strings.TrimSpace(login) == "" {
+		return errors.New("member is required, set -with")
+	}
+	if err := s.clientService.RemoveOrgMember(ctx, org.ID, login); err != nil {
+		return fmt.Errorf("clientService.RemoveOrgMember: %w", err)
+	}
+	if login == s.user.Login {
+		fmt.Printf("left %s\n", org.Name)
+		return nil
+	}
+	collections, err := s.clientService.FindCollections(ctx, org.ID)
+	if err != nil {
+		return fmt.Errorf("clientService.FindCollections: %w", err)
+	}
+	for _, c := range collections {
+		if err := s.rekeyCollection(ctx, org, c); err != nil {
+			return fmt.Errorf("rekey %s: %w", c.Name, err)
+		}
+	}
+	fmt.Printf("removed %s from %s, re-keyed %d collection(s)\n", login, org.Name, len(collections))
+	return nil
+}
+
+func (s *session) rekeyCollection(ctx context.Context, org model.Org, c model.Collection) error {
+	_, priv, err := s.userKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("s.userKeys: %w", err)
+	}
+	oldKey, err := unwrapKey(priv, c.WrappedKey)
+	if err != nil {
+		return err
+	}
+	key, err := crypto.NewItemKey()
+	if err != nil {
+		return err
+	}
+	rekey := model.CollectionRekey{KeyVersion: c.KeyVersion + 1}
+	rekey.Keys, err = s.wrapForMembers(ctx, org, key)
+	if err != nil {
+		return err
+	}
+	items, err := s.clientService.FindOrgItems(ctx, org.ID, c.ID)
+	if err != nil {
+		return fmt.Errorf("clientService.FindOrgItems: %w", err)
+	}
+	for _, item := range items {
+		it, err := openItem(oldKey, item.Payload)
+		if err != nil {
+			return fmt.Errorf("item %s: %w", item.ID, err)
+		}
+		item.Payload, err = sealItem(key, it)
+		if err != nil {
+			return err
+		}
+		item.KeyVersion = rekey.KeyVersion
+		rekey.Items = append(rekey.Items, item)
+	}
+	if err := s.clientService.RekeyCollection(ctx, org.ID, c.ID, rekey); err != nil {
+		return fmt.Errorf("clientService.RekeyCollection: %w", err)
+	}
+	return nil
+}
+
+// wrapForMembers wraps a collection key for every member of the
+// organization.
+func (s *session) wrapForMembers(ctx context.Context, org model.Org,
+	key []byte) ([]model.CollectionKey, error) {
+	members, err := s.clientService.FindOrgMembers(ctx, org.ID)
+	if err != nil {
+		return nil, fmt.Errorf("clientService.FindOrgMembers: %w", err)
+	}
+	keys := make([]model.CollectionKey, 0, len(members))
+	for _, m := range members {
+		if m.PublicKey == "" {
+			return nil, fmt.Errorf("%s has no key yet, they have to sync once", m.Login)
+		}
+		pub, err := s.contactKey(ctx, m.Login, m.PublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("s.contactKey: %w", err)
+		}
+		wrapped, err := wrapKey(pub, key)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, model.CollectionKey{Login: m.Login, WrappedKey: wrapped})
+	}
+	return keys, nil
+}
+
+// moveToCollection moves a personal item into the collection.
+func (s *session) moveToCollection(ctx context.Context, org model.Org, c model.Collection,
+	id string) error {
+	if id == "" {
+		return errors.New("item id is required, set -id")
+	}
+	e, err := s.findEntry(ctx, id)
+	if err != nil {
+		return fmt.Errorf("s.findEntry: %w", err)
+	}
+	received, err := s.loadReceivedShares()
+	if err != nil {
+		return fmt.Errorf("s.loadReceivedShares: %w", err)
+	}
+	refs, err := s.loadOrgItems()
+	if err != nil {
+		return fmt.Errorf("s.loadOrgItems: %w", err)
+	}
+	if _, ok := received[e.id]; ok {
+		return errors.New("items shared with you can't be moved")
+	}
+	if _, ok := refs[e.id]; ok {
+		return errors.New("the item already belongs to an organization")
+	}
+
+	_, priv, err := s.userKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("s.userKeys: %w", err)
+	}
+	key, err := unwrapKey(priv, c.WrappedKey)
+	if err != nil {
+		return err
+	}
+	it, _, err := s.findItem(ctx, transfer.Kind(e.kind), e.id)
+	if err != nil {
+		return fmt.Errorf("s.findItem: %w", err)
+	}
+	payload, err := sealItem(key, it)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	item := model.OrgItem{ID: uuid.New().String(), Kind: e.kind, Payload: payload,
+		KeyVersion: c.KeyVersion, Status: model.StatusActive, ModifiedTms: now}
+	if err := s.clientService.SaveOrgItem(ctx, org.ID, c.ID, item); err != nil {
+		return fmt.Errorf("clientService.SaveOrgItem: %w", err)
+	}
+	if err := s.putItem(ctx, item.ID, it, now, true); err != nil {
+		return fmt.Errorf("s.putItem: %w", err)
+	}
+	if err := s.deleteEntry(ctx, e); err != nil {
+		return fmt.Errorf("s.deleteEntry: %w", err)
+	}
+	refs[item.ID] = orgItemRef{OrgID: org.ID, CollectionID: c.ID, Kind: transfer.Kind(e.kind)}
+	if err := s.saveOrgItems(refs); err != nil {
+		return fmt.Errorf("s.saveOrgItems: %w", err)
+	}
+	fmt.Printf("moved %s to %s/%s, its id is %s now\n", e.title, org.Name, c.Name, item.ID)
+	return nil
+}
+
+// syncOrgs brings the items of every collection the user can open up to
+// date. Local changes are pushed when the role allows writing, items of
+// collections the user lost access to are removed.
+func (s *session) syncOrgs(ctx context.Context) error {
+	_, priv, err := s.userKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("s.userKeys: %w", err)
+	}
+	orgs, err := s.clientService.FindOrgs(ctx)
+	if err != nil {
+		return fmt.Errorf("clientService.FindOrgs: %w", err)
+	}
+	prev, err := s.loadOrgItems()
+	if err != nil {
+		return fmt.Errorf("s.loadOrgItems: %w", err)
+	}
+
+	refs := make(map[string]orgItemRef)
+	for _, org := range orgs {
+		collections, err := s.clientService.FindCollections(ctx, org.ID)
+		if err != nil {
+			return fmt.Errorf("clientService.FindCollections: %w", err)
+		}
+		for _, c := range collections {
+			if c.WrappedKey == "" {
+				continue
+			}
+			key, err := unwrapKey(priv, c.WrappedKey)
+			if err != nil {
+				logger.Log.Error("unwrapKey", zap.String("collectionID", c.ID), zap.Error(err))
+				continue
+			}
+			items, err := s.clientService.FindOrgItems(ctx, org.ID, c.ID)
+			if err != nil {
+				return fmt.Errorf("clientService.FindOrgItems: %w", err)
+			}
+			for _, item := range items {
+				if item.Status == model.StatusActive {
+					refs[item.ID] = orgItemRef{OrgID: org.ID, CollectionID: c.ID,
+						Kind: transfer.Kind(item.Kind)}
+				}
+				err := s.syncOrgItem(ctx, org, c, key, item)
+				if err != nil {
+					logger.Log.Error("s.syncOrgItem", zap.String("itemID", item.ID), zap.Error(err))
+				}
+			}
+		}
+	}
+
+	for id, ref := range prev {
+		if _, ok := refs[id]; ok {
+			continue
+		}
+		err := s.deleteEntry(ctx, entry{id: id, kind: string(ref.Kind)})
+		if err != nil && !errors.Is(err, repo.ErrItemNotFound) {
+			return fmt.Errorf("s.deleteEntry: %w", err)
+		}
+	}
+	return s.saveOrgItems(refs)
+}
+
+func (s *session) syncOrgItem(ctx context.Context, org model.Org, c model.Collection,
+	key []byte, item model.OrgItem) error {
+	it, st, err := s.findItem(ctx, transfer.Kind(item.Kind), item.ID)
+	found := err == nil
+	if err != nil && !errors.Is(err, repo.ErrItemNotFound) {
+		return fmt.Errorf("s.findItem: %w", err)
+	}
+
+	if item.Status == model.StatusDeleted {
+		if found && !st.deleted {
+			return s.deleteEntry(ctx, entry{id: item.ID, kind: item.Kind})
+		}
+		return nil
+	}
+	if !found || item.ModifiedTms.After(st.modified) {
+		remote, err := openItem(key, item.Payload)
+		if err != nil {
+			return err
+		}
+		err = s.putItem(ctx, item.ID, remote, item.ModifiedTms, !found)
+		if err != nil {
+			return fmt.Errorf("s.putItem: %w", err)
+		}
+		return nil
+	}
+	if !st.modified.After(item.ModifiedTms) || !org.Role.CanWrite() {
+		return nil
+	}
+	item.Payload, err = sealItem(key, it)
+	if err != nil {
+		return err
+	}
+	item.KeyVersion, item.ModifiedTms = c.KeyVersion, st.modified
+	if st.deleted {
+		item.Status = model.StatusDeleted
+	}
+	err = s.clientService.SaveOrgItem(ctx, org.ID, c.ID, item)
+	if err != nil {
+		return fmt.Errorf("clientService.SaveOrgItem: %w", err)
+	}
+	return nil
+}
+
+// findOrg looks an organization of the user up by id or name.
+func (s *session) findOrg(ctx context.Context, ref string) (model.Org, error) {
+	if ref == "" {
+		return model.Org{}, errors.New("organization is required, set -org")
+	}
+	orgs, err := s.clientService.FindOrgs(ctx)
+	if err != nil {
+		return model.Org{}, fmt.Errorf("clientService.FindOrgs: %w", err)
+	}
+	for _, org := range orgs {
+		if org.ID == ref || org.Name == ref {
+			return org, nil
+		}
+	}
+	return model.Org{}, fmt.Errorf("organization %s: %w", ref, repo.ErrItemNotFound)
+}
+
+// findCollection looks a collection of the organization up by id or name.
+func (s *session) findCollection(ctx context.Context, org model.Org,
+	ref string) (model.Collection, error) {
+	if ref == "" {
+		return model.Collection{}, errors.New("collection is required, set -collection")
+	}
+	collections, err := s.clientService.FindCollections(ctx, org.ID)
+	if err != nil {
+		return model.Collection{}, fmt.Errorf("clientService.FindCollections: %w", err)
+	}
+	for _, c := range collections {
+		if c.ID == ref || c.Name == ref {
+			return c, nil
+		}
+	}
+	return model.Collection{}, fmt.Errorf("collection %s: %w", ref, repo.ErrItemNotFound)
+}
+
+func (s *session) loadOrgItems() (map[string]orgItemRef, error) {
+	res := make(map[string]orgItemRef)
+	data, err := os.ReadFile(filepath.Join(s.wd, orgsFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return res, nil
+		}
+		return nil, fmt.Errorf("os.ReadFile: %w", err)
+	}
+	err = json.Unmarshal(data, &res)
+	if err != nil {
+		return nil, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+	return res, nil
+}
+
+func (s *session) saveOrgItems(refs map[string]orgItemRef) error {
+	data, err := json.Marshal(refs)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+	err = os.WriteFile(filepath.Join(s.wd, orgsFile), data, 0600)
+	if err != nil {
+		return fmt.Errorf("os.WriteFile: %w", err)
+	}
+	return nil
+}
+
+func roleName(r model.Role) string {
+	return strings.ReplaceAll(strings.ToLower(string(r)), "_", "-")
+}
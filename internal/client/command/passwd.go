@@ -0,0 +1,250 @@
+package command
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/config"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/repo"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/repo/rest"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/service"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/crypto"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"os"
+	"strings"
+)
+
+// register creates the user with a random vault key and prints the
+// recovery kit.
+func register(ctx context.Context, clientService *service.ClientService,
+	login, password string) (model.User, string, error) {
+	key, err := crypto.NewItemKey()
+	if err != nil {
+		return model.User{}, "", fmt.Errorf("crypto.NewItemKey: %w", err)
+	}
+	vault, err := wrapVaultKey(password, key)
+	if err != nil {
+		return model.User{}, "", err
+	}
+	recoveryKey, kit, err := newRecoveryKit(key)
+	if err != nil {
+		return model.User{}, "", err
+	}
+	user, token, err := clientService.RegisterUser(ctx, model.AuthUser{
+		Login:    login,
+		Password: crypto.LoginSecret(login, password),
+		Vault:    &vault,
+		Recovery: &kit,
+	})
+	if err != nil {
+		return model.User{}, "", fmt.Errorf("clientService.RegisterUser: %w", err)
+	}
+	printRecoveryKit(login, recoveryKey)
+	return user, token, nil
+}
+
+// DoPasswd changes the password. The vault key is only wrapped again, the
+// items stay as they are. Users without a recovery kit get one, and a
+// random vault key their items are sealed with again.
+func DoPasswd(ctx context.Context, conf *config.Config, s *session) error {
+	if conf.NewPassword == conf.UserPassword {
+		return errors.New("new password is the same as the current one")
+	}
+	legacy := s.user.Vault == nil
+	key := s.dealer.Key()
+	if legacy {
+		// every item has to be here to be sealed with the new key
+		err := s.sync(ctx)
+		if err != nil {
+			return fmt.Errorf("s.sync: %w", err)
+		}
+		key, err = crypto.NewItemKey()
+		if err != nil {
+			return fmt.Errorf("crypto.NewItemKey: %w", err)
+		}
+		err = s.markRekey()
+		if err != nil {
+			return err
+		}
+	}
+	vault, err := wrapVaultKey(conf.NewPassword, key)
+	if err != nil {
+		return err
+	}
+	pc := model.PasswordChange{
+		Password:    loginPassword(s.user, conf.UserPassword),
+		NewPassword: crypto.LoginSecret(s.user.Login, conf.NewPassword),
+		Vault:       vault,
+	}
+	var recoveryKey string
+	if s.user.Vault == nil {
+		var kit model.RecoveryKit
+		recoveryKey, kit, err = newRecoveryKit(key)
+		if err != nil {
+			return err
+		}
+		pc.Recovery = &kit
+	}
+	user, err := s.clientService.ChangePassword(ctx, s.user, pc)
+	if err != nil {
+		return fmt.Errorf("clientService.ChangePassword: %w", err)
+	}
+	s.user = user
+	if legacy {
+		s.dealer, err = crypto.NewDealerFromKey(key)
+		if err != nil {
+			return fmt.Errorf("crypto.NewDealerFromKey: %w", err)
+		}
+		err = s.finishRekey(ctx)
+		if err != nil {
+			return fmt.Errorf("s.finishRekey: %w", err)
+		}
+	}
+	fmt.Println("password changed")
+	if recoveryKey != "" {
+		printRecoveryKit(user.Login, recoveryKey)
+	}
+	return nil
+}
+
+// DoRecover sets a new password with the recovery key when the password is
+// lost and logs in with it. It runs without a session, the vault can't be
+// opened yet.
+func DoRecover(ctx context.Context, conf *config.Config) error {
+	if strings.TrimSpace(conf.UserLogin) == "" {
+		return errors.New("user login is required, set -ul")
+	}
+	wrap, proof, err := crypto.RecoveryKeys(conf.RecoveryKey)
+	if err != nil {
+		return fmt.Errorf("crypto.RecoveryKeys: %w", err)
+	}
+	err = os.MkdirAll(conf.WorkingDir, 0755)
+	if err != nil {
+		return fmt.Errorf("os.MkdirAll: %w", err)
+	}
+	repository, _ := newRepository(conf.WorkingDir)
+	stored, err := repository.FindUser(ctx)
+	if err == nil && stored.Login != conf.UserLogin {
+		return fmt.Errorf("working directory belongs to %s", stored.Login)
+	}
+	if err != nil && !errors.Is(err, repo.ErrItemNotFound) {
+		return fmt.Errorf("repository.FindUser: %w", err)
+	}
+	r, err := newRESTClient(conf, nil)
+	if err != nil {
+		return err
+	}
+	clientService := service.NewClientService(repository, rest.NewRESTRepositoryImpl(r))
+
+	auth := base64.StdEncoding.EncodeToString(proof)
+	kit, err := clientService.FindRecoveryKit(ctx, model.RecoveryRequest{
+		Login: conf.UserLogin,
+		Auth:  auth,
+	})
+	if err != nil {
+		if errors.Is(err, repo.ErrItemNotFound) {
+			return errors.New("wrong login or recovery key")
+		}
+		return fmt.Errorf("clientService.FindRecoveryKit: %w", err)
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(kit.WrappedKey)
+	if err != nil {
+		return fmt.Errorf("base64.StdEncoding.DecodeString: %w", err)
+	}
+	key, err := crypto.Open(wrap, wrapped)
+	if err != nil {
+		return fmt.Errorf("crypto.Open: %w", err)
+	}
+	vault, err := wrapVaultKey(conf.NewPassword, key)
+	if err != nil {
+		return err
+	}
+	user, token, err := clientService.ResetPassword(ctx, model.PasswordReset{
+		Login:       conf.UserLogin,
+		Auth:        auth,
+		NewPassword: crypto.LoginSecret(conf.UserLogin, conf.NewPassword),
+		Vault:       vault,
+	})
+	if err != nil {
+		return fmt.Errorf("clientService.ResetPassword: %w", err)
+	}
+	err = saveSession(conf, user, token)
+	if err != nil {
+		return err
+	}
+	fmt.Println("password reset, the recovery kit stays valid")
+	return nil
+}
+
+// loginPassword is what proves the password of user to the server: the
+// login secret, or the password itself for accounts without a vault key.
+func loginPassword(user model.User, password string) string {
+	if user.Vault == nil {
+		return password
+	}
+	return crypto.LoginSecret(user.Login, password)
+}
+
+// wrapVaultKey wraps the vault key with a key derived from the password and
+// a new salt.
+func wrapVaultKey(password string, key []byte) (model.VaultKey, error) {
+	salt, err := crypto.NewSalt()
+	if err != nil {
+		return model.VaultKey{}, fmt.Errorf("crypto.NewSalt: %w", err)
+	}
+	wrapped, err := crypto.Seal(crypto.DeriveKey(password, salt), key)
+	if err != nil {
+		return model.VaultKey{}, fmt.Errorf("crypto.Seal: %w", err)
+	}
+	return model.VaultKey{
+		WrappedKey: base64.StdEncoding.EncodeToString(wrapped),
+		Salt:       base64.StdEncoding.EncodeToString(salt),
+	}, nil
+}
+
+func unwrapVaultKey(password string, vault model.VaultKey) ([]byte, error) {
+	salt, err := base64.StdEncoding.DecodeString(vault.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("base64.StdEncoding.DecodeString: %w", err)
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(vault.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("base64.StdEncoding.DecodeString: %w", err)
+	}
+	key, err := crypto.Open(crypto.DeriveKey(password, salt), wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("crypto.Open: %w", err)
+	}
+	return key, nil
+}
+
+// newRecoveryKit returns a new recovery key and the vault key wrapped with
+// it.
+func newRecoveryKit(key []byte) (string, model.RecoveryKit, error) {
+	recoveryKey, err := crypto.NewRecoveryKey()
+	if err != nil {
+		return "", model.RecoveryKit{}, fmt.Errorf("crypto.NewRecoveryKey: %w", err)
+	}
+	wrap, proof, err := crypto.RecoveryKeys(recoveryKey)
+	if err != nil {
+		return "", model.RecoveryKit{}, fmt.Errorf("crypto.RecoveryKeys: %w", err)
+	}
+	wrapped, err := crypto.Seal(wrap, key)
+	if err != nil {
+		return "", model.RecoveryKit{}, fmt.Errorf("crypto.Seal: %w", err)
+	}
+	return recoveryKey, model.RecoveryKit{
+		WrappedKey: base64.StdEncoding.EncodeToString(wrapped),
+		Auth:       base64.StdEncoding.EncodeToString(proof),
+	}, nil
+}
+
+func printRecoveryKit(login, recoveryKey string) {
+	fmt.Println("=== gophkeeper recovery kit ===")
+	fmt.Printf("login:        %s\n", login)
+	fmt.Printf("recovery key: %s\n", recoveryKey)
+	fmt.Println("Print or write it down and keep it offline. It resets a lost password")
+	fmt.Println("with: client recover -ul <login> -key <recovery key>")
+	fmt.Println("It is shown only once.")
+}
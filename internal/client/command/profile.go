@@ -0,0 +1,88 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/config"
+	"path/filepath"
+)
+
+// DoProfile lists, adds or selects the profiles of the client config file.
+// It needs neither the server nor the vault.
+func DoProfile(conf *config.Config) error {
+	path, err := config.ProfilesPath()
+	if err != nil {
+		return fmt.Errorf("config.ProfilesPath: %w", err)
+	}
+	profiles, err := config.LoadProfiles(path)
+	if err != nil {
+		return fmt.Errorf("config.LoadProfiles: %w", err)
+	}
+
+	switch conf.ProfileAction {
+	case "add":
+		if conf.Profile == "" {
+			return errors.New("profile name is required, set -name")
+		}
+		wd := conf.WorkingDir
+		if wd == "" {
+			wd = profiles.DefaultWorkingDir(conf.Profile)
+		}
+		// the profile is used from any directory
+		wd, err = filepath.Abs(wd)
+		if err != nil {
+			return fmt.Errorf("filepath.Abs: %w", err)
+		}
+		ca := conf.ServerCA
+		if ca != "" {
+			ca, err = filepath.Abs(ca)
+			if err != nil {
+				return fmt.Errorf("filepath.Abs: %w", err)
+			}
+		}
+		err = profiles.Add(conf.Profile, config.Profile{
+			Server:     conf.ServerAddress,
+			ServerCA:   ca,
+			ServerPin:  conf.ServerPin,
+			WorkingDir: wd,
+			Login:      conf.UserLogin,
+		}, conf.Force)
+		if err != nil {
+			return fmt.Errorf("profiles.Add: %w", err)
+		}
+		err = profiles.Save()
+		if err != nil {
+			return fmt.Errorf("profiles.Save: %w", err)
+		}
+		fmt.Printf("added profile %s, working directory %s\n", conf.Profile, wd)
+		return nil
+	case "use":
+		if conf.Profile == "" {
+			return errors.New("profile name is required, set -name")
+		}
+		err = profiles.Use(conf.Profile)
+		if err != nil {
+			return fmt.Errorf("profiles.Use: %w", err)
+		}
+		err = profiles.Save()
+		if err != nil {
+			return fmt.Errorf("profiles.Save: %w", err)
+		}
+		fmt.Printf("using profile %s\n", conf.Profile)
+		return nil
+	default:
+		if len(profiles.Profiles) == 0 {
+			fmt.Printf("no profiles in %s\n", profiles.Path())
+			return nil
+		}
+		for _, name := range profiles.Names() {
+			p := profiles.Profiles[name]
+			current := ""
+			if name == profiles.Current {
+				current = "*"
+			}
+			fmt.Printf("%s\t%s\t%s\t%s\t%s\n", current, name, p.Server, p.Login, p.WorkingDir)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,197 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/repo"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/vault"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/crypto"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/logger"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// rekeyFile marks a working directory whose vault moves from the key
+// derived from id and login to a random vault key. It is written before the
+// server gets the new key and removed once every item is sealed with it,
+// it is left alone while the server has no vault key for the user.
+const rekeyFile = "vault-rekey"
+
+// legacyDealer is the dealer of users registered before vault keys were
+// wrapped, anyone knowing the id and the login can compute it.
+func (s *session) legacyDealer() (*crypto.Dealer, error) {
+	dealer, err := crypto.NewDealer(s.user.ID + s.user.Login)
+	if err != nil {
+		return nil, fmt.Errorf("crypto.NewDealer %w", err)
+	}
+	return dealer, nil
+}
+
+func (s *session) rekeyPending() (bool, error) {
+	_, err := os.Stat(filepath.Join(s.wd, rekeyFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("os.Stat: %w", err)
+	}
+	return true, nil
+}
+
+func (s *session) markRekey() error {
+	err := os.WriteFile(filepath.Join(s.wd, rekeyFile), nil, 0600)
+	if err != nil {
+		return fmt.Errorf("os.WriteFile: %w", err)
+	}
+	return nil
+}
+
+// finishRekey seals the items, the private key and the emergency accesses
+// of the user that are still under the legacy key with the vault key of the
+// session and pushes them. It runs again after an interruption, values that
+// open with the vault key already are kept.
+func (s *session) finishRekey(ctx context.Context) error {
+	from, err := s.legacyDealer()
+	if err != nil {
+		return err
+	}
+	resealed, err := s.resealItems(ctx, from)
+	if err != nil {
+		return err
+	}
+
+	key, err := s.clientService.FindUserKey(ctx)
+	if err != nil && !errors.Is(err, repo.ErrItemNotFound) {
+		return fmt.Errorf("clientService.FindUserKey: %w", err)
+	}
+	if err == nil {
+		if _, _, _, err := openUserKey(s.dealer, key); err != nil {
+			pub, priv, _, err := openUserKey(from, key)
+			if err != nil {
+				return err
+			}
+			err = s.saveUserKey(ctx, pub, priv)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	accesses, err := s.clientService.FindEmergencyAccess(ctx)
+	if err != nil {
+		return fmt.Errorf("clientService.FindEmergencyAccess: %w", err)
+	}
+	for _, e := range accesses {
+		if e.OwnerLogin != s.user.Login {
+			continue
+		}
+		// the wrapped legacy key is of no use to the contact anymore
+		err = s.grantEmergencyKey(ctx, e.ContactLogin, e.WaitHours)
+		if err != nil {
+			logger.Log.Error("s.grantEmergencyKey", zap.String("contact", e.ContactLogin), zap.Error(err))
+			fmt.Printf("grant the emergency access of %s again, it was not moved to the new key\n",
+				e.ContactLogin)
+		}
+	}
+
+	err = s.sync(ctx)
+	if err != nil {
+		return fmt.Errorf("s.sync: %w", err)
+	}
+	err = os.Remove(filepath.Join(s.wd, rekeyFile))
+	if err != nil {
+		return fmt.Errorf("os.Remove: %w", err)
+	}
+	logger.Log.Info(fmt.Sprintf("sealed %d items with the new vault key", resealed))
+	return nil
+}
+
+// resealItems seals the local items that do not open with the vault key of
+// the session from the from dealer, the next sync pushes them.
+func (s *session) resealItems(ctx context.Context, from *crypto.Dealer) (int, error) {
+	now := time.Now().UTC()
+	resealed := 0
+	creds, err := s.clientService.FindCredentialsByUserID(ctx, s.user.ID)
+	if err != nil {
+		return 0, fmt.Errorf("clientService.FindCredentialsByUserID: %w", err)
+	}
+	for _, c := range creds {
+		if _, _, err := vault.OpenCredentials(s.dealer, *c); err == nil {
+			continue
+		}
+		enc, err := vault.ResealCredentials(from, s.dealer, *c)
+		if err != nil {
+			return 0, fmt.Errorf("vault.ResealCredentials: %w", err)
+		}
+		enc.ModifiedTms = now
+		err = s.clientService.SaveCredentials(ctx, enc)
+		if err != nil {
+			return 0, fmt.Errorf("clientService.SaveCredentials: %w", err)
+		}
+		resealed++
+	}
+
+	cards, err := s.clientService.FindCardsByUserID(ctx, s.user.ID)
+	if err != nil {
+		return 0, fmt.Errorf("clientService.FindCardsByUserID: %w", err)
+	}
+	for _, c := range cards {
+		if _, _, err := vault.OpenCard(s.dealer, *c); err == nil {
+			continue
+		}
+		enc, err := vault.ResealCard(from, s.dealer, *c)
+		if err != nil {
+			return 0, fmt.Errorf("vault.ResealCard: %w", err)
+		}
+		enc.ModifiedTms = now
+		err = s.clientService.SaveCard(ctx, enc)
+		if err != nil {
+			return 0, fmt.Errorf("clientService.SaveCard: %w", err)
+		}
+		resealed++
+	}
+
+	texts, err := s.clientService.FindTextsByUserID(ctx, s.user.ID)
+	if err != nil {
+		return 0, fmt.Errorf("clientService.FindTextsByUserID: %w", err)
+	}
+	for _, t := range texts {
+		if _, err := vault.OpenText(s.dealer, t.Txt); err == nil {
+			continue
+		}
+		t.Txt, err = vault.ResealText(from, s.dealer, t.Txt)
+		if err != nil {
+			return 0, fmt.Errorf("vault.ResealText: %w", err)
+		}
+		t.ModifiedTms = now
+		err = s.clientService.SaveText(ctx, t)
+		if err != nil {
+			return 0, fmt.Errorf("clientService.SaveText: %w", err)
+		}
+		resealed++
+	}
+
+	binaries, err := s.clientService.FindBinariesByUserID(ctx, s.user.ID)
+	if err != nil {
+		return 0, fmt.Errorf("clientService.FindBinariesByUserID: %w", err)
+	}
+	for _, b := range binaries {
+		if _, err := vault.OpenBinary(s.dealer, b.Data); err == nil {
+			continue
+		}
+		b.Data, err = vault.ResealBinary(from, s.dealer, b.Data)
+		if err != nil {
+			return 0, fmt.Errorf("vault.ResealBinary: %w", err)
+		}
+		b.ModifiedTms = now
+		err = s.clientService.SaveBinary(ctx, b)
+		if err != nil {
+			return 0, fmt.Errorf("clientService.SaveBinary: %w", err)
+		}
+		resealed++
+	}
+	return resealed, nil
+}
@@ -0,0 +1,525 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/config"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/prompt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/vault"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"github.com/google/uuid"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// errLocked aborts a command when the vault locks while it waits for input.
+var errLocked = errors.New("vault is locked")
+
+// clearValue entered on edit empties an optional field.
+const clearValue = "-"
+
+const replHelp = `commands:
+  list [cred|card|text|file]  list stored items
+  search <term>               find items by title or visible fields
+  view <id> [show]            show an item, secrets are masked unless show is given
+  copy <id> [field]           copy a field to the clipboard
+  add cred|card|text|file     add a new item
+  edit <id>                   edit an item, empty input keeps the value, - clears it
+  delete <id>                 delete an item
+  sync                        synchronize with the server
+  lock                        lock the vault
+  help                        show this help
+  exit                        leave the session
+ids may be shortened to any unique prefix`
+
+type repl struct {
+	s           *session
+	in          *prompt.Prompter
+	out         io.Writer
+	idle        time.Duration
+	clipTimeout time.Duration
+}
+
+// DoRepl runs an interactive session over stdin. The vault is unlocked once
+// and locked again after conf.IdleTimeout without input.
+func DoRepl(ctx context.Context, conf *config.Config, s *session, p *prompt.Prompter) error {
+	r := &repl{
+		s:           s,
+		in:          p,
+		out:         os.Stdout,
+		idle:        conf.IdleTimeout,
+		clipTimeout: conf.ClipTimeout,
+	}
+	return r.run(ctx)
+}
+
+func (r *repl) run(ctx context.Context) error {
+	fmt.Fprintln(r.out, "type help for the list of commands")
+	for {
+		if r.s.locked() {
+			password, err := r.askSecret(ctx, "user password")
+			if err != nil {
+				return ignoreExit(err)
+			}
+			if err := r.s.unlock(password); err != nil {
+				fmt.Fprintln(r.out, "wrong password")
+			}
+			continue
+		}
+		line, err := r.ask(ctx, "")
+		if err != nil {
+			if errors.Is(err, errLocked) {
+				continue
+			}
+			return ignoreExit(err)
+		}
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			continue
+		}
+		if args[0] == "exit" || args[0] == "quit" {
+			return nil
+		}
+		if err := r.exec(ctx, args); err != nil {
+			if ctx.Err() != nil || errors.Is(err, io.EOF) {
+				return ignoreExit(err)
+			}
+			fmt.Fprintln(r.out, "error:", err)
+		}
+	}
+}
+
+// ignoreExit treats closed input and interruption as a normal exit.
+func ignoreExit(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+// next waits for an input line. When the vault is unlocked and nothing is
+// entered for the idle period the vault is locked, the pending input is
+// discarded and errLocked returned.
+func (r *repl) next(ctx context.Context, secret bool) (string, error) {
+	type result struct {
+		line string
+		err  error
+	}
+	// the read can't be interrupted, so it is finished even after a lock
+	ch := make(chan result, 1)
+	go func() {
+		read := r.in.ReadLine
+		if secret {
+			read = r.in.ReadSecret
+		}
+		line, err := read()
+		ch <- result{line: line, err: err}
+	}()
+
+	var timeout <-chan time.Time
+	if r.idle > 0 && !r.s.locked() {
+		timer := time.NewTimer(r.idle)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	var expired bool
+	for {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case res := <-ch:
+			if expired {
+				return "", errLocked
+			}
+			return res.line, res.err
+		case <-timeout:
+			r.s.lock()
+			fmt.Fprintln(r.out, "\nvault locked after inactivity, press enter")
+			expired = true
+			timeout = nil
+		}
+	}
+}
+
+func (r *repl) ask(ctx context.Context, label string) (string, error) {
+	if label == "" {
+		fmt.Fprint(r.out, "> ")
+	} else {
+		fmt.Fprintf(r.out, "%s: ", label)
+	}
+	line, err := r.next(ctx, false)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// askSecret reads a value without echo when running on a terminal.
+func (r *repl) askSecret(ctx context.Context, label string) (string, error) {
+	fmt.Fprintf(r.out, "%s: ", label)
+	return r.next(ctx, true)
+}
+
+// askDefault returns current when nothing is entered and an empty string
+// for clearValue.
+func (r *repl) askDefault(ctx context.Context, label, current string,
+	secret bool) (string, error) {
+	shown := current
+	if secret && current != "" {
+		shown = "hidden"
+	}
+	if shown != "" {
+		label = fmt.Sprintf("%s [%s]", label, shown)
+	}
+	ask := r.ask
+	if secret {
+		ask = r.askSecret
+	}
+	v, err := ask(ctx, label)
+	if err != nil {
+		return "", err
+	}
+	switch v {
+	case "":
+		return current, nil
+	case clearValue:
+		return "", nil
+	}
+	return v, nil
+}
+
+func (r *repl) exec(ctx context.Context, args []string) error {
+	switch args[0] {
+	case "help":
+		fmt.Fprintln(r.out, replHelp)
+	case "list":
+		kind := ""
+		if len(args) > 1 {
+			kind = args[1]
+		}
+		return r.list(ctx, func(e entry) bool { return kind == "" || e.kind == kind })
+	case "search":
+		if len(args) < 2 {
+			return errors.New("usage: search <term>")
+		}
+		term := strings.Join(args[1:], " ")
+		return r.list(ctx, func(e entry) bool { return e.matches(term) })
+	case "view":
+		if len(args) < 2 {
+			return errors.New("usage: view <id> [show]")
+		}
+		return r.view(ctx, args[1], len(args) > 2 && args[2] == "show")
+	case "copy":
+		if len(args) < 2 {
+			return errors.New("usage: copy <id> [field]")
+		}
+		name := ""
+		if len(args) > 2 {
+			name = args[2]
+		}
+		return r.copy(ctx, args[1], name)
+	case "add":
+		if len(args) < 2 {
+			return errors.New("usage: add cred|card|text|file")
+		}
+		return r.add(ctx, args[1])
+	case "edit":
+		if len(args) < 2 {
+			return errors.New("usage: edit <id>")
+		}
+		return r.edit(ctx, args[1])
+	case "delete":
+		if len(args) < 2 {
+			return errors.New("usage: delete <id>")
+		}
+		return r.delete(ctx, args[1])
+	case "sync":
+		if err := r.s.sync(ctx); err != nil {
+			return fmt.Errorf("s.sync: %w", err)
+		}
+		fmt.Fprintln(r.out, "synchronized")
+	case "lock":
+		r.s.lock()
+		fmt.Fprintln(r.out, "vault locked")
+	default:
+		return fmt.Errorf("unknown command %q, type help", args[0])
+	}
+	return nil
+}
+
+func (r *repl) list(ctx context.Context, keep func(e entry) bool) error {
+	entries, err := r.s.loadEntries(ctx)
+	if err != nil {
+		return fmt.Errorf("s.loadEntries: %w", err)
+	}
+	var n int
+	for _, e := range entries {
+		if !keep(e) {
+			continue
+		}
+		fmt.Fprintf(r.out, "%-4s  %s  %s\n", e.kind, e.id, e.title)
+		n++
+	}
+	fmt.Fprintf(r.out, "%d item(s)\n", n)
+	return nil
+}
+
+func (r *repl) view(ctx context.Context, id string, reveal bool) error {
+	e, err := r.s.findEntry(ctx, id)
+	if err != nil {
+		return fmt.Errorf("s.findEntry: %w", err)
+	}
+	fmt.Fprintf(r.out, "%s %s\n", e.kind, e.id)
+	for _, f := range e.fields {
+		fmt.Fprintf(r.out, "  %s: %s\n", f.name, f.show(reveal))
+	}
+	return nil
+}
+
+func (r *repl) copy(ctx context.Context, id, name string) error {
+	e, err := r.s.findEntry(ctx, id)
+	if err != nil {
+		return fmt.Errorf("s.findEntry: %w", err)
+	}
+	return copyField(r.out, e, name, r.clipTimeout)
+}
+
+func (r *repl) delete(ctx context.Context, id string) error {
+	e, err := r.s.findEntry(ctx, id)
+	if err != nil {
+		return fmt.Errorf("s.findEntry: %w", err)
+	}
+	answer, err := r.ask(ctx, fmt.Sprintf("delete %s %q? [y/N]", e.kind, e.title))
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(answer, "y") {
+		return nil
+	}
+	if err := r.s.deleteEntry(ctx, e); err != nil {
+		return fmt.Errorf("s.deleteEntry: %w", err)
+	}
+	fmt.Fprintln(r.out, "deleted")
+	return nil
+}
+
+func (r *repl) add(ctx context.Context, kind string) error {
+	id := uuid.New().String()
+	var err error
+	switch kind {
+	case kindCred:
+		err = r.saveCredentials(ctx, id, true, "", "")
+	case kindCard:
+		err = r.saveCard(ctx, id, true, model.CardInput{})
+	case kindText:
+		err = r.saveText(ctx, id, true, "")
+	case kindFile:
+		err = r.saveFile(ctx, id, true)
+	default:
+		return fmt.Errorf("unknown kind %q", kind)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(r.out, "saved %s id = %s\n", kind, id)
+	return nil
+}
+
+func (r *repl) edit(ctx context.Context, id string) error {
+	e, err := r.s.findEntry(ctx, id)
+	if err != nil {
+		return fmt.Errorf("s.findEntry: %w", err)
+	}
+	switch e.kind {
+	case kindCred:
+		err = r.editCredentials(ctx, e.id)
+	case kindCard:
+		err = r.editCard(ctx, e.id)
+	case kindText:
+		err = r.editText(ctx, e.id)
+	case kindFile:
+		err = r.saveFile(ctx, e.id, false)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(r.out, "saved %s id = %s\n", e.kind, e.id)
+	return nil
+}
+
+func (r *repl) editCredentials(ctx context.Context, id string) error {
+	cred, err := r.s.clientService.FindCredentialsByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("clientService.FindCredentialsByID: %w", err)
+	}
+	login, password, err := vault.OpenCredentials(r.s.dealer, cred)
+	if err != nil {
+		return fmt.Errorf("vault.OpenCredentials: %w", err)
+	}
+	return r.saveCredentials(ctx, id, false, login, password)
+}
+
+func (r *repl) editCard(ctx context.Context, id string) error {
+	card, err := r.s.clientService.FindCardByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("clientService.FindCardByID: %w", err)
+	}
+	in, _, err := vault.OpenCard(r.s.dealer, card)
+	if err != nil {
+		return fmt.Errorf("vault.OpenCard: %w", err)
+	}
+	return r.saveCard(ctx, id, false, in)
+}
+
+func (r *repl) editText(ctx context.Context, id string) error {
+	txt, err := r.s.clientService.FindTextByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("clientService.FindTextByID: %w", err)
+	}
+	current, err := vault.OpenText(r.s.dealer, txt.Txt)
+	if err != nil {
+		return fmt.Errorf("vault.OpenText: %w", err)
+	}
+	return r.saveText(ctx, id, false, current)
+}
+
+func (r *repl) saveCredentials(ctx context.Context, id string, isNew bool,
+	login, password string) error {
+	login, err := r.askDefault(ctx, "login", login, false)
+	if err != nil {
+		return err
+	}
+	password, err = r.askDefault(ctx, "password", password, true)
+	if err != nil {
+		return err
+	}
+	err = model.ValidateCredentialsInput(login, password)
+	if err != nil {
+		return fmt.Errorf("model.ValidateCredentialsInput: %w", err)
+	}
+	cred, err := vault.SealCredentials(r.s.dealer, login, password)
+	if err != nil {
+		return fmt.Errorf("vault.SealCredentials: %w", err)
+	}
+	cred.ID = id
+	cred.New = isNew
+	cred.UserID = r.s.user.ID
+	cred.Status = model.StatusActive
+	cred.ModifiedTms = time.Now().UTC()
+	err = r.s.clientService.SaveCredentials(ctx, cred)
+	if err != nil {
+		return fmt.Errorf("clientService.SaveCredentials: %w", err)
+	}
+	return nil
+}
+
+func (r *repl) saveCard(ctx context.Context, id string, isNew bool, in model.CardInput) error {
+	addr := &in.BillingAddress
+	prompts := []struct {
+		label  string
+		value  *string
+		secret bool
+	}{
+		{"number", &in.Num, true},
+		{"cvc", &in.CVC, true},
+		{"holder name", &in.HolderName, false},
+		{"expiry month", &in.ExpMonth, false},
+		{"expiry year", &in.ExpYear, false},
+		{"pin (optional)", &in.PIN, true},
+		{"billing line 1 (optional)", &addr.Line1, false},
+		{"billing line 2 (optional)", &addr.Line2, false},
+		{"billing city (optional)", &addr.City, false},
+		{"billing region (optional)", &addr.Region, false},
+		{"billing postal code (optional)", &addr.PostalCode, false},
+		{"billing country code (optional)", &addr.Country, false},
+	}
+	for _, p := range prompts {
+		v, err := r.askDefault(ctx, p.label, *p.value, p.secret)
+		if err != nil {
+			return err
+		}
+		*p.value = v
+	}
+	addr.Country = strings.ToUpper(addr.Country)
+
+	err := model.ValidateCardInput(in, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("model.ValidateCardInput: %w", err)
+	}
+	card, err := vault.SealCard(r.s.dealer, in)
+	if err != nil {
+		return fmt.Errorf("vault.SealCard: %w", err)
+	}
+	card.ID = id
+	card.New = isNew
+	card.UserID = r.s.user.ID
+	card.Status = model.StatusActive
+	card.ModifiedTms = time.Now().UTC()
+	err = r.s.clientService.SaveCard(ctx, card)
+	if err != nil {
+		return fmt.Errorf("clientService.SaveCard: %w", err)
+	}
+	return nil
+}
+
+func (r *repl) saveText(ctx context.Context, id string, isNew bool, current string) error {
+	txt, err := r.askDefault(ctx, "text", current, false)
+	if err != nil {
+		return err
+	}
+	if err := model.ValidateTextInput(txt); err != nil {
+		return fmt.Errorf("model.ValidateTextInput: %w", err)
+	}
+	enc, err := vault.SealText(r.s.dealer, txt)
+	if err != nil {
+		return fmt.Errorf("vault.SealText: %w", err)
+	}
+	t := model.Text{
+		ID:          id,
+		Txt:         enc,
+		New:         isNew,
+		UserID:      r.s.user.ID,
+		Status:      model.StatusActive,
+		ModifiedTms: time.Now().UTC(),
+	}
+	err = r.s.clientService.SaveText(ctx, &t)
+	if err != nil {
+		return fmt.Errorf("clientService.SaveText: %w", err)
+	}
+	return nil
+}
+
+func (r *repl) saveFile(ctx context.Context, id string, isNew bool) error {
+	filename, err := r.ask(ctx, "file path")
+	if err != nil {
+		return err
+	}
+	if filename == "" {
+		return errors.New("file path is empty")
+	}
+	data, _, err := readFile(filename)
+	if err != nil {
+		return fmt.Errorf("readFile: %w", err)
+	}
+	enc, err := vault.SealBinary(r.s.dealer, data)
+	if err != nil {
+		return fmt.Errorf("vault.SealBinary: %w", err)
+	}
+	b := model.Binary{
+		ID:          id,
+		Name:        filename,
+		Data:        enc,
+		New:         isNew,
+		UserID:      r.s.user.ID,
+		Status:      model.StatusActive,
+		ModifiedTms: time.Now().UTC(),
+	}
+	err = r.s.clientService.SaveBinary(ctx, &b)
+	if err != nil {
+		return fmt.Errorf("clientService.SaveBinary: %w", err)
+	}
+	return nil
+}
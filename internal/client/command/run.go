@@ -3,8 +3,10 @@ package command
 import (
 	"context"
 	"fmt"
-	"github.com/denis-oreshkevich/gophkeeper/internal/shared/config"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/clipboard"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/config"
 	"github.com/denis-oreshkevich/gophkeeper/internal/shared/logger"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/tracing"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"os"
@@ -19,6 +21,10 @@ var buildDate = "N/A"
 var buildCommit = "N/A"
 
 func Run() error {
+	if len(os.Args) > 1 && os.Args[1] == clipboard.ClearCommand {
+		return clipboard.RunClear(os.Args[2:])
+	}
+
 	ctx := context.Background()
 	err := logger.Initialize(zapcore.DebugLevel.String())
 	if err != nil {
@@ -39,6 +45,18 @@ func Run() error {
 	ctx, stop := signal.NotifyContext(ctx, syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT)
 	defer stop()
 
+	// spans go to stderr, stdout is for the command output
+	flushTracing, err := tracing.Setup(ctx, "gophkeeper-client", tracing.Config{
+		Exporter: conf.TraceExporter,
+		Endpoint: conf.OTLPEndpoint,
+		Insecure: conf.OTLPInsecure,
+		Writer:   os.Stderr,
+	})
+	if err != nil {
+		logger.Log.Fatal("tracing setup", zap.Error(err))
+	}
+	defer flushTracing()
+
 	err = Do(ctx, conf)
 	return err
 }
@@ -0,0 +1,112 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/config"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/prompt"
+)
+
+// readSecrets fills secret values that were not passed as flags. They are
+// asked without echo on a terminal, or read one per line from stdin with
+// -stdin: the user password first, then the item secrets, the export
+// passphrase or the new password in prompt order.
+func readSecrets(conf *config.Config, p *prompt.Prompter) error {
+	required := func(v *string, label string) error {
+		if *v != "" {
+			return nil
+		}
+		s, err := p.Secret(label)
+		if err != nil {
+			return fmt.Errorf("read %s: %w", label, err)
+		}
+		*v = s
+		return nil
+	}
+	optional := func(v *string, label string) error {
+		if *v != "" {
+			return nil
+		}
+		s, err := p.Optional(label)
+		if err != nil {
+			return fmt.Errorf("read %s: %w", label, err)
+		}
+		*v = s
+		return nil
+	}
+
+	newPassword := func() error {
+		if err := required(&conf.NewPassword, "new password"); err != nil {
+			return err
+		}
+		if p.Interactive() {
+			again, err := p.Secret("repeat new password")
+			if err != nil {
+				return fmt.Errorf("read new password: %w", err)
+			}
+			if again != conf.NewPassword {
+				return errors.New("new passwords do not match")
+			}
+		}
+		return nil
+	}
+
+	if conf.IsWipe && !conf.WipeAccount {
+		return nil
+	}
+	if conf.IsRecover {
+		if err := required(&conf.RecoveryKey, "recovery key"); err != nil {
+			return err
+		}
+		return newPassword()
+	}
+	if err := required(&conf.UserPassword, "user password"); err != nil {
+		return err
+	}
+	if conf.IsRegister && p.Interactive() {
+		again, err := p.Secret("repeat user password")
+		if err != nil {
+			return fmt.Errorf("read user password: %w", err)
+		}
+		if again != conf.UserPassword {
+			return errors.New("passwords do not match")
+		}
+	}
+	if conf.IsPasswd {
+		return newPassword()
+	}
+	if conf.IsExport && conf.ExportFormat != "" {
+		return nil
+	}
+	if conf.IsExport || conf.IsRestore {
+		if err := required(&conf.ExportPassphrase, "export passphrase"); err != nil {
+			return err
+		}
+		if conf.IsExport && p.Interactive() {
+			again, err := p.Secret("repeat export passphrase")
+			if err != nil {
+				return fmt.Errorf("read export passphrase: %w", err)
+			}
+			if again != conf.ExportPassphrase {
+				return errors.New("export passphrases do not match")
+			}
+		}
+		return nil
+	}
+	if conf.Action != config.ActionSave {
+		return nil
+	}
+	switch {
+	case conf.IsCredentialsFlagsParsed && !conf.Generate:
+		return required(&conf.CredentialsPassword, "password")
+	case conf.IsCardFlagsParsed:
+		if err := required(&conf.CardNum, "card number"); err != nil {
+			return err
+		}
+		if err := required(&conf.CardCVC, "CVC"); err != nil {
+			return err
+		}
+		return optional(&conf.CardPIN, "PIN")
+	}
+	return nil
+}
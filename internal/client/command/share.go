@@ -0,0 +1,339 @@
+package command
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/config"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/repo"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/transfer"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/crypto"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/logger"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sharesFile keeps the ids of the shares received by the user, so the
+// local copies can be removed once the access is revoked.
+const sharesFile = "shares.json"
+
+// DoShare gives another user access to a vault item or revokes it.
+func DoShare(ctx context.Context, conf *config.Config, s *session) error {
+	if conf.ShowFingerprint {
+		pub, _, err := s.userKeys(ctx)
+		if err != nil {
+			return fmt.Errorf("s.userKeys: %w", err)
+		}
+		fmt.Printf("key fingerprint of %s: %s\n", s.user.Login, crypto.Fingerprint(pub))
+		return nil
+	}
+	if conf.ID == "" || strings.TrimSpace(conf.ShareWith) == "" {
+		return errors.New("item id and user are required, set -id and -with")
+	}
+	if conf.ShareWith == s.user.Login {
+		return errors.New("items can't be shared with yourself")
+	}
+	e, err := s.findEntry(ctx, conf.ID)
+	if err != nil {
+		return fmt.Errorf("s.findEntry: %w", err)
+	}
+	received, err := s.loadReceivedShares()
+	if err != nil {
+		return fmt.Errorf("s.loadReceivedShares: %w", err)
+	}
+	if _, ok := received[e.id]; ok {
+		return errors.New("items shared with you can't be shared again")
+	}
+	refs, err := s.loadOrgItems()
+	if err != nil {
+		return fmt.Errorf("s.loadOrgItems: %w", err)
+	}
+	if _, ok := refs[e.id]; ok {
+		return errors.New("organization items are shared through their collection")
+	}
+	shares, err := s.clientService.FindShares(ctx)
+	if err != nil {
+		return fmt.Errorf("clientService.FindShares: %w", err)
+	}
+	var share *model.Share
+	for i := range shares {
+		if shares[i].ItemID == e.id {
+			share = &shares[i]
+		}
+	}
+
+	if conf.ShareRevoke {
+		if share == nil {
+			return fmt.Errorf("%s is not shared", e.id)
+		}
+		err := s.clientService.RevokeShare(ctx, share.ID, conf.ShareWith)
+		if err != nil {
+			if errors.Is(err, repo.ErrItemNotFound) {
+				return fmt.Errorf("%s has no access to %s", conf.ShareWith, e.id)
+			}
+			return fmt.Errorf("clientService.RevokeShare: %w", err)
+		}
+		fmt.Printf("revoked the access of %s to %s\n", conf.ShareWith, e.title)
+		return nil
+	}
+
+	recipient, err := s.clientService.FindPublicKey(ctx, conf.ShareWith)
+	if err != nil {
+		if errors.Is(err, repo.ErrItemNotFound) {
+			return fmt.Errorf("%s has no key yet, they have to sync once", conf.ShareWith)
+		}
+		return fmt.Errorf("clientService.FindPublicKey: %w", err)
+	}
+	recipientPub, err := s.contactKey(ctx, conf.ShareWith, recipient.PublicKey)
+	if err != nil {
+		return fmt.Errorf("s.contactKey: %w", err)
+	}
+	pub, priv, err := s.userKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("s.userKeys: %w", err)
+	}
+
+	id := uuid.New().String()
+	var key []byte
+	if share != nil {
+		id = share.ID
+		key, err = unwrapKey(priv, share.WrappedKey)
+	} else {
+		key, err = crypto.NewItemKey()
+	}
+	if err != nil {
+		return err
+	}
+
+	it, st, err := s.findItem(ctx, transfer.Kind(e.kind), e.id)
+	if err != nil {
+		return fmt.Errorf("s.findItem: %w", err)
+	}
+	payload, err := sealItem(key, it)
+	if err != nil {
+		return err
+	}
+	own, err := wrapKey(pub, key)
+	if err != nil {
+		return err
+	}
+	theirs, err := wrapKey(recipientPub, key)
+	if err != nil {
+		return err
+	}
+	err = s.clientService.SaveShare(ctx, model.Share{
+		ID:          id,
+		ItemID:      e.id,
+		Kind:        e.kind,
+		Payload:     payload,
+		ModifiedTms: st.modified,
+		Grants: []model.ShareGrant{
+			{Login: s.user.Login, WrappedKey: own, Access: model.AccessWrite},
+			{Login: conf.ShareWith, WrappedKey: theirs, Access: conf.ShareAccess},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("clientService.SaveShare: %w", err)
+	}
+	fmt.Printf("shared %s with %s, %s access\n", e.title, conf.ShareWith,
+		strings.ToLower(string(conf.ShareAccess)))
+	return nil
+}
+
+// userKeys returns the keypair of the user, a new one is generated and
+// published on first use. A private key wrapped by an older client with the
+// static nonce of the dealer is wrapped again.
+func (s *session) userKeys(ctx context.Context) (pub, priv []byte, err error) {
+	key, err := s.clientService.FindUserKey(ctx)
+	if err == nil {
+		pub, priv, sealed, err := openUserKey(s.dealer, key)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !sealed {
+			err = s.saveUserKey(ctx, pub, priv)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		return pub, priv, nil
+	}
+	if !errors.Is(err, repo.ErrItemNotFound) {
+		return nil, nil, fmt.Errorf("clientService.FindUserKey: %w", err)
+	}
+
+	pub, priv, err = crypto.GenerateKeyPair()
+	if err != nil {
+		return nil, nil, fmt.Errorf("crypto.GenerateKeyPair: %w", err)
+	}
+	err = s.saveUserKey(ctx, pub, priv)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pub, priv, nil
+}
+
+// openUserKey opens the private key of key with dealer, sealed is false for
+// a key wrapped with the static nonce.
+func openUserKey(dealer *crypto.Dealer, key model.UserKey) (pub, priv []byte, sealed bool, err error) {
+	pub, err = base64.StdEncoding.DecodeString(key.PublicKey)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("base64.StdEncoding.DecodeString: %w", err)
+	}
+	encoded, sealed := strings.CutPrefix(key.WrappedPrivateKey, crypto.SealedPrefix)
+	wrapped, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("base64.StdEncoding.DecodeString: %w", err)
+	}
+	if sealed {
+		priv, err = crypto.Open(dealer.Key(), wrapped)
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("crypto.Open: %w", err)
+		}
+		return pub, priv, true, nil
+	}
+	priv, err = dealer.DecryptLegacy(wrapped)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("dealer.DecryptLegacy: %w", err)
+	}
+	return pub, priv, false, nil
+}
+
+// saveUserKey publishes the keypair with the private key sealed under the
+// vault key.
+func (s *session) saveUserKey(ctx context.Context, pub, priv []byte) error {
+	wrapped, err := crypto.Seal(s.dealer.Key(), priv)
+	if err != nil {
+		return fmt.Errorf("crypto.Seal: %w", err)
+	}
+	err = s.clientService.SaveUserKey(ctx, model.UserKey{
+		PublicKey:         base64.StdEncoding.EncodeToString(pub),
+		WrappedPrivateKey: crypto.SealedPrefix + base64.StdEncoding.EncodeToString(wrapped),
+	})
+	if err != nil {
+		return fmt.Errorf("clientService.SaveUserKey: %w", err)
+	}
+	return nil
+}
+
+// syncShares brings the shared items up to date. Newer server copies are
+// written into the vault, local changes are pushed when the user may write
+// them. Received items whose access was revoked are removed.
+func (s *session) syncShares(ctx context.Context) error {
+	_, priv, err := s.userKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("s.userKeys: %w", err)
+	}
+	shares, err := s.clientService.FindShares(ctx)
+	if err != nil {
+		return fmt.Errorf("clientService.FindShares: %w", err)
+	}
+	prev, err := s.loadReceivedShares()
+	if err != nil {
+		return fmt.Errorf("s.loadReceivedShares: %w", err)
+	}
+
+	received := make(map[string]transfer.Kind)
+	for _, sh := range shares {
+		owned := sh.OwnerID == s.user.ID
+		localID := sh.ID
+		if owned {
+			localID = sh.ItemID
+		} else {
+			received[sh.ID] = transfer.Kind(sh.Kind)
+		}
+		err := s.syncShare(ctx, sh, localID, owned, priv)
+		if err != nil {
+			logger.Log.Error("s.syncShare", zap.String("shareID", sh.ID), zap.Error(err))
+		}
+	}
+
+	for id, kind := range prev {
+		if _, ok := received[id]; ok {
+			continue
+		}
+		err := s.deleteEntry(ctx, entry{id: id, kind: string(kind)})
+		if err != nil && !errors.Is(err, repo.ErrItemNotFound) {
+			return fmt.Errorf("s.deleteEntry: %w", err)
+		}
+	}
+	return s.saveReceivedShares(received)
+}
+
+func (s *session) syncShare(ctx context.Context, sh model.Share, localID string,
+	owned bool, priv []byte) error {
+	key, err := unwrapKey(priv, sh.WrappedKey)
+	if err != nil {
+		return err
+	}
+	it, st, err := s.findItem(ctx, transfer.Kind(sh.Kind), localID)
+	found := err == nil
+	if err != nil && !errors.Is(err, repo.ErrItemNotFound) {
+		return fmt.Errorf("s.findItem: %w", err)
+	}
+	if !found && owned {
+		// the owner deleted the item, the last copy stays with the others
+		return nil
+	}
+	tms := st.modified
+
+	if !found || sh.ModifiedTms.After(tms) {
+		remote, err := openItem(key, sh.Payload)
+		if err != nil {
+			return err
+		}
+		err = s.putItem(ctx, localID, remote, sh.ModifiedTms, !found)
+		if err != nil {
+			return fmt.Errorf("s.putItem: %w", err)
+		}
+		return nil
+	}
+	// deleting a local copy does not delete the shared item
+	if st.deleted || !tms.After(sh.ModifiedTms) || !(owned || sh.Access == model.AccessWrite) {
+		return nil
+	}
+	payload, err := sealItem(key, it)
+	if err != nil {
+		return err
+	}
+	err = s.clientService.UpdateSharePayload(ctx, sh.ID,
+		model.SharePayload{Payload: payload, ModifiedTms: tms})
+	if err != nil {
+		return fmt.Errorf("clientService.UpdateSharePayload: %w", err)
+	}
+	return nil
+}
+
+func (s *session) loadReceivedShares() (map[string]transfer.Kind, error) {
+	res := make(map[string]transfer.Kind)
+	data, err := os.ReadFile(filepath.Join(s.wd, sharesFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return res, nil
+		}
+		return nil, fmt.Errorf("os.ReadFile: %w", err)
+	}
+	err = json.Unmarshal(data, &res)
+	if err != nil {
+		return nil, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+	return res, nil
+}
+
+func (s *session) saveReceivedShares(received map[string]transfer.Kind) error {
+	data, err := json.Marshal(received)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+	err = os.WriteFile(filepath.Join(s.wd, sharesFile), data, 0600)
+	if err != nil {
+		return fmt.Errorf("os.WriteFile: %w", err)
+	}
+	return nil
+}
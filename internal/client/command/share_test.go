@@ -0,0 +1,76 @@
+package command
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/crypto"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// legacySeal encrypts like older clients did, with the nonce taken from the
+// end of the key.
+func legacySeal(t *testing.T, key, msg []byte) []byte {
+	t.Helper()
+	block, err := aes.NewCipher(key)
+	require.NoError(t, err)
+	aead, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+	return aead.Seal(nil, key[len(key)-aead.NonceSize():], msg, nil)
+}
+
+func TestOpenUserKey(t *testing.T) {
+	dealer, err := crypto.NewDealer("id" + "alice")
+	require.NoError(t, err)
+	other, err := crypto.NewDealer("id" + "bob")
+	require.NoError(t, err)
+	pub, priv, err := crypto.GenerateKeyPair()
+	require.NoError(t, err)
+
+	sealed, err := crypto.Seal(dealer.Key(), priv)
+	require.NoError(t, err)
+	published := base64.StdEncoding.EncodeToString(pub)
+	sealedKey := model.UserKey{
+		PublicKey:         published,
+		WrappedPrivateKey: crypto.SealedPrefix + base64.StdEncoding.EncodeToString(sealed),
+	}
+	legacyKey := model.UserKey{
+		PublicKey:         published,
+		WrappedPrivateKey: base64.StdEncoding.EncodeToString(legacySeal(t, dealer.Key(), priv)),
+	}
+
+	tests := []struct {
+		name       string
+		dealer     *crypto.Dealer
+		key        model.UserKey
+		wantSealed bool
+		wantErr    bool
+	}{
+		{name: "sealed", dealer: dealer, key: sealedKey, wantSealed: true},
+		{name: "legacy", dealer: dealer, key: legacyKey},
+		{name: "sealed, other vault key", dealer: other, key: sealedKey, wantErr: true},
+		{name: "legacy, other vault key", dealer: other, key: legacyKey, wantErr: true},
+		{
+			name:    "not base64",
+			dealer:  dealer,
+			key:     model.UserKey{PublicKey: published, WrappedPrivateKey: crypto.SealedPrefix + "!"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotPub, gotPriv, gotSealed, err := openUserKey(tt.dealer, tt.key)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, pub, gotPub)
+			assert.Equal(t, priv, gotPriv)
+			assert.Equal(t, tt.wantSealed, gotSealed)
+		})
+	}
+}
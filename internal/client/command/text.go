@@ -3,34 +3,57 @@ package command
 import (
 	"context"
 	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/config"
 	"github.com/denis-oreshkevich/gophkeeper/internal/client/service"
-	"github.com/denis-oreshkevich/gophkeeper/internal/shared/config"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/vault"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/crypto"
 	"github.com/denis-oreshkevich/gophkeeper/internal/shared/logger"
 	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
 	"github.com/google/uuid"
+	"os"
 	"time"
 )
 
 func DoText(ctx context.Context, conf *config.Config, clientService *service.ClientService,
-	user model.User) error {
+	dealer *crypto.Dealer, user model.User) error {
 	switch conf.Action {
 	case config.ActionGet:
 		byID, err := clientService.FindTextByID(ctx, conf.ID)
 		if err != nil {
 			return fmt.Errorf("clientService.FindTextByID: %w", err)
 		}
-		logger.Log.Info(byID.Txt)
+		e, err := textEntry(dealer, byID)
+		if err != nil {
+			return fmt.Errorf("textEntry: %w", err)
+		}
+		txt := e.fields[0].value
+		switch {
+		case conf.CopyField != "":
+			return copyField(os.Stdout, e, conf.CopyField, conf.ClipTimeout)
+		case conf.ShowSecrets:
+			fmt.Println(txt)
+		default:
+			fmt.Printf("text %s has %d characters, use -show or -copy\n",
+				byID.ID, len([]rune(txt)))
+		}
 	case config.ActionSave:
+		if err := model.ValidateTextInput(conf.Text); err != nil {
+			return fmt.Errorf("model.ValidateTextInput: %w", err)
+		}
+		enc, err := vault.SealText(dealer, conf.Text)
+		if err != nil {
+			return fmt.Errorf("vault.SealText: %w", err)
+		}
 		id := uuid.New()
 		txt := model.Text{
 			ID:          id.String(),
-			Txt:         conf.Text,
+			Txt:         enc,
 			New:         conf.IsNew,
 			UserID:      user.ID,
 			Status:      model.StatusActive,
 			ModifiedTms: time.Now().UTC(),
 		}
-		err := clientService.SaveText(ctx, &txt)
+		err = clientService.SaveText(ctx, &txt)
 		if err != nil {
 			return fmt.Errorf("clientService.SaveText: %w", err)
 		}
@@ -0,0 +1,259 @@
+package command
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/config"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/auth"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	deviceCertFile = "device.pem"
+	deviceKeyFile  = "device-key.pem"
+)
+
+// knownServersFile keeps the pins of the servers trusted on first use, one
+// "address pin" per line.
+const knownServersFile = "known_servers"
+
+var ErrServerKeyChanged = errors.New("server key changed")
+
+// tlsConfig verifies the server against the CA bundle, the pin, or both.
+// With neither the key seen first for the address is trusted and kept in
+// the working directory.
+func tlsConfig(conf *config.Config, dev *device) (*tls.Config, error) {
+	tlsConf := &tls.Config{MinVersion: tls.VersionTLS12}
+	if dev != nil {
+		tlsConf.GetClientCertificate = dev.clientCertificate
+	}
+	pin := normalizePin(conf.ServerPin)
+
+	if conf.ServerCA != "" {
+		data, err := os.ReadFile(conf.ServerCA)
+		if err != nil {
+			return nil, fmt.Errorf("os.ReadFile: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates in %s", conf.ServerCA)
+		}
+		tlsConf.RootCAs = pool
+		if pin != "" {
+			tlsConf.VerifyPeerCertificate = verifyPin(pin)
+		}
+		return tlsConf, nil
+	}
+
+	// the chain is not verified, the pin alone says who the server is
+	tlsConf.InsecureSkipVerify = true
+	if pin != "" {
+		tlsConf.VerifyPeerCertificate = verifyPin(pin)
+		return tlsConf, nil
+	}
+	known := &knownServers{
+		path:    filepath.Join(conf.WorkingDir, knownServersFile),
+		address: conf.ServerAddress,
+	}
+	tlsConf.VerifyPeerCertificate = known.verify
+	return tlsConf, nil
+}
+
+func normalizePin(pin string) string {
+	pin = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(pin)), "sha256:")
+	return strings.ReplaceAll(pin, ":", "")
+}
+
+func leafPin(rawCerts [][]byte) (string, error) {
+	if len(rawCerts) == 0 {
+		return "", errors.New("no server certificate")
+	}
+	cert, err := x509.ParseCertificate(rawCerts[0])
+	if err != nil {
+		return "", fmt.Errorf("x509.ParseCertificate: %w", err)
+	}
+	return auth.Pin(cert), nil
+}
+
+func verifyPin(pin string) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		got, err := leafPin(rawCerts)
+		if err != nil {
+			return err
+		}
+		if got != pin {
+			return fmt.Errorf("%w: pin %s, want %s", ErrServerKeyChanged, got, pin)
+		}
+		return nil
+	}
+}
+
+// knownServers trusts the key of an address on first use.
+type knownServers struct {
+	path    string
+	address string
+
+	mu sync.Mutex
+}
+
+func (k *knownServers) verify(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	got, err := leafPin(rawCerts)
+	if err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	pins, err := k.load()
+	if err != nil {
+		return fmt.Errorf("k.load: %w", err)
+	}
+	if want, ok := pins[k.address]; ok {
+		if got != want {
+			return fmt.Errorf("%w: %s has pin %s, %s is trusted, remove it from %s if the change is expected",
+				ErrServerKeyChanged, k.address, got, want, k.path)
+		}
+		return nil
+	}
+
+	err = k.add(got)
+	if err != nil {
+		return fmt.Errorf("k.add: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "trusting %s on first use, pin %s\n", k.address, got)
+	return nil
+}
+
+func (k *knownServers) load() (map[string]string, error) {
+	pins := make(map[string]string)
+	f, err := os.Open(k.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return pins, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("os.Open: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 {
+			pins[fields[0]] = fields[1]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scanner.Err: %w", err)
+	}
+	return pins, nil
+}
+
+func (k *knownServers) add(pin string) error {
+	err := os.MkdirAll(filepath.Dir(k.path), 0700)
+	if err != nil {
+		return fmt.Errorf("os.MkdirAll: %w", err)
+	}
+	f, err := os.OpenFile(k.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("os.OpenFile: %w", err)
+	}
+	_, err = fmt.Fprintf(f, "%s %s\n", k.address, pin)
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("fmt.Fprintf: %w", err)
+	}
+	return f.Close()
+}
+
+// device is the identity of the client when the server signed a
+// certificate for it at registration.
+type device struct {
+	wd string
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+// loadDevice reads the device certificate of the working directory, there
+// may be none.
+func loadDevice(wd string) (*device, error) {
+	d := &device{wd: wd}
+	cert, err := tls.LoadX509KeyPair(filepath.Join(wd, deviceCertFile), filepath.Join(wd, deviceKeyFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return d, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("tls.LoadX509KeyPair: %w", err)
+	}
+	d.cert = &cert
+	return d, nil
+}
+
+func (d *device) has() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.cert != nil
+}
+
+// clientCertificate answers the certificate request of the server, an
+// empty certificate sends none.
+func (d *device) clientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.cert == nil {
+		return &tls.Certificate{}, nil
+	}
+	return d.cert, nil
+}
+
+// newRequest makes a key and a PEM CSR for it, the key is kept until the
+// certificate comes back.
+func (d *device) newRequest() (string, *ecdsa.PrivateKey, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", nil, fmt.Errorf("ecdsa.GenerateKey: %w", err)
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{}, key)
+	if err != nil {
+		return "", nil, fmt.Errorf("x509.CreateCertificateRequest: %w", err)
+	}
+	csr := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})
+	return string(csr), key, nil
+}
+
+// save keeps the signed certificate with its key, the next connections
+// present it.
+func (d *device) save(certPEM string, key *ecdsa.PrivateKey) error {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("x509.MarshalPKCS8PrivateKey: %w", err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	cert, err := tls.X509KeyPair([]byte(certPEM), keyPEM)
+	if err != nil {
+		return fmt.Errorf("tls.X509KeyPair: %w", err)
+	}
+	err = os.WriteFile(filepath.Join(d.wd, deviceKeyFile), keyPEM, 0600)
+	if err != nil {
+		return fmt.Errorf("os.WriteFile: %w", err)
+	}
+	err = os.WriteFile(filepath.Join(d.wd, deviceCertFile), []byte(certPEM), 0600)
+	if err != nil {
+		return fmt.Errorf("os.WriteFile: %w", err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.cert = &cert
+	return nil
+}
@@ -0,0 +1,118 @@
+package command
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/config"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/prompt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// userFile marks a working directory, wipe refuses directories without it.
+const userFile = "user.json"
+
+// DoWipe securely removes the local working directory, the account on the
+// server stays.
+func DoWipe(conf *config.Config, p *prompt.Prompter) error {
+	dir, err := wipeTarget(conf.WorkingDir)
+	if err != nil {
+		return err
+	}
+	ok, err := confirm(p, conf, fmt.Sprintf("wipe %s", dir))
+	if err != nil || !ok {
+		return err
+	}
+	err = wipeDir(dir)
+	if err != nil {
+		return fmt.Errorf("wipeDir: %w", err)
+	}
+	fmt.Printf("wiped %s\n", dir)
+	return nil
+}
+
+// DoDeleteAccount deletes the account with all of its data on the server,
+// then wipes the working directory.
+func DoDeleteAccount(ctx context.Context, conf *config.Config, s *session, p *prompt.Prompter) error {
+	dir, err := wipeTarget(s.wd)
+	if err != nil {
+		return err
+	}
+	ok, err := confirm(p, conf, fmt.Sprintf("delete the account %s with all of its data "+
+		"and organizations it owns, and wipe %s", s.user.Login, dir))
+	if err != nil || !ok {
+		return err
+	}
+	err = s.clientService.DeleteUser(ctx, loginPassword(s.user, conf.UserPassword))
+	if err != nil {
+		return fmt.Errorf("clientService.DeleteUser: %w", err)
+	}
+	err = wipeDir(dir)
+	if err != nil {
+		return fmt.Errorf("account deleted, wipeDir: %w", err)
+	}
+	fmt.Printf("deleted the account %s and wiped %s\n", s.user.Login, dir)
+	return nil
+}
+
+func wipeTarget(wd string) (string, error) {
+	dir, err := filepath.Abs(wd)
+	if err != nil {
+		return "", fmt.Errorf("filepath.Abs: %w", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, userFile)); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("%s is not a working directory", dir)
+		}
+		return "", fmt.Errorf("os.Stat: %w", err)
+	}
+	return dir, nil
+}
+
+// wipeDir overwrites every file with random data before removing the
+// directory. Flash storage and copy-on-write file systems may still keep
+// the old blocks, disk encryption is what protects them.
+func wipeDir(dir string) error {
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		return shredFile(path)
+	})
+	if err != nil {
+		return fmt.Errorf("filepath.WalkDir: %w", err)
+	}
+	err = os.RemoveAll(dir)
+	if err != nil {
+		return fmt.Errorf("os.RemoveAll: %w", err)
+	}
+	return nil
+}
+
+func shredFile(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("os.OpenFile: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("f.Stat: %w", err)
+	}
+	_, err = io.CopyN(f, rand.Reader, info.Size())
+	if err != nil {
+		return fmt.Errorf("io.CopyN: %w", err)
+	}
+	err = f.Sync()
+	if err != nil {
+		return fmt.Errorf("f.Sync: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,612 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/caarlos0/env/v6"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/logger"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const stdinUsage = "Read secrets missing from flags from stdin, one per line: " +
+	"user password first, then the item secrets"
+
+const (
+	defaultClipTimeout = 30 * time.Second
+
+	clipTimeoutUsage = "Clear the clipboard after, 0 keeps the value"
+)
+
+const (
+	defaultHost = "127.0.0.1"
+
+	defaultPort = "8081"
+)
+
+var conf Config
+
+func Get() Config {
+	return conf
+}
+
+const profileUsage = "Profile of the client config file, the current one by default"
+
+// serverFlags registers the server address and how it is verified on set.
+func serverFlags(set *flag.FlagSet) {
+	set.StringVar(&conf.Profile, "profile", "", profileUsage)
+	set.StringVar(&conf.ServerAddress, "server", fmt.Sprintf("%s:%s", defaultHost, defaultPort),
+		"Server address")
+	set.StringVar(&conf.ServerCA, "server-ca", "", "CA bundle verifying the server certificate")
+	set.StringVar(&conf.ServerPin, "server-pin", "",
+		"Hex SHA-256 of the server public key, the key seen first is trusted by default")
+}
+
+// generatorFlags registers password generator options on set.
+func generatorFlags(set *flag.FlagSet) {
+	set.IntVar(&conf.GenLength, "len", 20, "Password length")
+	set.BoolVar(&conf.GenLower, "lower", true, "Use lowercase letters")
+	set.BoolVar(&conf.GenUpper, "upper", true, "Use uppercase letters")
+	set.BoolVar(&conf.GenDigits, "digits", true, "Use digits")
+	set.BoolVar(&conf.GenSymbols, "symbols", true, "Use symbols")
+	set.BoolVar(&conf.GenNoAmbiguous, "no-ambiguous", false, "Exclude look-alike characters")
+	set.StringVar(&conf.GenExclude, "exclude", "", "Characters to exclude")
+	set.StringVar(&conf.GenSite, "site", "", "Apply the password rules of a site")
+	set.StringVar(&conf.GenRulesFile, "rules", "", "Site rules JSON file, <wd>/rules.json by default")
+	set.IntVar(&conf.GenWords, "words", 0, "Generate a passphrase of that many words")
+	set.StringVar(&conf.GenSeparator, "sep", "-", "Passphrase word separator")
+	set.BoolVar(&conf.GenCapitalize, "cap", false, "Capitalize passphrase words")
+	set.BoolVar(&conf.GenNumber, "num", false, "Add a digit to a passphrase word")
+}
+
+func Parse() (*Config, error) {
+	flag.StringVar(&conf.WorkingDir, "wd", "saved", "Working directory")
+	flag.StringVar(&conf.Profile, "profile", "", profileUsage)
+
+	fileSet := flag.NewFlagSet("file", flag.ExitOnError)
+
+	fileSet.StringVar(&conf.WorkingDir, "wd", "saved", "Working directory")
+	serverFlags(fileSet)
+	fileSet.StringVar(&conf.UserLogin, "ul", "", "User login")
+	fileSet.StringVar(&conf.UserPassword, "up", "", "User password")
+	fileSet.BoolVar(&conf.SecretsFromStdin, "stdin", false, stdinUsage)
+
+	actionFn := func(s string) error {
+		if s == "" {
+			return errors.New("empty arg")
+		}
+		us := strings.ToUpper(s)
+		if us != string(ActionGet) && us != string(ActionSave) && us != string(ActionDelete) {
+			return fmt.Errorf("%s does not match action", s)
+		}
+		conf.Action = Action(us)
+		return nil
+	}
+
+	isNewFn := func(s string) error {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("strconv.ParseBool: %w", err)
+		}
+		conf.IsNew = b
+		return nil
+	}
+
+	fileSet.Func("a", "action get, save, delete", actionFn)
+	fileSet.Func("in", "is object new", isNewFn)
+
+	fileSet.StringVar(&conf.ID, "id", "", "ID")
+	fileSet.StringVar(&conf.Filename, "f", "", "filename")
+
+	textSet := flag.NewFlagSet("text", flag.ExitOnError)
+
+	textSet.StringVar(&conf.WorkingDir, "wd", "saved", "Working directory")
+	serverFlags(textSet)
+	textSet.StringVar(&conf.UserLogin, "ul", "", "User login")
+	textSet.StringVar(&conf.UserPassword, "up", "", "User password")
+	textSet.BoolVar(&conf.SecretsFromStdin, "stdin", false, stdinUsage)
+
+	textSet.Func("a", "action get, save, delete", actionFn)
+	textSet.Func("in", "is object new", isNewFn)
+
+	textSet.StringVar(&conf.ID, "id", "", "ID")
+	textSet.StringVar(&conf.Text, "t", "", "Text")
+	textSet.BoolVar(&conf.ShowSecrets, "show", false, "Print the text")
+	textSet.StringVar(&conf.CopyField, "copy", "", "Copy field text to clipboard")
+	textSet.DurationVar(&conf.ClipTimeout, "clip-timeout", defaultClipTimeout, clipTimeoutUsage)
+
+	cardSet := flag.NewFlagSet("card", flag.ExitOnError)
+
+	cardSet.StringVar(&conf.WorkingDir, "wd", "saved", "Working directory")
+	serverFlags(cardSet)
+	cardSet.StringVar(&conf.UserLogin, "ul", "", "User login")
+	cardSet.StringVar(&conf.UserPassword, "up", "", "User password")
+	cardSet.BoolVar(&conf.SecretsFromStdin, "stdin", false, stdinUsage)
+
+	cardSet.Func("a", "action get, save, delete", actionFn)
+	cardSet.Func("in", "is object new", isNewFn)
+
+	cardSet.StringVar(&conf.ID, "id", "", "ID")
+	cardSet.StringVar(&conf.CardNum, "n", "", "Number")
+	cardSet.StringVar(&conf.CardCVC, "c", "", "CVC")
+	cardSet.StringVar(&conf.CardHolderName, "hn", "", "Holder name")
+	cardSet.StringVar(&conf.CardExpMonth, "em", "", "Expiry month")
+	cardSet.StringVar(&conf.CardExpYear, "ey", "", "Expiry year")
+	cardSet.StringVar(&conf.CardPIN, "pin", "", "PIN")
+	cardSet.StringVar(&conf.CardBillingLine1, "bl1", "", "Billing address line 1")
+	cardSet.StringVar(&conf.CardBillingLine2, "bl2", "", "Billing address line 2")
+	cardSet.StringVar(&conf.CardBillingCity, "bc", "", "Billing city")
+	cardSet.StringVar(&conf.CardBillingRegion, "br", "", "Billing region")
+	cardSet.StringVar(&conf.CardBillingZip, "bz", "", "Billing postal code")
+	cardSet.StringVar(&conf.CardBillingCountry, "bco", "", "Billing country code")
+	cardSet.BoolVar(&conf.ShowSecrets, "show", false, "Print full card number, CVC and PIN")
+	cardSet.StringVar(&conf.CopyField, "copy", "", "Copy field num, cvc, pin, holder or exp to clipboard")
+	cardSet.DurationVar(&conf.ClipTimeout, "clip-timeout", defaultClipTimeout, clipTimeoutUsage)
+
+	credSet := flag.NewFlagSet("cred", flag.ExitOnError)
+
+	credSet.StringVar(&conf.WorkingDir, "wd", "saved", "Working directory")
+	serverFlags(credSet)
+	credSet.StringVar(&conf.UserLogin, "ul", "", "User login")
+	credSet.StringVar(&conf.UserPassword, "up", "", "User password")
+	credSet.BoolVar(&conf.SecretsFromStdin, "stdin", false, stdinUsage)
+
+	credSet.Func("a", "action get, save, delete", actionFn)
+	credSet.Func("in", "is object new", isNewFn)
+
+	credSet.StringVar(&conf.ID, "id", "", "ID")
+	credSet.StringVar(&conf.CredentialsLogin, "l", "", "Login")
+	credSet.StringVar(&conf.CredentialsPassword, "p", "", "Password")
+	credSet.BoolVar(&conf.ShowSecrets, "show", false, "Print the password")
+	credSet.BoolVar(&conf.Generate, "generate", false, "Generate the password")
+	generatorFlags(credSet)
+	credSet.StringVar(&conf.CopyField, "copy", "", "Copy field login or password to clipboard")
+	credSet.DurationVar(&conf.ClipTimeout, "clip-timeout", defaultClipTimeout, clipTimeoutUsage)
+
+	syncSet := flag.NewFlagSet("cred", flag.ExitOnError)
+	syncSet.StringVar(&conf.WorkingDir, "wd", "saved", "Working directory")
+	serverFlags(syncSet)
+	syncSet.StringVar(&conf.UserLogin, "ul", "", "User login")
+	syncSet.StringVar(&conf.UserPassword, "up", "", "User password")
+	syncSet.BoolVar(&conf.SecretsFromStdin, "stdin", false, stdinUsage)
+
+	replSet := flag.NewFlagSet("repl", flag.ExitOnError)
+	replSet.StringVar(&conf.WorkingDir, "wd", "saved", "Working directory")
+	serverFlags(replSet)
+	replSet.StringVar(&conf.UserLogin, "ul", "", "User login")
+	replSet.StringVar(&conf.UserPassword, "up", "", "User password")
+	replSet.BoolVar(&conf.SecretsFromStdin, "stdin", false, stdinUsage)
+	replSet.DurationVar(&conf.IdleTimeout, "idle", 5*time.Minute, "Lock the vault after inactivity")
+	replSet.DurationVar(&conf.ClipTimeout, "clip-timeout", defaultClipTimeout, clipTimeoutUsage)
+
+	generateSet := flag.NewFlagSet("generate", flag.ExitOnError)
+	generateSet.StringVar(&conf.WorkingDir, "wd", "saved", "Working directory")
+	generateSet.StringVar(&conf.Profile, "profile", "", profileUsage)
+	generatorFlags(generateSet)
+	generateSet.BoolVar(&conf.GenCopy, "copy", false, "Copy to clipboard instead of printing")
+	generateSet.DurationVar(&conf.ClipTimeout, "clip-timeout", defaultClipTimeout, clipTimeoutUsage)
+
+	importSet := flag.NewFlagSet("import", flag.ExitOnError)
+	importSet.StringVar(&conf.WorkingDir, "wd", "saved", "Working directory")
+	serverFlags(importSet)
+	importSet.StringVar(&conf.UserLogin, "ul", "", "User login")
+	importSet.StringVar(&conf.UserPassword, "up", "", "User password")
+	importSet.BoolVar(&conf.SecretsFromStdin, "stdin", false, stdinUsage)
+	importSet.StringVar(&conf.Filename, "f", "", "Export file of another password manager")
+	importSet.StringVar(&conf.ImportFormat, "format", "",
+		"bitwarden-json, keepass-xml, 1pux, 1password-csv or csv, detected from the file extension by default")
+	importSet.StringVar(&conf.ImportMapping, "map", "",
+		"CSV column mapping, e.g. title=Name,login=Username|Email,password=Password")
+	importSet.BoolVar(&conf.DryRun, "dry-run", false, "Report what would be imported without saving")
+	importSet.BoolVar(&conf.ImportKeepDuplicates, "keep-dups", false,
+		"Import entries that are already in the vault")
+
+	exportSet := flag.NewFlagSet("export", flag.ExitOnError)
+	exportSet.StringVar(&conf.WorkingDir, "wd", "saved", "Working directory")
+	serverFlags(exportSet)
+	exportSet.StringVar(&conf.UserLogin, "ul", "", "User login")
+	exportSet.StringVar(&conf.UserPassword, "up", "", "User password")
+	exportSet.BoolVar(&conf.SecretsFromStdin, "stdin", false, stdinUsage+", then the export passphrase")
+	exportSet.StringVar(&conf.Filename, "f", "", "Archive file")
+	exportSet.BoolVar(&conf.Force, "force", false, "Overwrite an existing file")
+	exportSet.StringVar(&conf.ExportFormat, "format", "",
+		"Write unencrypted csv, bitwarden-json or keepass-xml instead of an encrypted archive")
+	exportSet.BoolVar(&conf.AssumeYes, "yes", false, "Do not ask for confirmation")
+
+	restoreSet := flag.NewFlagSet("restore", flag.ExitOnError)
+	restoreSet.StringVar(&conf.WorkingDir, "wd", "saved", "Working directory")
+	serverFlags(restoreSet)
+	restoreSet.StringVar(&conf.UserLogin, "ul", "", "User login")
+	restoreSet.StringVar(&conf.UserPassword, "up", "", "User password")
+	restoreSet.BoolVar(&conf.SecretsFromStdin, "stdin", false, stdinUsage+", then the export passphrase")
+	restoreSet.StringVar(&conf.Filename, "f", "", "Archive file")
+	restoreSet.Func("mode", "merge keeps the vault items, replace deletes them first (default merge)",
+		func(s string) error {
+			switch s {
+			case "merge":
+				conf.RestoreReplace = false
+			case "replace":
+				conf.RestoreReplace = true
+			default:
+				return fmt.Errorf("%s does not match mode", s)
+			}
+			return nil
+		})
+	restoreSet.BoolVar(&conf.AssumeYes, "yes", false, "Do not ask for confirmation")
+
+	shareSet := flag.NewFlagSet("share", flag.ExitOnError)
+	shareSet.StringVar(&conf.WorkingDir, "wd", "saved", "Working directory")
+	serverFlags(shareSet)
+	shareSet.StringVar(&conf.UserLogin, "ul", "", "User login")
+	shareSet.StringVar(&conf.UserPassword, "up", "", "User password")
+	shareSet.BoolVar(&conf.SecretsFromStdin, "stdin", false, stdinUsage)
+	shareSet.StringVar(&conf.ID, "id", "", "Item id or unique id prefix")
+	shareSet.StringVar(&conf.ShareWith, "with", "", "Login of the user to share with")
+	conf.ShareAccess = model.AccessRead
+	shareSet.Func("access", "read or write (default read)", func(s string) error {
+		switch s {
+		case "read":
+			conf.ShareAccess = model.AccessRead
+		case "write":
+			conf.ShareAccess = model.AccessWrite
+		default:
+			return fmt.Errorf("%s does not match access", s)
+		}
+		return nil
+	})
+	shareSet.BoolVar(&conf.ShareRevoke, "revoke", false, "Revoke the access of the user instead")
+	shareSet.BoolVar(&conf.ShowFingerprint, "fingerprint", false,
+		"Show the fingerprint of your key, for others to compare")
+	shareSet.BoolVar(&conf.AssumeYes, "yes", false, "Trust keys of other users seen for the first time without asking")
+
+	orgSet := flag.NewFlagSet("org", flag.ExitOnError)
+	orgSet.StringVar(&conf.WorkingDir, "wd", "saved", "Working directory")
+	serverFlags(orgSet)
+	orgSet.StringVar(&conf.UserLogin, "ul", "", "User login")
+	orgSet.StringVar(&conf.UserPassword, "up", "", "User password")
+	orgSet.BoolVar(&conf.SecretsFromStdin, "stdin", false, stdinUsage)
+	conf.OrgAction = "list"
+	orgSet.Func("a", "action list, create, collection, add-member, remove-member, add (default list)",
+		func(s string) error {
+			switch s {
+			case "list", "create", "collection", "add-member", "remove-member", "add":
+				conf.OrgAction = s
+			default:
+				return fmt.Errorf("%s does not match org action", s)
+			}
+			return nil
+		})
+	orgSet.StringVar(&conf.OrgID, "org", "", "Organization id or name")
+	orgSet.StringVar(&conf.OrgName, "name", "", "Name of the new organization or collection")
+	orgSet.StringVar(&conf.ShareWith, "with", "", "Login of the member")
+	conf.OrgRole = model.RoleMember
+	orgSet.Func("role", "admin, member or read-only (default member)", func(s string) error {
+		switch s {
+		case "admin":
+			conf.OrgRole = model.RoleAdmin
+		case "member":
+			conf.OrgRole = model.RoleMember
+		case "read-only":
+			conf.OrgRole = model.RoleReadOnly
+		default:
+			return fmt.Errorf("%s does not match role", s)
+		}
+		return nil
+	})
+	orgSet.StringVar(&conf.CollectionID, "collection", "", "Collection id or name")
+	orgSet.StringVar(&conf.ID, "id", "", "Id or unique id prefix of the item to move into the collection")
+	orgSet.BoolVar(&conf.AssumeYes, "yes", false, "Trust keys of other users seen for the first time without asking")
+
+	emergencySet := flag.NewFlagSet("emergency", flag.ExitOnError)
+	emergencySet.StringVar(&conf.WorkingDir, "wd", "saved", "Working directory")
+	serverFlags(emergencySet)
+	emergencySet.StringVar(&conf.UserLogin, "ul", "", "User login")
+	emergencySet.StringVar(&conf.UserPassword, "up", "", "User password")
+	emergencySet.BoolVar(&conf.SecretsFromStdin, "stdin", false, stdinUsage)
+	conf.EmergencyAction = "list"
+	emergencySet.Func("a", "action list, grant, revoke, approve, decline as the owner, "+
+		"request, open as the contact (default list)",
+		func(s string) error {
+			switch s {
+			case "list", "grant", "revoke", "approve", "decline", "request", "open":
+				conf.EmergencyAction = s
+			default:
+				return fmt.Errorf("%s does not match emergency action", s)
+			}
+			return nil
+		})
+	emergencySet.StringVar(&conf.ShareWith, "with", "", "Login of the trusted contact, or of the owner")
+	emergencySet.DurationVar(&conf.EmergencyWait, "wait", 72*time.Hour,
+		"Waiting period before a request is approved, whole hours")
+	emergencySet.BoolVar(&conf.AssumeYes, "yes", false, "Trust keys of other users seen for the first time without asking")
+
+	passwdSet := flag.NewFlagSet("passwd", flag.ExitOnError)
+	passwdSet.StringVar(&conf.WorkingDir, "wd", "saved", "Working directory")
+	serverFlags(passwdSet)
+	passwdSet.StringVar(&conf.UserLogin, "ul", "", "User login")
+	passwdSet.StringVar(&conf.UserPassword, "up", "", "User password")
+	passwdSet.BoolVar(&conf.SecretsFromStdin, "stdin", false, stdinUsage+", then the new password")
+	passwdSet.StringVar(&conf.NewPassword, "np", "", "New password")
+
+	recoverSet := flag.NewFlagSet("recover", flag.ExitOnError)
+	recoverSet.StringVar(&conf.WorkingDir, "wd", "saved", "Working directory")
+	serverFlags(recoverSet)
+	recoverSet.StringVar(&conf.UserLogin, "ul", "", "User login")
+	recoverSet.BoolVar(&conf.SecretsFromStdin, "stdin", false,
+		"Read secrets missing from flags from stdin, one per line: the recovery key, then the new password")
+	recoverSet.StringVar(&conf.RecoveryKey, "key", "", "Recovery key from the recovery kit")
+	recoverSet.StringVar(&conf.NewPassword, "np", "", "New password")
+
+	wipeSet := flag.NewFlagSet("wipe", flag.ExitOnError)
+	wipeSet.StringVar(&conf.WorkingDir, "wd", "saved", "Working directory")
+	serverFlags(wipeSet)
+	wipeSet.StringVar(&conf.UserLogin, "ul", "", "User login")
+	wipeSet.StringVar(&conf.UserPassword, "up", "", "User password, needed with -account")
+	wipeSet.BoolVar(&conf.SecretsFromStdin, "stdin", false, stdinUsage)
+	wipeSet.BoolVar(&conf.WipeAccount, "account", false,
+		"Also delete the account and all of its data on the server")
+	wipeSet.BoolVar(&conf.AssumeYes, "yes", false, "Do not ask for confirmation")
+
+	auditSet := flag.NewFlagSet("audit", flag.ExitOnError)
+	auditSet.StringVar(&conf.WorkingDir, "wd", "saved", "Working directory")
+	serverFlags(auditSet)
+	auditSet.StringVar(&conf.UserLogin, "ul", "", "User login")
+	auditSet.StringVar(&conf.UserPassword, "up", "", "User password")
+	auditSet.BoolVar(&conf.SecretsFromStdin, "stdin", false, stdinUsage)
+	auditSet.IntVar(&conf.AuditLimit, "n", 20, "Number of events to show")
+	auditSet.Int64Var(&conf.AuditBefore, "before", 0, "Show events older than the event with this id")
+
+	deviceSet := flag.NewFlagSet("device", flag.ExitOnError)
+	deviceSet.StringVar(&conf.WorkingDir, "wd", "saved", "Working directory")
+	serverFlags(deviceSet)
+	deviceSet.StringVar(&conf.UserLogin, "ul", "", "User login")
+	deviceSet.StringVar(&conf.UserPassword, "up", "", "User password")
+	deviceSet.BoolVar(&conf.SecretsFromStdin, "stdin", false, stdinUsage)
+	conf.DeviceAction = "list"
+	deviceSet.Func("a", "action list or revoke (default list)", func(s string) error {
+		switch s {
+		case "list", "revoke":
+			conf.DeviceAction = s
+		default:
+			return fmt.Errorf("%s does not match device action", s)
+		}
+		return nil
+	})
+	deviceSet.StringVar(&conf.ID, "id", "", "Id of the client to revoke")
+	deviceSet.BoolVar(&conf.AssumeYes, "yes", false, "Allow revoking the current client")
+
+	registerSet := flag.NewFlagSet("register", flag.ExitOnError)
+	registerSet.StringVar(&conf.WorkingDir, "wd", "saved", "Working directory")
+	serverFlags(registerSet)
+	registerSet.StringVar(&conf.UserLogin, "ul", "", "User login")
+	registerSet.StringVar(&conf.UserPassword, "up", "", "User password")
+	registerSet.BoolVar(&conf.SecretsFromStdin, "stdin", false, stdinUsage)
+
+	loginSet := flag.NewFlagSet("login", flag.ExitOnError)
+	loginSet.StringVar(&conf.WorkingDir, "wd", "saved", "Working directory")
+	serverFlags(loginSet)
+	loginSet.StringVar(&conf.UserLogin, "ul", "", "User login")
+	loginSet.StringVar(&conf.UserPassword, "up", "", "User password")
+	loginSet.BoolVar(&conf.SecretsFromStdin, "stdin", false, stdinUsage)
+
+	logoutSet := flag.NewFlagSet("logout", flag.ExitOnError)
+	logoutSet.StringVar(&conf.WorkingDir, "wd", "saved", "Working directory")
+	logoutSet.StringVar(&conf.Profile, "profile", "", profileUsage)
+	logoutSet.BoolVar(&conf.Force, "force", false, "Log out even if local changes are not synced")
+
+	statusSet := flag.NewFlagSet("status", flag.ExitOnError)
+	statusSet.StringVar(&conf.WorkingDir, "wd", "saved", "Working directory")
+	serverFlags(statusSet)
+
+	profileSet := flag.NewFlagSet("profile", flag.ExitOnError)
+	conf.ProfileAction = "list"
+	profileSet.Func("a", "action list, add or use (default list)", func(s string) error {
+		switch s {
+		case "list", "add", "use":
+			conf.ProfileAction = s
+		default:
+			return fmt.Errorf("%s does not match profile action", s)
+		}
+		return nil
+	})
+	profileSet.StringVar(&conf.Profile, "name", "", "Profile name")
+	profileSet.StringVar(&conf.ServerAddress, "server", fmt.Sprintf("%s:%s", defaultHost, defaultPort),
+		"Server address")
+	profileSet.StringVar(&conf.ServerCA, "server-ca", "", "CA bundle verifying the server certificate")
+	profileSet.StringVar(&conf.ServerPin, "server-pin", "", "Hex SHA-256 of the server public key")
+	profileSet.StringVar(&conf.WorkingDir, "wd", "",
+		"Working directory, next to the config file by default")
+	profileSet.StringVar(&conf.UserLogin, "ul", "", "Default user login")
+	profileSet.BoolVar(&conf.Force, "force", false, "Replace a profile of the same name")
+
+	// flags before the command, like -profile, apply to every command
+	flag.Parse()
+	args := flag.Args()
+
+	var parsed *flag.FlagSet
+	if len(args) >= 1 {
+		switch args[0] {
+		case "file":
+			parsed = fileSet
+			err := fileSet.Parse(args[1:])
+			if err != nil {
+				return nil, fmt.Errorf("fileSet.Parse: %w", err)
+			}
+			conf.IsFileFlagsParsed = true
+		case "text":
+			parsed = textSet
+			err := textSet.Parse(args[1:])
+			if err != nil {
+				return nil, fmt.Errorf("textSet.Parse: %w", err)
+			}
+			conf.IsTextFlagsParsed = true
+		case "card":
+			parsed = cardSet
+			err := cardSet.Parse(args[1:])
+			if err != nil {
+				return nil, fmt.Errorf("cardSet.Parse: %w", err)
+			}
+			conf.IsCardFlagsParsed = true
+		case "cred":
+			parsed = credSet
+			err := credSet.Parse(args[1:])
+			if err != nil {
+				return nil, fmt.Errorf("credSet.Parse: %w", err)
+			}
+			conf.IsCredentialsFlagsParsed = true
+		case "sync":
+			parsed = syncSet
+			err := syncSet.Parse(args[1:])
+			if err != nil {
+				return nil, fmt.Errorf("syncSet.Parse: %w", err)
+			}
+			conf.IsSync = true
+		case "generate":
+			parsed = generateSet
+			err := generateSet.Parse(args[1:])
+			if err != nil {
+				return nil, fmt.Errorf("generateSet.Parse: %w", err)
+			}
+			conf.IsGenerate = true
+		case "import":
+			parsed = importSet
+			err := importSet.Parse(args[1:])
+			if err != nil {
+				return nil, fmt.Errorf("importSet.Parse: %w", err)
+			}
+			conf.IsImport = true
+		case "export":
+			parsed = exportSet
+			err := exportSet.Parse(args[1:])
+			if err != nil {
+				return nil, fmt.Errorf("exportSet.Parse: %w", err)
+			}
+			conf.IsExport = true
+		case "restore":
+			parsed = restoreSet
+			err := restoreSet.Parse(args[1:])
+			if err != nil {
+				return nil, fmt.Errorf("restoreSet.Parse: %w", err)
+			}
+			conf.IsRestore = true
+		case "share":
+			parsed = shareSet
+			err := shareSet.Parse(args[1:])
+			if err != nil {
+				return nil, fmt.Errorf("shareSet.Parse: %w", err)
+			}
+			conf.IsShare = true
+		case "org":
+			parsed = orgSet
+			err := orgSet.Parse(args[1:])
+			if err != nil {
+				return nil, fmt.Errorf("orgSet.Parse: %w", err)
+			}
+			conf.IsOrg = true
+		case "emergency":
+			parsed = emergencySet
+			err := emergencySet.Parse(args[1:])
+			if err != nil {
+				return nil, fmt.Errorf("emergencySet.Parse: %w", err)
+			}
+			conf.IsEmergency = true
+		case "passwd":
+			parsed = passwdSet
+			err := passwdSet.Parse(args[1:])
+			if err != nil {
+				return nil, fmt.Errorf("passwdSet.Parse: %w", err)
+			}
+			conf.IsPasswd = true
+		case "recover":
+			parsed = recoverSet
+			err := recoverSet.Parse(args[1:])
+			if err != nil {
+				return nil, fmt.Errorf("recoverSet.Parse: %w", err)
+			}
+			conf.IsRecover = true
+		case "wipe":
+			parsed = wipeSet
+			err := wipeSet.Parse(args[1:])
+			if err != nil {
+				return nil, fmt.Errorf("wipeSet.Parse: %w", err)
+			}
+			conf.IsWipe = true
+		case "audit":
+			parsed = auditSet
+			err := auditSet.Parse(args[1:])
+			if err != nil {
+				return nil, fmt.Errorf("auditSet.Parse: %w", err)
+			}
+			conf.IsAudit = true
+		case "device":
+			parsed = deviceSet
+			err := deviceSet.Parse(args[1:])
+			if err != nil {
+				return nil, fmt.Errorf("deviceSet.Parse: %w", err)
+			}
+			conf.IsDevice = true
+		case "register":
+			parsed = registerSet
+			err := registerSet.Parse(args[1:])
+			if err != nil {
+				return nil, fmt.Errorf("registerSet.Parse: %w", err)
+			}
+			conf.IsRegister = true
+		case "login":
+			parsed = loginSet
+			err := loginSet.Parse(args[1:])
+			if err != nil {
+				return nil, fmt.Errorf("loginSet.Parse: %w", err)
+			}
+			conf.IsLogin = true
+		case "logout":
+			parsed = logoutSet
+			err := logoutSet.Parse(args[1:])
+			if err != nil {
+				return nil, fmt.Errorf("logoutSet.Parse: %w", err)
+			}
+			conf.IsLogout = true
+		case "status":
+			parsed = statusSet
+			err := statusSet.Parse(args[1:])
+			if err != nil {
+				return nil, fmt.Errorf("statusSet.Parse: %w", err)
+			}
+			conf.IsStatus = true
+		case "profile":
+			err := profileSet.Parse(args[1:])
+			if err != nil {
+				return nil, fmt.Errorf("profileSet.Parse: %w", err)
+			}
+			conf.IsProfile = true
+		case "repl":
+			parsed = replSet
+			err := replSet.Parse(args[1:])
+			if err != nil {
+				return nil, fmt.Errorf("replSet.Parse: %w", err)
+			}
+			conf.IsRepl = true
+		default:
+			flag.PrintDefaults()
+			return nil, errors.New("unknown action")
+		}
+	}
+
+	if !conf.IsProfile {
+		err := applyProfile(flag.CommandLine, parsed)
+		if err != nil {
+			return nil, fmt.Errorf("applyProfile: %w", err)
+		}
+	}
+	err := env.Parse(&conf)
+	if err != nil {
+		return nil, fmt.Errorf("env.Parse: %w", err)
+	}
+
+	logger.Log.Info(fmt.Sprintf("initializing Config %s", conf))
+
+	return &conf, nil
+}
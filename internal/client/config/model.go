@@ -0,0 +1,160 @@
+package config
+
+import (
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"time"
+)
+
+type Action string
+
+const (
+	ActionGet Action = "GET"
+
+	ActionSave Action = "SAVE"
+
+	ActionDelete Action = "DELETE"
+)
+
+type Config struct {
+	Profile string
+
+	ServerAddress string `env:"SERVER_ADDRESS"`
+	ServerCA      string `env:"SERVER_CA"`
+	ServerPin     string `env:"SERVER_PIN"`
+
+	TraceExporter string `env:"TRACE_EXPORTER"`
+	OTLPEndpoint  string `env:"OTLP_ENDPOINT"`
+	OTLPInsecure  bool   `env:"OTLP_INSECURE"`
+
+	UserLogin    string `env:"USER_LOGIN"`
+	UserPassword string `env:"USER_PASSWORD"`
+
+	WorkingDir string `env:"WORKING_DIR"`
+
+	ID    string
+	IsNew bool
+
+	Filename string
+
+	Text string
+
+	CardNum            string
+	CardCVC            string
+	CardHolderName     string
+	CardExpMonth       string
+	CardExpYear        string
+	CardPIN            string
+	CardBillingLine1   string
+	CardBillingLine2   string
+	CardBillingCity    string
+	CardBillingRegion  string
+	CardBillingZip     string
+	CardBillingCountry string
+
+	ShowSecrets      bool
+	SecretsFromStdin bool
+	CopyField        string
+	ClipTimeout      time.Duration
+
+	Generate       bool
+	GenLength      int
+	GenLower       bool
+	GenUpper       bool
+	GenDigits      bool
+	GenSymbols     bool
+	GenNoAmbiguous bool
+	GenExclude     string
+	GenSite        string
+	GenRulesFile   string
+	GenWords       int
+	GenSeparator   string
+	GenCapitalize  bool
+	GenNumber      bool
+	GenCopy        bool
+
+	ImportFormat         string
+	ImportMapping        string
+	ImportKeepDuplicates bool
+	DryRun               bool
+
+	ExportPassphrase string
+	ExportFormat     string
+	Force            bool
+	RestoreReplace   bool
+	AssumeYes        bool
+
+	ShareWith   string
+	ShareAccess model.Access
+	ShareRevoke bool
+
+	ShowFingerprint bool
+
+	OrgAction    string
+	OrgID        string
+	OrgName      string
+	OrgRole      model.Role
+	CollectionID string
+
+	EmergencyAction string
+	EmergencyWait   time.Duration
+
+	NewPassword string
+	RecoveryKey string
+	WipeAccount bool
+
+	AuditLimit  int
+	AuditBefore int64
+
+	DeviceAction string
+
+	ProfileAction string
+
+	CredentialsLogin    string
+	CredentialsPassword string
+
+	IsFileFlagsParsed        bool
+	IsTextFlagsParsed        bool
+	IsCardFlagsParsed        bool
+	IsCredentialsFlagsParsed bool
+	IsSync                   bool
+	IsRepl                   bool
+	IsGenerate               bool
+	IsImport                 bool
+	IsExport                 bool
+	IsRestore                bool
+	IsShare                  bool
+	IsOrg                    bool
+	IsEmergency              bool
+	IsPasswd                 bool
+	IsRecover                bool
+	IsWipe                   bool
+	IsAudit                  bool
+	IsDevice                 bool
+	IsProfile                bool
+	IsRegister               bool
+	IsLogin                  bool
+	IsLogout                 bool
+	IsStatus                 bool
+
+	IdleTimeout time.Duration
+
+	Action Action
+}
+
+const redacted = "[REDACTED]"
+
+// String formats the config with secret values redacted, so it is safe to
+// log.
+func (c Config) String() string {
+	type plain Config
+	p := plain(c)
+	for _, v := range []*string{&p.UserPassword, &p.CredentialsPassword,
+		&p.CardNum, &p.CardCVC, &p.CardPIN, &p.Text, &p.ExportPassphrase,
+		&p.NewPassword, &p.RecoveryKey} {
+		if *v != "" {
+			*v = redacted
+		}
+	}
+	return fmt.Sprintf("%+v", p)
+}
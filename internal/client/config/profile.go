@@ -0,0 +1,207 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	// ProfilesEnv names the profiles file instead of the default one.
+	ProfilesEnv = "GOPHKEEPER_CONFIG"
+
+	// ProfileEnv selects the profile when there is no -profile flag.
+	ProfileEnv = "GOPHKEEPER_PROFILE"
+)
+
+var (
+	ErrProfileNotFound = errors.New("profile not found")
+	ErrProfileExists   = errors.New("profile already exists")
+)
+
+// Profile is a server with the account used on it.
+type Profile struct {
+	Server     string `yaml:"server"`
+	ServerCA   string `yaml:"server_ca,omitempty"`
+	ServerPin  string `yaml:"server_pin,omitempty"`
+	WorkingDir string `yaml:"working_dir"`
+	Login      string `yaml:"login,omitempty"`
+}
+
+// Profiles is the client config file, the current profile is used when
+// none is selected.
+type Profiles struct {
+	Current  string             `yaml:"current,omitempty"`
+	Profiles map[string]Profile `yaml:"profiles"`
+
+	path string
+}
+
+// ProfilesPath is $GOPHKEEPER_CONFIG or config.yaml in the user config
+// directory, ~/.config/gophkeeper on Linux.
+func ProfilesPath() (string, error) {
+	if path := os.Getenv(ProfilesEnv); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("os.UserConfigDir: %w", err)
+	}
+	return filepath.Join(dir, "gophkeeper", "config.yaml"), nil
+}
+
+// LoadProfiles reads the profiles file, a missing one has no profiles.
+func LoadProfiles(path string) (*Profiles, error) {
+	p := &Profiles{Profiles: make(map[string]Profile), path: path}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("os.Open: %w", err)
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	err = dec.Decode(p)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", path, err)
+	}
+	if p.Profiles == nil {
+		p.Profiles = make(map[string]Profile)
+	}
+	return p, nil
+}
+
+func (p *Profiles) Path() string {
+	return p.path
+}
+
+// Names returns the profile names sorted.
+func (p *Profiles) Names() []string {
+	names := make([]string, 0, len(p.Profiles))
+	for name := range p.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get returns the named profile, an empty name is the current one.
+func (p *Profiles) Get(name string) (Profile, error) {
+	if name == "" {
+		name = p.Current
+	}
+	prof, ok := p.Profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("%w: %s", ErrProfileNotFound, name)
+	}
+	return prof, nil
+}
+
+// Add keeps prof under name, replace allows overwriting a profile. The
+// first profile becomes the current one.
+func (p *Profiles) Add(name string, prof Profile, replace bool) error {
+	if name == "" {
+		return errors.New("profile name is required")
+	}
+	if _, ok := p.Profiles[name]; ok && !replace {
+		return fmt.Errorf("%w: %s", ErrProfileExists, name)
+	}
+	p.Profiles[name] = prof
+	if p.Current == "" {
+		p.Current = name
+	}
+	return nil
+}
+
+// Use makes name the current profile.
+func (p *Profiles) Use(name string) error {
+	if _, ok := p.Profiles[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrProfileNotFound, name)
+	}
+	p.Current = name
+	return nil
+}
+
+// DefaultWorkingDir keeps the vault of a profile next to the profiles file,
+// so profiles do not share one.
+func (p *Profiles) DefaultWorkingDir(name string) string {
+	return filepath.Join(filepath.Dir(p.path), name)
+}
+
+func (p *Profiles) Save() error {
+	data, err := yaml.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("yaml.Marshal: %w", err)
+	}
+	err = os.MkdirAll(filepath.Dir(p.path), 0700)
+	if err != nil {
+		return fmt.Errorf("os.MkdirAll: %w", err)
+	}
+	tmp := p.path + ".tmp"
+	err = os.WriteFile(tmp, data, 0600)
+	if err != nil {
+		return fmt.Errorf("os.WriteFile: %w", err)
+	}
+	err = os.Rename(tmp, p.path)
+	if err != nil {
+		return fmt.Errorf("os.Rename: %w", err)
+	}
+	return nil
+}
+
+// applyProfile fills the settings not given as flags of sets from the
+// selected profile. Without a profiles file or a selected profile nothing
+// changes.
+func applyProfile(sets ...*flag.FlagSet) error {
+	path, err := ProfilesPath()
+	if err != nil {
+		return fmt.Errorf("ProfilesPath: %w", err)
+	}
+	profiles, err := LoadProfiles(path)
+	if err != nil {
+		return fmt.Errorf("LoadProfiles: %w", err)
+	}
+	if conf.Profile == "" {
+		conf.Profile = os.Getenv(ProfileEnv)
+	}
+	if conf.Profile == "" && profiles.Current == "" {
+		return nil
+	}
+	prof, err := profiles.Get(conf.Profile)
+	if err != nil {
+		return fmt.Errorf("profiles.Get: %w", err)
+	}
+	if conf.Profile == "" {
+		conf.Profile = profiles.Current
+	}
+
+	given := make(map[string]bool)
+	for _, set := range sets {
+		if set != nil {
+			set.Visit(func(f *flag.Flag) { given[f.Name] = true })
+		}
+	}
+	for _, s := range []struct {
+		flag  string
+		value string
+		dst   *string
+	}{
+		{"server", prof.Server, &conf.ServerAddress},
+		{"server-ca", prof.ServerCA, &conf.ServerCA},
+		{"server-pin", prof.ServerPin, &conf.ServerPin},
+		{"wd", prof.WorkingDir, &conf.WorkingDir},
+		{"ul", prof.Login, &conf.UserLogin},
+	} {
+		if s.value != "" && !given[s.flag] {
+			*s.dst = s.value
+		}
+	}
+	return nil
+}
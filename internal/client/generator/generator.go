@@ -0,0 +1,206 @@
+package generator
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+const (
+	lowerChars  = "abcdefghijklmnopqrstuvwxyz"
+	upperChars  = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	digitChars  = "0123456789"
+	symbolChars = "!#$%&()*+,-./:;<=>?@[]^_{|}~"
+
+	// ambiguousChars look alike in many fonts.
+	ambiguousChars = "Il1|O0o"
+)
+
+const (
+	MinLength = 4
+	MaxLength = 1024
+)
+
+// Options describes a random password. Every enabled class is guaranteed
+// to appear at least once.
+type Options struct {
+	Length      int
+	Lower       bool
+	Upper       bool
+	Digits      bool
+	Symbols     bool
+	NoAmbiguous bool
+	// SymbolSet replaces the default symbols when not empty.
+	SymbolSet string
+	// Exclude lists characters that must never be used.
+	Exclude string
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Length:  20,
+		Lower:   true,
+		Upper:   true,
+		Digits:  true,
+		Symbols: true,
+	}
+}
+
+// Result is a generated secret with its entropy in bits.
+type Result struct {
+	Value   string
+	Entropy float64
+}
+
+func (o Options) classes() ([]string, error) {
+	symbols := symbolChars
+	if o.SymbolSet != "" {
+		symbols = o.SymbolSet
+	}
+	var res []string
+	for _, c := range []struct {
+		on    bool
+		chars string
+	}{
+		{o.Lower, lowerChars},
+		{o.Upper, upperChars},
+		{o.Digits, digitChars},
+		{o.Symbols, symbols},
+	} {
+		if !c.on {
+			continue
+		}
+		chars := strings.Map(func(r rune) rune {
+			if strings.ContainsRune(o.Exclude, r) ||
+				(o.NoAmbiguous && strings.ContainsRune(ambiguousChars, r)) {
+				return -1
+			}
+			return r
+		}, c.chars)
+		chars = dedup(chars)
+		if chars == "" {
+			return nil, errors.New("character class is empty after exclusions")
+		}
+		res = append(res, chars)
+	}
+	if len(res) == 0 {
+		return nil, errors.New("no character class is enabled")
+	}
+	// a character in two classes would make the class check ambiguous
+	seen := make(map[rune]bool)
+	for _, chars := range res {
+		for _, r := range chars {
+			if seen[r] {
+				return nil, fmt.Errorf("character %q is in more than one class", r)
+			}
+			seen[r] = true
+		}
+	}
+	return res, nil
+}
+
+func dedup(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		if !strings.ContainsRune(sb.String(), r) {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// Password generates a random password. Candidates missing a class are
+// rejected, so the result is uniform over all valid passwords and the
+// entropy is exact.
+func Password(o Options) (Result, error) {
+	if o.Length < MinLength || o.Length > MaxLength {
+		return Result{}, fmt.Errorf("length must be between %d and %d", MinLength, MaxLength)
+	}
+	classes, err := o.classes()
+	if err != nil {
+		return Result{}, err
+	}
+	if o.Length < len(classes) {
+		return Result{}, fmt.Errorf("length %d is shorter than the %d required classes",
+			o.Length, len(classes))
+	}
+	pool := []rune(strings.Join(classes, ""))
+
+	buf := make([]rune, o.Length)
+	for {
+		for i := range buf {
+			n, err := randInt(len(pool))
+			if err != nil {
+				return Result{}, err
+			}
+			buf[i] = pool[n]
+		}
+		if hasAll(string(buf), classes) {
+			break
+		}
+	}
+	return Result{
+		Value:   string(buf),
+		Entropy: passwordEntropy(o.Length, classes),
+	}, nil
+}
+
+func hasAll(s string, classes []string) bool {
+	for _, c := range classes {
+		if !strings.ContainsAny(s, c) {
+			return false
+		}
+	}
+	return true
+}
+
+// passwordEntropy counts strings that contain every class by
+// inclusion-exclusion and returns log2 of the count.
+func passwordEntropy(length int, classes []string) float64 {
+	total := 0
+	for _, c := range classes {
+		total += len([]rune(c))
+	}
+	count := new(big.Int)
+	for mask := 0; mask < 1<<len(classes); mask++ {
+		missing, bits := 0, 0
+		for i, c := range classes {
+			if mask&(1<<i) != 0 {
+				missing += len([]rune(c))
+				bits++
+			}
+		}
+		term := new(big.Int).Exp(big.NewInt(int64(total-missing)), big.NewInt(int64(length)), nil)
+		if bits%2 == 1 {
+			count.Sub(count, term)
+		} else {
+			count.Add(count, term)
+		}
+	}
+	return log2(count)
+}
+
+func log2(n *big.Int) float64 {
+	if n.Sign() <= 0 {
+		return 0
+	}
+	f, _ := new(big.Float).SetInt(n).Float64()
+	if !math.IsInf(f, 0) {
+		return math.Log2(f)
+	}
+	// too big for float64, keep the 64 most significant bits
+	shift := n.BitLen() - 64
+	top, _ := new(big.Float).SetInt(new(big.Int).Rsh(n, uint(shift))).Float64()
+	return math.Log2(top) + float64(shift)
+}
+
+func randInt(n int) (int, error) {
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, fmt.Errorf("rand.Int: %w", err)
+	}
+	return int(v.Int64()), nil
+}
@@ -0,0 +1,206 @@
+package generator
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+func TestPassword(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		allowed string
+		classes []string
+	}{
+		{name: "default", opts: DefaultOptions(),
+			allowed: lowerChars + upperChars + digitChars + symbolChars,
+			classes: []string{lowerChars, upperChars, digitChars, symbolChars}},
+		{name: "digits", opts: Options{Length: 8, Digits: true},
+			allowed: digitChars, classes: []string{digitChars}},
+		{name: "symbol set", opts: Options{Length: 12, Lower: true, Symbols: true, SymbolSet: "!-"},
+			allowed: lowerChars + "!-", classes: []string{lowerChars, "!-"}},
+		{name: "exclude", opts: Options{Length: 30, Digits: true, Exclude: "0123"},
+			allowed: "456789", classes: []string{"456789"}},
+		{name: "no ambiguous", opts: Options{Length: 40, Lower: true, Upper: true, Digits: true,
+			NoAmbiguous: true},
+			allowed: strings.Map(func(r rune) rune {
+				if strings.ContainsRune(ambiguousChars, r) {
+					return -1
+				}
+				return r
+			}, lowerChars+upperChars+digitChars)},
+		{name: "every class in the shortest password", opts: Options{Length: 4, Lower: true,
+			Upper: true, Digits: true, Symbols: true},
+			allowed: lowerChars + upperChars + digitChars + symbolChars,
+			classes: []string{lowerChars, upperChars, digitChars, symbolChars}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 20; i++ {
+				res, err := Password(tt.opts)
+				require.NoError(t, err)
+				assert.Len(t, []rune(res.Value), tt.opts.Length)
+				for _, r := range res.Value {
+					assert.Contains(t, tt.allowed, string(r))
+				}
+				for _, c := range tt.classes {
+					assert.True(t, strings.ContainsAny(res.Value, c), "%q has no char of %q", res.Value, c)
+				}
+			}
+		})
+	}
+}
+
+func TestPasswordErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{name: "too short", opts: Options{Length: MinLength - 1, Lower: true}},
+		{name: "too long", opts: Options{Length: MaxLength + 1, Lower: true}},
+		{name: "no class", opts: Options{Length: 10}},
+		{name: "class excluded", opts: Options{Length: 10, Lower: true, Digits: true, Exclude: digitChars}},
+		{name: "symbols overlap a class", opts: Options{Length: 10, Lower: true, Symbols: true,
+			SymbolSet: "a!"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Password(tt.opts)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestPasswordEntropy(t *testing.T) {
+	tests := []struct {
+		name    string
+		length  int
+		classes []string
+		want    float64
+	}{
+		{name: "one class", length: 4, classes: []string{digitChars},
+			want: 4 * math.Log2(10)},
+		// 36^4 strings less those without a letter or without a digit
+		{name: "two classes", length: 4, classes: []string{lowerChars, digitChars},
+			want: math.Log2(36*36*36*36 - 26*26*26*26 - 10*10*10*10)},
+		{name: "classes longer than the password", length: 2,
+			classes: []string{lowerChars, digitChars, upperChars}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.want, passwordEntropy(tt.length, tt.classes), 1e-9)
+		})
+	}
+}
+
+func TestPasswordEntropyLarge(t *testing.T) {
+	classes := []string{lowerChars, upperChars, digitChars, symbolChars}
+	got := passwordEntropy(MaxLength, classes)
+	assert.False(t, math.IsInf(got, 0) || math.IsNaN(got))
+	// nearly every string of that length has all classes
+	pool := len(lowerChars + upperChars + digitChars + symbolChars)
+	assert.InDelta(t, MaxLength*math.Log2(float64(pool)), got, 1e-6)
+}
+
+func TestWordList(t *testing.T) {
+	list := words()
+	require.Len(t, list, 7776, "the EFF large list has 6^5 words")
+	seen := make(map[string]bool, len(list))
+	for _, w := range list {
+		assert.False(t, seen[w], "duplicate word %q", w)
+		seen[w] = true
+		assert.NotEmpty(t, w)
+		assert.False(t, strings.ContainsFunc(w, unicode.IsSpace), "word %q has a space", w)
+	}
+}
+
+func TestPassphrase(t *testing.T) {
+	perWord := math.Log2(7776)
+	tests := []struct {
+		name        string
+		opts        PassphraseOptions
+		wantEntropy float64
+	}{
+		{name: "default", opts: DefaultPassphraseOptions(), wantEntropy: 6 * perWord},
+		{name: "capitalized", opts: PassphraseOptions{Words: 4, Separator: " ", Capitalize: true},
+			wantEntropy: 4 * perWord},
+		{name: "number", opts: PassphraseOptions{Words: 5, Separator: ".", Number: true},
+			wantEntropy: 5*perWord + math.Log2(50)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Passphrase(tt.opts)
+			require.NoError(t, err)
+			assert.InDelta(t, tt.wantEntropy, res.Entropy, 1e-9)
+
+			parts := strings.Split(res.Value, tt.opts.Separator)
+			require.Len(t, parts, tt.opts.Words)
+			digits := 0
+			for _, p := range parts {
+				word := strings.TrimRightFunc(p, unicode.IsDigit)
+				digits += len(p) - len(word)
+				if tt.opts.Capitalize {
+					assert.True(t, unicode.IsUpper([]rune(word)[0]), "%q is not capitalized", word)
+					word = strings.ToLower(word[:1]) + word[1:]
+				}
+				assert.Contains(t, words(), word)
+			}
+			if tt.opts.Number {
+				assert.Equal(t, 1, digits)
+			} else {
+				assert.Zero(t, digits)
+			}
+		})
+	}
+}
+
+func TestPassphraseErrors(t *testing.T) {
+	for _, n := range []int{MinWords - 1, MaxWords + 1} {
+		_, err := Passphrase(PassphraseOptions{Words: n})
+		assert.Error(t, err, "%d words", n)
+	}
+}
+
+func TestFindRule(t *testing.T) {
+	rules := []Rule{
+		{Site: "example.com", MaxLength: 16},
+		{Site: "login.example.com", MaxLength: 12},
+		{Site: "bank.test"},
+	}
+	tests := []struct {
+		site     string
+		wantSite string
+	}{
+		{site: "example.com", wantSite: "example.com"},
+		{site: "EXAMPLE.com.", wantSite: "example.com"},
+		{site: "www.example.com", wantSite: "example.com"},
+		{site: "login.example.com", wantSite: "login.example.com"},
+		{site: "a.login.example.com", wantSite: "login.example.com"},
+		{site: "notexample.com"},
+		{site: "bank.test.evil"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.site, func(t *testing.T) {
+			r, ok := FindRule(rules, tt.site)
+			assert.Equal(t, tt.wantSite != "", ok)
+			assert.Equal(t, tt.wantSite, r.Site)
+		})
+	}
+}
+
+func TestRuleApply(t *testing.T) {
+	no := false
+	r := Rule{Site: "example.com", MaxLength: 12, Symbols: &no, Exclude: "xyz"}
+	o := r.Apply(DefaultOptions())
+	assert.Equal(t, 12, o.Length)
+	assert.False(t, o.Symbols)
+	assert.True(t, o.Lower && o.Upper && o.Digits)
+	assert.Equal(t, "xyz", o.Exclude)
+
+	o = Rule{MinLength: 32}.Apply(DefaultOptions())
+	assert.Equal(t, 32, o.Length)
+}
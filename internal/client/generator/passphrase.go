@@ -0,0 +1,86 @@
+package generator
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+)
+
+// effLargeWordlist is the EFF large wordlist for diceware passphrases,
+// https://www.eff.org/deeplinks/2016/07/new-wordlists-random-passphrases,
+// licensed under CC BY 3.0 US.
+//
+//go:embed eff_large_wordlist.txt
+var effLargeWordlist string
+
+const (
+	MinWords = 3
+	MaxWords = 64
+)
+
+var words = sync.OnceValue(func() []string {
+	var res []string
+	scanner := bufio.NewScanner(strings.NewReader(effLargeWordlist))
+	for scanner.Scan() {
+		_, word, ok := strings.Cut(scanner.Text(), "\t")
+		if ok {
+			res = append(res, word)
+		}
+	}
+	return res
+})
+
+// PassphraseOptions describes a diceware style passphrase.
+type PassphraseOptions struct {
+	Words      int
+	Separator  string
+	Capitalize bool
+	// Number appends a random digit to one random word.
+	Number bool
+}
+
+func DefaultPassphraseOptions() PassphraseOptions {
+	return PassphraseOptions{
+		Words:     6,
+		Separator: "-",
+	}
+}
+
+// Passphrase picks words uniformly from the embedded wordlist.
+func Passphrase(o PassphraseOptions) (Result, error) {
+	if o.Words < MinWords || o.Words > MaxWords {
+		return Result{}, fmt.Errorf("words must be between %d and %d", MinWords, MaxWords)
+	}
+	list := words()
+	res := make([]string, o.Words)
+	for i := range res {
+		n, err := randInt(len(list))
+		if err != nil {
+			return Result{}, err
+		}
+		res[i] = list[n]
+		if o.Capitalize {
+			res[i] = strings.ToUpper(res[i][:1]) + res[i][1:]
+		}
+	}
+	entropy := float64(o.Words) * math.Log2(float64(len(list)))
+	if o.Number {
+		pos, err := randInt(o.Words)
+		if err != nil {
+			return Result{}, err
+		}
+		digit, err := randInt(10)
+		if err != nil {
+			return Result{}, err
+		}
+		res[pos] += fmt.Sprint(digit)
+		entropy += math.Log2(float64(10 * o.Words))
+	}
+	return Result{
+		Value:   strings.Join(res, o.Separator),
+		Entropy: entropy,
+	}, nil
+}
@@ -0,0 +1,89 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Rule holds password constraints of a site, e.g. a maximum length or a
+// restricted symbol set. Unset fields keep the requested options.
+type Rule struct {
+	Site      string `json:"site"`
+	MinLength int    `json:"min_length,omitempty"`
+	MaxLength int    `json:"max_length,omitempty"`
+	Lower     *bool  `json:"lower,omitempty"`
+	Upper     *bool  `json:"upper,omitempty"`
+	Digits    *bool  `json:"digits,omitempty"`
+	Symbols   *bool  `json:"symbols,omitempty"`
+	SymbolSet string `json:"symbol_set,omitempty"`
+	Exclude   string `json:"exclude,omitempty"`
+}
+
+// LoadRules reads a JSON array of rules.
+func LoadRules(path string) ([]Rule, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadFile: %w", err)
+	}
+	var rules []Rule
+	if err := json.Unmarshal(b, &rules); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+	for i, r := range rules {
+		if strings.TrimSpace(r.Site) == "" {
+			return nil, fmt.Errorf("rule %d has no site", i)
+		}
+		if r.MinLength > 0 && r.MaxLength > 0 && r.MinLength > r.MaxLength {
+			return nil, fmt.Errorf("rule for %s: min_length is greater than max_length", r.Site)
+		}
+	}
+	return rules, nil
+}
+
+// FindRule returns the rule of site, a rule for example.com also matches
+// its subdomains. The most specific rule wins.
+func FindRule(rules []Rule, site string) (Rule, bool) {
+	site = strings.ToLower(strings.TrimSuffix(site, "."))
+	var best Rule
+	var found bool
+	for _, r := range rules {
+		rs := strings.ToLower(r.Site)
+		if site != rs && !strings.HasSuffix(site, "."+rs) {
+			continue
+		}
+		if !found || len(rs) > len(best.Site) {
+			best, found = r, true
+		}
+	}
+	return best, found
+}
+
+// Apply narrows o to what the site accepts.
+func (r Rule) Apply(o Options) Options {
+	if r.MinLength > 0 && o.Length < r.MinLength {
+		o.Length = r.MinLength
+	}
+	if r.MaxLength > 0 && o.Length > r.MaxLength {
+		o.Length = r.MaxLength
+	}
+	for _, f := range []struct {
+		rule *bool
+		opt  *bool
+	}{
+		{r.Lower, &o.Lower},
+		{r.Upper, &o.Upper},
+		{r.Digits, &o.Digits},
+		{r.Symbols, &o.Symbols},
+	} {
+		if f.rule != nil {
+			*f.opt = *f.rule
+		}
+	}
+	if r.SymbolSet != "" {
+		o.SymbolSet = r.SymbolSet
+	}
+	o.Exclude += r.Exclude
+	return o
+}
@@ -0,0 +1,95 @@
+package prompt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"golang.org/x/term"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrNoInput is returned when a value is asked but stdin is neither a
+// terminal nor allowed to be read as a pipe.
+var ErrNoInput = errors.New("no terminal to prompt on, pass the value with -stdin")
+
+// Prompter reads values from stdin. On a terminal secrets are read without
+// echo, otherwise values are read one per line if pipe input is allowed.
+type Prompter struct {
+	in       *bufio.Reader
+	fd       int
+	out      io.Writer
+	terminal bool
+	pipe     bool
+}
+
+func New(in *os.File, out io.Writer, allowPipe bool) *Prompter {
+	fd := int(in.Fd())
+	return &Prompter{
+		in:       bufio.NewReader(in),
+		fd:       fd,
+		out:      out,
+		terminal: term.IsTerminal(fd),
+		pipe:     allowPipe,
+	}
+}
+
+// Interactive reports whether a user can answer prompts.
+func (p *Prompter) Interactive() bool {
+	return p.terminal
+}
+
+// Line asks for a plain value.
+func (p *Prompter) Line(label string) (string, error) {
+	p.label(label)
+	return p.ReadLine()
+}
+
+// Secret asks for a value without echoing it.
+func (p *Prompter) Secret(label string) (string, error) {
+	p.label(label)
+	return p.ReadSecret()
+}
+
+// Optional asks for a secret value that may be skipped: missing input is
+// an empty value rather than an error.
+func (p *Prompter) Optional(label string) (string, error) {
+	if !p.terminal && !p.pipe {
+		return "", nil
+	}
+	v, err := p.Secret(label + " (optional)")
+	if errors.Is(err, io.EOF) {
+		return "", nil
+	}
+	return v, err
+}
+
+func (p *Prompter) ReadLine() (string, error) {
+	if !p.terminal && !p.pipe {
+		return "", ErrNoInput
+	}
+	line, err := p.in.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (p *Prompter) ReadSecret() (string, error) {
+	if !p.terminal {
+		return p.ReadLine()
+	}
+	b, err := term.ReadPassword(p.fd)
+	fmt.Fprintln(p.out)
+	if err != nil {
+		return "", fmt.Errorf("term.ReadPassword: %w", err)
+	}
+	return string(b), nil
+}
+
+func (p *Prompter) label(label string) {
+	if p.terminal {
+		fmt.Fprintf(p.out, "%s: ", label)
+	}
+}
@@ -9,11 +9,15 @@ import (
 	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 )
 
+// maxLineSize bounds a single stored item, binaries are kept inline.
+const maxLineSize = 64 << 20
+
 type BaseRepository[T model.Base] struct {
 	filename     string
 	replFilename string
@@ -38,57 +42,59 @@ func (r *BaseRepository[T]) save(ctx context.Context, bs T) error {
 	r.mx.Lock()
 	defer r.mx.Unlock()
 
-	file, err := os.OpenFile(r.filename, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	file, err := os.OpenFile(r.filename, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
 	if err != nil {
 		return fmt.Errorf("os.OpenFile: %w", err)
 	}
 	defer file.Close()
 
-	if !bs.IsNew() {
-
-		scanner := bufio.NewScanner(file)
-		searchStr := fmt.Sprintf(`"id":"%s"`, bs.GetID())
-
-		tmp, err := os.CreateTemp("", r.replFilename)
-		if err != nil {
-			return fmt.Errorf("os.CreateTemp: %w", err)
-		}
-		defer tmp.Close()
-
-		for scanner.Scan() {
-			text := scanner.Text()
-			if strings.Contains(text, searchStr) {
-				var bs model.Base
-				if err := json.Unmarshal(scanner.Bytes(), &bs); err != nil {
-					return fmt.Errorf("json.Unmarshal: %w", err)
-				}
-				if bs.GetStatus() == model.StatusActive {
-					text = strings.Replace(text, text, "", 1)
-				}
-			}
-			_, err := io.WriteString(tmp, text)
-			if err != nil {
-				return fmt.Errorf("io.WriteString: %w", err)
-			}
-
-			if _, err = file.WriteString("\n"); err != nil {
-				return fmt.Errorf("file.WriteString: %w", err)
-			}
-		}
-		if err := scanner.Err(); err != nil {
-			return fmt.Errorf("scanner.Err: %w", err)
-		}
-		err = os.Rename(tmp.Name(), r.filename)
-		if err != nil {
-			return fmt.Errorf("os.Rename: %w", err)
-		}
-	} else {
+	if bs.IsNew() {
 		if _, err = file.Write(bytes); err != nil {
 			return fmt.Errorf("file.Write: %w", err)
 		}
 		if _, err = file.WriteString("\n"); err != nil {
 			return fmt.Errorf("file.WriteString: %w", err)
 		}
+		return nil
+	}
+
+	// existing item is replaced in place, unknown one is appended
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, maxLineSize)
+	searchStr := fmt.Sprintf(`"id":"%s"`, bs.GetID())
+
+	tmp, err := os.CreateTemp(filepath.Dir(r.filename), r.replFilename)
+	if err != nil {
+		return fmt.Errorf("os.CreateTemp: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	var replaced bool
+	for scanner.Scan() {
+		text := scanner.Text()
+		if !replaced && strings.Contains(text, searchStr) {
+			text = string(bytes)
+			replaced = true
+		}
+		if _, err := io.WriteString(tmp, text+"\n"); err != nil {
+			return fmt.Errorf("io.WriteString: %w", err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("scanner.Err: %w", err)
+	}
+	if !replaced {
+		if _, err := io.WriteString(tmp, string(bytes)+"\n"); err != nil {
+			return fmt.Errorf("io.WriteString: %w", err)
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("tmp.Close: %w", err)
+	}
+	err = os.Rename(tmp.Name(), r.filename)
+	if err != nil {
+		return fmt.Errorf("os.Rename: %w", err)
 	}
 	return nil
 }
@@ -97,7 +103,7 @@ func (r *BaseRepository[T]) findByID(ctx context.Context, id string) (T, error)
 	r.mx.Lock()
 	defer r.mx.Unlock()
 
-	file, err := os.OpenFile(r.filename, os.O_RDONLY|os.O_CREATE, 0666)
+	file, err := os.OpenFile(r.filename, os.O_RDONLY|os.O_CREATE, 0600)
 	if err != nil {
 		var t T
 		return t, fmt.Errorf("os.OpenFile: %w", err)
@@ -105,6 +111,7 @@ func (r *BaseRepository[T]) findByID(ctx context.Context, id string) (T, error)
 	defer file.Close()
 
 	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, maxLineSize)
 	searchStr := fmt.Sprintf(`"id":"%s"`, id)
 
 	var found bool
@@ -138,13 +145,14 @@ func (r *BaseRepository[T]) findByUserID(ctx context.Context,
 	r.mx.Lock()
 	defer r.mx.Unlock()
 
-	file, err := os.OpenFile(r.filename, os.O_RDONLY|os.O_CREATE, 0666)
+	file, err := os.OpenFile(r.filename, os.O_RDONLY|os.O_CREATE, 0600)
 	if err != nil {
 		return nil, fmt.Errorf("os.OpenFile: %w", err)
 	}
 	defer file.Close()
 
 	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, maxLineSize)
 	var res []T
 	for scanner.Scan() {
 		var bs T
@@ -152,7 +160,9 @@ func (r *BaseRepository[T]) findByUserID(ctx context.Context,
 		if err != nil {
 			return nil, fmt.Errorf("json.Unmarshal: %w", err)
 		}
-		res = append(res, bs)
+		if bs.GetUserID() == userID && bs.GetStatus() == model.StatusActive {
+			res = append(res, bs)
+		}
 	}
 	if err := scanner.Err(); err != nil {
 		return nil, fmt.Errorf("scanner.Err: %w", err)
@@ -166,13 +176,14 @@ func (r *BaseRepository[T]) findActiveModifiedAfter(ctx context.Context, userID
 	r.mx.Lock()
 	defer r.mx.Unlock()
 
-	file, err := os.OpenFile(r.filename, os.O_RDONLY|os.O_CREATE, 0666)
+	file, err := os.OpenFile(r.filename, os.O_RDONLY|os.O_CREATE, 0600)
 	if err != nil {
 		return nil, fmt.Errorf("os.OpenFile: %w", err)
 	}
 	defer file.Close()
 
 	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, maxLineSize)
 	var res []T
 	for scanner.Scan() {
 		var bs T
@@ -196,13 +207,14 @@ func (r *BaseRepository[T]) findDeletedModifiedAfter(ctx context.Context, userID
 	r.mx.Lock()
 	defer r.mx.Unlock()
 
-	file, err := os.OpenFile(r.filename, os.O_RDONLY|os.O_CREATE, 0666)
+	file, err := os.OpenFile(r.filename, os.O_RDONLY|os.O_CREATE, 0600)
 	if err != nil {
 		return nil, fmt.Errorf("os.OpenFile: %w", err)
 	}
 	defer file.Close()
 
 	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, maxLineSize)
 	var res []T
 	for scanner.Scan() {
 		var bs T
@@ -224,21 +236,24 @@ func (r *BaseRepository[T]) deleteByID(ctx context.Context, id string) error {
 	r.mx.Lock()
 	defer r.mx.Unlock()
 
-	file, err := os.OpenFile(r.filename, os.O_RDONLY|os.O_CREATE, 0666)
+	file, err := os.OpenFile(r.filename, os.O_RDONLY|os.O_CREATE, 0600)
 	if err != nil {
 		return fmt.Errorf("os.OpenFile: %w", err)
 	}
 	defer file.Close()
 
 	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, maxLineSize)
 	searchStr := fmt.Sprintf(`"id":"%s"`, id)
 
-	tmp, err := os.CreateTemp("", r.replFilename)
+	tmp, err := os.CreateTemp(filepath.Dir(r.filename), r.replFilename)
 	if err != nil {
 		return fmt.Errorf("os.CreateTemp: %w", err)
 	}
+	defer os.Remove(tmp.Name())
 	defer tmp.Close()
 
+	var found bool
 	for scanner.Scan() {
 		text := scanner.Text()
 		if strings.Contains(text, searchStr) {
@@ -247,24 +262,27 @@ func (r *BaseRepository[T]) deleteByID(ctx context.Context, id string) error {
 				return fmt.Errorf("json.Unmarshal: %w", err)
 			}
 			bs.SetStatus(model.StatusDeleted)
+			bs.SetModifiedTms(time.Now().UTC())
 			bytes, err := json.Marshal(bs)
 			if err != nil {
 				return fmt.Errorf("json.Marshal: %w", err)
 			}
-			text = strings.Replace(text, text, string(bytes), 1)
+			text = string(bytes)
+			found = true
 		}
-		_, err := io.WriteString(tmp, text)
-		if err != nil {
+		if _, err := io.WriteString(tmp, text+"\n"); err != nil {
 			return fmt.Errorf("io.WriteString: %w", err)
 		}
-
-		if _, err = file.WriteString("\n"); err != nil {
-			return fmt.Errorf("file.WriteString: %w", err)
-		}
 	}
 	if err := scanner.Err(); err != nil {
 		return fmt.Errorf("scanner.Err: %w", err)
 	}
+	if !found {
+		return repo.ErrItemNotFound
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("tmp.Close: %w", err)
+	}
 	err = os.Rename(tmp.Name(), r.filename)
 	if err != nil {
 		return fmt.Errorf("os.Rename: %w", err)
@@ -20,6 +20,15 @@ func (r *Repository) FindCardByID(ctx context.Context, id string) (*model.Card,
 	}
 	return byID, nil
 }
+func (r *Repository) FindCardsByUserID(ctx context.Context,
+	userID string) ([]*model.Card, error) {
+	cards, err := r.cardRepo.findByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("cardRepo.findByUserID: %w", err)
+	}
+	return cards, nil
+}
+
 func (r *Repository) FindCardsModifiedAfter(ctx context.Context, userID string,
 	tms time.Time) ([]*model.Card, error) {
 	mod, err := r.cardRepo.findActiveModifiedAfter(ctx, userID, tms)
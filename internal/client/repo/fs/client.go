@@ -33,7 +33,7 @@ func (r *ClientRepository) FindClient(ctx context.Context) (model.Client, error)
 	r.mx.Lock()
 	defer r.mx.Unlock()
 
-	file, err := os.OpenFile(r.filename, os.O_RDONLY|os.O_CREATE, 0666)
+	file, err := os.OpenFile(r.filename, os.O_RDONLY|os.O_CREATE, 0600)
 	if err != nil {
 		return model.Client{}, fmt.Errorf("os.OpenFile: %w", err)
 	}
@@ -75,7 +75,7 @@ func (r *Repository) CreateClient(ctx context.Context, client model.Client) (mod
 		}
 	}
 	file, err := os.OpenFile(r.clientRepo.filename, os.O_RDWR|os.O_APPEND|os.O_CREATE,
-		0666)
+		0600)
 	if err != nil {
 		return model.Client{}, fmt.Errorf("os.OpenFile: %w", err)
 	}
@@ -100,7 +100,7 @@ func (r *Repository) UpdateClientLastSyncTmsByID(ctx context.Context, id string,
 	r.clientRepo.mx.Lock()
 	defer r.clientRepo.mx.Unlock()
 
-	file, err := os.OpenFile(r.clientRepo.filename, os.O_RDONLY|os.O_CREATE, 0666)
+	file, err := os.OpenFile(r.clientRepo.filename, os.O_RDONLY|os.O_CREATE, 0600)
 	if err != nil {
 		return fmt.Errorf("os.OpenFile: %w", err)
 	}
@@ -151,7 +151,7 @@ func (r *Repository) FindClientByID(ctx context.Context, id string) (model.Clien
 	r.clientRepo.mx.Lock()
 	defer r.clientRepo.mx.Unlock()
 
-	file, err := os.OpenFile(r.clientRepo.filename, os.O_RDONLY|os.O_CREATE, 0666)
+	file, err := os.OpenFile(r.clientRepo.filename, os.O_RDONLY|os.O_CREATE, 0600)
 	if err != nil {
 		return model.Client{}, fmt.Errorf("os.OpenFile: %w", err)
 	}
@@ -23,6 +23,15 @@ func (r *Repository) FindCredentialsByID(ctx context.Context,
 
 }
 
+func (r *Repository) FindCredentialsByUserID(ctx context.Context,
+	userID string) ([]*model.Credentials, error) {
+	creds, err := r.credentialsRepo.findByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("credentialsRepo.findByUserID: %w", err)
+	}
+	return creds, nil
+}
+
 func (r *Repository) FindCredentialsModifiedAfter(ctx context.Context, userID string,
 	tms time.Time) ([]*model.Credentials, error) {
 	mod, err := r.credentialsRepo.findActiveModifiedAfter(ctx, userID, tms)
@@ -30,7 +30,7 @@ func (r *Repository) FindUser(ctx context.Context) (model.User, error) {
 		return model.User{}, repo.ErrItemNotFound
 	}
 
-	file, err := os.OpenFile(r.userRepo.filename, os.O_RDONLY, 0666)
+	file, err := os.OpenFile(r.userRepo.filename, os.O_RDONLY, 0600)
 	if err != nil {
 		return model.User{}, fmt.Errorf("os.OpenFile: %w", err)
 	}
@@ -62,7 +62,7 @@ func (r *Repository) CreateUser(ctx context.Context, usr model.User) (model.User
 	if !create {
 		return model.User{}, repo.ErrUserAlreadyExist
 	}
-	file, err := os.OpenFile(r.userRepo.filename, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0666)
+	file, err := os.OpenFile(r.userRepo.filename, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0600)
 	if err != nil {
 		return model.User{}, fmt.Errorf("os.OpenFile: %w", err)
 	}
@@ -95,7 +95,7 @@ func (r *Repository) FindUserByLogin(ctx context.Context, login string) (model.U
 		return model.User{}, repo.ErrItemNotFound
 	}
 
-	file, err := os.OpenFile(r.userRepo.filename, os.O_RDONLY, 0666)
+	file, err := os.OpenFile(r.userRepo.filename, os.O_RDONLY, 0600)
 	if err != nil {
 		return model.User{}, fmt.Errorf("os.OpenFile: %w", err)
 	}
@@ -114,3 +114,24 @@ func (r *Repository) FindUserByLogin(ctx context.Context, login string) (model.U
 	}
 	return usr, nil
 }
+
+// UpdateUser replaces the stored user, e.g. after a password change.
+func (r *Repository) UpdateUser(ctx context.Context, usr model.User) error {
+	r.userRepo.mx.Lock()
+	defer r.userRepo.mx.Unlock()
+
+	bytes, err := json.Marshal(usr)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+	err = os.WriteFile(r.userRepo.filename, append(bytes, '\n'), 0600)
+	if err != nil {
+		return fmt.Errorf("os.WriteFile: %w", err)
+	}
+	// older clients created the file readable by others
+	err = os.Chmod(r.userRepo.filename, 0600)
+	if err != nil {
+		return fmt.Errorf("os.Chmod: %w", err)
+	}
+	return nil
+}
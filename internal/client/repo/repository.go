@@ -15,6 +15,7 @@ var ErrItemNotFound = errors.New("item not found")
 type ClientRepository interface {
 	CreateUser(ctx context.Context, usr model.User) (model.User, error)
 	FindUserByLogin(ctx context.Context, login string) (model.User, error)
+	UpdateUser(ctx context.Context, usr model.User) error
 
 	CreateClient(ctx context.Context, client model.Client) (model.Client, error)
 	UpdateClientLastSyncTmsByID(ctx context.Context, id string, syncTms time.Time) error
@@ -22,12 +23,14 @@ type ClientRepository interface {
 
 	SaveCredentials(ctx context.Context, cred *model.Credentials) error
 	FindCredentialsByID(ctx context.Context, id string) (*model.Credentials, error)
+	FindCredentialsByUserID(ctx context.Context, userID string) ([]*model.Credentials, error)
 	FindCredentialsModifiedAfter(ctx context.Context, userID string,
 		tms time.Time) ([]*model.Credentials, error)
 	DeleteCredentialsByID(ctx context.Context, id string) error
 
 	SaveText(ctx context.Context, txt *model.Text) error
 	FindTextByID(ctx context.Context, id string) (*model.Text, error)
+	FindTextsByUserID(ctx context.Context, userID string) ([]*model.Text, error)
 	FindActiveTextsModifiedAfter(ctx context.Context, userID string,
 		tms time.Time) ([]*model.Text, error)
 	FindDeletedTextsModifiedAfter(ctx context.Context, userID string,
@@ -36,6 +39,7 @@ type ClientRepository interface {
 
 	SaveBinary(ctx context.Context, bin *model.Binary) error
 	FindBinaryByID(ctx context.Context, id string) (*model.Binary, error)
+	FindBinariesByUserID(ctx context.Context, userID string) ([]*model.Binary, error)
 	FindActiveBinariesModifiedAfter(ctx context.Context, userID string,
 		tms time.Time) ([]*model.Binary, error)
 	FindDeletedBinariesModifiedAfter(ctx context.Context, userID string,
@@ -44,6 +48,7 @@ type ClientRepository interface {
 
 	SaveCard(ctx context.Context, card *model.Card) error
 	FindCardByID(ctx context.Context, id string) (*model.Card, error)
+	FindCardsByUserID(ctx context.Context, userID string) ([]*model.Card, error)
 	FindCardsModifiedAfter(ctx context.Context, userID string,
 		tms time.Time) ([]*model.Card, error)
 	DeleteCardByID(ctx context.Context, id string) error
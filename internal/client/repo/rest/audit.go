@@ -0,0 +1,20 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+)
+
+// ClientIDHeaderName tells the server which client sends the request, it
+// ends up in the audit log.
+const ClientIDHeaderName = "X-Client-ID"
+
+func (r RESTRepositoryImpl) FindAuditEvents(ctx context.Context, before int64,
+	limit int) (model.AuditPage, error) {
+	ctx, span := tracer.Start(ctx, "RESTRepository.FindAuditEvents")
+	defer span.End()
+	var page model.AuditPage
+	err := r.get(ctx, fmt.Sprintf(`/api/user/audit?limit=%d&before=%d`, limit, before), &page)
+	return page, err
+}
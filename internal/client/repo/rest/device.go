@@ -0,0 +1,21 @@
+package rest
+
+import (
+	"context"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"net/http"
+)
+
+func (r RESTRepositoryImpl) FindClients(ctx context.Context) ([]model.Client, error) {
+	ctx, span := tracer.Start(ctx, "RESTRepository.FindClients")
+	defer span.End()
+	var res []model.Client
+	err := r.get(ctx, `/api/user/client`, &res)
+	return res, err
+}
+
+func (r RESTRepositoryImpl) RevokeClient(ctx context.Context, id string) error {
+	ctx, span := tracer.Start(ctx, "RESTRepository.RevokeClient")
+	defer span.End()
+	return r.send(ctx, http.MethodDelete, `/api/user/client/`+id, nil, http.StatusOK)
+}
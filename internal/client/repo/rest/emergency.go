@@ -0,0 +1,42 @@
+package rest
+
+import (
+	"context"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"net/http"
+)
+
+func (r RESTRepositoryImpl) GrantEmergencyAccess(ctx context.Context, e model.EmergencyAccess) error {
+	ctx, span := tracer.Start(ctx, "RESTRepository.GrantEmergencyAccess")
+	defer span.End()
+	return r.send(ctx, http.MethodPost, `/api/user/emergency`, e, http.StatusAccepted)
+}
+
+func (r RESTRepositoryImpl) FindEmergencyAccess(ctx context.Context) ([]model.EmergencyAccess, error) {
+	ctx, span := tracer.Start(ctx, "RESTRepository.FindEmergencyAccess")
+	defer span.End()
+	var res []model.EmergencyAccess
+	err := r.get(ctx, `/api/user/emergency`, &res)
+	return res, err
+}
+
+func (r RESTRepositoryImpl) RevokeEmergencyAccess(ctx context.Context, id string) error {
+	ctx, span := tracer.Start(ctx, "RESTRepository.RevokeEmergencyAccess")
+	defer span.End()
+	return r.send(ctx, http.MethodDelete, `/api/user/emergency/`+id, nil, http.StatusAccepted)
+}
+
+// MoveEmergencyAccess requests, approves or declines the access.
+func (r RESTRepositoryImpl) MoveEmergencyAccess(ctx context.Context, id, step string) error {
+	ctx, span := tracer.Start(ctx, "RESTRepository.MoveEmergencyAccess")
+	defer span.End()
+	return r.send(ctx, http.MethodPost, `/api/user/emergency/`+id+`/`+step, nil, http.StatusAccepted)
+}
+
+func (r RESTRepositoryImpl) FindEmergencyVault(ctx context.Context, id string) (model.EmergencyVault, error) {
+	ctx, span := tracer.Start(ctx, "RESTRepository.FindEmergencyVault")
+	defer span.End()
+	var v model.EmergencyVault
+	err := r.get(ctx, `/api/user/emergency/`+id+`/vault`, &v)
+	return v, err
+}
@@ -0,0 +1,140 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/repo"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"net/http"
+	"net/url"
+)
+
+func (r RESTRepositoryImpl) CreateOrg(ctx context.Context, org model.Org) error {
+	ctx, span := tracer.Start(ctx, "RESTRepository.CreateOrg")
+	defer span.End()
+	return r.send(ctx, http.MethodPost, `/api/user/orgs`, org, http.StatusCreated)
+}
+
+func (r RESTRepositoryImpl) FindOrgs(ctx context.Context) ([]model.Org, error) {
+	ctx, span := tracer.Start(ctx, "RESTRepository.FindOrgs")
+	defer span.End()
+	var orgs []model.Org
+	err := r.get(ctx, `/api/user/orgs`, &orgs)
+	return orgs, err
+}
+
+func (r RESTRepositoryImpl) FindOrgMembers(ctx context.Context, orgID string) ([]model.OrgMember, error) {
+	ctx, span := tracer.Start(ctx, "RESTRepository.FindOrgMembers")
+	defer span.End()
+	var members []model.OrgMember
+	err := r.get(ctx, orgPath(orgID, `/members`), &members)
+	return members, err
+}
+
+func (r RESTRepositoryImpl) SaveOrgMember(ctx context.Context, orgID string,
+	member model.OrgMember) error {
+	ctx, span := tracer.Start(ctx, "RESTRepository.SaveOrgMember")
+	defer span.End()
+	return r.send(ctx, http.MethodPost, orgPath(orgID, `/members`), member, http.StatusAccepted)
+}
+
+func (r RESTRepositoryImpl) RemoveOrgMember(ctx context.Context, orgID, login string) error {
+	ctx, span := tracer.Start(ctx, "RESTRepository.RemoveOrgMember")
+	defer span.End()
+	return r.send(ctx, http.MethodDelete, orgPath(orgID, `/members/`+url.PathEscape(login)),
+		nil, http.StatusAccepted)
+}
+
+func (r RESTRepositoryImpl) CreateCollection(ctx context.Context, orgID string,
+	c model.Collection) error {
+	ctx, span := tracer.Start(ctx, "RESTRepository.CreateCollection")
+	defer span.End()
+	return r.send(ctx, http.MethodPost, orgPath(orgID, `/collections`), c, http.StatusCreated)
+}
+
+func (r RESTRepositoryImpl) FindCollections(ctx context.Context, orgID string) ([]model.Collection, error) {
+	ctx, span := tracer.Start(ctx, "RESTRepository.FindCollections")
+	defer span.End()
+	var collections []model.Collection
+	err := r.get(ctx, orgPath(orgID, `/collections`), &collections)
+	return collections, err
+}
+
+func (r RESTRepositoryImpl) RekeyCollection(ctx context.Context, orgID, id string,
+	rekey model.CollectionRekey) error {
+	ctx, span := tracer.Start(ctx, "RESTRepository.RekeyCollection")
+	defer span.End()
+	return r.send(ctx, http.MethodPut, orgPath(orgID, `/collections/`+id+`/key`), rekey,
+		http.StatusAccepted)
+}
+
+func (r RESTRepositoryImpl) FindOrgItems(ctx context.Context, orgID,
+	collectionID string) ([]model.OrgItem, error) {
+	ctx, span := tracer.Start(ctx, "RESTRepository.FindOrgItems")
+	defer span.End()
+	var items []model.OrgItem
+	err := r.get(ctx, orgPath(orgID, `/collections/`+collectionID+`/items`), &items)
+	return items, err
+}
+
+func (r RESTRepositoryImpl) SaveOrgItem(ctx context.Context, orgID, collectionID string,
+	item model.OrgItem) error {
+	ctx, span := tracer.Start(ctx, "RESTRepository.SaveOrgItem")
+	defer span.End()
+	return r.send(ctx, http.MethodPost, orgPath(orgID, `/collections/`+collectionID+`/items`),
+		item, http.StatusAccepted)
+}
+
+func orgPath(orgID, path string) string {
+	return `/api/user/orgs/` + orgID + path
+}
+
+// send executes a request with a JSON body, 404 is reported as
+// repo.ErrItemNotFound.
+func (r RESTRepositoryImpl) send(ctx context.Context, method, path string,
+	body any, want int) error {
+	req := r.client.R().SetContext(ctx)
+	if body != nil {
+		marshal, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("json.Marshal: %w", err)
+		}
+		req.SetBody(marshal)
+	}
+	response, err := req.Execute(method, r.client.BaseURL+path)
+	if err != nil {
+		return fmt.Errorf("client.R().Execute: %w", err)
+	}
+	status := response.StatusCode()
+	if status != want {
+		if status == http.StatusNotFound {
+			return repo.ErrItemNotFound
+		}
+		return responseError(response)
+	}
+	return nil
+}
+
+// get reads a JSON response into dst, 204 leaves dst empty.
+func (r RESTRepositoryImpl) get(ctx context.Context, path string, dst any) error {
+	response, err := r.client.R().
+		SetContext(ctx).Get(r.client.BaseURL + path)
+	if err != nil {
+		return fmt.Errorf("client.R().Get: %w", err)
+	}
+	switch response.StatusCode() {
+	case http.StatusOK:
+	case http.StatusNoContent:
+		return nil
+	case http.StatusNotFound:
+		return repo.ErrItemNotFound
+	default:
+		return responseError(response)
+	}
+	err = json.Unmarshal(response.Body(), dst)
+	if err != nil {
+		return fmt.Errorf("json.Unmarshal: %w", err)
+	}
+	return nil
+}
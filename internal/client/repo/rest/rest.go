@@ -6,21 +6,69 @@ import (
 	"fmt"
 	"github.com/denis-oreshkevich/gophkeeper/internal/client/repo"
 	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/tracing"
 	"github.com/go-resty/resty/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"net/http"
 	"time"
 )
 
+var tracer = tracing.Tracer("client/repo/rest")
+
 type RESTRepository interface {
-	Login(ctx context.Context, usr model.AuthUser) (model.User, error)
-	CreateUser(ctx context.Context, usr model.AuthUser) (model.User, error)
+	Login(ctx context.Context, usr model.AuthUser) (model.User, string, error)
+	CreateUser(ctx context.Context, usr model.AuthUser) (model.User, string, error)
+	Prelogin(ctx context.Context, req model.PreloginRequest) (model.Prelogin, error)
+	ChangePassword(ctx context.Context, pc model.PasswordChange) error
+	FindRecoveryKit(ctx context.Context, req model.RecoveryRequest) (model.RecoveryKit, error)
+	ResetPassword(ctx context.Context, req model.PasswordReset) (model.User, string, error)
+	DeleteUser(ctx context.Context, d model.UserDeletion) error
 	CreateClient(ctx context.Context, client model.Client) (model.Client, error)
 	UpdateClientLastSyncTms(ctx context.Context, id string, syncTms time.Time) error
+	FindClients(ctx context.Context) ([]model.Client, error)
+	RevokeClient(ctx context.Context, id string) error
 
 	SyncCredentials(ctx context.Context, sync *model.CredSync) ([]model.Credentials, error)
 	SyncCard(ctx context.Context, sync *model.CardSync) ([]model.Card, error)
 	SyncText(ctx context.Context, sync *model.TextSync) ([]*model.Text, error)
 	SyncBinary(ctx context.Context, sync *model.BinarySync) ([]*model.Binary, error)
+
+	SaveUserKey(ctx context.Context, key model.UserKey) error
+	FindUserKey(ctx context.Context) (model.UserKey, error)
+	FindPublicKey(ctx context.Context, login string) (model.UserKey, error)
+	SaveShare(ctx context.Context, share model.Share) error
+	FindShares(ctx context.Context) ([]model.Share, error)
+	UpdateSharePayload(ctx context.Context, id string, payload model.SharePayload) error
+	RevokeShare(ctx context.Context, id, login string) error
+
+	CreateOrg(ctx context.Context, org model.Org) error
+	FindOrgs(ctx context.Context) ([]model.Org, error)
+	FindOrgMembers(ctx context.Context, orgID string) ([]model.OrgMember, error)
+	SaveOrgMember(ctx context.Context, orgID string, member model.OrgMember) error
+	RemoveOrgMember(ctx context.Context, orgID, login string) error
+	CreateCollection(ctx context.Context, orgID string, c model.Collection) error
+	FindCollections(ctx context.Context, orgID string) ([]model.Collection, error)
+	RekeyCollection(ctx context.Context, orgID, id string, rekey model.CollectionRekey) error
+	FindOrgItems(ctx context.Context, orgID, collectionID string) ([]model.OrgItem, error)
+	SaveOrgItem(ctx context.Context, orgID, collectionID string, item model.OrgItem) error
+
+	GrantEmergencyAccess(ctx context.Context, e model.EmergencyAccess) error
+	FindEmergencyAccess(ctx context.Context) ([]model.EmergencyAccess, error)
+	RevokeEmergencyAccess(ctx context.Context, id string) error
+	MoveEmergencyAccess(ctx context.Context, id, step string) error
+	FindEmergencyVault(ctx context.Context, id string) (model.EmergencyVault, error)
+
+	FindAuditEvents(ctx context.Context, before int64, limit int) (model.AuditPage, error)
+}
+
+// AuthorizationHeaderName carries the token the server issues on login and
+// registration, later requests send it back.
+const AuthorizationHeaderName = "Authorization"
+
+type errorResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
 }
 
 type RESTRepositoryImpl struct {
@@ -35,59 +83,68 @@ func NewRESTRepositoryImpl(client *resty.Client) *RESTRepositoryImpl {
 	}
 }
 
-func (r RESTRepositoryImpl) Login(ctx context.Context, usr model.AuthUser) (model.User, error) {
+func (r RESTRepositoryImpl) Login(ctx context.Context, usr model.AuthUser) (model.User, string, error) {
+	ctx, span := tracer.Start(ctx, "RESTRepository.Login")
+	defer span.End()
 	marshal, err := json.Marshal(usr)
 	if err != nil {
-		return model.User{}, fmt.Errorf("json.Marshal: %w", err)
+		return model.User{}, "", fmt.Errorf("json.Marshal: %w", err)
 	}
 	response, err := r.client.R().
 		SetContext(ctx).SetBody(marshal).Post(r.client.BaseURL + `/api/user/login`)
 	if err != nil {
-		return model.User{}, fmt.Errorf("client.R().Post: %w", err)
+		return model.User{}, "", fmt.Errorf("client.R().Post: %w", err)
 	}
 	status := response.StatusCode()
 	if status != http.StatusOK {
 		if status == http.StatusUnauthorized {
-			return model.User{}, repo.ErrItemNotFound
+			return model.User{}, "", repo.ErrItemNotFound
 		}
-		return model.User{}, fmt.Errorf("response status code = %d", status)
+		return model.User{}, "", responseError(response)
 	}
 
 	body := response.Body()
 	var user model.User
 	err = json.Unmarshal(body, &user)
 	if err != nil {
-		return model.User{}, fmt.Errorf("json.Unmarshal: %w", err)
+		return model.User{}, "", fmt.Errorf("json.Unmarshal: %w", err)
 	}
-	return user, nil
+	return user, response.Header().Get(AuthorizationHeaderName), nil
 }
 
-func (r RESTRepositoryImpl) CreateUser(ctx context.Context, usr model.AuthUser) (model.User, error) {
+func (r RESTRepositoryImpl) CreateUser(ctx context.Context, usr model.AuthUser) (model.User, string, error) {
+	ctx, span := tracer.Start(ctx, "RESTRepository.CreateUser")
+	defer span.End()
 	marshal, err := json.Marshal(usr)
 	if err != nil {
-		return model.User{}, fmt.Errorf("json.Marshal: %w", err)
+		return model.User{}, "", fmt.Errorf("json.Marshal: %w", err)
 	}
 	response, err := r.client.R().
 		SetContext(ctx).SetBody(marshal).Post(r.client.BaseURL + `/api/user/register`)
 	if err != nil {
-		return model.User{}, fmt.Errorf("client.R().Post: %w", err)
+		return model.User{}, "", fmt.Errorf("client.R().Post: %w", err)
 	}
 	status := response.StatusCode()
 	if status != http.StatusOK {
-		return model.User{}, fmt.Errorf("response status code = %d", status)
+		if status == http.StatusConflict {
+			return model.User{}, "", repo.ErrUserAlreadyExist
+		}
+		return model.User{}, "", responseError(response)
 	}
 
 	body := response.Body()
 	var user model.User
 	err = json.Unmarshal(body, &user)
 	if err != nil {
-		return model.User{}, fmt.Errorf("json.Unmarshal: %w", err)
+		return model.User{}, "", fmt.Errorf("json.Unmarshal: %w", err)
 	}
-	return user, nil
+	return user, response.Header().Get(AuthorizationHeaderName), nil
 }
 
 func (r RESTRepositoryImpl) CreateClient(ctx context.Context,
 	client model.Client) (model.Client, error) {
+	ctx, span := tracer.Start(ctx, "RESTRepository.CreateClient")
+	defer span.End()
 	marshal, err := json.Marshal(client)
 	if err != nil {
 		return model.Client{}, fmt.Errorf("json.Marshal: %w", err)
@@ -99,7 +156,7 @@ func (r RESTRepositoryImpl) CreateClient(ctx context.Context,
 	}
 	status := response.StatusCode()
 	if status != http.StatusCreated {
-		return model.Client{}, fmt.Errorf("response status code = %d", status)
+		return model.Client{}, responseError(response)
 	}
 	body := response.Body()
 	err = json.Unmarshal(body, &client)
@@ -110,6 +167,8 @@ func (r RESTRepositoryImpl) CreateClient(ctx context.Context,
 }
 
 func (r RESTRepositoryImpl) UpdateClientLastSyncTms(ctx context.Context, id string, syncTms time.Time) error {
+	ctx, span := tracer.Start(ctx, "RESTRepository.UpdateClientLastSyncTms")
+	defer span.End()
 	client := model.Client{
 		ID:      id,
 		SyncTms: syncTms,
@@ -126,13 +185,16 @@ func (r RESTRepositoryImpl) UpdateClientLastSyncTms(ctx context.Context, id stri
 	}
 	status := response.StatusCode()
 	if status != http.StatusOK {
-		return fmt.Errorf("response status code = %d", status)
+		return responseError(response)
 	}
 	return nil
 }
 
 func (r RESTRepositoryImpl) SyncCredentials(ctx context.Context,
 	sync *model.CredSync) ([]model.Credentials, error) {
+	ctx, span := tracer.Start(ctx, "RESTRepository.SyncCredentials",
+		trace.WithAttributes(attribute.Int("sync.sent", len(sync.Credentials))))
+	defer span.End()
 	marshal, err := json.Marshal(sync)
 	if err != nil {
 		return nil, fmt.Errorf("json.Marshal: %w", err)
@@ -144,7 +206,7 @@ func (r RESTRepositoryImpl) SyncCredentials(ctx context.Context,
 	}
 	status := response.StatusCode()
 	if status != http.StatusAccepted {
-		return nil, fmt.Errorf("response status code = %d", status)
+		return nil, responseError(response)
 	}
 	body := response.Body()
 	var dc []model.Credentials
@@ -157,6 +219,9 @@ func (r RESTRepositoryImpl) SyncCredentials(ctx context.Context,
 
 func (r RESTRepositoryImpl) SyncCard(ctx context.Context,
 	sync *model.CardSync) ([]model.Card, error) {
+	ctx, span := tracer.Start(ctx, "RESTRepository.SyncCard",
+		trace.WithAttributes(attribute.Int("sync.sent", len(sync.Cards))))
+	defer span.End()
 	marshal, err := json.Marshal(sync)
 	if err != nil {
 		return nil, fmt.Errorf("json.Marshal: %w", err)
@@ -168,7 +233,7 @@ func (r RESTRepositoryImpl) SyncCard(ctx context.Context,
 	}
 	status := response.StatusCode()
 	if status != http.StatusAccepted {
-		return nil, fmt.Errorf("response status code = %d", status)
+		return nil, responseError(response)
 	}
 	body := response.Body()
 	var dc []model.Card
@@ -181,6 +246,9 @@ func (r RESTRepositoryImpl) SyncCard(ctx context.Context,
 
 func (r RESTRepositoryImpl) SyncText(ctx context.Context,
 	sync *model.TextSync) ([]*model.Text, error) {
+	ctx, span := tracer.Start(ctx, "RESTRepository.SyncText",
+		trace.WithAttributes(attribute.Int("sync.sent", len(sync.Texts))))
+	defer span.End()
 	marshal, err := json.Marshal(sync)
 	if err != nil {
 		return nil, fmt.Errorf("json.Marshal: %w", err)
@@ -192,7 +260,7 @@ func (r RESTRepositoryImpl) SyncText(ctx context.Context,
 	}
 	status := response.StatusCode()
 	if status != http.StatusAccepted {
-		return nil, fmt.Errorf("response status code = %d", status)
+		return nil, responseError(response)
 	}
 	body := response.Body()
 	var dt []*model.Text
@@ -205,6 +273,9 @@ func (r RESTRepositoryImpl) SyncText(ctx context.Context,
 
 func (r RESTRepositoryImpl) SyncBinary(ctx context.Context,
 	sync *model.BinarySync) ([]*model.Binary, error) {
+	ctx, span := tracer.Start(ctx, "RESTRepository.SyncBinary",
+		trace.WithAttributes(attribute.Int("sync.sent", len(sync.Binaries))))
+	defer span.End()
 	marshal, err := json.Marshal(sync)
 	if err != nil {
 		return nil, fmt.Errorf("json.Marshal: %w", err)
@@ -216,7 +287,7 @@ func (r RESTRepositoryImpl) SyncBinary(ctx context.Context,
 	}
 	status := response.StatusCode()
 	if status != http.StatusAccepted {
-		return nil, fmt.Errorf("response status code = %d", status)
+		return nil, responseError(response)
 	}
 	body := response.Body()
 	var db []*model.Binary
@@ -226,3 +297,12 @@ func (r RESTRepositoryImpl) SyncBinary(ctx context.Context,
 	}
 	return db, nil
 }
+
+func responseError(response *resty.Response) error {
+	status := response.StatusCode()
+	var errResp errorResponse
+	if err := json.Unmarshal(response.Body(), &errResp); err != nil || errResp.Code == "" {
+		return fmt.Errorf("response status code = %d", status)
+	}
+	return fmt.Errorf("response status code = %d, %s: %s", status, errResp.Code, errResp.Message)
+}
@@ -0,0 +1,145 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/repo"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"net/http"
+	"net/url"
+)
+
+func (r RESTRepositoryImpl) SaveUserKey(ctx context.Context, key model.UserKey) error {
+	ctx, span := tracer.Start(ctx, "RESTRepository.SaveUserKey")
+	defer span.End()
+	marshal, err := json.Marshal(key)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+	response, err := r.client.R().
+		SetContext(ctx).SetBody(marshal).Put(r.client.BaseURL + `/api/user/keys`)
+	if err != nil {
+		return fmt.Errorf("client.R().Put: %w", err)
+	}
+	if response.StatusCode() != http.StatusOK {
+		return responseError(response)
+	}
+	return nil
+}
+
+func (r RESTRepositoryImpl) FindUserKey(ctx context.Context) (model.UserKey, error) {
+	ctx, span := tracer.Start(ctx, "RESTRepository.FindUserKey")
+	defer span.End()
+	return r.findKey(ctx, `/api/user/keys`)
+}
+
+func (r RESTRepositoryImpl) FindPublicKey(ctx context.Context, login string) (model.UserKey, error) {
+	ctx, span := tracer.Start(ctx, "RESTRepository.FindPublicKey")
+	defer span.End()
+	return r.findKey(ctx, `/api/user/keys/`+url.PathEscape(login))
+}
+
+func (r RESTRepositoryImpl) findKey(ctx context.Context, path string) (model.UserKey, error) {
+	response, err := r.client.R().
+		SetContext(ctx).Get(r.client.BaseURL + path)
+	if err != nil {
+		return model.UserKey{}, fmt.Errorf("client.R().Get: %w", err)
+	}
+	status := response.StatusCode()
+	if status != http.StatusOK {
+		if status == http.StatusNotFound {
+			return model.UserKey{}, repo.ErrItemNotFound
+		}
+		return model.UserKey{}, responseError(response)
+	}
+	var key model.UserKey
+	err = json.Unmarshal(response.Body(), &key)
+	if err != nil {
+		return model.UserKey{}, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+	return key, nil
+}
+
+func (r RESTRepositoryImpl) SaveShare(ctx context.Context, share model.Share) error {
+	ctx, span := tracer.Start(ctx, "RESTRepository.SaveShare")
+	defer span.End()
+	marshal, err := json.Marshal(share)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+	response, err := r.client.R().
+		SetContext(ctx).SetBody(marshal).Post(r.client.BaseURL + `/api/user/shares`)
+	if err != nil {
+		return fmt.Errorf("client.R().Post: %w", err)
+	}
+	status := response.StatusCode()
+	if status != http.StatusAccepted {
+		if status == http.StatusNotFound {
+			return repo.ErrItemNotFound
+		}
+		return responseError(response)
+	}
+	return nil
+}
+
+func (r RESTRepositoryImpl) FindShares(ctx context.Context) ([]model.Share, error) {
+	ctx, span := tracer.Start(ctx, "RESTRepository.FindShares")
+	defer span.End()
+	response, err := r.client.R().
+		SetContext(ctx).Get(r.client.BaseURL + `/api/user/shares`)
+	if err != nil {
+		return nil, fmt.Errorf("client.R().Get: %w", err)
+	}
+	switch response.StatusCode() {
+	case http.StatusOK:
+	case http.StatusNoContent:
+		return nil, nil
+	default:
+		return nil, responseError(response)
+	}
+	var shares []model.Share
+	err = json.Unmarshal(response.Body(), &shares)
+	if err != nil {
+		return nil, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+	return shares, nil
+}
+
+func (r RESTRepositoryImpl) UpdateSharePayload(ctx context.Context, id string,
+	payload model.SharePayload) error {
+	ctx, span := tracer.Start(ctx, "RESTRepository.UpdateSharePayload")
+	defer span.End()
+	marshal, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+	response, err := r.client.R().
+		SetContext(ctx).SetBody(marshal).Put(r.client.BaseURL + `/api/user/shares/` + id)
+	if err != nil {
+		return fmt.Errorf("client.R().Put: %w", err)
+	}
+	if response.StatusCode() != http.StatusAccepted {
+		return responseError(response)
+	}
+	return nil
+}
+
+func (r RESTRepositoryImpl) RevokeShare(ctx context.Context, id, login string) error {
+	ctx, span := tracer.Start(ctx, "RESTRepository.RevokeShare")
+	defer span.End()
+	response, err := r.client.R().
+		SetContext(ctx).Delete(r.client.BaseURL + `/api/user/shares/` + id +
+		`/grants/` + url.PathEscape(login))
+	if err != nil {
+		return fmt.Errorf("client.R().Delete: %w", err)
+	}
+	status := response.StatusCode()
+	if status != http.StatusAccepted {
+		if status == http.StatusNotFound {
+			return repo.ErrItemNotFound
+		}
+		return responseError(response)
+	}
+	return nil
+}
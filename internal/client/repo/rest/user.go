@@ -0,0 +1,76 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/client/repo"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"net/http"
+)
+
+func (r RESTRepositoryImpl) ChangePassword(ctx context.Context, pc model.PasswordChange) error {
+	ctx, span := tracer.Start(ctx, "RESTRepository.ChangePassword")
+	defer span.End()
+	return r.send(ctx, http.MethodPut, `/api/user/password`, pc, http.StatusAccepted)
+}
+
+func (r RESTRepositoryImpl) Prelogin(ctx context.Context,
+	req model.PreloginRequest) (model.Prelogin, error) {
+	ctx, span := tracer.Start(ctx, "RESTRepository.Prelogin")
+	defer span.End()
+	var res model.Prelogin
+	_, err := r.postAuth(ctx, `/api/user/prelogin`, req, &res)
+	return res, err
+}
+
+func (r RESTRepositoryImpl) FindRecoveryKit(ctx context.Context,
+	req model.RecoveryRequest) (model.RecoveryKit, error) {
+	ctx, span := tracer.Start(ctx, "RESTRepository.FindRecoveryKit")
+	defer span.End()
+	var kit model.RecoveryKit
+	_, err := r.postAuth(ctx, `/api/user/recovery`, req, &kit)
+	return kit, err
+}
+
+func (r RESTRepositoryImpl) ResetPassword(ctx context.Context,
+	req model.PasswordReset) (model.User, string, error) {
+	ctx, span := tracer.Start(ctx, "RESTRepository.ResetPassword")
+	defer span.End()
+	var user model.User
+	token, err := r.postAuth(ctx, `/api/user/recovery/reset`, req, &user)
+	return user, token, err
+}
+
+// postAuth posts an unauthenticated request, a rejected login or proof is
+// reported as repo.ErrItemNotFound like on login. The token the server
+// issues is returned, if any.
+func (r RESTRepositoryImpl) postAuth(ctx context.Context, path string, body, dst any) (string, error) {
+	marshal, err := json.Marshal(body)
+	if err != nil {
+		return "", fmt.Errorf("json.Marshal: %w", err)
+	}
+	response, err := r.client.R().
+		SetContext(ctx).SetBody(marshal).Post(r.client.BaseURL + path)
+	if err != nil {
+		return "", fmt.Errorf("client.R().Post: %w", err)
+	}
+	switch response.StatusCode() {
+	case http.StatusOK:
+	case http.StatusUnauthorized:
+		return "", repo.ErrItemNotFound
+	default:
+		return "", responseError(response)
+	}
+	err = json.Unmarshal(response.Body(), dst)
+	if err != nil {
+		return "", fmt.Errorf("json.Unmarshal: %w", err)
+	}
+	return response.Header().Get(AuthorizationHeaderName), nil
+}
+
+func (r RESTRepositoryImpl) DeleteUser(ctx context.Context, d model.UserDeletion) error {
+	ctx, span := tracer.Start(ctx, "RESTRepository.DeleteUser")
+	defer span.End()
+	return r.send(ctx, http.MethodDelete, `/api/user`, d, http.StatusAccepted)
+}
@@ -0,0 +1,16 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+)
+
+func (s *ClientService) FindAuditEvents(ctx context.Context, before int64,
+	limit int) (model.AuditPage, error) {
+	page, err := s.remoteRepo.FindAuditEvents(ctx, before, limit)
+	if err != nil {
+		return model.AuditPage{}, fmt.Errorf("remoteRepo.FindAuditEvents: %w", err)
+	}
+	return page, nil
+}
@@ -27,70 +27,145 @@ func NewClientService(baseRepo repo.ClientRepository,
 	}
 }
 
-func (s *ClientService) RegisterUser(ctx context.Context, login,
-	password string) (model.User, error) {
-	ausr := model.AuthUser{
-		Login:    login,
-		Password: password,
-	}
-	usr, err := s.remoteRepo.CreateUser(ctx, ausr)
+// RegisterUser creates the user on the server. The vault key and recovery
+// kit of ausr are wrapped by the caller. The token the server issues is
+// returned with the stored user.
+func (s *ClientService) RegisterUser(ctx context.Context, ausr model.AuthUser) (model.User, string, error) {
+	usr, token, err := s.remoteRepo.CreateUser(ctx, ausr)
 	if err != nil {
-		return model.User{}, fmt.Errorf("remoteRepo.CreateUser: %w", err)
+		return model.User{}, "", fmt.Errorf("remoteRepo.CreateUser: %w", err)
 	}
 
-	ePassword, err := auth.EncryptPassword(password)
-	newUser := model.NewUser(login, ePassword)
-	newUser.ID = usr.ID
+	ePassword, err := auth.EncryptPassword(ausr.Password)
 	if err != nil {
-		return model.User{}, fmt.Errorf("auth.EncryptPassword: %w", err)
+		return model.User{}, "", fmt.Errorf("auth.EncryptPassword: %w", err)
 	}
+	newUser := model.NewUser(ausr.Login, ePassword)
+	newUser.ID = usr.ID
+	newUser.Vault = ausr.Vault
 	newUser, err = s.baseRepo.CreateUser(ctx, newUser)
 	if err != nil {
-		return model.User{}, fmt.Errorf("baseRepo.CreateUser: %w", err)
+		return model.User{}, "", fmt.Errorf("baseRepo.CreateUser: %w", err)
 	}
 
-	return newUser, nil
+	return newUser, token, nil
 }
 
-func (s *ClientService) Login(ctx context.Context, login, password string) (model.User, error) {
-	us, err := s.baseRepo.FindUserByLogin(ctx, login)
+// Prelogin asks the server whether login takes the login secret.
+func (s *ClientService) Prelogin(ctx context.Context, login string) (model.Prelogin, error) {
+	res, err := s.remoteRepo.Prelogin(ctx, model.PreloginRequest{Login: login})
 	if err != nil {
-		if !errors.Is(err, repo.ErrItemNotFound) {
-			return model.User{}, fmt.Errorf("baseRepo.FindUserByLogin: %w", err)
-		}
-		authUser := model.AuthUser{
-			Login:    login,
-			Password: password,
-		}
-		user, err := s.remoteRepo.Login(ctx, authUser)
-		if err != nil {
-			return model.User{}, fmt.Errorf("remoteRepo.Login: %w", err)
-		}
-		us.ID = user.ID
-		us.Login = login
-		ePassword, err := auth.EncryptPassword(password)
-		if err != nil {
-			return model.User{}, fmt.Errorf("auth.EncryptPassword: %w", err)
-		}
-		us.HashedPassword = ePassword
+		return model.Prelogin{}, fmt.Errorf("remoteRepo.Prelogin: %w", err)
+	}
+	return res, nil
+}
+
+// Login logs in on the server and stores the user, so the vault can be
+// unlocked offline. The token the server issues is returned with it.
+func (s *ClientService) Login(ctx context.Context, login, password string) (model.User, string, error) {
+	authUser := model.AuthUser{
+		Login:    login,
+		Password: password,
+	}
+	user, token, err := s.remoteRepo.Login(ctx, authUser)
+	if err != nil {
+		return model.User{}, "", fmt.Errorf("remoteRepo.Login: %w", err)
+	}
+	us, err := s.localUser(user, password)
+	if err != nil {
+		return model.User{}, "", err
+	}
+
+	_, err = s.baseRepo.FindUserByLogin(ctx, login)
+	if errors.Is(err, repo.ErrItemNotFound) {
 		us, err = s.baseRepo.CreateUser(ctx, us)
 		if err != nil {
-			return model.User{}, fmt.Errorf("baseRepo.CreateUser: %w", err)
+			return model.User{}, "", fmt.Errorf("baseRepo.CreateUser: %w", err)
 		}
+		return us, token, nil
+	}
+	if err != nil {
+		return model.User{}, "", fmt.Errorf("baseRepo.FindUserByLogin: %w", err)
+	}
+	// the password may have changed on another device
+	if err := s.baseRepo.UpdateUser(ctx, us); err != nil {
+		return model.User{}, "", fmt.Errorf("baseRepo.UpdateUser: %w", err)
+	}
+	return us, token, nil
+}
+
+// localUser keeps what the client needs to unlock the vault offline.
+func (s *ClientService) localUser(user model.User, password string) (model.User, error) {
+	ePassword, err := auth.EncryptPassword(password)
+	if err != nil {
+		return model.User{}, fmt.Errorf("auth.EncryptPassword: %w", err)
 	}
-	err = auth.ComparePasswords(us.HashedPassword, password)
+	us := model.NewUser(user.Login, ePassword)
+	us.ID, us.Vault = user.ID, user.Vault
+	return us, nil
+}
+
+// ChangePassword changes the password on the server and of the stored user.
+func (s *ClientService) ChangePassword(ctx context.Context, user model.User,
+	pc model.PasswordChange) (model.User, error) {
+	err := s.remoteRepo.ChangePassword(ctx, pc)
+	if err != nil {
+		return model.User{}, fmt.Errorf("remoteRepo.ChangePassword: %w", err)
+	}
+	user.Vault = &pc.Vault
+	us, err := s.localUser(user, pc.NewPassword)
 	if err != nil {
 		return model.User{}, err
 	}
+	if err := s.baseRepo.UpdateUser(ctx, us); err != nil {
+		return model.User{}, fmt.Errorf("baseRepo.UpdateUser: %w", err)
+	}
 	return us, nil
 }
 
-func (s *ClientService) RegisterClient(ctx context.Context, userID string) (model.Client, error) {
+func (s *ClientService) DeleteUser(ctx context.Context, password string) error {
+	err := s.remoteRepo.DeleteUser(ctx, model.UserDeletion{Password: password})
+	if err != nil {
+		return fmt.Errorf("remoteRepo.DeleteUser: %w", err)
+	}
+	return nil
+}
+
+func (s *ClientService) FindRecoveryKit(ctx context.Context,
+	req model.RecoveryRequest) (model.RecoveryKit, error) {
+	kit, err := s.remoteRepo.FindRecoveryKit(ctx, req)
+	if err != nil {
+		return model.RecoveryKit{}, fmt.Errorf("remoteRepo.FindRecoveryKit: %w", err)
+	}
+	return kit, nil
+}
+
+// ResetPassword sets a new password with the recovery key and stores the
+// user with it. The token the server issues is returned with it.
+func (s *ClientService) ResetPassword(ctx context.Context, req model.PasswordReset) (model.User, string, error) {
+	user, token, err := s.remoteRepo.ResetPassword(ctx, req)
+	if err != nil {
+		return model.User{}, "", fmt.Errorf("remoteRepo.ResetPassword: %w", err)
+	}
+	us, err := s.localUser(user, req.NewPassword)
+	if err != nil {
+		return model.User{}, "", err
+	}
+	if err := s.baseRepo.UpdateUser(ctx, us); err != nil {
+		return model.User{}, "", fmt.Errorf("baseRepo.UpdateUser: %w", err)
+	}
+	return us, token, nil
+}
+
+// RegisterClient registers the client on the server and locally. A CSR
+// asks for a device certificate, the server may not sign it.
+func (s *ClientService) RegisterClient(ctx context.Context, userID, csr string) (model.Client, error) {
 	id := uuid.New()
 	client := model.Client{
 		ID:      id.String(),
 		UserID:  userID,
 		SyncTms: time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC),
+		CSR:     csr,
 	}
 	client, err := s.remoteRepo.CreateClient(ctx, client)
 	if err != nil {
@@ -126,6 +201,15 @@ func (s *ClientService) FindCredentialsByID(ctx context.Context, id string) (mod
 
 }
 
+func (s *ClientService) FindCredentialsByUserID(ctx context.Context,
+	userID string) ([]*model.Credentials, error) {
+	creds, err := s.baseRepo.FindCredentialsByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("baseRepo.FindCredentialsByUserID: %w", err)
+	}
+	return creds, nil
+}
+
 func (s *ClientService) DeleteCredentialsByID(ctx context.Context, id string) error {
 	err := s.baseRepo.DeleteCredentialsByID(ctx, id)
 	if err != nil {
@@ -150,6 +234,15 @@ func (s *ClientService) FindTextByID(ctx context.Context, id string) (*model.Tex
 	return text, nil
 }
 
+func (s *ClientService) FindTextsByUserID(ctx context.Context,
+	userID string) ([]*model.Text, error) {
+	texts, err := s.baseRepo.FindTextsByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("baseRepo.FindTextsByUserID: %w", err)
+	}
+	return texts, nil
+}
+
 func (s *ClientService) DeleteTextByID(ctx context.Context, id string) error {
 	err := s.baseRepo.DeleteTextByID(ctx, id)
 	if err != nil {
@@ -174,6 +267,15 @@ func (s *ClientService) FindBinaryByID(ctx context.Context, id string) (*model.B
 	return b, nil
 }
 
+func (s *ClientService) FindBinariesByUserID(ctx context.Context,
+	userID string) ([]*model.Binary, error) {
+	binaries, err := s.baseRepo.FindBinariesByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("baseRepo.FindBinariesByUserID: %w", err)
+	}
+	return binaries, nil
+}
+
 func (s *ClientService) DeleteBinaryByID(ctx context.Context, id string) error {
 	err := s.baseRepo.DeleteBinaryByID(ctx, id)
 	if err != nil {
@@ -198,6 +300,15 @@ func (s *ClientService) FindCardByID(ctx context.Context, id string) (model.Card
 	return *card, nil
 }
 
+func (s *ClientService) FindCardsByUserID(ctx context.Context,
+	userID string) ([]*model.Card, error) {
+	cards, err := s.baseRepo.FindCardsByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("baseRepo.FindCardsByUserID: %w", err)
+	}
+	return cards, nil
+}
+
 func (s *ClientService) DeleteCardByID(ctx context.Context, id string) error {
 	err := s.baseRepo.DeleteCardByID(ctx, id)
 	if err != nil {
@@ -354,3 +465,35 @@ func (s *ClientService) UpdateClientLastSyncTms(ctx context.Context, client mode
 
 	return nil
 }
+
+// PendingChanges counts the items changed locally after since, the next
+// sync sends them to the server.
+func (s *ClientService) PendingChanges(ctx context.Context, userID string,
+	since time.Time) (int, error) {
+	cred, err := s.baseRepo.FindCredentialsModifiedAfter(ctx, userID, since)
+	if err != nil {
+		return 0, fmt.Errorf("baseRepo.FindCredentialsModifiedAfter: %w", err)
+	}
+	cards, err := s.baseRepo.FindCardsModifiedAfter(ctx, userID, since)
+	if err != nil {
+		return 0, fmt.Errorf("baseRepo.FindCardsModifiedAfter: %w", err)
+	}
+	texts, err := s.baseRepo.FindActiveTextsModifiedAfter(ctx, userID, since)
+	if err != nil {
+		return 0, fmt.Errorf("baseRepo.FindActiveTextsModifiedAfter: %w", err)
+	}
+	deletedTexts, err := s.baseRepo.FindDeletedTextsModifiedAfter(ctx, userID, since)
+	if err != nil {
+		return 0, fmt.Errorf("baseRepo.FindDeletedTextsModifiedAfter: %w", err)
+	}
+	binaries, err := s.baseRepo.FindActiveBinariesModifiedAfter(ctx, userID, since)
+	if err != nil {
+		return 0, fmt.Errorf("baseRepo.FindActiveBinariesModifiedAfter: %w", err)
+	}
+	deletedBinaries, err := s.baseRepo.FindDeletedBinariesModifiedAfter(ctx, userID, since)
+	if err != nil {
+		return 0, fmt.Errorf("baseRepo.FindDeletedBinariesModifiedAfter: %w", err)
+	}
+	return len(cred) + len(cards) + len(texts) + len(deletedTexts) +
+		len(binaries) + len(deletedBinaries), nil
+}
@@ -0,0 +1,23 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+)
+
+func (s *ClientService) FindClients(ctx context.Context) ([]model.Client, error) {
+	clients, err := s.remoteRepo.FindClients(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("remoteRepo.FindClients: %w", err)
+	}
+	return clients, nil
+}
+
+func (s *ClientService) RevokeClient(ctx context.Context, id string) error {
+	err := s.remoteRepo.RevokeClient(ctx, id)
+	if err != nil {
+		return fmt.Errorf("remoteRepo.RevokeClient: %w", err)
+	}
+	return nil
+}
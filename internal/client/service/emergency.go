@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+)
+
+func (s *ClientService) GrantEmergencyAccess(ctx context.Context, e model.EmergencyAccess) error {
+	err := s.remoteRepo.GrantEmergencyAccess(ctx, e)
+	if err != nil {
+		return fmt.Errorf("remoteRepo.GrantEmergencyAccess: %w", err)
+	}
+	return nil
+}
+
+func (s *ClientService) FindEmergencyAccess(ctx context.Context) ([]model.EmergencyAccess, error) {
+	res, err := s.remoteRepo.FindEmergencyAccess(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("remoteRepo.FindEmergencyAccess: %w", err)
+	}
+	return res, nil
+}
+
+func (s *ClientService) RevokeEmergencyAccess(ctx context.Context, id string) error {
+	err := s.remoteRepo.RevokeEmergencyAccess(ctx, id)
+	if err != nil {
+		return fmt.Errorf("remoteRepo.RevokeEmergencyAccess: %w", err)
+	}
+	return nil
+}
+
+func (s *ClientService) MoveEmergencyAccess(ctx context.Context, id, step string) error {
+	err := s.remoteRepo.MoveEmergencyAccess(ctx, id, step)
+	if err != nil {
+		return fmt.Errorf("remoteRepo.MoveEmergencyAccess: %w", err)
+	}
+	return nil
+}
+
+func (s *ClientService) FindEmergencyVault(ctx context.Context, id string) (model.EmergencyVault, error) {
+	v, err := s.remoteRepo.FindEmergencyVault(ctx, id)
+	if err != nil {
+		return model.EmergencyVault{}, fmt.Errorf("remoteRepo.FindEmergencyVault: %w", err)
+	}
+	return v, nil
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+)
+
+func (s *ClientService) CreateOrg(ctx context.Context, org model.Org) error {
+	err := s.remoteRepo.CreateOrg(ctx, org)
+	if err != nil {
+		return fmt.Errorf("remoteRepo.CreateOrg: %w", err)
+	}
+	return nil
+}
+
+func (s *ClientService) FindOrgs(ctx context.Context) ([]model.Org, error) {
+	orgs, err := s.remoteRepo.FindOrgs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("remoteRepo.FindOrgs: %w", err)
+	}
+	return orgs, nil
+}
+
+func (s *ClientService) FindOrgMembers(ctx context.Context, orgID string) ([]model.OrgMember, error) {
+	members, err := s.remoteRepo.FindOrgMembers(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("remoteRepo.FindOrgMembers: %w", err)
+	}
+	return members, nil
+}
+
+func (s *ClientService) SaveOrgMember(ctx context.Context, orgID string,
+	member model.OrgMember) error {
+	err := s.remoteRepo.SaveOrgMember(ctx, orgID, member)
+	if err != nil {
+		return fmt.Errorf("remoteRepo.SaveOrgMember: %w", err)
+	}
+	return nil
+}
+
+func (s *ClientService) RemoveOrgMember(ctx context.Context, orgID, login string) error {
+	err := s.remoteRepo.RemoveOrgMember(ctx, orgID, login)
+	if err != nil {
+		return fmt.Errorf("remoteRepo.RemoveOrgMember: %w", err)
+	}
+	return nil
+}
+
+func (s *ClientService) CreateCollection(ctx context.Context, orgID string,
+	c model.Collection) error {
+	err := s.remoteRepo.CreateCollection(ctx, orgID, c)
+	if err != nil {
+		return fmt.Errorf("remoteRepo.CreateCollection: %w", err)
+	}
+	return nil
+}
+
+func (s *ClientService) FindCollections(ctx context.Context, orgID string) ([]model.Collection, error) {
+	collections, err := s.remoteRepo.FindCollections(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("remoteRepo.FindCollections: %w", err)
+	}
+	return collections, nil
+}
+
+func (s *ClientService) RekeyCollection(ctx context.Context, orgID, id string,
+	rekey model.CollectionRekey) error {
+	err := s.remoteRepo.RekeyCollection(ctx, orgID, id, rekey)
+	if err != nil {
+		return fmt.Errorf("remoteRepo.RekeyCollection: %w", err)
+	}
+	return nil
+}
+
+func (s *ClientService) FindOrgItems(ctx context.Context, orgID,
+	collectionID string) ([]model.OrgItem, error) {
+	items, err := s.remoteRepo.FindOrgItems(ctx, orgID, collectionID)
+	if err != nil {
+		return nil, fmt.Errorf("remoteRepo.FindOrgItems: %w", err)
+	}
+	return items, nil
+}
+
+func (s *ClientService) SaveOrgItem(ctx context.Context, orgID, collectionID string,
+	item model.OrgItem) error {
+	err := s.remoteRepo.SaveOrgItem(ctx, orgID, collectionID, item)
+	if err != nil {
+		return fmt.Errorf("remoteRepo.SaveOrgItem: %w", err)
+	}
+	return nil
+}
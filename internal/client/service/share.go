@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+)
+
+func (s *ClientService) SaveUserKey(ctx context.Context, key model.UserKey) error {
+	err := s.remoteRepo.SaveUserKey(ctx, key)
+	if err != nil {
+		return fmt.Errorf("remoteRepo.SaveUserKey: %w", err)
+	}
+	return nil
+}
+
+func (s *ClientService) FindUserKey(ctx context.Context) (model.UserKey, error) {
+	key, err := s.remoteRepo.FindUserKey(ctx)
+	if err != nil {
+		return model.UserKey{}, fmt.Errorf("remoteRepo.FindUserKey: %w", err)
+	}
+	return key, nil
+}
+
+func (s *ClientService) FindPublicKey(ctx context.Context, login string) (model.UserKey, error) {
+	key, err := s.remoteRepo.FindPublicKey(ctx, login)
+	if err != nil {
+		return model.UserKey{}, fmt.Errorf("remoteRepo.FindPublicKey: %w", err)
+	}
+	return key, nil
+}
+
+func (s *ClientService) SaveShare(ctx context.Context, share model.Share) error {
+	err := s.remoteRepo.SaveShare(ctx, share)
+	if err != nil {
+		return fmt.Errorf("remoteRepo.SaveShare: %w", err)
+	}
+	return nil
+}
+
+func (s *ClientService) FindShares(ctx context.Context) ([]model.Share, error) {
+	shares, err := s.remoteRepo.FindShares(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("remoteRepo.FindShares: %w", err)
+	}
+	return shares, nil
+}
+
+func (s *ClientService) UpdateSharePayload(ctx context.Context, id string,
+	payload model.SharePayload) error {
+	err := s.remoteRepo.UpdateSharePayload(ctx, id, payload)
+	if err != nil {
+		return fmt.Errorf("remoteRepo.UpdateSharePayload: %w", err)
+	}
+	return nil
+}
+
+func (s *ClientService) RevokeShare(ctx context.Context, id, login string) error {
+	err := s.remoteRepo.RevokeShare(ctx, id, login)
+	if err != nil {
+		return fmt.Errorf("remoteRepo.RevokeShare: %w", err)
+	}
+	return nil
+}
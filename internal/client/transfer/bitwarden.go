@@ -0,0 +1,257 @@
+package transfer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"github.com/google/uuid"
+	"io"
+	"sort"
+	"strings"
+)
+
+const FormatBitwarden = "bitwarden-json"
+
+func init() {
+	Register(FormatBitwarden, func(Options) (Parser, error) {
+		return bitwardenParser{}, nil
+	})
+	RegisterWriter(FormatBitwarden, bitwardenWriter{})
+}
+
+// Bitwarden item types.
+const (
+	bitwardenLogin      = 1
+	bitwardenSecureNote = 2
+	bitwardenCard       = 3
+	bitwardenIdentity   = 4
+	bitwardenSSHKey     = 5
+)
+
+type bitwardenExport struct {
+	Encrypted bool            `json:"encrypted"`
+	Folders   []any           `json:"folders"`
+	Items     []bitwardenItem `json:"items"`
+}
+
+type bitwardenItem struct {
+	ID         string             `json:"id,omitempty"`
+	Type       int                `json:"type"`
+	Name       string             `json:"name"`
+	Notes      *string            `json:"notes"`
+	Favorite   bool               `json:"favorite"`
+	Login      *bitwardenLoginVal `json:"login,omitempty"`
+	Card       *bitwardenCardVal  `json:"card,omitempty"`
+	SecureNote *bitwardenNoteVal  `json:"secureNote,omitempty"`
+	Identity   map[string]any     `json:"identity,omitempty"`
+	SSHKey     map[string]any     `json:"sshKey,omitempty"`
+	Fields     []bitwardenField   `json:"fields,omitempty"`
+}
+
+type bitwardenNoteVal struct {
+	Type int `json:"type"`
+}
+
+type bitwardenLoginVal struct {
+	Username *string        `json:"username"`
+	Password *string        `json:"password"`
+	TOTP     *string        `json:"totp"`
+	URIs     []bitwardenURI `json:"uris"`
+}
+
+type bitwardenURI struct {
+	URI *string `json:"uri"`
+}
+
+type bitwardenCardVal struct {
+	CardholderName *string `json:"cardholderName"`
+	Brand          *string `json:"brand"`
+	Number         *string `json:"number"`
+	ExpMonth       *string `json:"expMonth"`
+	ExpYear        *string `json:"expYear"`
+	Code           *string `json:"code"`
+}
+
+type bitwardenField struct {
+	Name  string  `json:"name"`
+	Value *string `json:"value"`
+}
+
+type bitwardenParser struct{}
+
+// Parse reads an unencrypted Bitwarden JSON export.
+func (bitwardenParser) Parse(data []byte) ([]Entry, error) {
+	var export bitwardenExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+	if export.Encrypted {
+		return nil, errors.New("encrypted Bitwarden exports are not supported, export as unencrypted JSON")
+	}
+	res := make([]Entry, 0, len(export.Items))
+	for i, it := range export.Items {
+		items, err := it.items()
+		res = append(res, Entry{
+			Ref:   fmt.Sprintf("item %d %q", i+1, it.Name),
+			Items: items,
+			Err:   err,
+		})
+	}
+	return res, nil
+}
+
+func (it bitwardenItem) items() ([]Item, error) {
+	c := newCompanion(it.Name)
+	for _, f := range it.Fields {
+		c.add(f.Name, str(f.Value))
+	}
+	c.setNotes(str(it.Notes))
+
+	switch it.Type {
+	case bitwardenLogin:
+		if it.Login == nil {
+			return nil, errors.New("login item has no login data")
+		}
+		for _, u := range it.Login.URIs {
+			c.add("URL", str(u.URI))
+		}
+		c.add("TOTP", str(it.Login.TOTP))
+		cred := Item{
+			Kind:     KindCredentials,
+			Title:    it.Name,
+			Login:    str(it.Login.Username),
+			Password: str(it.Login.Password),
+		}
+		return withCompanion([]Item{cred}, c), nil
+	case bitwardenCard:
+		if it.Card == nil {
+			return nil, errors.New("card item has no card data")
+		}
+		card := Item{
+			Kind:  KindCard,
+			Title: it.Name,
+			Card: model.CardInput{
+				Num:        str(it.Card.Number),
+				CVC:        str(it.Card.Code),
+				HolderName: str(it.Card.CardholderName),
+				ExpMonth:   padMonth(str(it.Card.ExpMonth)),
+				ExpYear:    model.NormalizeExpYear(str(it.Card.ExpYear)),
+			},
+		}
+		return withCompanion([]Item{card}, c), nil
+	case bitwardenSecureNote:
+		if it, ok := c.item(); ok {
+			return []Item{it}, nil
+		}
+		return nil, errors.New("secure note is empty")
+	case bitwardenIdentity, bitwardenSSHKey:
+		values := it.Identity
+		if it.Type == bitwardenSSHKey {
+			values = it.SSHKey
+		}
+		keys := make([]string, 0, len(values))
+		for k := range values {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if v, ok := values[k].(string); ok {
+				c.add(k, v)
+			}
+		}
+		if it, ok := c.item(); ok {
+			return []Item{it}, nil
+		}
+		return nil, errors.New("item is empty")
+	}
+	return nil, fmt.Errorf("unsupported item type %d", it.Type)
+}
+
+type bitwardenWriter struct{}
+
+// Supports reports false for files, Bitwarden exports attachments apart.
+func (bitwardenWriter) Supports(k Kind) bool {
+	return k != KindFile
+}
+
+func (bitwardenWriter) Write(w io.Writer, items []Item) error {
+	export := bitwardenExport{Folders: []any{}, Items: make([]bitwardenItem, 0, len(items))}
+	for _, it := range items {
+		bi := bitwardenItem{ID: uuid.New().String(), Name: it.Title}
+		switch it.Kind {
+		case KindCredentials:
+			bi.Type = bitwardenLogin
+			bi.Login = &bitwardenLoginVal{
+				Username: ptr(it.Login),
+				Password: ptr(it.Password),
+				URIs:     []bitwardenURI{},
+			}
+		case KindCard:
+			c := it.Card
+			bi.Type = bitwardenCard
+			bi.Card = &bitwardenCardVal{
+				CardholderName: ptr(c.HolderName),
+				Brand:          ptr(bitwardenBrand(model.DetectBrand(c.Num))),
+				Number:         ptr(c.Num),
+				ExpMonth:       ptr(strings.TrimLeft(c.ExpMonth, "0")),
+				ExpYear:        ptr(c.ExpYear),
+				Code:           ptr(c.CVC),
+			}
+			if c.PIN != "" {
+				bi.Fields = append(bi.Fields, bitwardenField{Name: "PIN", Value: ptr(c.PIN)})
+			}
+		case KindText:
+			bi.Type = bitwardenSecureNote
+			bi.SecureNote = &bitwardenNoteVal{}
+			bi.Notes = ptr(it.Text)
+		default:
+			continue
+		}
+		export.Items = append(export.Items, bi)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(export); err != nil {
+		return fmt.Errorf("enc.Encode: %w", err)
+	}
+	return nil
+}
+
+// bitwardenBrand names card brands the way Bitwarden does.
+func bitwardenBrand(b model.Brand) string {
+	switch b {
+	case model.BrandVisa:
+		return "Visa"
+	case model.BrandMastercard:
+		return "Mastercard"
+	case model.BrandAmex:
+		return "Amex"
+	case model.BrandDiscover:
+		return "Discover"
+	case model.BrandJCB:
+		return "JCB"
+	case model.BrandDiners:
+		return "Diners Club"
+	case model.BrandUnionPay:
+		return "UnionPay"
+	case model.BrandMaestro:
+		return "Maestro"
+	}
+	return "Other"
+}
+
+func ptr(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func str(p *string) string {
+	if p == nil {
+		return ""
+	}
+	return *p
+}
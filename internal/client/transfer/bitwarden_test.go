@@ -0,0 +1,110 @@
+package transfer
+
+import (
+	"bytes"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+const bitwardenSample = `{
+  "encrypted": false,
+  "folders": [],
+  "items": [
+    {"type": 1, "name": "Mail", "notes": "work account",
+     "fields": [{"name": "recovery", "value": "blue"}],
+     "login": {"username": "alice", "password": "s3cret", "totp": "otpauth://x",
+       "uris": [{"uri": "https://mail.example.com"}]}},
+    {"type": 1, "name": "Bare", "login": {"username": "bob", "password": "pw"}},
+    {"type": 3, "name": "Visa",
+     "card": {"cardholderName": "Alice", "brand": "Visa", "number": "4111111111111111",
+       "expMonth": "3", "expYear": "27", "code": "123"}},
+    {"type": 2, "name": "Wifi", "notes": "password is hunter2", "secureNote": {"type": 0}},
+    {"type": 2, "name": "Empty", "secureNote": {"type": 0}},
+    {"type": 4, "name": "Passport", "identity": {"firstName": "Alice", "passportNumber": "X1"}},
+    {"type": 1, "name": "Broken"},
+    {"type": 9, "name": "Future"}
+  ]
+}`
+
+func TestBitwardenParse(t *testing.T) {
+	entries := parse(t, bitwardenParser{}, []byte(bitwardenSample))
+	require.Len(t, entries, 8)
+
+	tests := []struct {
+		ref       string
+		wantItems []Item
+		wantErr   bool
+	}{
+		{ref: `item 1 "Mail"`, wantItems: []Item{
+			{Kind: KindCredentials, Title: "Mail", Login: "alice", Password: "s3cret"},
+			{Kind: KindText, Title: "Mail", Text: "Mail\n\nrecovery: blue\nURL: https://mail.example.com\n" +
+				"TOTP: otpauth://x\n\nwork account"},
+		}},
+		{ref: `item 2 "Bare"`, wantItems: []Item{
+			{Kind: KindCredentials, Title: "Bare", Login: "bob", Password: "pw"},
+		}},
+		{ref: `item 3 "Visa"`, wantItems: []Item{
+			{Kind: KindCard, Title: "Visa", Card: model.CardInput{Num: "4111111111111111", CVC: "123",
+				HolderName: "Alice", ExpMonth: "03", ExpYear: "2027"}},
+		}},
+		{ref: `item 4 "Wifi"`, wantItems: []Item{
+			{Kind: KindText, Title: "Wifi", Text: "Wifi\n\npassword is hunter2"},
+		}},
+		{ref: `item 5 "Empty"`, wantErr: true},
+		{ref: `item 6 "Passport"`, wantItems: []Item{
+			{Kind: KindText, Title: "Passport", Text: "Passport\n\nfirstName: Alice\npassportNumber: X1"},
+		}},
+		{ref: `item 7 "Broken"`, wantErr: true},
+		{ref: `item 8 "Future"`, wantErr: true},
+	}
+	for i, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			e := entries[i]
+			assert.Equal(t, tt.ref, e.Ref)
+			if tt.wantErr {
+				assert.Error(t, e.Err)
+				assert.Empty(t, e.Items)
+				return
+			}
+			assert.NoError(t, e.Err)
+			assert.Equal(t, tt.wantItems, e.Items)
+		})
+	}
+}
+
+func TestBitwardenParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "not json", data: "title,login"},
+		{name: "encrypted", data: `{"encrypted": true, "items": []}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := bitwardenParser{}.Parse([]byte(tt.data))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestBitwardenRoundTrip(t *testing.T) {
+	items := []Item{
+		{Kind: KindCredentials, Title: "Mail", Login: "alice", Password: "s3cret"},
+		{Kind: KindCard, Title: "Visa", Card: model.CardInput{Num: "4111111111111111", CVC: "123",
+			HolderName: "Alice", ExpMonth: "03", ExpYear: "2027"}},
+		{Kind: KindText, Title: "Wifi", Text: "Wifi\n\npassword is hunter2"},
+	}
+	var buf bytes.Buffer
+	require.NoError(t, bitwardenWriter{}.Write(&buf, items))
+
+	entries := parse(t, bitwardenParser{}, buf.Bytes())
+	var got []Item
+	for _, e := range entries {
+		require.NoError(t, e.Err)
+		got = append(got, e.Items...)
+	}
+	assert.Equal(t, items, got)
+}
@@ -0,0 +1,229 @@
+package transfer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"io"
+	"strings"
+)
+
+const (
+	FormatCSV            = "csv"
+	FormatOnePasswordCSV = "1password-csv"
+)
+
+// DefaultCSVMapping matches the column names most password managers use.
+const DefaultCSVMapping = "title=title|name,login=login|username|user,password=password," +
+	"url=url|uri|website,notes=notes|note,type=type," +
+	"card_number=card_number|number,card_cvc=card_cvc|cvc|cvv|code," +
+	"card_holder=card_holder|cardholder|cardholder_name,card_exp=card_exp|expiry|expiration," +
+	"card_exp_month=card_exp_month|exp_month,card_exp_year=card_exp_year|exp_year,card_pin=card_pin|pin"
+
+const onePasswordCSVMapping = "title=Title,login=Username,password=Password,notes=Notes|notesPlain," +
+	"url=Url|URL|Website,type=Type"
+
+// csvTargets are the fields a column can be mapped to.
+var csvTargets = map[string]bool{
+	"title": true, "login": true, "password": true, "url": true, "notes": true, "type": true,
+	"card_number": true, "card_cvc": true, "card_holder": true, "card_exp": true,
+	"card_exp_month": true, "card_exp_year": true, "card_pin": true,
+}
+
+func init() {
+	Register(FormatCSV, func(o Options) (Parser, error) {
+		mapping := o.Mapping
+		if mapping == "" {
+			mapping = DefaultCSVMapping
+		}
+		return NewCSVParser(mapping)
+	})
+	Register(FormatOnePasswordCSV, func(o Options) (Parser, error) {
+		mapping := o.Mapping
+		if mapping == "" {
+			mapping = onePasswordCSVMapping
+		}
+		return NewCSVParser(mapping)
+	})
+	RegisterWriter(FormatCSV, csvWriter{})
+}
+
+type csvParser struct {
+	mapping map[string][]string
+}
+
+// NewCSVParser returns a parser of CSV files with a header row. The mapping
+// is a comma separated list of field=Column, alternative column names are
+// separated by |, e.g. "login=Username|Email,password=Password". Column
+// names are matched case insensitively, unmapped columns go to the notes.
+func NewCSVParser(mapping string) (Parser, error) {
+	p := csvParser{mapping: make(map[string][]string)}
+	for _, pair := range strings.Split(mapping, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		field, columns, ok := strings.Cut(pair, "=")
+		field = strings.ToLower(strings.TrimSpace(field))
+		if !ok || columns == "" {
+			return nil, fmt.Errorf("bad mapping %q, expected field=Column", pair)
+		}
+		if !csvTargets[field] {
+			return nil, fmt.Errorf("unknown mapping field %q", field)
+		}
+		for _, c := range strings.Split(columns, "|") {
+			p.mapping[field] = append(p.mapping[field], strings.ToLower(strings.TrimSpace(c)))
+		}
+	}
+	return p, nil
+}
+
+func (p csvParser) Parse(data []byte) ([]Entry, error) {
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("csv.ReadAll: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, errors.New("file is empty")
+	}
+	header := records[0]
+	columns := make(map[string]int)
+	used := make(map[int]bool)
+	for field, names := range p.mapping {
+		for _, name := range names {
+			if i := indexFold(header, name); i >= 0 {
+				columns[field] = i
+				used[i] = true
+				break
+			}
+		}
+	}
+
+	res := make([]Entry, 0, len(records)-1)
+	for n, rec := range records[1:] {
+		get := func(field string) string {
+			i, ok := columns[field]
+			if !ok || i >= len(rec) {
+				return ""
+			}
+			return strings.TrimSpace(rec[i])
+		}
+		var extra [][2]string
+		for i, v := range rec {
+			if !used[i] && i < len(header) {
+				extra = append(extra, [2]string{header[i], v})
+			}
+		}
+		items, err := csvItems(get, extra)
+		res = append(res, Entry{
+			Ref:   fmt.Sprintf("line %d %q", n+2, get("title")),
+			Items: items,
+			Err:   err,
+		})
+	}
+	return res, nil
+}
+
+func csvItems(get func(string) string, extra [][2]string) ([]Item, error) {
+	title := get("title")
+	c := newCompanion(title)
+	c.add("URL", get("url"))
+	for _, e := range extra {
+		c.add(e[0], e[1])
+	}
+	c.setNotes(get("notes"))
+
+	kind := strings.ToLower(get("type"))
+	switch {
+	case kind == "card" || kind == "credit card" || kind == "" && get("card_number") != "":
+		card := Item{
+			Kind:  KindCard,
+			Title: title,
+			Card: model.CardInput{
+				Num:        get("card_number"),
+				CVC:        get("card_cvc"),
+				HolderName: get("card_holder"),
+				ExpMonth:   padMonth(get("card_exp_month")),
+				ExpYear:    model.NormalizeExpYear(get("card_exp_year")),
+				PIN:        get("card_pin"),
+			},
+		}
+		if exp := get("card_exp"); exp != "" {
+			m, y, err := splitExpiry(exp)
+			if err != nil {
+				return nil, err
+			}
+			card.Card.ExpMonth, card.Card.ExpYear = m, y
+		}
+		return withCompanion([]Item{card}, c), nil
+	case kind == "note" || kind == "secure note" || kind == "text" ||
+		kind == "" && get("login") == "" && get("password") == "":
+		if it, ok := c.item(); ok {
+			return []Item{it}, nil
+		}
+		return nil, errors.New("row is empty")
+	case kind == "" || kind == "login" || kind == "password" || kind == "cred":
+		cred := Item{
+			Kind:     KindCredentials,
+			Title:    title,
+			Login:    get("login"),
+			Password: get("password"),
+		}
+		return withCompanion([]Item{cred}, c), nil
+	}
+	return nil, fmt.Errorf("unsupported type %q", kind)
+}
+
+func indexFold(header []string, name string) int {
+	for i, h := range header {
+		if strings.EqualFold(strings.TrimSpace(h), name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// csvColumns are written by csvWriter, the default mapping reads them back.
+var csvColumns = []string{"type", "title", "login", "password", "notes",
+	"card_number", "card_cvc", "card_holder", "card_exp_month", "card_exp_year", "card_pin"}
+
+type csvWriter struct{}
+
+// Supports reports false for files, CSV has no place for attachments.
+func (csvWriter) Supports(k Kind) bool {
+	return k != KindFile
+}
+
+func (csvWriter) Write(w io.Writer, items []Item) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvColumns); err != nil {
+		return fmt.Errorf("cw.Write: %w", err)
+	}
+	for _, it := range items {
+		var row []string
+		switch it.Kind {
+		case KindCredentials:
+			row = []string{"login", it.Title, it.Login, it.Password, "", "", "", "", "", "", ""}
+		case KindCard:
+			c := it.Card
+			row = []string{"card", it.Title, "", "", "", c.Num, c.CVC, c.HolderName,
+				c.ExpMonth, c.ExpYear, c.PIN}
+		case KindText:
+			row = []string{"note", it.Title, "", "", it.Text, "", "", "", "", "", ""}
+		default:
+			continue
+		}
+		if err := cw.Write(row); err != nil {
+			return fmt.Errorf("cw.Write: %w", err)
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("cw.Flush: %w", err)
+	}
+	return nil
+}
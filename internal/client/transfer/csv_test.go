@@ -0,0 +1,149 @@
+package transfer
+
+import (
+	"bytes"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestNewCSVParser(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping string
+		wantErr bool
+	}{
+		{name: "default", mapping: DefaultCSVMapping},
+		{name: "alternatives and spaces", mapping: " login = Username|Email , password=Password,"},
+		{name: "no column", mapping: "login=", wantErr: true},
+		{name: "no equals sign", mapping: "login", wantErr: true},
+		{name: "unknown field", mapping: "email=Email", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewCSVParser(tt.mapping)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestCSVParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		format    string
+		mapping   string
+		data      string
+		wantItems [][]Item
+		wantErrs  []bool
+	}{
+		{
+			name:   "default mapping",
+			format: FormatCSV,
+			data: "\xef\xbb\xbfName,Username,Password,URL,Folder\n" +
+				"Mail,alice,s3cret,https://mail.example.com,work\n" +
+				"Bare,bob,pw,,\n",
+			wantItems: [][]Item{
+				{
+					{Kind: KindCredentials, Title: "Mail", Login: "alice", Password: "s3cret"},
+					{Kind: KindText, Title: "Mail", Text: "Mail\n\nURL: https://mail.example.com\nFolder: work"},
+				},
+				{{Kind: KindCredentials, Title: "Bare", Login: "bob", Password: "pw"}},
+			},
+			wantErrs: []bool{false, false},
+		},
+		{
+			name:   "cards and notes",
+			format: FormatCSV,
+			data: "type,title,number,cvv,cardholder,expiry,notes\n" +
+				"card,Visa,4111111111111111,123,Alice,03/27,\n" +
+				",Amex,378282246310005,1234,Bob,2026-11,\n" +
+				"note,Wifi,,,,,hunter2\n" +
+				"card,Bad,4111111111111111,,,December,\n" +
+				",,,,,,\n" +
+				"identity,Passport,,,,,\n",
+			wantItems: [][]Item{
+				{{Kind: KindCard, Title: "Visa", Card: model.CardInput{Num: "4111111111111111", CVC: "123",
+					HolderName: "Alice", ExpMonth: "03", ExpYear: "2027"}}},
+				{{Kind: KindCard, Title: "Amex", Card: model.CardInput{Num: "378282246310005", CVC: "1234",
+					HolderName: "Bob", ExpMonth: "11", ExpYear: "2026"}}},
+				{{Kind: KindText, Title: "Wifi", Text: "Wifi\n\nhunter2"}},
+				nil,
+				nil,
+				nil,
+			},
+			wantErrs: []bool{false, false, false, true, true, true},
+		},
+		{
+			name:    "custom mapping",
+			format:  FormatCSV,
+			mapping: "title=Site,login=Email|User,password=Secret",
+			data:    "Site,Email,Secret\nShop,alice@example.com,pw\n",
+			wantItems: [][]Item{
+				{{Kind: KindCredentials, Title: "Shop", Login: "alice@example.com", Password: "pw"}},
+			},
+			wantErrs: []bool{false},
+		},
+		{
+			name:   "1password csv",
+			format: FormatOnePasswordCSV,
+			data:   "Title,Username,Password,URL,Notes\nMail,alice,s3cret,,work account\n",
+			wantItems: [][]Item{
+				{
+					{Kind: KindCredentials, Title: "Mail", Login: "alice", Password: "s3cret"},
+					{Kind: KindText, Title: "Mail", Text: "Mail\n\nwork account"},
+				},
+			},
+			wantErrs: []bool{false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewParser(tt.format, Options{Mapping: tt.mapping})
+			require.NoError(t, err)
+			entries := parse(t, p, []byte(tt.data))
+			require.Len(t, entries, len(tt.wantItems))
+			for i, e := range entries {
+				if tt.wantErrs[i] {
+					assert.Error(t, e.Err, e.Ref)
+					continue
+				}
+				assert.NoError(t, e.Err, e.Ref)
+				assert.Equal(t, tt.wantItems[i], e.Items, e.Ref)
+			}
+		})
+	}
+}
+
+func TestCSVParseErrors(t *testing.T) {
+	p, err := NewCSVParser(DefaultCSVMapping)
+	require.NoError(t, err)
+	for _, data := range []string{"", "title,login\n\"unterminated,x\n"} {
+		_, err := p.Parse([]byte(data))
+		assert.Error(t, err, "%q", data)
+	}
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	items := []Item{
+		{Kind: KindCredentials, Title: "Mail", Login: "alice", Password: "s3cret"},
+		{Kind: KindCard, Title: "Visa", Card: model.CardInput{Num: "4111111111111111", CVC: "123",
+			HolderName: "Alice", ExpMonth: "03", ExpYear: "2027", PIN: "0000"}},
+		{Kind: KindText, Title: "Wifi", Text: "Wifi\n\npassword, \"quoted\"\nsecond line"},
+	}
+	var buf bytes.Buffer
+	require.NoError(t, csvWriter{}.Write(&buf, items))
+
+	p, err := NewCSVParser(DefaultCSVMapping)
+	require.NoError(t, err)
+	var got []Item
+	for _, e := range parse(t, p, buf.Bytes()) {
+		require.NoError(t, e.Err)
+		got = append(got, e.Items...)
+	}
+	assert.Equal(t, items, got)
+}
@@ -0,0 +1,276 @@
+package transfer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const FormatKeePass = "keepass-xml"
+
+func init() {
+	Register(FormatKeePass, func(Options) (Parser, error) {
+		return keepassParser{}, nil
+	})
+	RegisterWriter(FormatKeePass, keepassWriter{})
+}
+
+type keepassFile struct {
+	XMLName xml.Name `xml:"KeePassFile"`
+	Meta    struct {
+		Generator      string          `xml:"Generator,omitempty"`
+		RecycleBinUUID string          `xml:"RecycleBinUUID,omitempty"`
+		Binaries       []keepassBinary `xml:"Binaries>Binary,omitempty"`
+	} `xml:"Meta"`
+	Root struct {
+		Groups []keepassGroup `xml:"Group"`
+	} `xml:"Root"`
+}
+
+type keepassBinary struct {
+	ID         string `xml:"ID,attr"`
+	Compressed bool   `xml:"Compressed,attr,omitempty"`
+	Value      string `xml:",chardata"`
+}
+
+type keepassGroup struct {
+	UUID    string         `xml:"UUID"`
+	Name    string         `xml:"Name"`
+	Entries []keepassEntry `xml:"Entry"`
+	Groups  []keepassGroup `xml:"Group"`
+}
+
+type keepassEntry struct {
+	UUID     string             `xml:"UUID,omitempty"`
+	Strings  []keepassString    `xml:"String"`
+	Binaries []keepassBinaryRef `xml:"Binary"`
+}
+
+type keepassString struct {
+	Key   string `xml:"Key"`
+	Value struct {
+		Protected bool   `xml:"Protected,attr,omitempty"`
+		Value     string `xml:",chardata"`
+	} `xml:"Value"`
+}
+
+type keepassBinaryRef struct {
+	Key   string `xml:"Key"`
+	Value struct {
+		Ref   string `xml:"Ref,attr,omitempty"`
+		Value string `xml:",chardata"`
+	} `xml:"Value"`
+}
+
+type keepassParser struct{}
+
+// Parse reads a KeePass 2.x XML export. Protected values must be exported
+// unprotected, KeePass does so by default.
+func (keepassParser) Parse(data []byte) ([]Entry, error) {
+	var file keepassFile
+	if err := xml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("xml.Unmarshal: %w", err)
+	}
+	binaries := make(map[string][]byte, len(file.Meta.Binaries))
+	for _, b := range file.Meta.Binaries {
+		v, err := decodeKeepassBinary(b.Value, b.Compressed)
+		if err != nil {
+			return nil, fmt.Errorf("binary %s: %w", b.ID, err)
+		}
+		binaries[b.ID] = v
+	}
+	p := keepassWalker{recycleBin: file.Meta.RecycleBinUUID, binaries: binaries}
+	for _, g := range file.Root.Groups {
+		p.walk(g, "")
+	}
+	return p.entries, nil
+}
+
+type keepassWalker struct {
+	recycleBin string
+	binaries   map[string][]byte
+	entries    []Entry
+}
+
+func (w *keepassWalker) walk(g keepassGroup, path string) {
+	if w.recycleBin != "" && g.UUID == w.recycleBin {
+		return
+	}
+	if path != "" {
+		path += "/"
+	}
+	path += g.Name
+	for _, e := range g.Entries {
+		title := e.value("Title")
+		items, err := w.items(e, title)
+		w.entries = append(w.entries, Entry{
+			Ref:   fmt.Sprintf("%s/%s", path, title),
+			Items: items,
+			Err:   err,
+		})
+	}
+	for _, sub := range g.Groups {
+		w.walk(sub, path)
+	}
+}
+
+func (w *keepassWalker) items(e keepassEntry, title string) ([]Item, error) {
+	var items []Item
+	c := newCompanion(title)
+	for _, s := range e.Strings {
+		if s.Value.Protected {
+			return nil, fmt.Errorf("field %s is protected, export it unprotected", s.Key)
+		}
+		switch s.Key {
+		case "Title", "UserName", "Password":
+		case "Notes":
+			c.setNotes(s.Value.Value)
+		default:
+			c.add(s.Key, s.Value.Value)
+		}
+	}
+	login, password := e.value("UserName"), e.value("Password")
+	if login != "" || password != "" {
+		items = append(items, Item{
+			Kind:     KindCredentials,
+			Title:    title,
+			Login:    login,
+			Password: password,
+		})
+	}
+	for _, b := range e.Binaries {
+		var data []byte
+		if b.Value.Ref != "" {
+			v, ok := w.binaries[b.Value.Ref]
+			if !ok {
+				return nil, fmt.Errorf("attachment %s refers to missing binary %s", b.Key, b.Value.Ref)
+			}
+			data = v
+		} else {
+			v, err := decodeKeepassBinary(b.Value.Value, false)
+			if err != nil {
+				return nil, fmt.Errorf("attachment %s: %w", b.Key, err)
+			}
+			data = v
+		}
+		items = append(items, Item{
+			Kind:     KindFile,
+			Title:    title,
+			FileName: sanitizeFileName(b.Key),
+			Data:     data,
+		})
+	}
+	items = withCompanion(items, c)
+	if len(items) == 0 {
+		return nil, errors.New("entry is empty")
+	}
+	return items, nil
+}
+
+func (e keepassEntry) value(key string) string {
+	for _, s := range e.Strings {
+		if s.Key == key {
+			return s.Value.Value
+		}
+	}
+	return ""
+}
+
+func decodeKeepassBinary(value string, compressed bool) ([]byte, error) {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil {
+		return nil, fmt.Errorf("base64.DecodeString: %w", err)
+	}
+	if !compressed {
+		return b, nil
+	}
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("gzip.NewReader: %w", err)
+	}
+	defer r.Close()
+	res, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("io.ReadAll: %w", err)
+	}
+	return res, nil
+}
+
+type keepassWriter struct{}
+
+func (keepassWriter) Supports(Kind) bool {
+	return true
+}
+
+// Write puts all items into one group, card values and file attachments
+// are kept in entry fields and binaries.
+func (keepassWriter) Write(w io.Writer, items []Item) error {
+	var file keepassFile
+	file.Meta.Generator = "gophkeeper"
+	group := keepassGroup{UUID: keepassUUID(), Name: "gophkeeper"}
+	for _, it := range items {
+		e := keepassEntry{UUID: keepassUUID()}
+		e.add("Title", it.Title)
+		switch it.Kind {
+		case KindCredentials:
+			e.add("UserName", it.Login)
+			e.add("Password", it.Password)
+		case KindCard:
+			c := it.Card
+			e.add("Card number", c.Num)
+			e.add("CVC", c.CVC)
+			e.add("Card holder", c.HolderName)
+			e.add("Expiry", c.ExpMonth+"/"+c.ExpYear)
+			e.add("PIN", c.PIN)
+		case KindText:
+			e.add("Notes", it.Text)
+		case KindFile:
+			id := strconv.Itoa(len(file.Meta.Binaries))
+			file.Meta.Binaries = append(file.Meta.Binaries, keepassBinary{
+				ID:    id,
+				Value: base64.StdEncoding.EncodeToString(it.Data),
+			})
+			var ref keepassBinaryRef
+			ref.Key = it.FileName
+			ref.Value.Ref = id
+			e.Binaries = append(e.Binaries, ref)
+		}
+		group.Entries = append(group.Entries, e)
+	}
+	file.Root.Groups = []keepassGroup{group}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("io.WriteString: %w", err)
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "\t")
+	if err := enc.Encode(file); err != nil {
+		return fmt.Errorf("enc.Encode: %w", err)
+	}
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return fmt.Errorf("io.WriteString: %w", err)
+	}
+	return nil
+}
+
+func (e *keepassEntry) add(key, value string) {
+	if value == "" || value == "/" {
+		return
+	}
+	var s keepassString
+	s.Key = key
+	s.Value.Value = value
+	e.Strings = append(e.Strings, s)
+}
+
+func keepassUUID() string {
+	id := uuid.New()
+	return base64.StdEncoding.EncodeToString(id[:])
+}
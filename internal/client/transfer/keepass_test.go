@@ -0,0 +1,153 @@
+package transfer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func gzipBase64(t *testing.T, data string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write([]byte(data))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestKeepassParse(t *testing.T) {
+	data := `<?xml version="1.0" encoding="utf-8"?>
+<KeePassFile>
+	<Meta>
+		<RecycleBinUUID>bin</RecycleBinUUID>
+		<Binaries>
+			<Binary ID="0" Compressed="True">` + gzipBase64(t, "ssh key") + `</Binary>
+		</Binaries>
+	</Meta>
+	<Root>
+		<Group>
+			<UUID>root</UUID>
+			<Name>Root</Name>
+			<Entry>
+				<String><Key>Title</Key><Value>Mail</Value></String>
+				<String><Key>UserName</Key><Value>alice</Value></String>
+				<String><Key>Password</Key><Value>s3cret</Value></String>
+				<String><Key>URL</Key><Value>https://mail.example.com</Value></String>
+				<String><Key>Notes</Key><Value>work account</Value></String>
+			</Entry>
+			<Group>
+				<UUID>servers</UUID>
+				<Name>Servers</Name>
+				<Entry>
+					<String><Key>Title</Key><Value>Host</Value></String>
+					<Binary><Key>../id_ed25519</Key><Value Ref="0"/></Binary>
+					<Binary><Key>inline.txt</Key><Value>` + base64.StdEncoding.EncodeToString([]byte("inline")) + `</Value></Binary>
+				</Entry>
+				<Entry>
+					<String><Key>Title</Key><Value>Locked</Value></String>
+					<String><Key>Password</Key><Value Protected="True">c2VjcmV0</Value></String>
+				</Entry>
+				<Entry>
+					<String><Key>Title</Key><Value>Dangling</Value></String>
+					<Binary><Key>a.txt</Key><Value Ref="7"/></Binary>
+				</Entry>
+				<Entry>
+					<String><Key>Title</Key><Value>Empty</Value></String>
+				</Entry>
+			</Group>
+			<Group>
+				<UUID>bin</UUID>
+				<Name>Recycle Bin</Name>
+				<Entry>
+					<String><Key>Title</Key><Value>Deleted</Value></String>
+					<String><Key>Password</Key><Value>old</Value></String>
+				</Entry>
+			</Group>
+		</Group>
+	</Root>
+</KeePassFile>`
+	entries := parse(t, keepassParser{}, []byte(data))
+
+	tests := []struct {
+		ref       string
+		wantItems []Item
+		wantErr   bool
+	}{
+		{ref: "Root/Mail", wantItems: []Item{
+			{Kind: KindCredentials, Title: "Mail", Login: "alice", Password: "s3cret"},
+			{Kind: KindText, Title: "Mail", Text: "Mail\n\nURL: https://mail.example.com\n\nwork account"},
+		}},
+		{ref: "Root/Servers/Host", wantItems: []Item{
+			{Kind: KindFile, Title: "Host", FileName: "id_ed25519", Data: []byte("ssh key")},
+			{Kind: KindFile, Title: "Host", FileName: "inline.txt", Data: []byte("inline")},
+		}},
+		{ref: "Root/Servers/Locked", wantErr: true},
+		{ref: "Root/Servers/Dangling", wantErr: true},
+		{ref: "Root/Servers/Empty", wantErr: true},
+	}
+	require.Len(t, entries, len(tests), "the recycle bin is skipped")
+	for i, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			e := entries[i]
+			assert.Equal(t, tt.ref, e.Ref)
+			if tt.wantErr {
+				assert.Error(t, e.Err)
+				return
+			}
+			assert.NoError(t, e.Err)
+			assert.Equal(t, tt.wantItems, e.Items)
+		})
+	}
+}
+
+func TestKeepassParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "not xml", data: "{}"},
+		{name: "bad binary", data: `<KeePassFile><Meta><Binaries><Binary ID="0">!!</Binary></Binaries></Meta></KeePassFile>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := keepassParser{}.Parse([]byte(tt.data))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestKeepassRoundTrip(t *testing.T) {
+	items := []Item{
+		{Kind: KindCredentials, Title: "Mail", Login: "alice", Password: "s3cret"},
+		{Kind: KindText, Title: "Wifi", Text: "Wifi\n\npassword is hunter2"},
+		{Kind: KindFile, Title: "Key", FileName: "id_ed25519", Data: []byte{0, 1, 2, 0xff}},
+	}
+	var buf bytes.Buffer
+	require.NoError(t, keepassWriter{}.Write(&buf, items))
+
+	var got []Item
+	for _, e := range parse(t, keepassParser{}, buf.Bytes()) {
+		require.NoError(t, e.Err)
+		got = append(got, e.Items...)
+	}
+	assert.Equal(t, items, got)
+}
+
+func TestKeepassWriteCard(t *testing.T) {
+	card := Item{Kind: KindCard, Title: "Visa", Card: model.CardInput{Num: "4111111111111111",
+		CVC: "123", HolderName: "Alice", ExpMonth: "03", ExpYear: "2027"}}
+	var buf bytes.Buffer
+	require.NoError(t, keepassWriter{}.Write(&buf, []Item{card}))
+
+	entries := parse(t, keepassParser{}, buf.Bytes())
+	require.Len(t, entries, 1)
+	require.NoError(t, entries[0].Err)
+	assert.Equal(t, []Item{{Kind: KindText, Title: "Visa",
+		Text: "Visa\n\nCard number: 4111111111111111\nCVC: 123\nCard holder: Alice\nExpiry: 03/2027"}},
+		entries[0].Items, "cards come back as text, KeePass has no card entries")
+}
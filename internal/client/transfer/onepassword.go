@@ -0,0 +1,249 @@
+package transfer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"io"
+)
+
+const FormatOnePassword = "1pux"
+
+func init() {
+	Register(FormatOnePassword, func(Options) (Parser, error) {
+		return onePasswordParser{}, nil
+	})
+}
+
+// 1Password item categories.
+const (
+	onePasswordLogin    = "001"
+	onePasswordCard     = "002"
+	onePasswordNote     = "003"
+	onePasswordPassword = "005"
+	onePasswordDocument = "006"
+)
+
+const (
+	onePasswordArchived   = "archived"
+	onePasswordExportData = "export.data"
+)
+
+type onePasswordExport struct {
+	Accounts []struct {
+		Vaults []struct {
+			Attrs struct {
+				Name string `json:"name"`
+			} `json:"attrs"`
+			Items []onePasswordItem `json:"items"`
+		} `json:"vaults"`
+	} `json:"accounts"`
+}
+
+type onePasswordItem struct {
+	State        string `json:"state"`
+	CategoryUUID string `json:"categoryUuid"`
+	Overview     struct {
+		Title string `json:"title"`
+		URL   string `json:"url"`
+		URLs  []struct {
+			URL string `json:"url"`
+		} `json:"urls"`
+	} `json:"overview"`
+	Details struct {
+		LoginFields []struct {
+			Value       string `json:"value"`
+			Name        string `json:"name"`
+			Designation string `json:"designation"`
+		} `json:"loginFields"`
+		NotesPlain string `json:"notesPlain"`
+		Password   string `json:"password"`
+		Sections   []struct {
+			Title  string             `json:"title"`
+			Fields []onePasswordField `json:"fields"`
+		} `json:"sections"`
+		DocumentAttributes *struct {
+			FileName   string `json:"fileName"`
+			DocumentID string `json:"documentId"`
+		} `json:"documentAttributes"`
+	} `json:"details"`
+}
+
+type onePasswordField struct {
+	Title string                     `json:"title"`
+	ID    string                     `json:"id"`
+	Value map[string]json.RawMessage `json:"value"`
+}
+
+// text returns the field value whatever its type is, month-year values
+// like 202512 are returned as digits.
+func (f onePasswordField) text() string {
+	for _, raw := range f.Value {
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			return s
+		}
+		var n json.Number
+		if err := json.Unmarshal(raw, &n); err == nil {
+			return n.String()
+		}
+	}
+	return ""
+}
+
+type onePasswordParser struct{}
+
+// Parse reads a 1PUX archive, the export.data file and the attached
+// documents in it.
+func (onePasswordParser) Parse(data []byte) ([]Entry, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("zip.NewReader: %w", err)
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	f, ok := files[onePasswordExportData]
+	if !ok {
+		return nil, errors.New("export.data not found in archive")
+	}
+	b, err := readZipFile(f)
+	if err != nil {
+		return nil, err
+	}
+	var export onePasswordExport
+	if err := json.Unmarshal(b, &export); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+
+	var res []Entry
+	for _, acc := range export.Accounts {
+		for _, v := range acc.Vaults {
+			for _, it := range v.Items {
+				if it.State == onePasswordArchived {
+					continue
+				}
+				items, err := it.items(files)
+				res = append(res, Entry{
+					Ref:   fmt.Sprintf("%s/%s", v.Attrs.Name, it.Overview.Title),
+					Items: items,
+					Err:   err,
+				})
+			}
+		}
+	}
+	return res, nil
+}
+
+func (it onePasswordItem) items(files map[string]*zip.File) ([]Item, error) {
+	title := it.Overview.Title
+	c := newCompanion(title)
+	c.add("URL", it.Overview.URL)
+	for _, u := range it.Overview.URLs {
+		if u.URL != it.Overview.URL {
+			c.add("URL", u.URL)
+		}
+	}
+	c.setNotes(it.Details.NotesPlain)
+
+	card := model.CardInput{}
+	for _, s := range it.Details.Sections {
+		for _, f := range s.Fields {
+			v := f.text()
+			if it.CategoryUUID == onePasswordCard {
+				switch f.ID {
+				case "cardholder":
+					card.HolderName = v
+					continue
+				case "ccnum":
+					card.Num = v
+					continue
+				case "cvv":
+					card.CVC = v
+					continue
+				case "pin":
+					card.PIN = v
+					continue
+				case "expiry":
+					if v != "" {
+						m, y, err := splitExpiry(v)
+						if err != nil {
+							return nil, err
+						}
+						card.ExpMonth, card.ExpYear = m, y
+					}
+					continue
+				case "type":
+					continue
+				}
+			}
+			name := f.Title
+			if name == "" {
+				name = f.ID
+			}
+			c.add(name, v)
+		}
+	}
+
+	switch it.CategoryUUID {
+	case onePasswordLogin:
+		cred := Item{Kind: KindCredentials, Title: title}
+		for _, f := range it.Details.LoginFields {
+			switch f.Designation {
+			case "username":
+				cred.Login = f.Value
+			case "password":
+				cred.Password = f.Value
+			default:
+				c.add(f.Name, f.Value)
+			}
+		}
+		return withCompanion([]Item{cred}, c), nil
+	case onePasswordPassword:
+		cred := Item{Kind: KindCredentials, Title: title, Password: it.Details.Password}
+		return withCompanion([]Item{cred}, c), nil
+	case onePasswordCard:
+		return withCompanion([]Item{{Kind: KindCard, Title: title, Card: card}}, c), nil
+	case onePasswordDocument:
+		doc := it.Details.DocumentAttributes
+		if doc == nil {
+			return nil, errors.New("document has no attributes")
+		}
+		f, ok := files["files/"+doc.DocumentID+"__"+doc.FileName]
+		if !ok {
+			return nil, fmt.Errorf("document %s not found in archive", doc.FileName)
+		}
+		data, err := readZipFile(f)
+		if err != nil {
+			return nil, err
+		}
+		file := Item{Kind: KindFile, Title: title, FileName: sanitizeFileName(doc.FileName), Data: data}
+		return withCompanion([]Item{file}, c), nil
+	}
+	// Secure notes and the categories without a vault type of their own,
+	// e.g. identities or bank accounts, are kept as text.
+	if it, ok := c.item(); ok {
+		return []Item{it}, nil
+	}
+	if it.CategoryUUID == onePasswordNote {
+		return nil, errors.New("secure note is empty")
+	}
+	return nil, errors.New("item is empty")
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	r, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("f.Open: %w", err)
+	}
+	defer r.Close()
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("io.ReadAll: %w", err)
+	}
+	return b, nil
+}
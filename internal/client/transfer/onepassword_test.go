@@ -0,0 +1,110 @@
+package transfer
+
+import (
+	"archive/zip"
+	"bytes"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+const onePasswordSample = `{"accounts": [{"vaults": [{"attrs": {"name": "Private"}, "items": [
+  {"categoryUuid": "001", "overview": {"title": "Mail", "url": "https://mail.example.com",
+     "urls": [{"url": "https://mail.example.com"}, {"url": "https://webmail.example.com"}]},
+   "details": {"notesPlain": "work account", "loginFields": [
+     {"designation": "username", "name": "username", "value": "alice"},
+     {"designation": "password", "name": "password", "value": "s3cret"},
+     {"name": "pin", "value": "1234"}]}},
+  {"categoryUuid": "005", "overview": {"title": "Router"}, "details": {"password": "admin"}},
+  {"categoryUuid": "002", "overview": {"title": "Visa"}, "details": {"sections": [{"fields": [
+     {"id": "cardholder", "title": "cardholder name", "value": {"string": "Alice"}},
+     {"id": "type", "value": {"cctype": "visa"}},
+     {"id": "ccnum", "value": {"creditCardNumber": "4111111111111111"}},
+     {"id": "cvv", "value": {"concealed": "123"}},
+     {"id": "expiry", "value": {"monthYear": 202703}},
+     {"id": "bank", "title": "issuing bank", "value": {"string": "Bank"}}]}]}},
+  {"categoryUuid": "006", "overview": {"title": "Scan"},
+   "details": {"documentAttributes": {"fileName": "passport.pdf", "documentId": "d1"}}},
+  {"categoryUuid": "006", "overview": {"title": "Lost"},
+   "details": {"documentAttributes": {"fileName": "gone.pdf", "documentId": "d2"}}},
+  {"categoryUuid": "003", "overview": {"title": "Empty"}, "details": {}},
+  {"categoryUuid": "001", "state": "archived", "overview": {"title": "Old"}, "details": {}}
+]}]}]}`
+
+func onePasswordArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range files {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(data))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestOnePasswordParse(t *testing.T) {
+	data := onePasswordArchive(t, map[string]string{
+		onePasswordExportData:    onePasswordSample,
+		"files/d1__passport.pdf": "%PDF",
+	})
+	entries := parse(t, onePasswordParser{}, data)
+
+	tests := []struct {
+		ref       string
+		wantItems []Item
+		wantErr   bool
+	}{
+		{ref: "Private/Mail", wantItems: []Item{
+			{Kind: KindCredentials, Title: "Mail", Login: "alice", Password: "s3cret"},
+			{Kind: KindText, Title: "Mail", Text: "Mail\n\nURL: https://mail.example.com\n" +
+				"URL: https://webmail.example.com\npin: 1234\n\nwork account"},
+		}},
+		{ref: "Private/Router", wantItems: []Item{
+			{Kind: KindCredentials, Title: "Router", Password: "admin"},
+		}},
+		{ref: "Private/Visa", wantItems: []Item{
+			{Kind: KindCard, Title: "Visa", Card: model.CardInput{Num: "4111111111111111", CVC: "123",
+				HolderName: "Alice", ExpMonth: "03", ExpYear: "2027"}},
+			{Kind: KindText, Title: "Visa", Text: "Visa\n\nissuing bank: Bank"},
+		}},
+		{ref: "Private/Scan", wantItems: []Item{
+			{Kind: KindFile, Title: "Scan", FileName: "passport.pdf", Data: []byte("%PDF")},
+		}},
+		{ref: "Private/Lost", wantErr: true},
+		{ref: "Private/Empty", wantErr: true},
+	}
+	require.Len(t, entries, len(tests), "archived items are skipped")
+	for i, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			e := entries[i]
+			assert.Equal(t, tt.ref, e.Ref)
+			if tt.wantErr {
+				assert.Error(t, e.Err)
+				return
+			}
+			assert.NoError(t, e.Err)
+			assert.Equal(t, tt.wantItems, e.Items)
+		})
+	}
+}
+
+func TestOnePasswordParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "not zip", data: []byte(onePasswordSample)},
+		{name: "no export data", data: onePasswordArchive(t, map[string]string{"files/x": "x"})},
+		{name: "bad export data", data: onePasswordArchive(t, map[string]string{onePasswordExportData: "{"})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := onePasswordParser{}.Parse(tt.data)
+			assert.Error(t, err)
+		})
+	}
+}
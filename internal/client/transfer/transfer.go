@@ -0,0 +1,225 @@
+// Package transfer converts vault items from and to the export formats of
+// other password managers.
+//
+// Credentials only hold a login and a password, so titles, URLs, notes and
+// custom fields of a login or a card are kept in a companion text item when
+// there is anything besides the title to keep.
+package transfer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"io"
+	"sort"
+	"strings"
+)
+
+type Kind string
+
+const (
+	KindCredentials Kind = "cred"
+	KindCard        Kind = "card"
+	KindText        Kind = "text"
+	KindFile        Kind = "file"
+)
+
+// Item is a plain vault value, parsed from an export or opened from the
+// vault.
+type Item struct {
+	Kind     Kind
+	Title    string
+	Login    string
+	Password string
+	Card     model.CardInput
+	Text     string
+	FileName string
+	Data     []byte
+}
+
+// Key identifies an item for duplicate detection.
+func (i Item) Key() string {
+	switch i.Kind {
+	case KindCredentials:
+		return "cred:" + strings.ToLower(strings.TrimSpace(i.Login)) + "\x00" + i.Password
+	case KindCard:
+		return "card:" + model.NormalizeCardNum(i.Card.Num) + "\x00" +
+			strings.TrimLeft(i.Card.ExpMonth, "0") + "/" + model.NormalizeExpYear(i.Card.ExpYear)
+	case KindText:
+		return "text:" + digest([]byte(strings.TrimSpace(i.Text)))
+	case KindFile:
+		return "file:" + i.FileName + "\x00" + digest(i.Data)
+	}
+	return ""
+}
+
+func digest(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// Entry is one record of an export file. A record may carry several items,
+// e.g. a login with notes and attachments. Err is set when the record can't
+// be converted, the other records are still usable.
+type Entry struct {
+	Ref   string
+	Items []Item
+	Err   error
+}
+
+// Parser reads an export file.
+type Parser interface {
+	Parse(data []byte) ([]Entry, error)
+}
+
+// Options configure a parser.
+type Options struct {
+	// Mapping binds item fields to CSV columns, see NewCSVParser.
+	Mapping string
+}
+
+type Factory func(o Options) (Parser, error)
+
+var parsers = map[string]Factory{}
+
+// Register makes a parser available under format.
+func Register(format string, f Factory) {
+	parsers[format] = f
+}
+
+func NewParser(format string, o Options) (Parser, error) {
+	f, ok := parsers[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown import format %q, supported: %s",
+			format, strings.Join(Formats(), ", "))
+	}
+	return f(o)
+}
+
+// Formats lists registered import formats.
+func Formats() []string {
+	res := make([]string, 0, len(parsers))
+	for f := range parsers {
+		res = append(res, f)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Writer writes items into an export file. Items of kinds the format
+// can't hold are left out by the caller.
+type Writer interface {
+	Supports(k Kind) bool
+	Write(w io.Writer, items []Item) error
+}
+
+var writers = map[string]Writer{}
+
+// RegisterWriter makes a writer available under format.
+func RegisterWriter(format string, w Writer) {
+	writers[format] = w
+}
+
+func NewWriter(format string) (Writer, error) {
+	w, ok := writers[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown export format %q, supported: %s",
+			format, strings.Join(WriterFormats(), ", "))
+	}
+	return w, nil
+}
+
+// WriterFormats lists registered export formats.
+func WriterFormats() []string {
+	res := make([]string, 0, len(writers))
+	for f := range writers {
+		res = append(res, f)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// companion is the text item keeping what credentials and cards can't hold.
+// Nothing is returned when there is nothing but the title.
+type companion struct {
+	title string
+	lines []string
+	notes string
+}
+
+func newCompanion(title string) *companion {
+	return &companion{title: title}
+}
+
+func (c *companion) add(name, value string) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return
+	}
+	c.lines = append(c.lines, name+": "+value)
+}
+
+func (c *companion) setNotes(notes string) {
+	c.notes = strings.TrimSpace(notes)
+}
+
+func (c *companion) item() (Item, bool) {
+	if len(c.lines) == 0 && c.notes == "" {
+		return Item{}, false
+	}
+	var parts []string
+	// a note exported by this package already starts with its title
+	if c.title != "" && !strings.HasPrefix(c.notes, c.title) {
+		parts = append(parts, c.title)
+	}
+	if len(c.lines) > 0 {
+		parts = append(parts, strings.Join(c.lines, "\n"))
+	}
+	if c.notes != "" {
+		parts = append(parts, c.notes)
+	}
+	return Item{Kind: KindText, Title: c.title, Text: strings.Join(parts, "\n\n")}, true
+}
+
+// withCompanion appends the companion text to items when there is one.
+func withCompanion(items []Item, c *companion) []Item {
+	if it, ok := c.item(); ok {
+		items = append(items, it)
+	}
+	return items
+}
+
+// splitExpiry accepts MM/YY, MM/YYYY, YYYY-MM and YYYYMM.
+func splitExpiry(s string) (string, string, error) {
+	s = strings.TrimSpace(s)
+	if m, y, ok := strings.Cut(s, "/"); ok {
+		return padMonth(m), model.NormalizeExpYear(y), nil
+	}
+	if y, m, ok := strings.Cut(s, "-"); ok {
+		return padMonth(m), y, nil
+	}
+	if len(s) == 6 {
+		return padMonth(s[4:]), s[:4], nil
+	}
+	return "", "", fmt.Errorf("can't parse expiry %q", s)
+}
+
+func padMonth(m string) string {
+	m = strings.TrimSpace(m)
+	if len(m) == 1 {
+		return "0" + m
+	}
+	return m
+}
+
+func sanitizeFileName(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	if name == "" || name == "." || name == ".." {
+		return "attachment"
+	}
+	return name
+}
@@ -0,0 +1,116 @@
+package transfer
+
+import (
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSplitExpiry(t *testing.T) {
+	tests := []struct {
+		in        string
+		wantMonth string
+		wantYear  string
+		wantErr   bool
+	}{
+		{in: "12/25", wantMonth: "12", wantYear: "2025"},
+		{in: "3/2027", wantMonth: "03", wantYear: "2027"},
+		{in: " 2026-7 ", wantMonth: "07", wantYear: "2026"},
+		{in: "202512", wantMonth: "12", wantYear: "2025"},
+		{in: "1225", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			m, y, err := splitExpiry(tt.in)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantMonth, m)
+			assert.Equal(t, tt.wantYear, y)
+		})
+	}
+}
+
+func TestItemKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     Item
+		wantSame bool
+	}{
+		{name: "login case and spaces",
+			a:        Item{Kind: KindCredentials, Title: "a", Login: " Alice ", Password: "pw"},
+			b:        Item{Kind: KindCredentials, Title: "b", Login: "alice", Password: "pw"},
+			wantSame: true},
+		{name: "password differs",
+			a: Item{Kind: KindCredentials, Login: "alice", Password: "pw"},
+			b: Item{Kind: KindCredentials, Login: "alice", Password: "PW"}},
+		{name: "card number format and expiry",
+			a: Item{Kind: KindCard, Card: model.CardInput{Num: "4111 1111 1111 1111",
+				ExpMonth: "03", ExpYear: "27"}},
+			b: Item{Kind: KindCard, Card: model.CardInput{Num: "4111-1111-1111-1111",
+				ExpMonth: "3", ExpYear: "2027"}},
+			wantSame: true},
+		{name: "card expiry differs",
+			a: Item{Kind: KindCard, Card: model.CardInput{Num: "4111111111111111", ExpMonth: "03", ExpYear: "2027"}},
+			b: Item{Kind: KindCard, Card: model.CardInput{Num: "4111111111111111", ExpMonth: "04", ExpYear: "2027"}}},
+		{name: "text spaces",
+			a:        Item{Kind: KindText, Text: "note\n"},
+			b:        Item{Kind: KindText, Text: " note"},
+			wantSame: true},
+		{name: "file name differs",
+			a: Item{Kind: KindFile, FileName: "a.txt", Data: []byte("x")},
+			b: Item{Kind: KindFile, FileName: "b.txt", Data: []byte("x")}},
+		{name: "kind differs",
+			a: Item{Kind: KindText, Text: "x"},
+			b: Item{Kind: KindFile, Data: []byte("x")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantSame {
+				assert.Equal(t, tt.a.Key(), tt.b.Key())
+			} else {
+				assert.NotEqual(t, tt.a.Key(), tt.b.Key())
+			}
+		})
+	}
+}
+
+func TestSanitizeFileName(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "report.pdf", want: "report.pdf"},
+		{in: "../../etc/passwd", want: "passwd"},
+		{in: `C:\Users\alice\key.pem`, want: "key.pem"},
+		{in: "dir/", want: "attachment"},
+		{in: "..", want: "attachment"},
+		{in: "", want: "attachment"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			assert.Equal(t, tt.want, sanitizeFileName(tt.in))
+		})
+	}
+}
+
+func TestNewParser(t *testing.T) {
+	for _, f := range []string{FormatBitwarden, FormatCSV, FormatOnePasswordCSV, FormatKeePass, FormatOnePassword} {
+		_, err := NewParser(f, Options{})
+		assert.NoError(t, err, f)
+	}
+	_, err := NewParser("lastpass", Options{})
+	assert.Error(t, err)
+}
+
+// parse runs p and fails the test on a file level error.
+func parse(t *testing.T, p Parser, data []byte) []Entry {
+	t.Helper()
+	entries, err := p.Parse(data)
+	require.NoError(t, err)
+	return entries
+}
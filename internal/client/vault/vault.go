@@ -0,0 +1,269 @@
+package vault
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/crypto"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"strings"
+)
+
+// Vault values are encrypted on the client with the user key, the server
+// and the local store only see the sealed models.
+
+func SealCredentials(dealer *crypto.Dealer, login, password string) (model.Credentials, error) {
+	crLogin, err := dealer.Encrypt(login)
+	if err != nil {
+		return model.Credentials{}, fmt.Errorf("dealer.Encrypt(login): %w", err)
+	}
+	crPwd, err := dealer.Encrypt(password)
+	if err != nil {
+		return model.Credentials{}, fmt.Errorf("dealer.Encrypt(password): %w", err)
+	}
+	return model.Credentials{Login: crLogin, Password: crPwd}, nil
+}
+
+func OpenCredentials(dealer *crypto.Dealer, cred model.Credentials) (string, string, error) {
+	login, err := dealer.Decrypt(cred.Login)
+	if err != nil {
+		return "", "", fmt.Errorf("dealer.Decrypt(cred.Login): %w", err)
+	}
+	password, err := dealer.Decrypt(cred.Password)
+	if err != nil {
+		return "", "", fmt.Errorf("dealer.Decrypt(cred.Password): %w", err)
+	}
+	return login, password, nil
+}
+
+// SealCard encrypts plain card values. The card number is stored without
+// separators and the brand is detected from it before encryption.
+func SealCard(dealer *crypto.Dealer, in model.CardInput) (model.Card, error) {
+	num := model.NormalizeCardNum(in.Num)
+	addr := in.BillingAddress
+	plain := []string{num, in.CVC, in.HolderName, in.ExpMonth, model.NormalizeExpYear(in.ExpYear),
+		in.PIN, string(model.DetectBrand(num)), addr.Line1, addr.Line2, addr.City, addr.Region,
+		addr.PostalCode, addr.Country}
+	enc := make([]string, len(plain))
+	for i, p := range plain {
+		if p == "" {
+			continue
+		}
+		e, err := dealer.Encrypt(p)
+		if err != nil {
+			return model.Card{}, fmt.Errorf("dealer.Encrypt: %w", err)
+		}
+		enc[i] = e
+	}
+	return model.Card{
+		Num:        enc[0],
+		CVC:        enc[1],
+		HolderName: enc[2],
+		ExpMonth:   enc[3],
+		ExpYear:    enc[4],
+		PIN:        enc[5],
+		Brand:      enc[6],
+		BillingAddress: model.BillingAddress{
+			Line1:      enc[7],
+			Line2:      enc[8],
+			City:       enc[9],
+			Region:     enc[10],
+			PostalCode: enc[11],
+			Country:    enc[12],
+		},
+	}, nil
+}
+
+// OpenCard decrypts card values, empty values stay empty.
+func OpenCard(dealer *crypto.Dealer, card model.Card) (model.CardInput, model.Brand, error) {
+	addr := card.BillingAddress
+	enc := []string{card.Num, card.CVC, card.HolderName, card.ExpMonth, card.ExpYear, card.PIN,
+		card.Brand, addr.Line1, addr.Line2, addr.City, addr.Region, addr.PostalCode, addr.Country}
+	plain := make([]string, len(enc))
+	for i, e := range enc {
+		if e == "" {
+			continue
+		}
+		p, err := dealer.Decrypt(e)
+		if err != nil {
+			return model.CardInput{}, "", fmt.Errorf("dealer.Decrypt: %w", err)
+		}
+		plain[i] = p
+	}
+	brand := model.Brand(plain[6])
+	if brand == "" {
+		brand = model.DetectBrand(plain[0])
+	}
+	return model.CardInput{
+		Num:        plain[0],
+		CVC:        plain[1],
+		HolderName: plain[2],
+		ExpMonth:   plain[3],
+		ExpYear:    plain[4],
+		PIN:        plain[5],
+		BillingAddress: model.BillingAddress{
+			Line1:      plain[7],
+			Line2:      plain[8],
+			City:       plain[9],
+			Region:     plain[10],
+			PostalCode: plain[11],
+			Country:    plain[12],
+		},
+	}, brand, nil
+}
+
+// SealText encrypts a text note.
+func SealText(dealer *crypto.Dealer, txt string) (string, error) {
+	enc, err := dealer.Encrypt(txt)
+	if err != nil {
+		return "", fmt.Errorf("dealer.Encrypt: %w", err)
+	}
+	return enc, nil
+}
+
+// OpenText decrypts a text note. Notes kept as plain text by older clients
+// are sealed once by MigrateText, they are not read as they are.
+func OpenText(dealer *crypto.Dealer, txt string) (string, error) {
+	dec, err := dealer.Decrypt(txt)
+	if err != nil {
+		return "", fmt.Errorf("dealer.Decrypt: %w", err)
+	}
+	return dec, nil
+}
+
+// SealBinary encrypts file content into the base64 form the model keeps.
+func SealBinary(dealer *crypto.Dealer, data []byte) (string, error) {
+	sealed, err := dealer.EncryptBytes(data)
+	if err != nil {
+		return "", fmt.Errorf("dealer.EncryptBytes: %w", err)
+	}
+	return crypto.SealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// OpenBinary decrypts file content. Content without the sealed prefix was
+// encrypted by an older client with the static nonce.
+func OpenBinary(dealer *crypto.Dealer, data string) ([]byte, error) {
+	rest, sealed := strings.CutPrefix(data, crypto.SealedPrefix)
+	raw, err := base64.StdEncoding.DecodeString(rest)
+	if err != nil {
+		return nil, fmt.Errorf("base64.StdEncoding.DecodeString: %w", err)
+	}
+	var dec []byte
+	if sealed {
+		dec, err = dealer.DecryptBytes(raw)
+	} else {
+		dec, err = dealer.DecryptLegacy(raw)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decrypt: %w", err)
+	}
+	return dec, nil
+}
+
+// MigrateText seals a note of an older client with a random nonce, it
+// reports false for a note that is sealed already. A note that does not
+// decrypt is taken for plain text, so it must run once per vault.
+func MigrateText(dealer *crypto.Dealer, txt string) (string, bool, error) {
+	if crypto.IsSealed(txt) {
+		return txt, false, nil
+	}
+	plain, err := dealer.Decrypt(txt)
+	if err != nil {
+		plain = txt
+	}
+	enc, err := SealText(dealer, plain)
+	if err != nil {
+		return "", false, err
+	}
+	return enc, true, nil
+}
+
+// MigrateBinary is MigrateText for file content.
+func MigrateBinary(dealer *crypto.Dealer, data string) (string, bool, error) {
+	if crypto.IsSealed(data) {
+		return data, false, nil
+	}
+	plain, err := OpenBinary(dealer, data)
+	if err != nil {
+		plain, err = base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return "", false, fmt.Errorf("base64.StdEncoding.DecodeString: %w", err)
+		}
+	}
+	enc, err := SealBinary(dealer, plain)
+	if err != nil {
+		return "", false, err
+	}
+	return enc, true, nil
+}
+
+// CredentialsSealed reports whether both values of cred were sealed with a
+// random nonce.
+func CredentialsSealed(cred model.Credentials) bool {
+	return sealed(cred.Login, cred.Password)
+}
+
+// CardSealed reports whether every value of card was sealed with a random
+// nonce.
+func CardSealed(card model.Card) bool {
+	a := card.BillingAddress
+	return sealed(card.Num, card.CVC, card.HolderName, card.ExpMonth, card.ExpYear, card.PIN,
+		card.Brand, a.Line1, a.Line2, a.City, a.Region, a.PostalCode, a.Country)
+}
+
+func sealed(values ...string) bool {
+	for _, v := range values {
+		if v != "" && !crypto.IsSealed(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// ResealCredentials opens cred with from and seals it with to, the other
+// fields are kept.
+func ResealCredentials(from, to *crypto.Dealer, cred model.Credentials) (model.Credentials, error) {
+	login, password, err := OpenCredentials(from, cred)
+	if err != nil {
+		return model.Credentials{}, err
+	}
+	enc, err := SealCredentials(to, login, password)
+	if err != nil {
+		return model.Credentials{}, err
+	}
+	cred.Login, cred.Password = enc.Login, enc.Password
+	return cred, nil
+}
+
+// ResealCard opens card with from and seals it with to, the other fields
+// are kept.
+func ResealCard(from, to *crypto.Dealer, card model.Card) (model.Card, error) {
+	in, _, err := OpenCard(from, card)
+	if err != nil {
+		return model.Card{}, err
+	}
+	enc, err := SealCard(to, in)
+	if err != nil {
+		return model.Card{}, err
+	}
+	enc.ID, enc.New, enc.UserID, enc.Status, enc.ModifiedTms =
+		card.ID, card.New, card.UserID, card.Status, card.ModifiedTms
+	return enc, nil
+}
+
+// ResealText opens a note with from and seals it with to.
+func ResealText(from, to *crypto.Dealer, txt string) (string, error) {
+	plain, err := OpenText(from, txt)
+	if err != nil {
+		return "", err
+	}
+	return SealText(to, plain)
+}
+
+// ResealBinary opens file content with from and seals it with to.
+func ResealBinary(from, to *crypto.Dealer, data string) (string, error) {
+	plain, err := OpenBinary(from, data)
+	if err != nil {
+		return "", err
+	}
+	return SealBinary(to, plain)
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"github.com/denis-oreshkevich/gophkeeper/internal/server/service"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"net/http"
+	"strconv"
+)
+
+const ClientIDHeaderName = "X-Client-ID"
+
+// RequestInfo passes the address, the client and the request id to the
+// audit log. Client ids that are not UUIDs are dropped.
+func RequestInfo(next http.Handler) http.Handler {
+	f := func(w http.ResponseWriter, r *http.Request) {
+		clientID := r.Header.Get(ClientIDHeaderName)
+		if _, err := uuid.Parse(clientID); err != nil {
+			clientID = ""
+		}
+		ctx := service.WithRequestInfo(r.Context(), service.RequestInfo{
+			IP:        clientIP(r),
+			ClientID:  clientID,
+			RequestID: middleware.GetReqID(r.Context()),
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+	return http.HandlerFunc(f)
+}
+
+// HandleGetAudit returns a page of the events of the user, newest first.
+// The limit and before query parameters are optional.
+func (c *Controller) HandleGetAudit(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var valErrors []model.ValidationErrEntry
+	limit, err := queryInt(r, "limit")
+	if err != nil || limit < 0 {
+		valErrors = append(valErrors, model.NewValidationErr("limit",
+			[]string{"limit must be a positive number"}))
+	}
+	before, err := queryInt(r, "before")
+	if err != nil || before < 0 {
+		valErrors = append(valErrors, model.NewValidationErr("before",
+			[]string{"before must be an event id"}))
+	}
+	if len(valErrors) > 0 {
+		writeError(w, http.StatusBadRequest, CodeValidation, "request validation failed",
+			valErrors...)
+		return
+	}
+	page, err := c.svc.FindAuditEvents(ctx, before, int(limit))
+	if err != nil {
+		writeServiceError(w, "svc.FindAuditEvents", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// queryInt parses the query parameter, a missing one is zero.
+func queryInt(r *http.Request, name string) (int64, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(v, 10, 64)
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"github.com/denis-oreshkevich/gophkeeper/internal/server/service"
 	"github.com/denis-oreshkevich/gophkeeper/internal/shared/auth"
 	"github.com/denis-oreshkevich/gophkeeper/internal/shared/logger"
 	"go.uber.org/zap"
@@ -11,33 +12,53 @@ import (
 const AuthorizationHeaderName = "Authorization"
 
 var whiteList = map[string]struct{}{
-	"/api/user/register": {},
-	"/api/user/login":    {},
+	"/api/user/register":       {},
+	"/api/user/prelogin":       {},
+	"/api/user/login":          {},
+	"/api/user/recovery":       {},
+	"/api/user/recovery/reset": {},
 }
 
 var log = logger.Log.With(zap.String("cat", "AUTH"))
 
-func Auth(next http.Handler) http.Handler {
+// Auth authenticates the request with a verified device certificate or,
+// without one, with the bearer token. A presented certificate that is
+// revoked or unknown is refused even with a token.
+func (c *Controller) Auth(next http.Handler) http.Handler {
 	f := func(w http.ResponseWriter, r *http.Request) {
 		uri := r.RequestURI
 		_, ok := whiteList[uri]
 		if ok {
 			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := r.Context()
+		if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+			client, err := c.svc.AuthenticateDevice(ctx, r.TLS.VerifiedChains[0][0])
+			if err != nil {
+				log.Debug("device is not valid", zap.Error(err))
+				writeError(w, http.StatusUnauthorized, CodeUnauthorized, "device certificate is not valid")
+				return
+			}
+			ctx = context.WithValue(ctx, auth.UserIDKey{}, client.UserID)
+			ctx = service.WithClientID(ctx, client.ID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
 		}
 
 		tokenString := r.Header.Get(AuthorizationHeaderName)
 		if tokenString == "" {
 			log.Debug(AuthorizationHeaderName + " header not found")
-			w.WriteHeader(http.StatusUnauthorized)
+			writeError(w, http.StatusUnauthorized, CodeUnauthorized, "authorization required")
 			return
 		}
 		claims, isValid := auth.ValidateToken(tokenString)
 		if !isValid {
 			log.Debug("token is not valid")
-			w.WriteHeader(http.StatusUnauthorized)
+			writeError(w, http.StatusUnauthorized, CodeUnauthorized, "token is not valid")
 			return
 		}
-		ctx := r.Context()
 		ctx = context.WithValue(ctx, auth.UserIDKey{}, claims.Subject)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	}
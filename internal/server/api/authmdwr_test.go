@@ -0,0 +1,178 @@
+package api
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/denis-oreshkevich/gophkeeper/internal/server/repo"
+	"github.com/denis-oreshkevich/gophkeeper/internal/server/service"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/auth"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// deviceRepo keeps the clients of one user in memory, the other methods of
+// the repository are not expected to be called.
+type deviceRepo struct {
+	repo.ServerRepository
+	clients map[string]model.Client
+}
+
+func (r *deviceRepo) FindClientByFingerprint(_ context.Context, fingerprint string) (model.Client, error) {
+	for _, c := range r.clients {
+		if c.CertFingerprint == fingerprint {
+			return c, nil
+		}
+	}
+	return model.Client{}, repo.ErrItemNotFound
+}
+
+func (r *deviceRepo) RevokeClient(_ context.Context, id, userID string) (model.Client, error) {
+	c, ok := r.clients[id]
+	if !ok || c.UserID != userID {
+		return model.Client{}, repo.ErrItemNotFound
+	}
+	now := time.Now().UTC()
+	c.RevokedTms = &now
+	r.clients[id] = c
+	return c, nil
+}
+
+func (r *deviceRepo) FindRevokedClients(context.Context) ([]model.Client, error) {
+	res := make([]model.Client, 0)
+	for _, c := range r.clients {
+		if c.RevokedTms != nil {
+			res = append(res, c)
+		}
+	}
+	return res, nil
+}
+
+func (r *deviceRepo) SaveAuditEvent(context.Context, model.AuditEvent) error {
+	return nil
+}
+
+// newDevice signs a certificate for a new key as the server does on
+// registration.
+func newDevice(t *testing.T, m *auth.CertManager, clientID string) (tls.Certificate, auth.DeviceCert) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: clientID},
+	}, key)
+	require.NoError(t, err)
+	cert, err := m.SignClient(string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr})),
+		clientID)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	pair, err := tls.X509KeyPair([]byte(cert.PEM), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	require.NoError(t, err)
+	return pair, cert
+}
+
+func TestAuthDevice(t *testing.T) {
+	const userID = "user"
+	tests := []struct {
+		name string
+		// revoke takes the device away after it was registered
+		revoke     func(t *testing.T, svc *service.ServerService, m *auth.CertManager, cert auth.DeviceCert)
+		register   bool
+		wantStatus int
+	}{
+		{
+			name:       "registered",
+			revoke:     func(*testing.T, *service.ServerService, *auth.CertManager, auth.DeviceCert) {},
+			register:   true,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "revoked",
+			revoke: func(t *testing.T, svc *service.ServerService, _ *auth.CertManager, _ auth.DeviceCert) {
+				ctx := context.WithValue(context.Background(), auth.UserIDKey{}, userID)
+				require.NoError(t, svc.RevokeClient(ctx, "device"))
+			},
+			register:   true,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "on the CRL only",
+			revoke: func(t *testing.T, _ *service.ServerService, m *auth.CertManager, cert auth.DeviceCert) {
+				require.NoError(t, m.UpdateCRL([]auth.RevokedCert{{Serial: cert.Serial, RevokedTms: time.Now()}}))
+			},
+			register:   true,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "signed but not registered",
+			revoke:     func(*testing.T, *service.ServerService, *auth.CertManager, auth.DeviceCert) {},
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			m, err := auth.NewCertManager(auth.CertConfig{
+				CertPath:  filepath.Join(dir, "server.pem"),
+				KeyPath:   filepath.Join(dir, "server-key.pem"),
+				CAPath:    filepath.Join(dir, "ca.pem"),
+				CAKeyPath: filepath.Join(dir, "ca-key.pem"),
+				CRLPath:   filepath.Join(dir, "crl.pem"),
+				Hosts:     []string{"127.0.0.1"},
+			})
+			require.NoError(t, err)
+			r := &deviceRepo{clients: make(map[string]model.Client)}
+			svc, err := service.NewServerService(r, bcrypt.MinCost, m)
+			require.NoError(t, err)
+			require.NoError(t, svc.RefreshCRL(context.Background()))
+
+			pair, cert := newDevice(t, m, "device")
+			if tt.register {
+				r.clients["device"] = model.Client{ID: "device", UserID: userID,
+					CertFingerprint: cert.Fingerprint, CertSerial: cert.Serial}
+			}
+			tt.revoke(t, &svc, m, cert)
+
+			var gotUserID string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotUserID, _ = auth.GetUserID(r.Context())
+				w.WriteHeader(http.StatusOK)
+			})
+			serverCert, err := tls.LoadX509KeyPair(m.CertPath, m.KeyPath)
+			require.NoError(t, err)
+			ts := httptest.NewUnstartedServer(NewController(svc, nil).Auth(next))
+			ts.TLS = &tls.Config{
+				Certificates: []tls.Certificate{serverCert},
+				ClientAuth:   tls.VerifyClientCertIfGiven,
+				ClientCAs:    m.ClientCAs(),
+			}
+			ts.StartTLS()
+			defer ts.Close()
+
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+				RootCAs:      m.ClientCAs(),
+				Certificates: []tls.Certificate{pair},
+			}}}
+			resp, err := client.Get(ts.URL + "/api/text")
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, userID, gotUserID)
+			}
+		})
+	}
+}
@@ -1,34 +1,28 @@
 package api
 
 import (
-	"encoding/json"
+	"errors"
+	"github.com/denis-oreshkevich/gophkeeper/internal/server/repo"
 	"github.com/denis-oreshkevich/gophkeeper/internal/shared/logger"
 	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
-	"io"
 	"net/http"
 )
 
 func (c *Controller) HandlePostBinary(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		logger.Log.Error("io.ReadAll", zap.Error(err))
-		w.WriteHeader(http.StatusBadRequest)
+	binary, ok := decodeAndValidate[model.Binary](w, r, nil)
+	if !ok {
 		return
 	}
-	var binary model.Binary
-	err = json.Unmarshal(body, &binary)
-	if err != nil {
-		logger.Log.Error("json.Unmarshal", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-	err = c.svc.SaveBinary(ctx, &binary)
+	err := c.svc.SaveBinary(ctx, &binary)
 	if err != nil {
+		if writeValidationError(w, err) {
+			return
+		}
 		logger.Log.Error("svc.SaveBinary", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
+		writeInternalError(w)
 		return
 	}
 	w.WriteHeader(http.StatusAccepted)
@@ -40,7 +34,7 @@ func (c *Controller) HandleDeleteBinaryByID(w http.ResponseWriter, r *http.Reque
 	err := c.svc.DeleteBinaryByID(ctx, id)
 	if err != nil {
 		logger.Log.Error("svc.DeleteBinaryByID", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
+		writeInternalError(w)
 		return
 	}
 	w.WriteHeader(http.StatusAccepted)
@@ -51,22 +45,14 @@ func (c *Controller) HandleGetUserBinaries(w http.ResponseWriter, r *http.Reques
 	binaries, err := c.svc.FindBinariesByUserID(ctx)
 	if err != nil {
 		logger.Log.Error("svc.FindBinariesByUserID", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
+		writeInternalError(w)
 		return
 	}
 	if len(binaries) == 0 {
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
-	result, err := json.Marshal(binaries)
-	if err != nil {
-		logger.Log.Error("json.Marshal", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(result)
+	writeJSON(w, http.StatusOK, binaries)
 }
 
 func (c *Controller) HandleGetBinaryByID(w http.ResponseWriter, r *http.Request) {
@@ -74,49 +60,31 @@ func (c *Controller) HandleGetBinaryByID(w http.ResponseWriter, r *http.Request)
 	id := chi.URLParam(r, "id")
 	binary, err := c.svc.FindBinaryByID(ctx, id)
 	if err != nil {
+		if errors.Is(err, repo.ErrItemNotFound) {
+			writeError(w, http.StatusNotFound, CodeNotFound, "binary not found")
+			return
+		}
 		logger.Log.Error("svc.FindBinaryByID", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-	result, err := json.Marshal(binary)
-	if err != nil {
-		logger.Log.Error("json.Marshal", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
+		writeInternalError(w)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(result)
+	writeJSON(w, http.StatusOK, binary)
 }
 
 func (c *Controller) HandlePostSyncBinary(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		logger.Log.Error("io.ReadAll", zap.Error(err))
-		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
-	var binarySync model.BinarySync
-	err = json.Unmarshal(body, &binarySync)
-	if err != nil {
-		logger.Log.Error("json.Unmarshal", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
+	binarySync, ok := decodeAndValidate[model.BinarySync](w, r, nil)
+	if !ok {
 		return
 	}
 	binaries, err := c.svc.SyncBinary(ctx, &binarySync)
 	if err != nil {
+		if writeValidationError(w, err) {
+			return
+		}
 		logger.Log.Error("svc.SyncBinary", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
+		writeInternalError(w)
 		return
 	}
-	result, err := json.Marshal(binaries)
-	if err != nil {
-		logger.Log.Error("json.Marshal", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusAccepted)
-	w.Write(result)
+	writeJSON(w, http.StatusAccepted, binaries)
 }
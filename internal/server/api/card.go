@@ -1,34 +1,28 @@
 package api
 
 import (
-	"encoding/json"
+	"errors"
+	"github.com/denis-oreshkevich/gophkeeper/internal/server/repo"
 	"github.com/denis-oreshkevich/gophkeeper/internal/shared/logger"
 	model2 "github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
-	"io"
 	"net/http"
 )
 
 func (c *Controller) HandlePostCard(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		logger.Log.Error("io.ReadAll", zap.Error(err))
-		w.WriteHeader(http.StatusBadRequest)
+	card, ok := decodeAndValidate[model2.Card](w, r, nil)
+	if !ok {
 		return
 	}
-	var card model2.Card
-	err = json.Unmarshal(body, &card)
-	if err != nil {
-		logger.Log.Error("json.Unmarshal", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-	err = c.svc.SaveCard(ctx, card)
+	err := c.svc.SaveCard(ctx, card)
 	if err != nil {
+		if writeValidationError(w, err) {
+			return
+		}
 		logger.Log.Error("svc.SaveCard", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
+		writeInternalError(w)
 		return
 	}
 	w.WriteHeader(http.StatusAccepted)
@@ -40,7 +34,7 @@ func (c *Controller) HandleDeleteCardByID(w http.ResponseWriter, r *http.Request
 	err := c.svc.DeleteCardByID(ctx, id)
 	if err != nil {
 		logger.Log.Error("svc.DeleteCardByID", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
+		writeInternalError(w)
 		return
 	}
 	w.WriteHeader(http.StatusAccepted)
@@ -51,22 +45,14 @@ func (c *Controller) HandleGetUserCards(w http.ResponseWriter, r *http.Request)
 	cards, err := c.svc.FindCardsByUserID(ctx)
 	if err != nil {
 		logger.Log.Error("svc.FindCardsByUserID", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
+		writeInternalError(w)
 		return
 	}
 	if len(cards) == 0 {
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
-	result, err := json.Marshal(cards)
-	if err != nil {
-		logger.Log.Error("json.Marshal", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(result)
+	writeJSON(w, http.StatusOK, cards)
 }
 
 func (c *Controller) HandleGetCardByID(w http.ResponseWriter, r *http.Request) {
@@ -74,49 +60,31 @@ func (c *Controller) HandleGetCardByID(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	card, err := c.svc.FindCardByID(ctx, id)
 	if err != nil {
+		if errors.Is(err, repo.ErrItemNotFound) {
+			writeError(w, http.StatusNotFound, CodeNotFound, "card not found")
+			return
+		}
 		logger.Log.Error("svc.FindCardByID", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-	result, err := json.Marshal(card)
-	if err != nil {
-		logger.Log.Error("json.Marshal", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
+		writeInternalError(w)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(result)
+	writeJSON(w, http.StatusOK, card)
 }
 
 func (c *Controller) HandlePostSyncCard(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		logger.Log.Error("io.ReadAll", zap.Error(err))
-		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
-	var cardSync model2.CardSync
-	err = json.Unmarshal(body, &cardSync)
-	if err != nil {
-		logger.Log.Error("json.Unmarshal", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
+	cardSync, ok := decodeAndValidate[model2.CardSync](w, r, nil)
+	if !ok {
 		return
 	}
 	cards, err := c.svc.SyncCard(ctx, &cardSync)
 	if err != nil {
+		if writeValidationError(w, err) {
+			return
+		}
 		logger.Log.Error("svc.SyncCard", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
+		writeInternalError(w)
 		return
 	}
-	result, err := json.Marshal(cards)
-	if err != nil {
-		logger.Log.Error("json.Marshal", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusAccepted)
-	w.Write(result)
+	writeJSON(w, http.StatusAccepted, cards)
 }
@@ -1,68 +1,69 @@
 package api
 
 import (
-	"encoding/json"
 	"github.com/denis-oreshkevich/gophkeeper/internal/shared/logger"
 	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
-	"io"
 	"net/http"
 )
 
 func (c *Controller) HandlePostClient(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		logger.Log.Error("io.ReadAll", zap.Error(err))
-		w.WriteHeader(http.StatusBadRequest)
+	client, ok := decodeAndValidate[model.Client](w, r, nil)
+	if !ok {
 		return
 	}
-	var client model.Client
-	err = json.Unmarshal(body, &client)
+	client, err := c.svc.RegisterClient(ctx, client)
 	if err != nil {
-		logger.Log.Error("json.Unmarshal", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
+		writeServiceError(w, "svc.RegisterClient", err)
 		return
 	}
-	client, err = c.svc.RegisterClient(ctx, client)
+	writeJSON(w, http.StatusCreated, client)
+}
 
-	if err != nil {
-		logger.Log.Error("svc.RegisterClient", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
+func (c *Controller) HandlePutClient(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	client, ok := decodeAndValidate[model.Client](w, r, nil)
+	if !ok {
 		return
 	}
-	result, err := json.Marshal(client)
+	err := c.svc.UpdateClientLastSyncTms(ctx, client)
 	if err != nil {
-		logger.Log.Error("json.Marshal", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
+		logger.Log.Error("svc.UpdateClientLastSyncTms", zap.Error(err))
+		writeInternalError(w)
 		return
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	w.Write(result)
+	w.WriteHeader(http.StatusOK)
 }
 
-func (c *Controller) HandlePutClient(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-	body, err := io.ReadAll(r.Body)
+// HandleGetClients lists the clients of the user, revoked ones included.
+func (c *Controller) HandleGetClients(w http.ResponseWriter, r *http.Request) {
+	clients, err := c.svc.FindClients(r.Context())
 	if err != nil {
-		logger.Log.Error("io.ReadAll", zap.Error(err))
-		w.WriteHeader(http.StatusBadRequest)
+		writeServiceError(w, "svc.FindClients", err)
 		return
 	}
-	var client model.Client
-	err = json.Unmarshal(body, &client)
+	writeJSON(w, http.StatusOK, clients)
+}
+
+func (c *Controller) HandleDeleteClient(w http.ResponseWriter, r *http.Request) {
+	err := c.svc.RevokeClient(r.Context(), chi.URLParam(r, "id"))
 	if err != nil {
-		logger.Log.Error("json.Unmarshal", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
+		writeServiceError(w, "svc.RevokeClient", err)
 		return
 	}
-	err = c.svc.UpdateClientLastSyncTms(ctx, client)
-	if err != nil {
-		logger.Log.Error("svc.UpdateClientLastSyncTms", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandleGetCRL serves the CRL of the device certificates.
+func (c *Controller) HandleGetCRL(w http.ResponseWriter, r *http.Request) {
+	crl := c.svc.CRL()
+	if crl == nil {
+		writeError(w, http.StatusNotFound, CodeNotFound, "device certificates are not enabled")
 		return
 	}
+	w.Header().Set("Content-Type", "application/pkix-crl")
 	w.WriteHeader(http.StatusOK)
+	w.Write(crl)
 }
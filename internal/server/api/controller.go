@@ -2,14 +2,17 @@ package api
 
 import (
 	"github.com/denis-oreshkevich/gophkeeper/internal/server/service"
+	"github.com/denis-oreshkevich/gophkeeper/internal/server/throttle"
 )
 
 type Controller struct {
-	svc service.ServerService
+	svc      service.ServerService
+	throttle *throttle.Throttle
 }
 
-func NewController(svc service.ServerService) *Controller {
+func NewController(svc service.ServerService, th *throttle.Throttle) *Controller {
 	return &Controller{
-		svc: svc,
+		svc:      svc,
+		throttle: th,
 	}
 }
@@ -1,34 +1,28 @@
 package api
 
 import (
-	"encoding/json"
+	"errors"
+	"github.com/denis-oreshkevich/gophkeeper/internal/server/repo"
 	"github.com/denis-oreshkevich/gophkeeper/internal/shared/logger"
 	model2 "github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
-	"io"
 	"net/http"
 )
 
 func (c *Controller) HandlePostCredentials(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		logger.Log.Error("io.ReadAll", zap.Error(err))
-		w.WriteHeader(http.StatusBadRequest)
+	cred, ok := decodeAndValidate[model2.Credentials](w, r, nil)
+	if !ok {
 		return
 	}
-	var cred model2.Credentials
-	err = json.Unmarshal(body, &cred)
-	if err != nil {
-		logger.Log.Error("json.Unmarshal", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-	err = c.svc.SaveCredentials(ctx, cred)
+	err := c.svc.SaveCredentials(ctx, cred)
 	if err != nil {
+		if writeValidationError(w, err) {
+			return
+		}
 		logger.Log.Error("svc.SaveCredentials", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
+		writeInternalError(w)
 		return
 	}
 	w.WriteHeader(http.StatusAccepted)
@@ -40,7 +34,7 @@ func (c *Controller) HandleDeleteCredentialsByID(w http.ResponseWriter, r *http.
 	err := c.svc.DeleteCredentialsByID(ctx, id)
 	if err != nil {
 		logger.Log.Error("svc.DeleteCredentialsByID", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
+		writeInternalError(w)
 		return
 	}
 	w.WriteHeader(http.StatusAccepted)
@@ -51,22 +45,14 @@ func (c *Controller) HandleGetUserCredentials(w http.ResponseWriter, r *http.Req
 	creds, err := c.svc.FindCredentialsByUserID(ctx)
 	if err != nil {
 		logger.Log.Error("svc.FindCredentialsByUserID", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
+		writeInternalError(w)
 		return
 	}
 	if len(creds) == 0 {
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
-	result, err := json.Marshal(creds)
-	if err != nil {
-		logger.Log.Error("json.Marshal", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(result)
+	writeJSON(w, http.StatusOK, creds)
 }
 
 func (c *Controller) HandleGetCredentialsByID(w http.ResponseWriter, r *http.Request) {
@@ -74,50 +60,31 @@ func (c *Controller) HandleGetCredentialsByID(w http.ResponseWriter, r *http.Req
 	id := chi.URLParam(r, "id")
 	cred, err := c.svc.FindCredentialsByID(ctx, id)
 	if err != nil {
+		if errors.Is(err, repo.ErrItemNotFound) {
+			writeError(w, http.StatusNotFound, CodeNotFound, "credentials not found")
+			return
+		}
 		logger.Log.Error("svc.FindCredentialsByID", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-	result, err := json.Marshal(cred)
-	if err != nil {
-		logger.Log.Error("json.Marshal", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
+		writeInternalError(w)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(result)
+	writeJSON(w, http.StatusOK, cred)
 }
 
 func (c *Controller) HandlePostSyncCredentials(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		logger.Log.Error("io.ReadAll", zap.Error(err))
-		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
-	var credSync model2.CredSync
-	err = json.Unmarshal(body, &credSync)
-	if err != nil {
-		logger.Log.Error("json.Unmarshal", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
+	credSync, ok := decodeAndValidate[model2.CredSync](w, r, nil)
+	if !ok {
 		return
 	}
 	credentials, err := c.svc.SyncCredentials(ctx, &credSync)
 	if err != nil {
+		if writeValidationError(w, err) {
+			return
+		}
 		logger.Log.Error("svc.SyncCredentials", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
+		writeInternalError(w)
 		return
 	}
-	result, err := json.Marshal(credentials)
-	if err != nil {
-		logger.Log.Error("json.Marshal", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusAccepted)
-	w.Write(result)
+	writeJSON(w, http.StatusAccepted, credentials)
 }
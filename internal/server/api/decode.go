@@ -0,0 +1,125 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/logger"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Limits are the maximum request body sizes in bytes per kind of route.
+type Limits struct {
+	Auth    int64
+	Default int64
+	Sync    int64
+	Binary  int64
+}
+
+var DefaultLimits = Limits{
+	Auth:    16 << 10,
+	Default: 1 << 20,
+	Sync:    32 << 20,
+	Binary:  64 << 20,
+}
+
+// unknownFieldPrefix starts the error encoding/json returns for a field
+// DisallowUnknownFields rejects, it has no error type of its own.
+const unknownFieldPrefix = "json: unknown field "
+
+type bodyLimitKey struct{}
+
+// BodyLimit sets the maximum request body size for the routes it wraps.
+func BodyLimit(limit int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		f := func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), bodyLimitKey{}, limit)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		}
+		return http.HandlerFunc(f)
+	}
+}
+
+func bodyLimit(r *http.Request) int64 {
+	limit, ok := r.Context().Value(bodyLimitKey{}).(int64)
+	if !ok {
+		return DefaultLimits.Default
+	}
+	return limit
+}
+
+// decodeJSON streams the request body into dst rejecting unknown fields,
+// trailing data and bodies larger than the route limit. On failure the
+// error response is already written and false is returned.
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst any) bool {
+	body := http.MaxBytesReader(w, r.Body, bodyLimit(r))
+	dec := json.NewDecoder(body)
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(dst)
+	if err == nil {
+		// a raw value, a struct would report the fields of a second object
+		// as unknown
+		err = dec.Decode(&json.RawMessage{})
+		if errors.Is(err, io.EOF) {
+			return true
+		}
+		if err == nil {
+			err = errors.New("request body must contain a single JSON value")
+		}
+	}
+	logger.Log.Debug("decodeJSON", zap.Error(err))
+	writeDecodeError(w, err)
+	return false
+}
+
+func writeDecodeError(w http.ResponseWriter, err error) {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		writeError(w, http.StatusRequestEntityTooLarge, CodeBodyTooLarge,
+			fmt.Sprintf("request body must not be larger than %d bytes", maxBytesErr.Limit))
+	case errors.Is(err, io.EOF):
+		writeError(w, http.StatusBadRequest, CodeInvalidJSON, "request body must not be empty")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		writeError(w, http.StatusBadRequest, CodeInvalidJSON, "request body contains malformed JSON")
+	case errors.As(err, &syntaxErr):
+		writeError(w, http.StatusBadRequest, CodeInvalidJSON,
+			fmt.Sprintf("request body contains malformed JSON at position %d", syntaxErr.Offset))
+	case errors.As(err, &typeErr):
+		writeError(w, http.StatusBadRequest, CodeInvalidJSON, "request body contains invalid value",
+			model.NewValidationErr(typeErr.Field, []string{fmt.Sprintf("must be %s", typeErr.Type)}))
+	case strings.HasPrefix(err.Error(), unknownFieldPrefix):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), unknownFieldPrefix), `"`)
+		writeError(w, http.StatusBadRequest, CodeInvalidJSON, "request body contains unknown field",
+			model.NewValidationErr(field, []string{"unknown field"}))
+	default:
+		writeError(w, http.StatusBadRequest, CodeInvalidJSON, err.Error())
+	}
+}
+
+// decodeAndValidate decodes the request body into T and runs validate on it.
+// A nil validate skips validation.
+func decodeAndValidate[T any](w http.ResponseWriter, r *http.Request,
+	validate func(T) []model.ValidationErrEntry) (T, bool) {
+	var v T
+	if !decodeJSON(w, r, &v) {
+		return v, false
+	}
+	if validate == nil {
+		return v, true
+	}
+	if valErrors := validate(v); len(valErrors) > 0 {
+		writeError(w, http.StatusBadRequest, CodeValidation, "request validation failed",
+			valErrors...)
+		return v, false
+	}
+	return v, true
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestUnknownFieldError pins the text writeDecodeError takes the field
+// from, encoding/json has no error type for it.
+func TestUnknownFieldError(t *testing.T) {
+	dec := json.NewDecoder(strings.NewReader(`{"name":"a","extra":1}`))
+	dec.DisallowUnknownFields()
+	var dst struct {
+		Name string `json:"name"`
+	}
+	err := dec.Decode(&dst)
+	require.Error(t, err)
+	assert.Equal(t, unknownFieldPrefix+`"extra"`, err.Error())
+}
+
+func TestDecodeJSON(t *testing.T) {
+	type request struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantCode   string
+		wantField  string
+	}{
+		{name: "valid", body: `{"name":"a","count":1}`, wantStatus: http.StatusOK},
+		{name: "valid with spaces after", body: "{\"name\":\"a\"}\n  ", wantStatus: http.StatusOK},
+		{
+			name: "unknown field", body: `{"name":"a","extra":1}`,
+			wantStatus: http.StatusBadRequest, wantCode: CodeInvalidJSON, wantField: "extra",
+		},
+		{
+			name: "trailing value", body: `{"name":"a"}{"name":"b"}`,
+			wantStatus: http.StatusBadRequest, wantCode: CodeInvalidJSON,
+		},
+		{
+			name: "trailing garbage", body: `{"name":"a"} x`,
+			wantStatus: http.StatusBadRequest, wantCode: CodeInvalidJSON,
+		},
+		{
+			name: "too large", body: `{"name":"` + strings.Repeat("a", 64) + `"}`,
+			wantStatus: http.StatusRequestEntityTooLarge, wantCode: CodeBodyTooLarge,
+		},
+		{name: "empty", body: "", wantStatus: http.StatusBadRequest, wantCode: CodeInvalidJSON},
+		{name: "truncated", body: `{"name":`, wantStatus: http.StatusBadRequest, wantCode: CodeInvalidJSON},
+		{name: "malformed", body: `{"name" "a"}`, wantStatus: http.StatusBadRequest, wantCode: CodeInvalidJSON},
+		{
+			name: "wrong type", body: `{"count":"1"}`,
+			wantStatus: http.StatusBadRequest, wantCode: CodeInvalidJSON, wantField: "count",
+		},
+	}
+	handler := BodyLimit(48)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req request
+		if decodeJSON(w, r, &req) {
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body)))
+			require.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantCode == "" {
+				return
+			}
+			var res ErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+			assert.Equal(t, tt.wantCode, res.Code)
+			if tt.wantField == "" {
+				assert.Empty(t, res.FieldErrors)
+				return
+			}
+			require.Len(t, res.FieldErrors, 1)
+			assert.Equal(t, tt.wantField, res.FieldErrors[0].Field)
+		})
+	}
+}
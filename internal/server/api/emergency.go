@@ -0,0 +1,85 @@
+package api
+
+import (
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+)
+
+func (c *Controller) HandlePostEmergencyAccess(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	e, ok := decodeAndValidate[model.EmergencyAccess](w, r, nil)
+	if !ok {
+		return
+	}
+	err := c.svc.GrantEmergencyAccess(ctx, e)
+	if err != nil {
+		writeServiceError(w, "svc.GrantEmergencyAccess", err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (c *Controller) HandleGetEmergencyAccess(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	res, err := c.svc.FindEmergencyAccess(ctx)
+	if err != nil {
+		writeServiceError(w, "svc.FindEmergencyAccess", err)
+		return
+	}
+	if len(res) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (c *Controller) HandleDeleteEmergencyAccess(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	err := c.svc.RevokeEmergencyAccess(ctx, chi.URLParam(r, "id"))
+	if err != nil {
+		writeServiceError(w, "svc.RevokeEmergencyAccess", err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (c *Controller) HandlePostEmergencyRequest(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	err := c.svc.RequestEmergencyAccess(ctx, chi.URLParam(r, "id"))
+	if err != nil {
+		writeServiceError(w, "svc.RequestEmergencyAccess", err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (c *Controller) HandlePostEmergencyApprove(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	err := c.svc.ApproveEmergencyAccess(ctx, chi.URLParam(r, "id"))
+	if err != nil {
+		writeServiceError(w, "svc.ApproveEmergencyAccess", err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (c *Controller) HandlePostEmergencyDecline(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	err := c.svc.DeclineEmergencyAccess(ctx, chi.URLParam(r, "id"))
+	if err != nil {
+		writeServiceError(w, "svc.DeclineEmergencyAccess", err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (c *Controller) HandleGetEmergencyVault(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	v, err := c.svc.FindEmergencyVault(ctx, chi.URLParam(r, "id"))
+	if err != nil {
+		writeServiceError(w, "svc.FindEmergencyVault", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
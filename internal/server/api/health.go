@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/logger"
+	"go.uber.org/zap"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// readyTimeout bounds the database checks of one readiness probe.
+const readyTimeout = 2 * time.Second
+
+// BuildInfo is the build of the server, set at link time.
+type BuildInfo struct {
+	Version string `json:"version"`
+	Date    string `json:"date"`
+	Commit  string `json:"commit"`
+}
+
+// ReadinessChecker tells whether the storage can serve requests.
+type ReadinessChecker interface {
+	Ready(ctx context.Context) error
+}
+
+// Health answers the probes of orchestrators, none of them needs a token.
+type Health struct {
+	checker      ReadinessChecker
+	build        BuildInfo
+	shuttingDown atomic.Bool
+}
+
+func NewHealth(checker ReadinessChecker, build BuildInfo) *Health {
+	return &Health{
+		checker: checker,
+		build:   build,
+	}
+}
+
+// SetShuttingDown fails the readiness probe from now on, so no new traffic
+// is routed to the server while it drains.
+func (h *Health) SetShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+// HandleHealthz answers as long as the process serves HTTP.
+func (h *Health) HandleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// HandleReadyz answers 503 while shutting down or when the database is not
+// reachable or not migrated.
+func (h *Health) HandleReadyz(w http.ResponseWriter, r *http.Request) {
+	if h.shuttingDown.Load() {
+		writeError(w, http.StatusServiceUnavailable, CodeUnavailable, "shutting down")
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+	if err := h.checker.Ready(ctx); err != nil {
+		logger.Log.Warn("readiness", zap.Error(err))
+		writeError(w, http.StatusServiceUnavailable, CodeUnavailable, "database is not ready")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+}
+
+func (h *Health) HandleVersion(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.build)
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+)
+
+func (c *Controller) HandlePostOrg(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	org, ok := decodeAndValidate[model.Org](w, r, nil)
+	if !ok {
+		return
+	}
+	err := c.svc.CreateOrg(ctx, org)
+	if err != nil {
+		writeServiceError(w, "svc.CreateOrg", err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (c *Controller) HandleGetOrgs(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	orgs, err := c.svc.FindOrgs(ctx)
+	if err != nil {
+		writeServiceError(w, "svc.FindOrgs", err)
+		return
+	}
+	if len(orgs) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, http.StatusOK, orgs)
+}
+
+func (c *Controller) HandleGetOrgMembers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	members, err := c.svc.FindOrgMembers(ctx, chi.URLParam(r, "org"))
+	if err != nil {
+		writeServiceError(w, "svc.FindOrgMembers", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, members)
+}
+
+func (c *Controller) HandlePostOrgMember(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	member, ok := decodeAndValidate[model.OrgMember](w, r, nil)
+	if !ok {
+		return
+	}
+	err := c.svc.SaveOrgMember(ctx, chi.URLParam(r, "org"), member)
+	if err != nil {
+		writeServiceError(w, "svc.SaveOrgMember", err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (c *Controller) HandleDeleteOrgMember(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	err := c.svc.RemoveOrgMember(ctx, chi.URLParam(r, "org"), chi.URLParam(r, "login"))
+	if err != nil {
+		writeServiceError(w, "svc.RemoveOrgMember", err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (c *Controller) HandleGetCollections(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	collections, err := c.svc.FindCollections(ctx, chi.URLParam(r, "org"))
+	if err != nil {
+		writeServiceError(w, "svc.FindCollections", err)
+		return
+	}
+	if len(collections) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, http.StatusOK, collections)
+}
+
+func (c *Controller) HandlePostCollection(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	collection, ok := decodeAndValidate[model.Collection](w, r, nil)
+	if !ok {
+		return
+	}
+	err := c.svc.CreateCollection(ctx, chi.URLParam(r, "org"), collection)
+	if err != nil {
+		writeServiceError(w, "svc.CreateCollection", err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (c *Controller) HandlePutCollectionKey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	rekey, ok := decodeAndValidate[model.CollectionRekey](w, r, nil)
+	if !ok {
+		return
+	}
+	err := c.svc.RekeyCollection(ctx, chi.URLParam(r, "org"), chi.URLParam(r, "id"), rekey)
+	if err != nil {
+		writeServiceError(w, "svc.RekeyCollection", err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (c *Controller) HandleGetOrgItems(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	items, err := c.svc.FindOrgItems(ctx, chi.URLParam(r, "org"), chi.URLParam(r, "id"))
+	if err != nil {
+		writeServiceError(w, "svc.FindOrgItems", err)
+		return
+	}
+	if len(items) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (c *Controller) HandlePostOrgItem(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	item, ok := decodeAndValidate[model.OrgItem](w, r, nil)
+	if !ok {
+		return
+	}
+	err := c.svc.SaveOrgItem(ctx, chi.URLParam(r, "org"), chi.URLParam(r, "id"), item)
+	if err != nil {
+		writeServiceError(w, "svc.SaveOrgItem", err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
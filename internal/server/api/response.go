@@ -0,0 +1,72 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/logger"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+const (
+	CodeInvalidJSON     = "invalid_json"
+	CodeBodyTooLarge    = "body_too_large"
+	CodeValidation      = "validation_failed"
+	CodeUnauthorized    = "unauthorized"
+	CodeForbidden       = "forbidden"
+	CodeNotFound        = "not_found"
+	CodeConflict        = "conflict"
+	CodeTooManyRequests = "too_many_requests"
+	CodeUnavailable     = "unavailable"
+	CodeInternal        = "internal_error"
+)
+
+// ErrorResponse is the JSON envelope of every error returned by the API.
+type ErrorResponse struct {
+	Code        string                     `json:"code"`
+	Message     string                     `json:"message"`
+	FieldErrors []model.ValidationErrEntry `json:"field_errors,omitempty"`
+}
+
+func NewErrorResponse(code, message string,
+	fieldErrors []model.ValidationErrEntry) ErrorResponse {
+	return ErrorResponse{
+		Code:        code,
+		Message:     message,
+		FieldErrors: fieldErrors,
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string,
+	fieldErrors ...model.ValidationErrEntry) {
+	writeJSON(w, status, NewErrorResponse(code, message, fieldErrors))
+}
+
+// writeValidationError writes 400 with field errors if err is a
+// model.ValidationError and reports whether it did so.
+func writeValidationError(w http.ResponseWriter, err error) bool {
+	var valErr *model.ValidationError
+	if !errors.As(err, &valErr) {
+		return false
+	}
+	writeError(w, http.StatusBadRequest, CodeValidation, "request validation failed",
+		valErr.Entries...)
+	return true
+}
+
+func writeInternalError(w http.ResponseWriter) {
+	writeError(w, http.StatusInternalServerError, CodeInternal, "internal server error")
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	result, err := json.Marshal(v)
+	if err != nil {
+		logger.Log.Error("json.Marshal", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(result)
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"errors"
+	"github.com/denis-oreshkevich/gophkeeper/internal/server/repo"
+	"github.com/denis-oreshkevich/gophkeeper/internal/server/service"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/auth"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/logger"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+func (c *Controller) HandlePutUserKey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	key, ok := decodeAndValidate[model.UserKey](w, r, nil)
+	if !ok {
+		return
+	}
+	err := c.svc.SaveUserKey(ctx, key)
+	if err != nil {
+		writeServiceError(w, "svc.SaveUserKey", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (c *Controller) HandleGetUserKey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	key, err := c.svc.FindUserKey(ctx)
+	if err != nil {
+		writeServiceError(w, "svc.FindUserKey", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, key)
+}
+
+func (c *Controller) HandleGetPublicKey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	login := chi.URLParam(r, "login")
+	key, err := c.svc.FindPublicKey(ctx, login)
+	if err != nil {
+		writeServiceError(w, "svc.FindPublicKey", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, key)
+}
+
+func (c *Controller) HandlePostShare(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	share, ok := decodeAndValidate[model.Share](w, r, nil)
+	if !ok {
+		return
+	}
+	err := c.svc.SaveShare(ctx, share)
+	if err != nil {
+		writeServiceError(w, "svc.SaveShare", err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (c *Controller) HandleGetShares(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shares, err := c.svc.FindShares(ctx)
+	if err != nil {
+		writeServiceError(w, "svc.FindShares", err)
+		return
+	}
+	if len(shares) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, http.StatusOK, shares)
+}
+
+func (c *Controller) HandlePutSharePayload(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := chi.URLParam(r, "id")
+	payload, ok := decodeAndValidate[model.SharePayload](w, r, nil)
+	if !ok {
+		return
+	}
+	err := c.svc.UpdateSharePayload(ctx, id, payload)
+	if err != nil {
+		writeServiceError(w, "svc.UpdateSharePayload", err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (c *Controller) HandleDeleteShareGrant(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := chi.URLParam(r, "id")
+	login := chi.URLParam(r, "login")
+	err := c.svc.RevokeShare(ctx, id, login)
+	if err != nil {
+		writeServiceError(w, "svc.RevokeShare", err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// writeServiceError maps the errors of the sharing, organization and
+// emergency access services to responses.
+func writeServiceError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case writeValidationError(w, err):
+	case errors.Is(err, auth.ErrCSR):
+		writeError(w, http.StatusBadRequest, CodeValidation, auth.ErrCSR.Error())
+	case errors.Is(err, repo.ErrItemNotFound):
+		writeError(w, http.StatusNotFound, CodeNotFound, "not found")
+	case errors.Is(err, service.ErrAccessDenied):
+		writeError(w, http.StatusForbidden, CodeForbidden, "access denied")
+	case errors.Is(err, service.ErrKeyExists):
+		writeError(w, http.StatusConflict, CodeConflict, service.ErrKeyExists.Error())
+	case errors.Is(err, service.ErrStale):
+		writeError(w, http.StatusConflict, CodeConflict, service.ErrStale.Error())
+	case errors.Is(err, service.ErrKeyVersion):
+		writeError(w, http.StatusConflict, CodeConflict, service.ErrKeyVersion.Error())
+	case errors.Is(err, service.ErrEmergencyState):
+		writeError(w, http.StatusConflict, CodeConflict, service.ErrEmergencyState.Error())
+	default:
+		logger.Log.Error(op, zap.Error(err))
+		writeInternalError(w)
+	}
+}
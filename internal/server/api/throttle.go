@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/server/metrics"
+	"github.com/denis-oreshkevich/gophkeeper/internal/server/repo"
+	"github.com/denis-oreshkevich/gophkeeper/internal/server/throttle"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/auth"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/logger"
+	"go.uber.org/zap"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// beginAttempt reserves a password attempt, it answers 429 when the address
+// or the login has to wait. The answer is the same whether the login exists
+// or not. The attempt has to be ended, Release after Fail or Succeed does
+// nothing.
+func (c *Controller) beginAttempt(w http.ResponseWriter, ip, login string) (*throttle.Attempt, bool) {
+	a, wait := c.throttle.Begin(ip, login)
+	if a != nil {
+		return a, true
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	writeError(w, http.StatusTooManyRequests, CodeTooManyRequests,
+		fmt.Sprintf("too many attempts, retry in %s", wait.Round(time.Second)))
+	return nil, false
+}
+
+// authFailed counts a wrong password or recovery key, every lockout is an
+// audit event.
+func (c *Controller) authFailed(ctx context.Context, a *throttle.Attempt, ip, login string) {
+	metrics.AuthFailure(metrics.Route(ctx))
+	for _, scope := range a.Fail() {
+		logger.Log.Warn("lockout", zap.String("scope", string(scope)),
+			zap.String("login", login), zap.String("ip", ip))
+		metrics.Lockout(string(scope))
+		c.svc.RecordLockout(ctx, login, string(scope))
+	}
+}
+
+// isAuthFailure tells a rejected login, password or recovery key from other
+// errors.
+func isAuthFailure(err error) bool {
+	return errors.Is(err, auth.ErrPasswordMismatch) || errors.Is(err, repo.ErrItemNotFound)
+}
+
+// clientIP is the address of the connection, forwarding headers are not
+// trusted.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// userKey throttles password confirmations of a logged in user apart from
+// logins, which are keyed by login name.
+func userKey(r *http.Request) string {
+	userID, err := auth.GetUserID(r.Context())
+	if err != nil {
+		return ""
+	}
+	return "user:" + userID
+}
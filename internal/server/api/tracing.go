@@ -0,0 +1,22 @@
+package api
+
+import (
+	"github.com/denis-oreshkevich/gophkeeper/internal/server/metrics"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+)
+
+// Tracing starts the span of the request, continuing the trace of the
+// client, and names it after the route once the request is served.
+func Tracing(next http.Handler) http.Handler {
+	f := func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+		route := metrics.RequestRoute(r)
+		span := trace.SpanFromContext(r.Context())
+		span.SetName(r.Method + " " + route)
+		span.SetAttributes(attribute.String("http.route", route))
+	}
+	return otelhttp.NewHandler(http.HandlerFunc(f), "http.server")
+}
@@ -1,150 +1,236 @@
 package api
 
 import (
-	"encoding/json"
 	"errors"
 	"github.com/denis-oreshkevich/gophkeeper/internal/server/repo"
 	"github.com/denis-oreshkevich/gophkeeper/internal/shared/auth"
 	"github.com/denis-oreshkevich/gophkeeper/internal/shared/logger"
 	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
 	"go.uber.org/zap"
-	"io"
 	"net/http"
 	"strings"
 )
 
-type ValidationErrEntry struct {
-	Field  string   `json:"field"`
-	Errors []string `json:"errors"`
-}
-
-func NewValidationErr(field string, errs []string) ValidationErrEntry {
-	return ValidationErrEntry{
-		Field:  field,
-		Errors: errs,
-	}
-}
-
 func (c *Controller) HandleRegisterUser(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	u, ok := readAndValidateUser(w, r)
+	u, ok := decodeAndValidate[model.AuthUser](w, r, validateUser)
 	if !ok {
-		logger.Log.Debug("readAndValidateUser is not ok")
+		logger.Log.Debug("decodeAndValidate user is not ok")
 		return
 	}
-	usr, err := c.svc.Register(ctx, u.Login, u.Password)
+	usr, err := c.svc.Register(ctx, u)
 	if err != nil {
 		if errors.Is(err, repo.ErrUserAlreadyExist) {
 			logger.Log.Debug("register user", zap.Error(err))
-			w.WriteHeader(http.StatusConflict)
+			writeError(w, http.StatusConflict, CodeConflict, "user already exists")
 			return
 		}
 		logger.Log.Error("register user", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-
-	bytes, err := json.Marshal(usr)
-	if err != nil {
-		logger.Log.Error("json.Marshal", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
+		writeInternalError(w)
 		return
 	}
 	token, err := auth.GenerateToken(usr.ID)
 	if err != nil {
 		logger.Log.Error("auth.GenerateToken", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
+		writeInternalError(w)
+		return
 	}
 	w.Header().Set(AuthorizationHeaderName, token)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(bytes)
+	writeJSON(w, http.StatusOK, usr)
 }
 
 func (c *Controller) HandleLoginUser(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	u, ok := readAndValidateUser(w, r)
+	u, ok := decodeAndValidate[model.AuthUser](w, r, validateUser)
+	if !ok {
+		logger.Log.Debug("decodeAndValidate user is not ok")
+		return
+	}
+	ip := clientIP(r)
+	a, ok := c.beginAttempt(w, ip, u.Login)
 	if !ok {
-		logger.Log.Debug("readAndValidateUser is not ok")
 		return
 	}
+	defer a.Release()
 	usr, err := c.svc.Login(ctx, u.Login, u.Password)
 	if err != nil {
-		if errors.Is(err, repo.ErrItemNotFound) {
-			logger.Log.Debug("user not found")
-			w.WriteHeader(http.StatusUnauthorized)
-			return
+		if isAuthFailure(err) {
+			c.authFailed(ctx, a, ip, u.Login)
 		}
-		logger.Log.Error("svc.Login", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
+		writeAuthError(w, "svc.Login", err)
 		return
 	}
+	a.Succeed()
 	token, err := auth.GenerateToken(usr.ID)
 	if err != nil {
 		logger.Log.Error("auth.GenerateToken", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
-	}
-	bytes, err := json.Marshal(usr)
-	if err != nil {
-		logger.Log.Error("json.Marshal", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
+		writeInternalError(w)
 		return
 	}
 	w.Header().Set(AuthorizationHeaderName, token)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(bytes)
+	writeJSON(w, http.StatusOK, usr)
 }
 
-func readAndValidateUser(w http.ResponseWriter, r *http.Request) (model.AuthUser, bool) {
-	body, err := io.ReadAll(r.Body)
+func validateUser(u model.AuthUser) []model.ValidationErrEntry {
+	login := strings.Trim(u.Login, " ")
+	isEV := len(login) > 0
+	pswd := strings.Trim(u.Password, " ")
+	isPV := len(pswd) > 0
+	var valErrors = make([]model.ValidationErrEntry, 0)
+	if !isEV {
+		validationErr := model.NewValidationErr("login", []string{"login is not valid"})
+		valErrors = append(valErrors, validationErr)
+	}
+	if !isPV {
+		validationErr := model.NewValidationErr("password", []string{"password is not valid"})
+		valErrors = append(valErrors, validationErr)
+	}
+	if u.Vault != nil {
+		valErrors = append(valErrors, nestedErrors("vault", u.Vault.Validate())...)
+	}
+	if u.Recovery != nil {
+		valErrors = append(valErrors, nestedErrors("recovery", u.Recovery.Validate())...)
+	}
+	return valErrors
+}
+
+// nestedErrors prefixes the validation entries of a part of the request
+// with its field.
+func nestedErrors(field string, err error) []model.ValidationErrEntry {
+	var valErr *model.ValidationError
+	if !errors.As(err, &valErr) {
+		return nil
+	}
+	res := make([]model.ValidationErrEntry, 0, len(valErr.Entries))
+	for _, e := range valErr.Entries {
+		res = append(res, model.NewValidationErr(field+"."+e.Field, e.Errors))
+	}
+	return res
+}
+
+func (c *Controller) HandlePutPassword(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	pc, ok := decodeAndValidate[model.PasswordChange](w, r, nil)
+	if !ok {
+		return
+	}
+	ip, key := clientIP(r), userKey(r)
+	a, ok := c.beginAttempt(w, ip, key)
+	if !ok {
+		return
+	}
+	defer a.Release()
+	err := c.svc.ChangePassword(ctx, pc)
 	if err != nil {
-		logger.Log.Error("io.ReadAll", zap.Error(err))
-		w.WriteHeader(http.StatusBadRequest)
-		return model.AuthUser{}, false
+		if isAuthFailure(err) {
+			c.authFailed(ctx, a, ip, key)
+		}
+		writeAuthError(w, "svc.ChangePassword", err)
+		return
+	}
+	a.Succeed()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (c *Controller) HandleDeleteUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	d, ok := decodeAndValidate[model.UserDeletion](w, r, nil)
+	if !ok {
+		return
 	}
-	var u model.AuthUser
-	if err = json.Unmarshal(body, &u); err != nil {
-		logger.Log.Error("json.Unmarshal", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
-		return model.AuthUser{}, false
+	ip, key := clientIP(r), userKey(r)
+	a, ok := c.beginAttempt(w, ip, key)
+	if !ok {
+		return
 	}
-	valResp, err := validateUser(u)
+	defer a.Release()
+	err := c.svc.DeleteUser(ctx, d)
 	if err != nil {
-		logger.Log.Debug("u is not valid", zap.Error(err))
-		bytes, err := json.Marshal(valResp)
-		if err != nil {
-			logger.Log.Error("validation json.Marshal", zap.Error(err))
-			w.WriteHeader(http.StatusInternalServerError)
-			return model.AuthUser{}, false
+		if isAuthFailure(err) {
+			c.authFailed(ctx, a, ip, key)
 		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write(bytes)
-		return model.AuthUser{}, false
+		writeAuthError(w, "svc.DeleteUser", err)
+		return
 	}
-	return u, true
+	a.Succeed()
+	w.WriteHeader(http.StatusAccepted)
 }
 
-var ErrUserValidation = errors.New("user validation error")
+func (c *Controller) HandlePostPrelogin(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	req, ok := decodeAndValidate[model.PreloginRequest](w, r, nil)
+	if !ok {
+		return
+	}
+	res, err := c.svc.Prelogin(ctx, req)
+	if err != nil {
+		writeServiceError(w, "svc.Prelogin", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
 
-func validateUser(user model.AuthUser) ([]ValidationErrEntry, error) {
-	login := strings.Trim(user.Login, " ")
-	isEV := len(login) > 0
-	pswd := strings.Trim(user.Password, " ")
-	isPV := len(pswd) > 0
-	var valErrors = make([]ValidationErrEntry, 0)
-	if !isEV {
-		validationErr := NewValidationErr("login", []string{"login is not valid"})
-		valErrors = append(valErrors, validationErr)
+func (c *Controller) HandlePostRecovery(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	req, ok := decodeAndValidate[model.RecoveryRequest](w, r, nil)
+	if !ok {
+		return
 	}
-	if !isPV {
-		validationErr := NewValidationErr("password", []string{"password is not valid"})
-		valErrors = append(valErrors, validationErr)
+	ip := clientIP(r)
+	a, ok := c.beginAttempt(w, ip, req.Login)
+	if !ok {
+		return
 	}
-	if len(valErrors) == 0 {
-		return nil, nil
+	defer a.Release()
+	kit, err := c.svc.FindRecoveryKit(ctx, req)
+	if err != nil {
+		if isAuthFailure(err) {
+			c.authFailed(ctx, a, ip, req.Login)
+		}
+		writeAuthError(w, "svc.FindRecoveryKit", err)
+		return
 	}
+	writeJSON(w, http.StatusOK, kit)
+}
 
-	return valErrors, ErrUserValidation
+func (c *Controller) HandlePostRecoveryReset(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	req, ok := decodeAndValidate[model.PasswordReset](w, r, nil)
+	if !ok {
+		return
+	}
+	ip := clientIP(r)
+	a, ok := c.beginAttempt(w, ip, req.Login)
+	if !ok {
+		return
+	}
+	defer a.Release()
+	usr, err := c.svc.ResetPassword(ctx, req)
+	if err != nil {
+		if isAuthFailure(err) {
+			c.authFailed(ctx, a, ip, req.Login)
+		}
+		writeAuthError(w, "svc.ResetPassword", err)
+		return
+	}
+	a.Succeed()
+	token, err := auth.GenerateToken(usr.ID)
+	if err != nil {
+		logger.Log.Error("auth.GenerateToken", zap.Error(err))
+		writeInternalError(w)
+		return
+	}
+	w.Header().Set(AuthorizationHeaderName, token)
+	writeJSON(w, http.StatusOK, usr)
+}
+
+// writeAuthError answers a wrong password, recovery key or login the
+// same way.
+func writeAuthError(w http.ResponseWriter, op string, err error) {
+	if isAuthFailure(err) {
+		logger.Log.Debug(op, zap.Error(err))
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "invalid login or password")
+		return
+	}
+	writeServiceError(w, op, err)
 }
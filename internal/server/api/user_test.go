@@ -0,0 +1,139 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/denis-oreshkevich/gophkeeper/internal/server/repo"
+	"github.com/denis-oreshkevich/gophkeeper/internal/server/service"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// userRepo knows the users by login, the other methods of the repository
+// are not expected to be called.
+type userRepo struct {
+	repo.ServerRepository
+	users map[string]model.User
+}
+
+func (r userRepo) FindUserByLogin(_ context.Context, login string) (model.User, error) {
+	u, ok := r.users[login]
+	if !ok {
+		return model.User{}, repo.ErrItemNotFound
+	}
+	return u, nil
+}
+
+func newTestController(t *testing.T) *Controller {
+	t.Helper()
+	r := userRepo{users: map[string]model.User{
+		"legacy": {ID: "1", Login: "legacy"},
+		"alice":  {ID: "2", Login: "alice", Vault: &model.VaultKey{}},
+	}}
+	svc, err := service.NewServerService(r, bcrypt.MinCost, nil)
+	require.NoError(t, err)
+	return NewController(svc, nil)
+}
+
+func TestHandlers(t *testing.T) {
+	c := newTestController(t)
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		body       string
+		wantStatus int
+		wantCode   string
+		wantFields []string
+		wantBody   string
+	}{
+		{
+			name:       "prelogin",
+			handler:    c.HandlePostPrelogin,
+			body:       `{"login":"alice"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"login_secret":true}`,
+		},
+		{
+			name:       "prelogin of a legacy account",
+			handler:    c.HandlePostPrelogin,
+			body:       `{"login":"legacy"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"login_secret":false}`,
+		},
+		{
+			name:       "prelogin of an unknown login",
+			handler:    c.HandlePostPrelogin,
+			body:       `{"login":"nobody"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"login_secret":true}`,
+		},
+		{
+			name:       "service validation",
+			handler:    c.HandlePostPrelogin,
+			body:       `{"login":" "}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   CodeValidation,
+			wantFields: []string{"login"},
+		},
+		{
+			name:       "handler validation",
+			handler:    c.HandleRegisterUser,
+			body:       `{"login":"","password":""}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   CodeValidation,
+			wantFields: []string{"login", "password"},
+		},
+		{
+			name:       "oversized body",
+			handler:    c.HandlePostPrelogin,
+			body:       `{"login":"` + strings.Repeat("a", int(DefaultLimits.Auth)) + `"}`,
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantCode:   CodeBodyTooLarge,
+		},
+		{
+			name:       "unknown field",
+			handler:    c.HandleLoginUser,
+			body:       `{"login":"alice","password":"secret","admin":true}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   CodeInvalidJSON,
+			wantFields: []string{"admin"},
+		},
+		{
+			name:       "trailing data",
+			handler:    c.HandlePostPrelogin,
+			body:       `{"login":"alice"}{"login":"legacy"}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   CodeInvalidJSON,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			BodyLimit(DefaultLimits.Auth)(tt.handler).ServeHTTP(w, r)
+			require.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+			if tt.wantBody != "" {
+				assert.JSONEq(t, tt.wantBody, w.Body.String())
+				return
+			}
+			var res ErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+			assert.Equal(t, tt.wantCode, res.Code)
+			fields := make([]string, 0, len(res.FieldErrors))
+			for _, f := range res.FieldErrors {
+				fields = append(fields, f.Field)
+			}
+			if len(tt.wantFields) == 0 {
+				assert.Empty(t, fields)
+			} else {
+				assert.Equal(t, tt.wantFields, fields)
+			}
+		})
+	}
+}
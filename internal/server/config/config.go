@@ -0,0 +1,359 @@
+// Package config loads the server configuration. Defaults are overridden
+// by the YAML file, then by environment variables, then by flags.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/caarlos0/env/v6"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/auth"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/tracing"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// FileEnv names the file to load when there is no -config flag.
+const FileEnv = "CONFIG"
+
+// minJWTKeyLength is the shortest HS256 key accepted.
+const minJWTKeyLength = 32
+
+type Config struct {
+	Address         string        `yaml:"address" env:"RUN_ADDRESS"`
+	AdminAddress    string        `yaml:"admin_address" env:"ADMIN_ADDRESS"`
+	LogLevel        string        `yaml:"log_level" env:"LOG_LEVEL"`
+	ShutdownDelay   time.Duration `yaml:"shutdown_delay" env:"SHUTDOWN_DELAY"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+
+	TLS     TLS     `yaml:"tls"`
+	DB      DB      `yaml:"db"`
+	Auth    Auth    `yaml:"auth"`
+	Limits  Limits  `yaml:"limits"`
+	Tracing Tracing `yaml:"tracing"`
+}
+
+type TLS struct {
+	Cert  string   `yaml:"cert" env:"TLS_CERT"`
+	Key   string   `yaml:"key" env:"TLS_KEY"`
+	CA    string   `yaml:"ca" env:"TLS_CA"`
+	CAKey string   `yaml:"ca_key" env:"TLS_CA_KEY"`
+	CRL   string   `yaml:"crl" env:"TLS_CRL"`
+	Hosts []string `yaml:"hosts" env:"TLS_HOSTS" envSeparator:","`
+	// MTLS signs device certificates of registering clients and accepts
+	// them instead of tokens.
+	MTLS bool `yaml:"mtls" env:"MTLS"`
+}
+
+type DB struct {
+	DSN             string        `yaml:"dsn" env:"DATABASE_URI"`
+	MaxConns        int32         `yaml:"max_conns" env:"DB_MAX_CONNS"`
+	MinConns        int32         `yaml:"min_conns" env:"DB_MIN_CONNS"`
+	MaxConnLifetime time.Duration `yaml:"max_conn_lifetime" env:"DB_MAX_CONN_LIFETIME"`
+	MaxConnIdleTime time.Duration `yaml:"max_conn_idle_time" env:"DB_MAX_CONN_IDLE_TIME"`
+}
+
+type Auth struct {
+	// JWTKeys sign and verify tokens. The first one signs, the others only
+	// verify, so a key can be rotated out. At least one is required.
+	JWTKeys    []string `yaml:"jwt_keys" env:"JWT_KEYS" envSeparator:","`
+	BcryptCost int      `yaml:"bcrypt_cost" env:"BCRYPT_COST"`
+}
+
+// Limits are the rate limits of password attempts and the request size
+// quotas.
+type Limits struct {
+	LockoutAttempts   int           `yaml:"lockout_attempts" env:"LOCKOUT_ATTEMPTS"`
+	IPLockoutAttempts int           `yaml:"ip_lockout_attempts" env:"IP_LOCKOUT_ATTEMPTS"`
+	LockoutDuration   time.Duration `yaml:"lockout_duration" env:"LOCKOUT_DURATION"`
+	LoginBackoff      time.Duration `yaml:"login_backoff" env:"LOGIN_BACKOFF"`
+
+	AuthBody    int64 `yaml:"auth_body" env:"AUTH_BODY_LIMIT"`
+	DefaultBody int64 `yaml:"default_body" env:"DEFAULT_BODY_LIMIT"`
+	SyncBody    int64 `yaml:"sync_body" env:"SYNC_BODY_LIMIT"`
+	BinaryBody  int64 `yaml:"binary_body" env:"BINARY_BODY_LIMIT"`
+}
+
+type Tracing struct {
+	Exporter string `yaml:"exporter" env:"TRACE_EXPORTER"`
+	Endpoint string `yaml:"endpoint" env:"OTLP_ENDPOINT"`
+	Insecure bool   `yaml:"insecure" env:"OTLP_INSECURE"`
+}
+
+// Default is the configuration without file, env and flags. There is no
+// default database, the DSN has to be given.
+func Default() Config {
+	return Config{
+		Address:         "127.0.0.1:8081",
+		AdminAddress:    "127.0.0.1:9091",
+		LogLevel:        "info",
+		ShutdownDelay:   5 * time.Second,
+		ShutdownTimeout: 30 * time.Second,
+		TLS: TLS{
+			Cert:  "certs/cert.pem",
+			Key:   "certs/key.pem",
+			CA:    "certs/ca.pem",
+			CAKey: "certs/ca-key.pem",
+			CRL:   "certs/crl.pem",
+			Hosts: []string{"localhost", "127.0.0.1", "::1"},
+		},
+		DB: DB{
+			MaxConns:        10,
+			MinConns:        0,
+			MaxConnLifetime: time.Hour,
+			MaxConnIdleTime: 30 * time.Minute,
+		},
+		Auth: Auth{
+			BcryptCost: 12,
+		},
+		Limits: Limits{
+			LockoutAttempts:   5,
+			IPLockoutAttempts: 20,
+			LockoutDuration:   15 * time.Minute,
+			LoginBackoff:      time.Second,
+			AuthBody:          16 << 10,
+			DefaultBody:       1 << 20,
+			SyncBody:          32 << 20,
+			BinaryBody:        64 << 20,
+		},
+		Tracing: Tracing{
+			Exporter: tracing.ExporterNone,
+			Endpoint: "localhost:4318",
+		},
+	}
+}
+
+// Load builds the configuration from the defaults, the file of the -config
+// flag or of $CONFIG, the environment and args, and validates it.
+func Load(args []string) (Config, error) {
+	conf := Default()
+
+	path := filePath(args)
+	if path != "" {
+		err := conf.readFile(path)
+		if err != nil {
+			return Config{}, fmt.Errorf("conf.readFile: %w", err)
+		}
+	}
+	err := env.Parse(&conf)
+	if err != nil {
+		return Config{}, fmt.Errorf("env.Parse: %w", err)
+	}
+
+	// flags are registered with the values so far, only the given ones
+	// change them
+	set := flag.NewFlagSet("server", flag.ContinueOnError)
+	set.String("config", path, "YAML configuration file, $"+FileEnv+" by default")
+	conf.flags(set)
+	err = set.Parse(args)
+	if err != nil {
+		return Config{}, fmt.Errorf("set.Parse: %w", err)
+	}
+
+	err = conf.Validate()
+	if err != nil {
+		return Config{}, err
+	}
+	return conf, nil
+}
+
+// filePath finds the config file before the flags are parsed, the file
+// comes before them.
+func filePath(args []string) string {
+	for i, arg := range args {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "config" {
+			continue
+		}
+		if hasValue {
+			return value
+		}
+		if i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return os.Getenv(FileEnv)
+}
+
+func (c *Config) readFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("os.Open: %w", err)
+	}
+	defer f.Close()
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	err = dec.Decode(c)
+	if err != nil {
+		return fmt.Errorf("decode %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Config) flags(set *flag.FlagSet) {
+	set.StringVar(&c.Address, "a", c.Address, "HTTP server address")
+	set.StringVar(&c.AdminAddress, "admin-address", c.AdminAddress,
+		"Admin HTTP address serving /metrics, empty disables it")
+	set.StringVar(&c.LogLevel, "log-level", c.LogLevel, "Log level: debug, info, warn or error")
+	set.DurationVar(&c.ShutdownDelay, "shutdown-delay", c.ShutdownDelay,
+		"Time /readyz fails before the server stops taking requests on shutdown")
+	set.DurationVar(&c.ShutdownTimeout, "shutdown-timeout", c.ShutdownTimeout,
+		"Time in-flight requests get to finish on shutdown")
+
+	set.StringVar(&c.TLS.Cert, "tls-cert", c.TLS.Cert, "Server certificate, issued by the local CA if missing")
+	set.StringVar(&c.TLS.Key, "tls-key", c.TLS.Key, "Server private key")
+	set.StringVar(&c.TLS.CA, "tls-ca", c.TLS.CA,
+		"Local CA certificate, created if missing, clients can verify the server with it")
+	set.StringVar(&c.TLS.CAKey, "tls-ca-key", c.TLS.CAKey, "Local CA private key")
+	set.StringVar(&c.TLS.CRL, "tls-crl", c.TLS.CRL, "CRL of the revoked device certificates")
+	listVar(set, &c.TLS.Hosts, "tls-hosts",
+		"Comma-separated DNS names and IPs the generated server certificate is valid for")
+	set.BoolVar(&c.TLS.MTLS, "mtls", c.TLS.MTLS,
+		"Sign device certificates of registering clients and accept them instead of tokens")
+
+	set.StringVar(&c.DB.DSN, "d", c.DB.DSN, "DataBase URI")
+	set.Func("db-max-conns", fmt.Sprintf("Maximum connections of the pool (default %d)", c.DB.MaxConns),
+		func(s string) error { return parseInt32(s, &c.DB.MaxConns) })
+	set.Func("db-min-conns", fmt.Sprintf("Connections the pool keeps open (default %d)", c.DB.MinConns),
+		func(s string) error { return parseInt32(s, &c.DB.MinConns) })
+	set.DurationVar(&c.DB.MaxConnLifetime, "db-max-conn-lifetime", c.DB.MaxConnLifetime,
+		"Connections are closed after")
+	set.DurationVar(&c.DB.MaxConnIdleTime, "db-max-conn-idle-time", c.DB.MaxConnIdleTime,
+		"Idle connections are closed after")
+
+	listVar(set, &c.Auth.JWTKeys, "jwt-keys",
+		"Comma-separated token keys, the first one signs")
+	set.IntVar(&c.Auth.BcryptCost, "bcrypt-cost", c.Auth.BcryptCost,
+		"Cost of password hashes, older hashes are replaced on login")
+
+	set.IntVar(&c.Limits.LockoutAttempts, "lockout-attempts", c.Limits.LockoutAttempts,
+		"Failed password attempts of a login before it is locked out")
+	set.IntVar(&c.Limits.IPLockoutAttempts, "ip-lockout-attempts", c.Limits.IPLockoutAttempts,
+		"Failed password attempts from an address before it is locked out")
+	set.DurationVar(&c.Limits.LockoutDuration, "lockout", c.Limits.LockoutDuration, "Lockout duration")
+	set.DurationVar(&c.Limits.LoginBackoff, "login-backoff", c.Limits.LoginBackoff,
+		"Wait after a failed password attempt, doubled with every further one")
+	set.Int64Var(&c.Limits.AuthBody, "auth-body-limit", c.Limits.AuthBody,
+		"Maximum body of the login, register and password requests in bytes")
+	set.Int64Var(&c.Limits.DefaultBody, "default-body-limit", c.Limits.DefaultBody,
+		"Maximum body of the other requests in bytes")
+	set.Int64Var(&c.Limits.SyncBody, "sync-body-limit", c.Limits.SyncBody,
+		"Maximum body of a sync request in bytes")
+	set.Int64Var(&c.Limits.BinaryBody, "binary-body-limit", c.Limits.BinaryBody,
+		"Maximum body of the binary, share and organization item requests in bytes")
+
+	set.StringVar(&c.Tracing.Exporter, "trace-exporter", c.Tracing.Exporter,
+		"Where spans go: none, stdout or otlp")
+	set.StringVar(&c.Tracing.Endpoint, "otlp-endpoint", c.Tracing.Endpoint,
+		"host:port of the OTLP/HTTP trace receiver")
+	set.BoolVar(&c.Tracing.Insecure, "otlp-insecure", c.Tracing.Insecure,
+		"Send spans to the OTLP receiver without TLS, as a local collector expects")
+}
+
+func listVar(set *flag.FlagSet, list *[]string, name, usage string) {
+	if len(*list) > 0 {
+		usage += fmt.Sprintf(" (default %s)", strings.Join(*list, ","))
+	}
+	set.Func(name, usage, func(s string) error {
+		*list = nil
+		for _, v := range strings.Split(s, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				*list = append(*list, v)
+			}
+		}
+		return nil
+	})
+}
+
+func parseInt32(s string, v *int32) error {
+	var n int32
+	_, err := fmt.Sscan(s, &n)
+	if err != nil {
+		return fmt.Errorf("fmt.Sscan: %w", err)
+	}
+	*v = n
+	return nil
+}
+
+// Validate reports every setting that is missing or out of range.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Address != "", "address is required")
+	_, err := zap.ParseAtomicLevel(c.LogLevel)
+	check(err == nil, "log level %q is not valid", c.LogLevel)
+	check(c.ShutdownDelay >= 0, "shutdown delay must not be negative")
+	check(c.ShutdownTimeout > 0, "shutdown timeout must be positive")
+
+	check(c.TLS.Cert != "" && c.TLS.Key != "", "tls cert and key are required")
+	check(c.TLS.CA != "" && c.TLS.CAKey != "", "tls ca and ca key are required")
+	check(len(c.TLS.Hosts) > 0, "tls hosts are required")
+
+	check(c.DB.DSN != "", "database dsn is required, set -d, DATABASE_URI or db.dsn")
+	check(c.DB.MaxConns > 0, "db max conns must be positive")
+	check(c.DB.MinConns >= 0 && c.DB.MinConns <= c.DB.MaxConns,
+		"db min conns must be between 0 and max conns")
+	check(c.DB.MaxConnLifetime > 0 && c.DB.MaxConnIdleTime > 0,
+		"db connection lifetime and idle time must be positive")
+
+	check(len(c.Auth.JWTKeys) > 0, "jwt keys are required, set -jwt-keys, JWT_KEYS or auth.jwt_keys")
+	for i, k := range c.Auth.JWTKeys {
+		check(len(k) >= minJWTKeyLength, "jwt key %d is shorter than %d bytes", i+1, minJWTKeyLength)
+	}
+	if err := auth.CheckPasswordCost(c.Auth.BcryptCost); err != nil {
+		errs = append(errs, err)
+	}
+
+	check(c.Limits.LockoutAttempts > 0 && c.Limits.IPLockoutAttempts > 0,
+		"lockout attempts must be positive")
+	check(c.Limits.LockoutDuration > 0, "lockout duration must be positive")
+	check(c.Limits.LoginBackoff >= 0, "login backoff must not be negative")
+	check(c.Limits.AuthBody > 0 && c.Limits.DefaultBody > 0 && c.Limits.SyncBody > 0 &&
+		c.Limits.BinaryBody > 0, "body limits must be positive")
+
+	switch c.Tracing.Exporter {
+	case tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP:
+	default:
+		errs = append(errs, fmt.Errorf("trace exporter %q is not valid", c.Tracing.Exporter))
+	}
+
+	return errors.Join(errs...)
+}
+
+const redacted = "[REDACTED]"
+
+// String formats the config with the DSN password and the keys redacted,
+// so it is safe to log.
+func (c Config) String() string {
+	c.DB.DSN = redactDSN(c.DB.DSN)
+	keys := make([]string, len(c.Auth.JWTKeys))
+	for i := range keys {
+		keys[i] = redacted
+	}
+	c.Auth.JWTKeys = keys
+	type plain Config
+	return fmt.Sprintf("%+v", plain(c))
+}
+
+// redactDSN hides the password of both key=value and URL connection strings.
+func redactDSN(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" {
+		return u.Redacted()
+	}
+	fields := strings.Fields(dsn)
+	for i, f := range fields {
+		if strings.HasPrefix(f, "password=") {
+			fields[i] = "password=" + redacted
+		}
+	}
+	return strings.Join(fields, " ")
+}
@@ -0,0 +1,197 @@
+package config
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const (
+	fileKey = "file-jwt-key-of-at-least-32-bytes"
+	envKey  = "env-jwt-key-of-at-least-32-bytes!"
+	flagKey = "flag-jwt-key-of-at-least-32-bytes"
+)
+
+// configEnv are the variables the tests set, they are cleared so the
+// environment of the test run doesn't leak in.
+var configEnv = []string{FileEnv, "RUN_ADDRESS", "LOG_LEVEL", "DATABASE_URI", "DB_MAX_CONNS",
+	"JWT_KEYS", "LOCKOUT_DURATION"}
+
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, name := range configEnv {
+		if v, ok := os.LookupEnv(name); ok {
+			require.NoError(t, os.Unsetenv(name))
+			t.Cleanup(func() { os.Setenv(name, v) })
+		}
+	}
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "server.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+const testFile = `
+address: file:8081
+log_level: warn
+db:
+  dsn: postgres://file
+  max_conns: 20
+auth:
+  jwt_keys: [` + fileKey + `]
+limits:
+  lockout_duration: 1m
+`
+
+func TestLoadPrecedence(t *testing.T) {
+	tests := []struct {
+		name string
+		// file is passed with -config, or with $CONFIG when fileEnv is set
+		file    string
+		fileEnv bool
+		env     map[string]string
+		args    []string
+		want    func(c *Config)
+	}{
+		{
+			name: "defaults",
+			args: []string{"-d", "postgres://flag", "-jwt-keys", flagKey},
+			want: func(c *Config) {
+				c.DB.DSN = "postgres://flag"
+				c.Auth.JWTKeys = []string{flagKey}
+			},
+		},
+		{
+			name: "file over defaults",
+			file: testFile,
+			want: func(c *Config) {
+				c.Address = "file:8081"
+				c.LogLevel = "warn"
+				c.DB.DSN = "postgres://file"
+				c.DB.MaxConns = 20
+				c.Auth.JWTKeys = []string{fileKey}
+				c.Limits.LockoutDuration = time.Minute
+			},
+		},
+		{
+			name:    "file from env",
+			file:    testFile,
+			fileEnv: true,
+			want: func(c *Config) {
+				c.Address = "file:8081"
+				c.LogLevel = "warn"
+				c.DB.DSN = "postgres://file"
+				c.DB.MaxConns = 20
+				c.Auth.JWTKeys = []string{fileKey}
+				c.Limits.LockoutDuration = time.Minute
+			},
+		},
+		{
+			name: "env over file",
+			file: testFile,
+			env: map[string]string{"RUN_ADDRESS": "env:8081", "DB_MAX_CONNS": "30",
+				"JWT_KEYS": envKey + "," + fileKey},
+			want: func(c *Config) {
+				c.Address = "env:8081"
+				c.LogLevel = "warn"
+				c.DB.DSN = "postgres://file"
+				c.DB.MaxConns = 30
+				c.Auth.JWTKeys = []string{envKey, fileKey}
+				c.Limits.LockoutDuration = time.Minute
+			},
+		},
+		{
+			name: "flags over env and file",
+			file: testFile,
+			env:  map[string]string{"RUN_ADDRESS": "env:8081", "LOG_LEVEL": "error", "JWT_KEYS": envKey},
+			args: []string{"-a", "flag:8081", "-db-max-conns", "40", "-jwt-keys", flagKey + ", " + envKey},
+			want: func(c *Config) {
+				c.Address = "flag:8081"
+				c.LogLevel = "error"
+				c.DB.DSN = "postgres://file"
+				c.DB.MaxConns = 40
+				c.Auth.JWTKeys = []string{flagKey, envKey}
+				c.Limits.LockoutDuration = time.Minute
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			args := tt.args
+			if tt.file != "" {
+				path := writeFile(t, tt.file)
+				if tt.fileEnv {
+					t.Setenv(FileEnv, path)
+				} else {
+					args = append([]string{"-config=" + path}, args...)
+				}
+			}
+
+			got, err := Load(args)
+			require.NoError(t, err)
+			want := Default()
+			tt.want(&want)
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		env     map[string]string
+		args    []string
+		wantErr string
+	}{
+		{name: "no jwt keys", args: []string{"-d", "postgres://flag"},
+			wantErr: "jwt keys are required"},
+		{name: "short jwt key", args: []string{"-d", "postgres://flag", "-jwt-keys", "short"},
+			wantErr: "jwt key 1 is shorter"},
+		{name: "no dsn", args: []string{"-jwt-keys", flagKey}, wantErr: "database dsn is required"},
+		{name: "unknown file field", file: "adress: x\n", args: []string{"-d", "x", "-jwt-keys", flagKey},
+			wantErr: "field adress not found"},
+		{name: "missing file", args: []string{"-config", "/nonexistent/server.yaml"},
+			wantErr: "os.Open"},
+		{name: "bad env", env: map[string]string{"DB_MAX_CONNS": "many"}, wantErr: "env.Parse"},
+		{name: "bad flag", args: []string{"-db-max-conns", "many"}, wantErr: "set.Parse"},
+		{name: "bad log level", args: []string{"-d", "x", "-jwt-keys", flagKey, "-log-level", "loud"},
+			wantErr: `log level "loud" is not valid`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"-config", writeFile(t, tt.file)}, args...)
+			}
+			_, err := Load(args)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestString(t *testing.T) {
+	c := Default()
+	c.Auth.JWTKeys = []string{flagKey}
+	for _, dsn := range []string{"postgres://user:hunter2@db/vault", "host=db user=u password=hunter2"} {
+		c.DB.DSN = dsn
+		s := c.String()
+		assert.NotContains(t, s, "hunter2")
+		assert.NotContains(t, s, flagKey)
+		assert.Contains(t, s, redacted)
+	}
+}
@@ -0,0 +1,222 @@
+// Package metrics holds the Prometheus metrics of the server. They are
+// served on the admin listener, apart from the API.
+package metrics
+
+import (
+	"context"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/logger"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const namespace = "gophkeeper"
+
+const unmatched = "unmatched"
+
+// storedBytesTimeout bounds the size query run on every scrape.
+const storedBytesTimeout = 5 * time.Second
+
+var registry = prometheus.NewRegistry()
+
+var (
+	requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route, method and status code.",
+	}, []string{"route", "method", "code"})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latencies by route and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	syncBatch = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "sync_batch_size",
+		Help:      "Records sent by clients in one sync by record type.",
+		Buckets:   []float64{0, 1, 5, 10, 50, 100, 500, 1000},
+	}, []string{"type"})
+
+	syncConflicts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sync_conflicts_total",
+		Help:      "Synced records skipped because the server has a newer version, by record type.",
+	}, []string{"type"})
+
+	authFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_failures_total",
+		Help:      "Wrong passwords and recovery keys by route.",
+	}, []string{"route"})
+
+	lockouts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "lockouts_total",
+		Help:      "Lockouts by scope, login or ip.",
+	}, []string{"scope"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		requests, requestDuration, syncBatch, syncConflicts, authFailures, lockouts,
+	)
+}
+
+// Handler serves the metrics in the Prometheus format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
+
+// Middleware counts requests and their latency by route pattern, so ids in
+// paths don't make new series. Unmatched routes count as one.
+func Middleware(next http.Handler) http.Handler {
+	f := func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		route := RequestRoute(r)
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		requests.WithLabelValues(route, r.Method, strconv.Itoa(status)).Inc()
+		requestDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+	}
+	return http.HandlerFunc(f)
+}
+
+// Route returns the route pattern the request of ctx matched.
+func Route(ctx context.Context) string {
+	rctx := chi.RouteContext(ctx)
+	if rctx == nil || rctx.RoutePattern() == "" {
+		return unmatched
+	}
+	return rctx.RoutePattern()
+}
+
+// RequestRoute returns the route pattern of a served request, also when a
+// middleware rejected it before routing.
+func RequestRoute(r *http.Request) string {
+	if route := Route(r.Context()); route != unmatched {
+		return route
+	}
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil || rctx.Routes == nil {
+		return unmatched
+	}
+	tctx := chi.NewRouteContext()
+	if !rctx.Routes.Match(tctx, r.Method, r.URL.Path) {
+		return unmatched
+	}
+	return tctx.RoutePattern()
+}
+
+// SyncBatch records the size of a sync batch of the record type.
+func SyncBatch(recordType string, size int) {
+	syncBatch.WithLabelValues(recordType).Observe(float64(size))
+}
+
+// SyncConflict counts a synced record the server had a newer version of.
+func SyncConflict(recordType string) {
+	syncConflicts.WithLabelValues(recordType).Inc()
+}
+
+func AuthFailure(route string) {
+	authFailures.WithLabelValues(route).Inc()
+}
+
+func Lockout(scope string) {
+	lockouts.WithLabelValues(scope).Inc()
+}
+
+// RegisterPool exposes the connection pool stats.
+func RegisterPool(pool func() *pgxpool.Stat) {
+	registry.MustRegister(&poolCollector{stat: pool})
+}
+
+// RegisterStoredBytes exposes the bytes stored per record type, stored is
+// asked on every scrape.
+func RegisterStoredBytes(stored func(ctx context.Context) (map[string]int64, error)) {
+	registry.MustRegister(&storedCollector{stored: stored})
+}
+
+var (
+	poolAcquired = prometheus.NewDesc(namespace+"_db_pool_acquired_conns",
+		"Connections in use.", nil, nil)
+	poolIdle = prometheus.NewDesc(namespace+"_db_pool_idle_conns",
+		"Idle connections.", nil, nil)
+	poolTotal = prometheus.NewDesc(namespace+"_db_pool_total_conns",
+		"Open connections.", nil, nil)
+	poolMax = prometheus.NewDesc(namespace+"_db_pool_max_conns",
+		"Maximum size of the pool.", nil, nil)
+	poolAcquires = prometheus.NewDesc(namespace+"_db_pool_acquires_total",
+		"Connections acquired from the pool.", nil, nil)
+	poolEmptyAcquires = prometheus.NewDesc(namespace+"_db_pool_empty_acquires_total",
+		"Acquires that had to wait for a connection.", nil, nil)
+	poolAcquireDuration = prometheus.NewDesc(namespace+"_db_pool_acquire_duration_seconds_total",
+		"Time spent acquiring connections.", nil, nil)
+
+	storedBytes = prometheus.NewDesc(namespace+"_stored_bytes",
+		"Bytes stored by record type, indexes included.", []string{"type"}, nil)
+)
+
+type poolCollector struct {
+	stat func() *pgxpool.Stat
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolAcquired
+	ch <- poolIdle
+	ch <- poolTotal
+	ch <- poolMax
+	ch <- poolAcquires
+	ch <- poolEmptyAcquires
+	ch <- poolAcquireDuration
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.stat()
+	ch <- prometheus.MustNewConstMetric(poolAcquired, prometheus.GaugeValue, float64(s.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(poolIdle, prometheus.GaugeValue, float64(s.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(poolTotal, prometheus.GaugeValue, float64(s.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(poolMax, prometheus.GaugeValue, float64(s.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(poolAcquires, prometheus.CounterValue, float64(s.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolEmptyAcquires, prometheus.CounterValue,
+		float64(s.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolAcquireDuration, prometheus.CounterValue,
+		s.AcquireDuration().Seconds())
+}
+
+type storedCollector struct {
+	stored func(ctx context.Context) (map[string]int64, error)
+}
+
+func (c *storedCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- storedBytes
+}
+
+func (c *storedCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), storedBytesTimeout)
+	defer cancel()
+	res, err := c.stored(ctx)
+	if err != nil {
+		logger.Log.Error("metrics stored bytes", zap.Error(err))
+		return
+	}
+	for recordType, n := range res {
+		ch <- prometheus.MustNewConstMetric(storedBytes, prometheus.GaugeValue, float64(n), recordType)
+	}
+}
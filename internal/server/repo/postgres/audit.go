@@ -0,0 +1,59 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"github.com/jackc/pgx/v5"
+)
+
+func (r *Repository) SaveAuditEvent(ctx context.Context, e model.AuditEvent) error {
+	query := `insert into keeper.audit(user_id, event, details, client_id, ip, request_id)
+	values (@user_id, @event, @details, @client_id, @ip, @request_id)`
+	args := pgx.NamedArgs{
+		"user_id":    nullable(e.UserID),
+		"event":      e.Event,
+		"details":    e.Details,
+		"client_id":  e.ClientID,
+		"ip":         e.IP,
+		"request_id": e.RequestID,
+	}
+	_, err := r.conn(ctx).Exec(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("db.Exec: %w", err)
+	}
+	return nil
+}
+
+// FindAuditEvents returns up to limit events of the user older than the
+// event with id before, newest first. Zero before starts from the newest.
+func (r *Repository) FindAuditEvents(ctx context.Context, userID string,
+	before int64, limit int) ([]model.AuditEvent, error) {
+	query := `select id, event, details, client_id, ip, request_id, created_tms
+	from keeper.audit where user_id = @user_id and (@before = 0 or id < @before)
+	order by id desc limit @limit`
+	args := pgx.NamedArgs{
+		"user_id": userID,
+		"before":  before,
+		"limit":   limit,
+	}
+	rows, err := r.conn(ctx).Query(ctx, query, args)
+	if err != nil {
+		return nil, fmt.Errorf("db.Query: %w", err)
+	}
+	defer rows.Close()
+	var res = make([]model.AuditEvent, 0)
+	for rows.Next() {
+		e := model.AuditEvent{UserID: userID}
+		err = rows.Scan(&e.ID, &e.Event, &e.Details, &e.ClientID, &e.IP,
+			&e.RequestID, &e.CreatedTms)
+		if err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+		res = append(res, e)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+	return res, nil
+}
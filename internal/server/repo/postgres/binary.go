@@ -22,7 +22,7 @@ func (r *Repository) SaveBinary(ctx context.Context, bin *model.Binary) error {
 		"status":       bin.Status,
 		"modified_tms": bin.ModifiedTms,
 	}
-	_, err := r.db.Exec(ctx, query, args)
+	_, err := r.conn(ctx).Exec(ctx, query, args)
 	if err != nil {
 		return fmt.Errorf("db.Exec: %w", err)
 	}
@@ -35,7 +35,7 @@ func (r *Repository) FindBinaryByID(ctx context.Context, id string) (*model.Bina
 	args := pgx.NamedArgs{
 		"id": id,
 	}
-	row := r.db.QueryRow(ctx, query, args)
+	row := r.conn(ctx).QueryRow(ctx, query, args)
 	var binary model.Binary
 	err := row.Scan(&binary.ID, &binary.Name, &binary.Data,
 		&binary.UserID, &binary.Status, &binary.ModifiedTms)
@@ -55,7 +55,7 @@ func (r *Repository) FindBinariesByUserID(ctx context.Context,
 	args := pgx.NamedArgs{
 		"user_id": userID,
 	}
-	rows, err := r.db.Query(ctx, query, args)
+	rows, err := r.conn(ctx).Query(ctx, query, args)
 	if err != nil {
 		return nil, fmt.Errorf("db.Query: %w", err)
 	}
@@ -85,7 +85,7 @@ func (r *Repository) FindActiveBinariesModifiedAfter(ctx context.Context, userID
 		"tms":     tms,
 		"status":  model.StatusActive,
 	}
-	rows, err := r.db.Query(ctx, query, args)
+	rows, err := r.conn(ctx).Query(ctx, query, args)
 	if err != nil {
 		return nil, fmt.Errorf("db.Query: %w", err)
 	}
@@ -115,7 +115,7 @@ func (r *Repository) FindDeletedBinariesModifiedAfter(ctx context.Context, userI
 		"tms":     tms,
 		"status":  model.StatusDeleted,
 	}
-	rows, err := r.db.Query(ctx, query, args)
+	rows, err := r.conn(ctx).Query(ctx, query, args)
 	if err != nil {
 		return nil, fmt.Errorf("db.Query: %w", err)
 	}
@@ -142,7 +142,7 @@ func (r *Repository) DeleteBinaryByID(ctx context.Context, id string) error {
 		"id":     id,
 		"status": model.StatusDeleted,
 	}
-	_, err := r.db.Exec(ctx, query, args)
+	_, err := r.conn(ctx).Exec(ctx, query, args)
 	if err != nil {
 		return fmt.Errorf("db.Exec: %w", err)
 	}
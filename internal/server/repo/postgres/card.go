@@ -10,36 +10,62 @@ import (
 	"time"
 )
 
+const cardColumns = `id, num, cvc, holder_name, exp_month, exp_year, pin, brand, 
+	billing_line1, billing_line2, billing_city, billing_region, billing_postal_code, 
+	billing_country, user_id, status, modified_tms`
+
+func scanCard(row pgx.Row, c *model.Card) error {
+	addr := &c.BillingAddress
+	return row.Scan(&c.ID, &c.Num, &c.CVC, &c.HolderName, &c.ExpMonth, &c.ExpYear,
+		&c.PIN, &c.Brand, &addr.Line1, &addr.Line2, &addr.City, &addr.Region,
+		&addr.PostalCode, &addr.Country, &c.UserID, &c.Status, &c.ModifiedTms)
+}
+
 func (r *Repository) SaveCard(ctx context.Context, card model.Card) error {
-	query := `insert into keeper.card(id, num, cvc, holder_name, user_id, status, modified_tms) 
-	values (@id, @num, @cvc, @holder_name, @user_id, @status, @modified_tms) 
+	query := `insert into keeper.card(` + cardColumns + `) 
+	values (@id, @num, @cvc, @holder_name, @exp_month, @exp_year, @pin, @brand, 
+	@billing_line1, @billing_line2, @billing_city, @billing_region, @billing_postal_code, 
+	@billing_country, @user_id, @status, @modified_tms) 
 	on conflict (id) do update set num = @num, cvc = @cvc, holder_name = @holder_name, 
+	exp_month = @exp_month, exp_year = @exp_year, pin = @pin, brand = @brand, 
+	billing_line1 = @billing_line1, billing_line2 = @billing_line2, 
+	billing_city = @billing_city, billing_region = @billing_region, 
+	billing_postal_code = @billing_postal_code, billing_country = @billing_country, 
 	status = @status, modified_tms = @modified_tms`
+	addr := card.BillingAddress
 	args := pgx.NamedArgs{
-		"id":           card.ID,
-		"num":          card.Num,
-		"cvc":          card.CVC,
-		"holder_name":  card.HolderName,
-		"user_id":      card.UserID,
-		"status":       card.Status,
-		"modified_tms": card.ModifiedTms,
+		"id":                  card.ID,
+		"num":                 card.Num,
+		"cvc":                 card.CVC,
+		"holder_name":         card.HolderName,
+		"exp_month":           card.ExpMonth,
+		"exp_year":            card.ExpYear,
+		"pin":                 card.PIN,
+		"brand":               card.Brand,
+		"billing_line1":       addr.Line1,
+		"billing_line2":       addr.Line2,
+		"billing_city":        addr.City,
+		"billing_region":      addr.Region,
+		"billing_postal_code": addr.PostalCode,
+		"billing_country":     addr.Country,
+		"user_id":             card.UserID,
+		"status":              card.Status,
+		"modified_tms":        card.ModifiedTms,
 	}
-	_, err := r.db.Exec(ctx, query, args)
+	_, err := r.conn(ctx).Exec(ctx, query, args)
 	if err != nil {
 		return fmt.Errorf("db.Exec: %w", err)
 	}
 	return nil
 }
 func (r *Repository) FindCardByID(ctx context.Context, id string) (model.Card, error) {
-	query := `select id, num, cvc, holder_name, user_id, status, modified_tms 
-	from keeper.card where id=@id`
+	query := `select ` + cardColumns + ` from keeper.card where id=@id`
 	args := pgx.NamedArgs{
 		"id": id,
 	}
-	row := r.db.QueryRow(ctx, query, args)
+	row := r.conn(ctx).QueryRow(ctx, query, args)
 	var card model.Card
-	err := row.Scan(&card.ID, &card.Num,
-		&card.CVC, &card.HolderName, &card.UserID, &card.Status, &card.ModifiedTms)
+	err := scanCard(row, &card)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return model.Card{}, repo.ErrItemNotFound
@@ -49,12 +75,11 @@ func (r *Repository) FindCardByID(ctx context.Context, id string) (model.Card, e
 	return card, nil
 }
 func (r *Repository) FindCardsByUserID(ctx context.Context, userID string) ([]model.Card, error) {
-	query := `select id, num, cvc, holder_name, user_id, status, modified_tms 
-	from keeper.card where user_id=@user_id`
+	query := `select ` + cardColumns + ` from keeper.card where user_id=@user_id`
 	args := pgx.NamedArgs{
 		"user_id": userID,
 	}
-	rows, err := r.db.Query(ctx, query, args)
+	rows, err := r.conn(ctx).Query(ctx, query, args)
 	if err != nil {
 		return nil, fmt.Errorf("db.Query: %w", err)
 	}
@@ -62,10 +87,9 @@ func (r *Repository) FindCardsByUserID(ctx context.Context, userID string) ([]mo
 	var res = make([]model.Card, 0)
 	for rows.Next() {
 		var c model.Card
-		errScan := rows.Scan(&c.ID, &c.Num,
-			&c.CVC, &c.HolderName, &c.UserID, &c.Status, &c.ModifiedTms)
+		errScan := scanCard(rows, &c)
 		if errScan != nil {
-			return nil, fmt.Errorf("cannot scan value. %w", err)
+			return nil, fmt.Errorf("cannot scan value. %w", errScan)
 		}
 		res = append(res, c)
 	}
@@ -78,13 +102,13 @@ func (r *Repository) FindCardsByUserID(ctx context.Context, userID string) ([]mo
 
 func (r *Repository) FindCardsModifiedAfter(ctx context.Context, userID string,
 	tms time.Time) ([]model.Card, error) {
-	query := `select id, num, cvc, holder_name, user_id, status, modified_tms 
+	query := `select ` + cardColumns + ` 
 	from keeper.card where user_id=@user_id and modified_tms > @tms`
 	args := pgx.NamedArgs{
 		"user_id": userID,
 		"tms":     tms,
 	}
-	rows, err := r.db.Query(ctx, query, args)
+	rows, err := r.conn(ctx).Query(ctx, query, args)
 	if err != nil {
 		return nil, fmt.Errorf("db.Query: %w", err)
 	}
@@ -92,10 +116,9 @@ func (r *Repository) FindCardsModifiedAfter(ctx context.Context, userID string,
 	var res = make([]model.Card, 0)
 	for rows.Next() {
 		var c model.Card
-		errScan := rows.Scan(&c.ID, &c.Num,
-			&c.CVC, &c.HolderName, &c.UserID, &c.Status, &c.ModifiedTms)
+		errScan := scanCard(rows, &c)
 		if errScan != nil {
-			return nil, fmt.Errorf("cannot scan value. %w", err)
+			return nil, fmt.Errorf("cannot scan value. %w", errScan)
 		}
 		res = append(res, c)
 	}
@@ -112,7 +135,7 @@ func (r *Repository) DeleteCardByID(ctx context.Context, id string) error {
 		"id":     id,
 		"status": model.StatusDeleted,
 	}
-	_, err := r.db.Exec(ctx, query, args)
+	_, err := r.conn(ctx).Exec(ctx, query, args)
 	if err != nil {
 		return fmt.Errorf("db.Exec: %w", err)
 	}
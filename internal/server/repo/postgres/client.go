@@ -10,15 +10,20 @@ import (
 	"time"
 )
 
+const clientColumns = `id, user_id, sync_tms, coalesce(cert_fingerprint, ''),
+	coalesce(cert_serial, ''), revoked_tms`
+
 func (r *Repository) CreateClient(ctx context.Context, client model.Client) (model.Client, error) {
-	query := `insert into keeper.client(id, user_id, sync_tms) 
-	values (@id, @user_id, @sync_tms) returning client.sync_tms`
+	query := `insert into keeper.client(id, user_id, sync_tms, cert_fingerprint, cert_serial) 
+	values (@id, @user_id, @sync_tms, @cert_fingerprint, @cert_serial) returning client.sync_tms`
 	args := pgx.NamedArgs{
-		"id":       client.ID,
-		"user_id":  client.UserID,
-		"sync_tms": client.SyncTms,
+		"id":               client.ID,
+		"user_id":          client.UserID,
+		"sync_tms":         client.SyncTms,
+		"cert_fingerprint": nullable(client.CertFingerprint),
+		"cert_serial":      nullable(client.CertSerial),
 	}
-	_, err := r.db.Exec(ctx, query, args)
+	_, err := r.conn(ctx).Exec(ctx, query, args)
 	if err != nil {
 		return model.Client{}, fmt.Errorf("db.Exec: %w", err)
 	}
@@ -32,20 +37,33 @@ func (r *Repository) UpdateClientLastSyncTmsByID(ctx context.Context,
 		"sync_tms": syncTms,
 		"id":       id,
 	}
-	_, err := r.db.Exec(ctx, query, args)
+	_, err := r.conn(ctx).Exec(ctx, query, args)
 	if err != nil {
 		return fmt.Errorf("db.Exec: %w", err)
 	}
 	return nil
 }
 func (r *Repository) FindClientByID(ctx context.Context, id string) (model.Client, error) {
-	query := `select id, user_id, sync_tms from keeper.client where id=@id`
+	query := `select ` + clientColumns + ` from keeper.client where id=@id`
 	args := pgx.NamedArgs{
 		"id": id,
 	}
-	row := r.db.QueryRow(ctx, query, args)
+	return r.findClient(ctx, query, args)
+}
+
+func (r *Repository) FindClientByFingerprint(ctx context.Context, fingerprint string) (model.Client, error) {
+	query := `select ` + clientColumns + ` from keeper.client where cert_fingerprint=@fingerprint`
+	args := pgx.NamedArgs{
+		"fingerprint": fingerprint,
+	}
+	return r.findClient(ctx, query, args)
+}
+
+func (r *Repository) findClient(ctx context.Context, query string, args pgx.NamedArgs) (model.Client, error) {
+	row := r.conn(ctx).QueryRow(ctx, query, args)
 	var client model.Client
-	err := row.Scan(&client.ID, &client.UserID, &client.SyncTms)
+	err := row.Scan(&client.ID, &client.UserID, &client.SyncTms, &client.CertFingerprint,
+		&client.CertSerial, &client.RevokedTms)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return model.Client{}, repo.ErrItemNotFound
@@ -54,12 +72,25 @@ func (r *Repository) FindClientByID(ctx context.Context, id string) (model.Clien
 	}
 	return client, nil
 }
+
 func (r *Repository) FindClientsByUserID(ctx context.Context, userID string) ([]model.Client, error) {
-	query := `select id, user_id, sync_tms from keeper.client where user_id=@user_id`
+	query := `select ` + clientColumns + ` from keeper.client where user_id=@user_id order by sync_tms desc`
 	args := pgx.NamedArgs{
 		"user_id": userID,
 	}
-	rows, err := r.db.Query(ctx, query, args)
+	return r.findClients(ctx, query, args)
+}
+
+// FindRevokedClients returns the revoked clients with certificates of all
+// users, they make up the CRL.
+func (r *Repository) FindRevokedClients(ctx context.Context) ([]model.Client, error) {
+	query := `select ` + clientColumns + ` from keeper.client
+	where revoked_tms is not null and cert_serial is not null`
+	return r.findClients(ctx, query, pgx.NamedArgs{})
+}
+
+func (r *Repository) findClients(ctx context.Context, query string, args pgx.NamedArgs) ([]model.Client, error) {
+	rows, err := r.conn(ctx).Query(ctx, query, args)
 	if err != nil {
 		return nil, fmt.Errorf("db.Query: %w", err)
 	}
@@ -67,9 +98,10 @@ func (r *Repository) FindClientsByUserID(ctx context.Context, userID string) ([]
 	var res = make([]model.Client, 0)
 	for rows.Next() {
 		var c model.Client
-		errScan := rows.Scan(&c.ID, &c.UserID, &c.SyncTms)
+		errScan := rows.Scan(&c.ID, &c.UserID, &c.SyncTms, &c.CertFingerprint,
+			&c.CertSerial, &c.RevokedTms)
 		if errScan != nil {
-			return nil, fmt.Errorf("cannot scan value. %w", err)
+			return nil, fmt.Errorf("rows.Scan: %w", errScan)
 		}
 		res = append(res, c)
 	}
@@ -79,3 +111,15 @@ func (r *Repository) FindClientsByUserID(ctx context.Context, userID string) ([]
 	}
 	return res, nil
 }
+
+// RevokeClient marks the client of the user revoked, revoking twice keeps
+// the first time.
+func (r *Repository) RevokeClient(ctx context.Context, id, userID string) (model.Client, error) {
+	query := `update keeper.client set revoked_tms = coalesce(revoked_tms, (now() at time zone 'utc'))
+	where id = @id and user_id = @user_id returning ` + clientColumns
+	args := pgx.NamedArgs{
+		"id":      id,
+		"user_id": userID,
+	}
+	return r.findClient(ctx, query, args)
+}
@@ -23,7 +23,7 @@ func (r *Repository) SaveCredentials(ctx context.Context, cred model.Credentials
 		"status":       cred.Status,
 		"modified_tms": cred.ModifiedTms,
 	}
-	_, err := r.db.Exec(ctx, query, args)
+	_, err := r.conn(ctx).Exec(ctx, query, args)
 	if err != nil {
 		return fmt.Errorf("db.Exec: %w", err)
 	}
@@ -35,7 +35,7 @@ func (r *Repository) FindCredentialsByID(ctx context.Context, id string) (model.
 	args := pgx.NamedArgs{
 		"id": id,
 	}
-	row := r.db.QueryRow(ctx, query, args)
+	row := r.conn(ctx).QueryRow(ctx, query, args)
 	var cred model.Credentials
 	err := row.Scan(&cred.ID, &cred.Login, &cred.Password, &cred.UserID,
 		&cred.Status, &cred.ModifiedTms)
@@ -55,7 +55,7 @@ func (r *Repository) FindCredentialsByUserID(ctx context.Context,
 	args := pgx.NamedArgs{
 		"user_id": userID,
 	}
-	rows, err := r.db.Query(ctx, query, args)
+	rows, err := r.conn(ctx).Query(ctx, query, args)
 	if err != nil {
 		return nil, fmt.Errorf("db.Query: %w", err)
 	}
@@ -84,7 +84,7 @@ func (r *Repository) FindCredentialsModifiedAfter(ctx context.Context, userID st
 		"user_id": userID,
 		"tms":     tms,
 	}
-	rows, err := r.db.Query(ctx, query, args)
+	rows, err := r.conn(ctx).Query(ctx, query, args)
 	if err != nil {
 		return nil, fmt.Errorf("db.Query: %w", err)
 	}
@@ -111,7 +111,7 @@ func (r *Repository) DeleteCredentialsByID(ctx context.Context, id string) error
 		"id":     id,
 		"status": model.StatusDeleted,
 	}
-	_, err := r.db.Exec(ctx, query, args)
+	_, err := r.conn(ctx).Exec(ctx, query, args)
 	if err != nil {
 		return fmt.Errorf("db.Exec: %w", err)
 	}
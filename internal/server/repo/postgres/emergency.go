@@ -0,0 +1,170 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/server/repo"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+const emergencySelect = `select e.id, e.owner_id, o.login, e.contact_id, c.login, e.wait_hours,
+	e.status, e.requested_tms, e.wrapped_key
+	from keeper.emergency_access e
+	join keeper.usr o on o.id = e.owner_id
+	join keeper.usr c on c.id = e.contact_id`
+
+// SaveEmergencyAccess creates the access or resets an existing one of the
+// same owner and contact, repo.ErrItemNotFound is returned when the id
+// belongs to another pair.
+func (r *Repository) SaveEmergencyAccess(ctx context.Context, e model.EmergencyAccess) error {
+	query := `insert into keeper.emergency_access(id, owner_id, contact_id, wait_hours, status,
+	requested_tms, wrapped_key)
+	values (@id, @owner_id, @contact_id, @wait_hours, @status, null, @wrapped_key)
+	on conflict (id) do update set wait_hours = @wait_hours, status = @status,
+	requested_tms = null, wrapped_key = @wrapped_key
+	where keeper.emergency_access.owner_id = excluded.owner_id
+	and keeper.emergency_access.contact_id = excluded.contact_id`
+	args := pgx.NamedArgs{
+		"id":          e.ID,
+		"owner_id":    e.OwnerID,
+		"contact_id":  e.ContactID,
+		"wait_hours":  e.WaitHours,
+		"status":      e.Status,
+		"wrapped_key": e.WrappedKey,
+	}
+	tag, err := r.conn(ctx).Exec(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("db.Exec: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repo.ErrItemNotFound
+	}
+	return nil
+}
+
+func (r *Repository) FindEmergencyAccessByID(ctx context.Context, id string) (model.EmergencyAccess, error) {
+	query := emergencySelect + ` where e.id = @id`
+	args := pgx.NamedArgs{
+		"id": id,
+	}
+	row := r.conn(ctx).QueryRow(ctx, query, args)
+	e, err := scanEmergencyAccess(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.EmergencyAccess{}, repo.ErrItemNotFound
+		}
+		return model.EmergencyAccess{}, fmt.Errorf("scanEmergencyAccess: %w", err)
+	}
+	return e, nil
+}
+
+func (r *Repository) FindEmergencyAccessByOwnerAndContact(ctx context.Context, ownerID,
+	contactID string) (model.EmergencyAccess, error) {
+	query := emergencySelect + ` where e.owner_id = @owner_id and e.contact_id = @contact_id`
+	args := pgx.NamedArgs{
+		"owner_id":   ownerID,
+		"contact_id": contactID,
+	}
+	row := r.conn(ctx).QueryRow(ctx, query, args)
+	e, err := scanEmergencyAccess(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.EmergencyAccess{}, repo.ErrItemNotFound
+		}
+		return model.EmergencyAccess{}, fmt.Errorf("scanEmergencyAccess: %w", err)
+	}
+	return e, nil
+}
+
+// FindEmergencyAccessByUserID returns the accesses the user granted or was
+// granted.
+func (r *Repository) FindEmergencyAccessByUserID(ctx context.Context,
+	userID string) ([]model.EmergencyAccess, error) {
+	query := emergencySelect + ` where e.owner_id = @user_id or e.contact_id = @user_id`
+	args := pgx.NamedArgs{
+		"user_id": userID,
+	}
+	rows, err := r.conn(ctx).Query(ctx, query, args)
+	if err != nil {
+		return nil, fmt.Errorf("db.Query: %w", err)
+	}
+	defer rows.Close()
+	var res = make([]model.EmergencyAccess, 0)
+	for rows.Next() {
+		e, errScan := scanEmergencyAccess(rows)
+		if errScan != nil {
+			return nil, fmt.Errorf("scanEmergencyAccess: %w", errScan)
+		}
+		res = append(res, e)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows.Err(). %w", err)
+	}
+	return res, nil
+}
+
+// UpdateEmergencyStatus moves the access from one status to another,
+// repo.ErrItemNotFound is returned when it is not in the from status.
+func (r *Repository) UpdateEmergencyStatus(ctx context.Context, id string, from,
+	to model.EmergencyStatus, requestedTms *time.Time) error {
+	query := `update keeper.emergency_access set status = @to, requested_tms = @requested_tms
+	where id = @id and status = @from`
+	args := pgx.NamedArgs{
+		"id":            id,
+		"from":          from,
+		"to":            to,
+		"requested_tms": requestedTms,
+	}
+	tag, err := r.conn(ctx).Exec(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("db.Exec: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repo.ErrItemNotFound
+	}
+	return nil
+}
+
+func (r *Repository) DeleteEmergencyAccess(ctx context.Context, id string) error {
+	query := `delete from keeper.emergency_access where id = @id`
+	args := pgx.NamedArgs{
+		"id": id,
+	}
+	tag, err := r.conn(ctx).Exec(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("db.Exec: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repo.ErrItemNotFound
+	}
+	return nil
+}
+
+// ApproveExpiredEmergencyAccess approves the requests whose waiting period
+// ended before now.
+func (r *Repository) ApproveExpiredEmergencyAccess(ctx context.Context, now time.Time) (int64, error) {
+	query := `update keeper.emergency_access set status = @approved
+	where status = @requested
+	and requested_tms + make_interval(hours => wait_hours) <= @now`
+	args := pgx.NamedArgs{
+		"approved":  model.EmergencyApproved,
+		"requested": model.EmergencyRequested,
+		"now":       now,
+	}
+	tag, err := r.conn(ctx).Exec(ctx, query, args)
+	if err != nil {
+		return 0, fmt.Errorf("db.Exec: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+func scanEmergencyAccess(row pgx.Row) (model.EmergencyAccess, error) {
+	var e model.EmergencyAccess
+	err := row.Scan(&e.ID, &e.OwnerID, &e.OwnerLogin, &e.ContactID, &e.ContactLogin,
+		&e.WaitHours, &e.Status, &e.RequestedTms, &e.WrappedKey)
+	return e, err
+}
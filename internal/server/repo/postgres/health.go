@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/migration"
+	"github.com/pressly/goose/v3"
+	"io/fs"
+)
+
+var ErrMigrationPending = errors.New("database schema is not up to date")
+
+// Ready checks the database answers and has the latest migration of the
+// build applied.
+func (r *Repository) Ready(ctx context.Context) error {
+	if err := r.db.Ping(ctx); err != nil {
+		return fmt.Errorf("db.Ping: %w", err)
+	}
+	latest, err := latestMigration(migration.SQLFiles)
+	if err != nil {
+		return fmt.Errorf("latestMigration: %w", err)
+	}
+	query := `select exists(select 1 from public.goose_db_version
+	where version_id = $1 and is_applied)`
+	var applied bool
+	err = r.db.QueryRow(ctx, query, latest).Scan(&applied)
+	if err != nil {
+		return fmt.Errorf("row.Scan: %w", err)
+	}
+	if !applied {
+		return ErrMigrationPending
+	}
+	return nil
+}
+
+func latestMigration(fsys fs.FS) (int64, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return 0, fmt.Errorf("fs.Glob: %w", err)
+	}
+	var latest int64
+	for _, name := range names {
+		v, err := goose.NumericComponent(name)
+		if err != nil {
+			return 0, fmt.Errorf("goose.NumericComponent: %w", err)
+		}
+		latest = max(latest, v)
+	}
+	return latest, nil
+}
@@ -0,0 +1,333 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/server/repo"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"github.com/jackc/pgx/v5"
+)
+
+func (r *Repository) SaveOrg(ctx context.Context, org model.Org) error {
+	query := `insert into keeper.org(id, name) values (@id, @name)`
+	args := pgx.NamedArgs{
+		"id":   org.ID,
+		"name": org.Name,
+	}
+	_, err := r.conn(ctx).Exec(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("db.Exec: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) FindOrgsByUserID(ctx context.Context, userID string) ([]model.Org, error) {
+	query := `select o.id, o.name, m.role from keeper.org o
+	join keeper.org_member m on m.org_id = o.id and m.user_id = @user_id
+	order by o.name`
+	args := pgx.NamedArgs{
+		"user_id": userID,
+	}
+	rows, err := r.conn(ctx).Query(ctx, query, args)
+	if err != nil {
+		return nil, fmt.Errorf("db.Query: %w", err)
+	}
+	defer rows.Close()
+	var res = make([]model.Org, 0)
+	for rows.Next() {
+		var o model.Org
+		if errScan := rows.Scan(&o.ID, &o.Name, &o.Role); errScan != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", errScan)
+		}
+		res = append(res, o)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows.Err(). %w", err)
+	}
+	return res, nil
+}
+
+func (r *Repository) SaveOrgMember(ctx context.Context, orgID string, member model.OrgMember) error {
+	query := `insert into keeper.org_member(org_id, user_id, role)
+	values (@org_id, @user_id, @role) on conflict (org_id, user_id) do update set role = @role`
+	args := pgx.NamedArgs{
+		"org_id":  orgID,
+		"user_id": member.UserID,
+		"role":    member.Role,
+	}
+	_, err := r.conn(ctx).Exec(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("db.Exec: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) FindOrgMember(ctx context.Context, orgID, userID string) (model.OrgMember, error) {
+	query := `select u.login, m.user_id, m.role from keeper.org_member m
+	join keeper.usr u on u.id = m.user_id
+	where m.org_id = @org_id and m.user_id = @user_id`
+	args := pgx.NamedArgs{
+		"org_id":  orgID,
+		"user_id": userID,
+	}
+	row := r.conn(ctx).QueryRow(ctx, query, args)
+	var m model.OrgMember
+	err := row.Scan(&m.Login, &m.UserID, &m.Role)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.OrgMember{}, repo.ErrItemNotFound
+		}
+		return model.OrgMember{}, fmt.Errorf("row.Scan: %w", err)
+	}
+	return m, nil
+}
+
+// FindOrgMembers returns the members with their public keys, members that
+// have not published a key yet have an empty one.
+func (r *Repository) FindOrgMembers(ctx context.Context, orgID string) ([]model.OrgMember, error) {
+	query := `select u.login, m.user_id, m.role, coalesce(k.public_key, '')
+	from keeper.org_member m
+	join keeper.usr u on u.id = m.user_id
+	left join keeper.usr_key k on k.user_id = m.user_id
+	where m.org_id = @org_id order by u.login`
+	args := pgx.NamedArgs{
+		"org_id": orgID,
+	}
+	rows, err := r.conn(ctx).Query(ctx, query, args)
+	if err != nil {
+		return nil, fmt.Errorf("db.Query: %w", err)
+	}
+	defer rows.Close()
+	var res = make([]model.OrgMember, 0)
+	for rows.Next() {
+		var m model.OrgMember
+		if errScan := rows.Scan(&m.Login, &m.UserID, &m.Role, &m.PublicKey); errScan != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", errScan)
+		}
+		res = append(res, m)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows.Err(). %w", err)
+	}
+	return res, nil
+}
+
+// DeleteOrgsByOwnerID deletes the organizations the user owns, their
+// members, collections and items go with them.
+func (r *Repository) DeleteOrgsByOwnerID(ctx context.Context, userID string) error {
+	query := `delete from keeper.org where id in
+	(select org_id from keeper.org_member where user_id = @user_id and role = @role)`
+	args := pgx.NamedArgs{
+		"user_id": userID,
+		"role":    model.RoleOwner,
+	}
+	_, err := r.conn(ctx).Exec(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("db.Exec: %w", err)
+	}
+	return nil
+}
+
+// DeleteOrgMember removes the member along with its collection keys.
+func (r *Repository) DeleteOrgMember(ctx context.Context, orgID, userID string) error {
+	query := `delete from keeper.collection_key where user_id = @user_id
+	and collection_id in (select id from keeper.collection where org_id = @org_id)`
+	args := pgx.NamedArgs{
+		"org_id":  orgID,
+		"user_id": userID,
+	}
+	_, err := r.conn(ctx).Exec(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("db.Exec: %w", err)
+	}
+	query = `delete from keeper.org_member where org_id = @org_id and user_id = @user_id`
+	tag, err := r.conn(ctx).Exec(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("db.Exec: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repo.ErrItemNotFound
+	}
+	return nil
+}
+
+func (r *Repository) SaveCollection(ctx context.Context, c model.Collection) error {
+	query := `insert into keeper.collection(id, org_id, name, key_version)
+	values (@id, @org_id, @name, @key_version)`
+	args := pgx.NamedArgs{
+		"id":          c.ID,
+		"org_id":      c.OrgID,
+		"name":        c.Name,
+		"key_version": c.KeyVersion,
+	}
+	_, err := r.conn(ctx).Exec(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("db.Exec: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) FindCollectionByID(ctx context.Context, id string) (model.Collection, error) {
+	query := `select id, org_id, name, key_version from keeper.collection where id = @id`
+	args := pgx.NamedArgs{
+		"id": id,
+	}
+	row := r.conn(ctx).QueryRow(ctx, query, args)
+	var c model.Collection
+	err := row.Scan(&c.ID, &c.OrgID, &c.Name, &c.KeyVersion)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Collection{}, repo.ErrItemNotFound
+		}
+		return model.Collection{}, fmt.Errorf("row.Scan: %w", err)
+	}
+	return c, nil
+}
+
+// FindCollectionsByOrgID returns the collections of the organization with
+// the key wrapped for the user.
+func (r *Repository) FindCollectionsByOrgID(ctx context.Context, orgID,
+	userID string) ([]model.Collection, error) {
+	query := `select c.id, c.org_id, c.name, c.key_version, coalesce(k.wrapped_key, '')
+	from keeper.collection c
+	left join keeper.collection_key k on k.collection_id = c.id and k.user_id = @user_id
+	where c.org_id = @org_id order by c.name`
+	args := pgx.NamedArgs{
+		"org_id":  orgID,
+		"user_id": userID,
+	}
+	rows, err := r.conn(ctx).Query(ctx, query, args)
+	if err != nil {
+		return nil, fmt.Errorf("db.Query: %w", err)
+	}
+	defer rows.Close()
+	var res = make([]model.Collection, 0)
+	for rows.Next() {
+		var c model.Collection
+		errScan := rows.Scan(&c.ID, &c.OrgID, &c.Name, &c.KeyVersion, &c.WrappedKey)
+		if errScan != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", errScan)
+		}
+		res = append(res, c)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows.Err(). %w", err)
+	}
+	return res, nil
+}
+
+func (r *Repository) UpdateCollectionKeyVersion(ctx context.Context, id string, version int) error {
+	query := `update keeper.collection set key_version = @key_version where id = @id`
+	args := pgx.NamedArgs{
+		"id":          id,
+		"key_version": version,
+	}
+	_, err := r.conn(ctx).Exec(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("db.Exec: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) SaveCollectionKey(ctx context.Context, collectionID string,
+	key model.CollectionKey) error {
+	query := `insert into keeper.collection_key(collection_id, user_id, wrapped_key)
+	values (@collection_id, @user_id, @wrapped_key) on conflict (collection_id, user_id)
+	do update set wrapped_key = @wrapped_key`
+	args := pgx.NamedArgs{
+		"collection_id": collectionID,
+		"user_id":       key.UserID,
+		"wrapped_key":   key.WrappedKey,
+	}
+	_, err := r.conn(ctx).Exec(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("db.Exec: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) DeleteCollectionKeys(ctx context.Context, collectionID string) error {
+	query := `delete from keeper.collection_key where collection_id = @collection_id`
+	args := pgx.NamedArgs{
+		"collection_id": collectionID,
+	}
+	_, err := r.conn(ctx).Exec(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("db.Exec: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) SaveOrgItem(ctx context.Context, item model.OrgItem) error {
+	query := `insert into keeper.org_item(id, collection_id, kind, payload, key_version,
+	status, modified_tms)
+	values (@id, @collection_id, @kind, @payload, @key_version, @status, @modified_tms)
+	on conflict (id) do update set payload = @payload, key_version = @key_version,
+	status = @status, modified_tms = @modified_tms`
+	args := pgx.NamedArgs{
+		"id":            item.ID,
+		"collection_id": item.CollectionID,
+		"kind":          item.Kind,
+		"payload":       item.Payload,
+		"key_version":   item.KeyVersion,
+		"status":        item.Status,
+		"modified_tms":  item.ModifiedTms,
+	}
+	_, err := r.conn(ctx).Exec(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("db.Exec: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) FindOrgItemByID(ctx context.Context, id string) (model.OrgItem, error) {
+	query := `select id, collection_id, kind, payload, key_version, status, modified_tms
+	from keeper.org_item where id = @id`
+	args := pgx.NamedArgs{
+		"id": id,
+	}
+	row := r.conn(ctx).QueryRow(ctx, query, args)
+	var i model.OrgItem
+	err := row.Scan(&i.ID, &i.CollectionID, &i.Kind, &i.Payload, &i.KeyVersion,
+		&i.Status, &i.ModifiedTms)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.OrgItem{}, repo.ErrItemNotFound
+		}
+		return model.OrgItem{}, fmt.Errorf("row.Scan: %w", err)
+	}
+	return i, nil
+}
+
+func (r *Repository) FindOrgItemsByCollectionID(ctx context.Context,
+	collectionID string) ([]model.OrgItem, error) {
+	query := `select id, collection_id, kind, payload, key_version, status, modified_tms
+	from keeper.org_item where collection_id = @collection_id`
+	args := pgx.NamedArgs{
+		"collection_id": collectionID,
+	}
+	rows, err := r.conn(ctx).Query(ctx, query, args)
+	if err != nil {
+		return nil, fmt.Errorf("db.Query: %w", err)
+	}
+	defer rows.Close()
+	var res = make([]model.OrgItem, 0)
+	for rows.Next() {
+		var i model.OrgItem
+		errScan := rows.Scan(&i.ID, &i.CollectionID, &i.Kind, &i.Payload, &i.KeyVersion,
+			&i.Status, &i.ModifiedTms)
+		if errScan != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", errScan)
+		}
+		res = append(res, i)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows.Err(). %w", err)
+	}
+	return res, nil
+}
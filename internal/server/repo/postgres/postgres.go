@@ -5,12 +5,16 @@ import (
 	"database/sql"
 	"fmt"
 	"github.com/denis-oreshkevich/gophkeeper/internal/server/repo"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/tracing"
 	"github.com/denis-oreshkevich/gophkeeper/migration"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/pressly/goose/v3"
 	"io/fs"
 	"sync"
+	"time"
 )
 
 var _ repo.ServerRepository = (*Repository)(nil)
@@ -22,12 +26,31 @@ var (
 	dbErr error
 )
 
+// Config is the connection string and the pool sizing, zero values keep
+// the pgxpool defaults.
+type Config struct {
+	DSN             string
+	MaxConns        int32
+	MinConns        int32
+	MaxConnLifetime time.Duration
+	MaxConnIdleTime time.Duration
+}
+
 type Repository struct {
 	db *pgxpool.Pool
 }
 
-func NewRepository(ctx context.Context, dsn string) (*Repository, error) {
-	pool, err := initPool(ctx, dsn)
+// querier is implemented by both the pool and a transaction.
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+type txKey struct{}
+
+func NewRepository(ctx context.Context, conf Config) (*Repository, error) {
+	pool, err := initPool(ctx, conf)
 	if err != nil {
 		return nil, fmt.Errorf("initPool: %w", err)
 	}
@@ -36,36 +59,69 @@ func NewRepository(ctx context.Context, dsn string) (*Repository, error) {
 	}, nil
 }
 
+// InTransaction runs transact in a transaction, the repository methods
+// called with the ctx it gets use it. Nested calls join the outer one.
 func (r *Repository) InTransaction(ctx context.Context,
-	transact func(context.Context) error) error {
+	transact func(context.Context) error) (err error) {
+	if _, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return transact(ctx)
+	}
+	ctx, span := tracer.Start(ctx, "Repository.InTransaction")
+	defer func() { tracing.End(span, err) }()
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("tx begin. %w", err)
 	}
 	defer tx.Rollback(ctx)
-	err = transact(ctx)
+	err = transact(context.WithValue(ctx, txKey{}, tx))
 	if err != nil {
 		return fmt.Errorf("transact: %w", err)
 	}
 	return tx.Commit(ctx)
 }
 
+// conn returns the transaction of ctx, or the pool outside of one.
+func (r *Repository) conn(ctx context.Context) querier {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return tx
+	}
+	return r.db
+}
+
 func (r *Repository) Close() {
 	r.db.Close()
 }
 
-func initPool(ctx context.Context, dsn string) (*pgxpool.Pool, error) {
+func initPool(ctx context.Context, conf Config) (*pgxpool.Pool, error) {
 	pgOnce.Do(func() {
-		pool, err := pgxpool.New(ctx, dsn)
+		poolConf, err := pgxpool.ParseConfig(conf.DSN)
+		if err != nil {
+			dbErr = fmt.Errorf("pgxpool.ParseConfig: %w", err)
+			return
+		}
+		poolConf.ConnConfig.Tracer = queryTracer{}
+		if conf.MaxConns > 0 {
+			poolConf.MaxConns = conf.MaxConns
+		}
+		if conf.MinConns > 0 {
+			poolConf.MinConns = conf.MinConns
+		}
+		if conf.MaxConnLifetime > 0 {
+			poolConf.MaxConnLifetime = conf.MaxConnLifetime
+		}
+		if conf.MaxConnIdleTime > 0 {
+			poolConf.MaxConnIdleTime = conf.MaxConnIdleTime
+		}
+		pool, err := pgxpool.NewWithConfig(ctx, poolConf)
 		if err != nil {
-			dbErr = fmt.Errorf("pgxpool.New: %w", err)
+			dbErr = fmt.Errorf("pgxpool.NewWithConfig: %w", err)
 			return
 		}
 		if err = pool.Ping(ctx); err != nil {
 			dbErr = fmt.Errorf("pool.Ping: %w", err)
 			return
 		}
-		if err = applyMigration(dsn, migration.SQLFiles); err != nil {
+		if err = applyMigration(conf.DSN, migration.SQLFiles); err != nil {
 			dbErr = fmt.Errorf("applyMigration: %w", err)
 			return
 		}
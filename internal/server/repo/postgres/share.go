@@ -0,0 +1,185 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/server/repo"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"github.com/jackc/pgx/v5"
+)
+
+func (r *Repository) SaveUserKey(ctx context.Context, key model.UserKey) error {
+	query := `insert into keeper.usr_key(user_id, public_key, wrapped_private_key)
+	values (@user_id, @public_key, @wrapped_private_key) on conflict (user_id) do update
+	set wrapped_private_key=excluded.wrapped_private_key
+	where usr_key.public_key=excluded.public_key`
+	args := pgx.NamedArgs{
+		"user_id":             key.UserID,
+		"public_key":          key.PublicKey,
+		"wrapped_private_key": key.WrappedPrivateKey,
+	}
+	_, err := r.conn(ctx).Exec(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("db.Exec: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) FindUserKeyByUserID(ctx context.Context, userID string) (model.UserKey, error) {
+	query := `select user_id, public_key, wrapped_private_key
+	from keeper.usr_key where user_id=@user_id`
+	args := pgx.NamedArgs{
+		"user_id": userID,
+	}
+	row := r.conn(ctx).QueryRow(ctx, query, args)
+	var key model.UserKey
+	err := row.Scan(&key.UserID, &key.PublicKey, &key.WrappedPrivateKey)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.UserKey{}, repo.ErrItemNotFound
+		}
+		return model.UserKey{}, fmt.Errorf("row.Scan: %w", err)
+	}
+	return key, nil
+}
+
+func (r *Repository) SaveShare(ctx context.Context, share model.Share) error {
+	query := `insert into keeper.share(id, item_id, owner_id, kind, payload, modified_tms)
+	values (@id, @item_id, @owner_id, @kind, @payload, @modified_tms) on conflict (id)
+	do update set payload = @payload, modified_tms = @modified_tms`
+	args := pgx.NamedArgs{
+		"id":           share.ID,
+		"item_id":      share.ItemID,
+		"owner_id":     share.OwnerID,
+		"kind":         share.Kind,
+		"payload":      share.Payload,
+		"modified_tms": share.ModifiedTms,
+	}
+	_, err := r.conn(ctx).Exec(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("db.Exec: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) FindShareByID(ctx context.Context, id string) (model.Share, error) {
+	query := `select id, item_id, owner_id, kind, payload, modified_tms
+	from keeper.share where id=@id`
+	args := pgx.NamedArgs{
+		"id": id,
+	}
+	row := r.conn(ctx).QueryRow(ctx, query, args)
+	var s model.Share
+	err := row.Scan(&s.ID, &s.ItemID, &s.OwnerID, &s.Kind, &s.Payload, &s.ModifiedTms)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Share{}, repo.ErrItemNotFound
+		}
+		return model.Share{}, fmt.Errorf("row.Scan: %w", err)
+	}
+	return s, nil
+}
+
+// FindSharesByUserID returns the shares granted to the user along with the
+// user's access and wrapped item key.
+func (r *Repository) FindSharesByUserID(ctx context.Context, userID string) ([]model.Share, error) {
+	query := `select s.id, s.item_id, s.owner_id, o.login, s.kind, s.payload, s.modified_tms,
+	g.access, g.wrapped_key
+	from keeper.share s
+	join keeper.share_grant g on g.share_id = s.id and g.user_id = @user_id
+	join keeper.usr o on o.id = s.owner_id`
+	args := pgx.NamedArgs{
+		"user_id": userID,
+	}
+	rows, err := r.conn(ctx).Query(ctx, query, args)
+	if err != nil {
+		return nil, fmt.Errorf("db.Query: %w", err)
+	}
+	defer rows.Close()
+	var res = make([]model.Share, 0)
+	for rows.Next() {
+		var s model.Share
+		errScan := rows.Scan(&s.ID, &s.ItemID, &s.OwnerID, &s.OwnerLogin, &s.Kind, &s.Payload,
+			&s.ModifiedTms, &s.Access, &s.WrappedKey)
+		if errScan != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", errScan)
+		}
+		res = append(res, s)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("rows.Err(). %w", err)
+	}
+	return res, nil
+}
+
+func (r *Repository) UpdateSharePayload(ctx context.Context, id string,
+	payload model.SharePayload) error {
+	query := `update keeper.share set payload = @payload, modified_tms = @modified_tms
+	where id = @id`
+	args := pgx.NamedArgs{
+		"id":           id,
+		"payload":      payload.Payload,
+		"modified_tms": payload.ModifiedTms,
+	}
+	_, err := r.conn(ctx).Exec(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("db.Exec: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) SaveShareGrant(ctx context.Context, shareID string, grant model.ShareGrant) error {
+	query := `insert into keeper.share_grant(share_id, user_id, wrapped_key, access)
+	values (@share_id, @user_id, @wrapped_key, @access) on conflict (share_id, user_id)
+	do update set wrapped_key = @wrapped_key, access = @access`
+	args := pgx.NamedArgs{
+		"share_id":    shareID,
+		"user_id":     grant.UserID,
+		"wrapped_key": grant.WrappedKey,
+		"access":      grant.Access,
+	}
+	_, err := r.conn(ctx).Exec(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("db.Exec: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) FindShareGrant(ctx context.Context, shareID,
+	userID string) (model.ShareGrant, error) {
+	query := `select g.user_id, u.login, g.wrapped_key, g.access
+	from keeper.share_grant g join keeper.usr u on u.id = g.user_id
+	where g.share_id = @share_id and g.user_id = @user_id`
+	args := pgx.NamedArgs{
+		"share_id": shareID,
+		"user_id":  userID,
+	}
+	row := r.conn(ctx).QueryRow(ctx, query, args)
+	var g model.ShareGrant
+	err := row.Scan(&g.UserID, &g.Login, &g.WrappedKey, &g.Access)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.ShareGrant{}, repo.ErrItemNotFound
+		}
+		return model.ShareGrant{}, fmt.Errorf("row.Scan: %w", err)
+	}
+	return g, nil
+}
+
+func (r *Repository) DeleteShareGrant(ctx context.Context, shareID, userID string) error {
+	query := `delete from keeper.share_grant where share_id = @share_id and user_id = @user_id`
+	args := pgx.NamedArgs{
+		"share_id": shareID,
+		"user_id":  userID,
+	}
+	tag, err := r.conn(ctx).Exec(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("db.Exec: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repo.ErrItemNotFound
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Stat returns the connection pool stats.
+func (r *Repository) Stat() *pgxpool.Stat {
+	return r.db.Stat()
+}
+
+// StoredBytes returns the size of the tables of every record type.
+func (r *Repository) StoredBytes(ctx context.Context) (map[string]int64, error) {
+	query := `select pg_total_relation_size('keeper.cred'), pg_total_relation_size('keeper.txt'),
+	pg_total_relation_size('keeper.binary'), pg_total_relation_size('keeper.card'),
+	pg_total_relation_size('keeper.share'), pg_total_relation_size('keeper.org_item')`
+	var cred, txt, bin, card, share, orgItem int64
+	err := r.db.QueryRow(ctx, query).Scan(&cred, &txt, &bin, &card, &share, &orgItem)
+	if err != nil {
+		return nil, fmt.Errorf("row.Scan: %w", err)
+	}
+	return map[string]int64{
+		"credentials": cred,
+		"text":        txt,
+		"binary":      bin,
+		"card":        card,
+		"share":       share,
+		"org_item":    orgItem,
+	}, nil
+}
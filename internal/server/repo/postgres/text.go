@@ -21,7 +21,7 @@ func (r *Repository) SaveText(ctx context.Context, txt *model.Text) error {
 		"status":       txt.Status,
 		"modified_tms": txt.ModifiedTms,
 	}
-	_, err := r.db.Exec(ctx, query, args)
+	_, err := r.conn(ctx).Exec(ctx, query, args)
 	if err != nil {
 		return fmt.Errorf("db.Exec: %w", err)
 	}
@@ -33,7 +33,7 @@ func (r *Repository) FindTextByID(ctx context.Context, id string) (*model.Text,
 	args := pgx.NamedArgs{
 		"id": id,
 	}
-	row := r.db.QueryRow(ctx, query, args)
+	row := r.conn(ctx).QueryRow(ctx, query, args)
 	var txt model.Text
 	err := row.Scan(&txt.ID, &txt.Txt, &txt.UserID, &txt.Status, &txt.ModifiedTms)
 	if err != nil {
@@ -49,7 +49,7 @@ func (r *Repository) FindTextsByUserID(ctx context.Context, userID string) ([]*m
 	args := pgx.NamedArgs{
 		"user_id": userID,
 	}
-	rows, err := r.db.Query(ctx, query, args)
+	rows, err := r.conn(ctx).Query(ctx, query, args)
 	if err != nil {
 		return nil, fmt.Errorf("db.Query: %w", err)
 	}
@@ -79,7 +79,7 @@ func (r *Repository) FindActiveTextsModifiedAfter(ctx context.Context, userID st
 		"tms":     tms,
 		"status":  model.StatusActive,
 	}
-	rows, err := r.db.Query(ctx, query, args)
+	rows, err := r.conn(ctx).Query(ctx, query, args)
 	if err != nil {
 		return nil, fmt.Errorf("db.Query: %w", err)
 	}
@@ -109,7 +109,7 @@ func (r *Repository) FindDeletedTextsModifiedAfter(ctx context.Context, userID s
 		"tms":     tms,
 		"status":  model.StatusDeleted,
 	}
-	rows, err := r.db.Query(ctx, query, args)
+	rows, err := r.conn(ctx).Query(ctx, query, args)
 	if err != nil {
 		return nil, fmt.Errorf("db.Query: %w", err)
 	}
@@ -136,7 +136,7 @@ func (r *Repository) DeleteTextByID(ctx context.Context, id string) error {
 		"id":     id,
 		"status": model.StatusDeleted,
 	}
-	_, err := r.db.Exec(ctx, query, args)
+	_, err := r.conn(ctx).Exec(ctx, query, args)
 	if err != nil {
 		return fmt.Errorf("db.Exec: %w", err)
 	}
@@ -11,13 +11,19 @@ import (
 	"github.com/jackc/pgx/v5/pgconn"
 )
 
+const userColumns = `id, login, password, vault_key, kdf_salt, recovery_key, recovery_hash`
+
 func (r *Repository) CreateUser(ctx context.Context, usr model.User) (model.User, error) {
-	query := `insert into keeper.usr(login, password) values (@login, @password) returning usr.id`
+	query := `insert into keeper.usr(login, password, vault_key, kdf_salt, recovery_key, recovery_hash)
+	values (@login, @password, @vault_key, @kdf_salt, @recovery_key, @recovery_hash) returning usr.id`
 	args := pgx.NamedArgs{
-		"login":    usr.Login,
-		"password": usr.HashedPassword,
+		"login":         usr.Login,
+		"password":      usr.HashedPassword,
+		"recovery_key":  nullable(usr.RecoveryKey),
+		"recovery_hash": nullable(usr.RecoveryHash),
 	}
-	row := r.db.QueryRow(ctx, query, args)
+	setVaultArgs(args, usr.Vault)
+	row := r.conn(ctx).QueryRow(ctx, query, args)
 	err := row.Scan(&usr.ID)
 	if err != nil {
 		var pgErr *pgconn.PgError
@@ -32,18 +38,104 @@ func (r *Repository) CreateUser(ctx context.Context, usr model.User) (model.User
 	return usr, nil
 }
 func (r *Repository) FindUserByLogin(ctx context.Context, login string) (model.User, error) {
-	query := `select id, login, password from keeper.usr where login=@login`
+	query := `select ` + userColumns + ` from keeper.usr where login=@login`
 	args := pgx.NamedArgs{
 		"login": login,
 	}
-	row := r.db.QueryRow(ctx, query, args)
+	return scanUser(r.conn(ctx).QueryRow(ctx, query, args))
+}
+
+func (r *Repository) FindUserByID(ctx context.Context, id string) (model.User, error) {
+	query := `select ` + userColumns + ` from keeper.usr where id=@id`
+	args := pgx.NamedArgs{
+		"id": id,
+	}
+	return scanUser(r.conn(ctx).QueryRow(ctx, query, args))
+}
+
+// UpdateUserPassword sets the password hash and the vault key wrapped for
+// it. The recovery kit is only replaced when usr has one.
+func (r *Repository) UpdateUserPassword(ctx context.Context, usr model.User) error {
+	query := `update keeper.usr set password=@password, vault_key=@vault_key, kdf_salt=@kdf_salt,
+	recovery_key=coalesce(@recovery_key, recovery_key), recovery_hash=coalesce(@recovery_hash, recovery_hash)
+	where id=@id`
+	args := pgx.NamedArgs{
+		"id":            usr.ID,
+		"password":      usr.HashedPassword,
+		"recovery_key":  nullable(usr.RecoveryKey),
+		"recovery_hash": nullable(usr.RecoveryHash),
+	}
+	setVaultArgs(args, usr.Vault)
+	tag, err := r.conn(ctx).Exec(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("db.Exec: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repo.ErrItemNotFound
+	}
+	return nil
+}
+
+// UpdateUserPasswordHash replaces the hash of the same password.
+func (r *Repository) UpdateUserPasswordHash(ctx context.Context, id, hash string) error {
+	query := `update keeper.usr set password=@password where id=@id`
+	args := pgx.NamedArgs{
+		"id":       id,
+		"password": hash,
+	}
+	_, err := r.conn(ctx).Exec(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("db.Exec: %w", err)
+	}
+	return nil
+}
+
+func setVaultArgs(args pgx.NamedArgs, vault *model.VaultKey) {
+	args["vault_key"], args["kdf_salt"] = nil, nil
+	if vault != nil {
+		args["vault_key"], args["kdf_salt"] = vault.WrappedKey, vault.Salt
+	}
+}
+
+func nullable(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func scanUser(row pgx.Row) (model.User, error) {
 	var usr model.User
-	err := row.Scan(&usr.ID, &usr.Login, &usr.HashedPassword)
+	var vaultKey, salt, recoveryKey, recoveryHash *string
+	err := row.Scan(&usr.ID, &usr.Login, &usr.HashedPassword, &vaultKey, &salt,
+		&recoveryKey, &recoveryHash)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return model.User{}, repo.ErrItemNotFound
 		}
 		return model.User{}, fmt.Errorf("row.Scan: %w", err)
 	}
+	if vaultKey != nil && salt != nil {
+		usr.Vault = &model.VaultKey{WrappedKey: *vaultKey, Salt: *salt}
+	}
+	if recoveryKey != nil && recoveryHash != nil {
+		usr.RecoveryKey, usr.RecoveryHash = *recoveryKey, *recoveryHash
+	}
 	return usr, nil
 }
+
+// DeleteUser deletes the user, the foreign keys cascade to all of its data.
+func (r *Repository) DeleteUser(ctx context.Context, id string) error {
+	query := `delete from keeper.usr where id=@id`
+	args := pgx.NamedArgs{
+		"id": id,
+	}
+	tag, err := r.conn(ctx).Exec(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("db.Exec: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repo.ErrItemNotFound
+	}
+	return nil
+}
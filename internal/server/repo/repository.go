@@ -15,11 +15,18 @@ var ErrItemNotFound = errors.New("item not found")
 type ServerRepository interface {
 	CreateUser(ctx context.Context, usr model.User) (model.User, error)
 	FindUserByLogin(ctx context.Context, login string) (model.User, error)
+	FindUserByID(ctx context.Context, id string) (model.User, error)
+	UpdateUserPassword(ctx context.Context, usr model.User) error
+	UpdateUserPasswordHash(ctx context.Context, id, hash string) error
+	DeleteUser(ctx context.Context, id string) error
 
 	CreateClient(ctx context.Context, client model.Client) (model.Client, error)
 	UpdateClientLastSyncTmsByID(ctx context.Context, id string, syncTms time.Time) error
 	FindClientByID(ctx context.Context, id string) (model.Client, error)
 	FindClientsByUserID(ctx context.Context, userID string) ([]model.Client, error)
+	FindClientByFingerprint(ctx context.Context, fingerprint string) (model.Client, error)
+	FindRevokedClients(ctx context.Context) ([]model.Client, error)
+	RevokeClient(ctx context.Context, id, userID string) (model.Client, error)
 
 	SaveCredentials(ctx context.Context, cred model.Credentials) error
 	FindCredentialsByID(ctx context.Context, id string) (model.Credentials, error)
@@ -53,5 +60,47 @@ type ServerRepository interface {
 		tms time.Time) ([]model.Card, error)
 	DeleteCardByID(ctx context.Context, id string) error
 
+	SaveUserKey(ctx context.Context, key model.UserKey) error
+	FindUserKeyByUserID(ctx context.Context, userID string) (model.UserKey, error)
+
+	SaveShare(ctx context.Context, share model.Share) error
+	FindShareByID(ctx context.Context, id string) (model.Share, error)
+	FindSharesByUserID(ctx context.Context, userID string) ([]model.Share, error)
+	UpdateSharePayload(ctx context.Context, id string, payload model.SharePayload) error
+	SaveShareGrant(ctx context.Context, shareID string, grant model.ShareGrant) error
+	FindShareGrant(ctx context.Context, shareID, userID string) (model.ShareGrant, error)
+	DeleteShareGrant(ctx context.Context, shareID, userID string) error
+
+	SaveOrg(ctx context.Context, org model.Org) error
+	FindOrgsByUserID(ctx context.Context, userID string) ([]model.Org, error)
+	SaveOrgMember(ctx context.Context, orgID string, member model.OrgMember) error
+	FindOrgMember(ctx context.Context, orgID, userID string) (model.OrgMember, error)
+	FindOrgMembers(ctx context.Context, orgID string) ([]model.OrgMember, error)
+	DeleteOrgMember(ctx context.Context, orgID, userID string) error
+	DeleteOrgsByOwnerID(ctx context.Context, userID string) error
+	SaveCollection(ctx context.Context, c model.Collection) error
+	FindCollectionByID(ctx context.Context, id string) (model.Collection, error)
+	FindCollectionsByOrgID(ctx context.Context, orgID, userID string) ([]model.Collection, error)
+	UpdateCollectionKeyVersion(ctx context.Context, id string, version int) error
+	SaveCollectionKey(ctx context.Context, collectionID string, key model.CollectionKey) error
+	DeleteCollectionKeys(ctx context.Context, collectionID string) error
+	SaveOrgItem(ctx context.Context, item model.OrgItem) error
+	FindOrgItemByID(ctx context.Context, id string) (model.OrgItem, error)
+	FindOrgItemsByCollectionID(ctx context.Context, collectionID string) ([]model.OrgItem, error)
+
+	SaveEmergencyAccess(ctx context.Context, e model.EmergencyAccess) error
+	FindEmergencyAccessByID(ctx context.Context, id string) (model.EmergencyAccess, error)
+	FindEmergencyAccessByOwnerAndContact(ctx context.Context, ownerID,
+		contactID string) (model.EmergencyAccess, error)
+	FindEmergencyAccessByUserID(ctx context.Context, userID string) ([]model.EmergencyAccess, error)
+	UpdateEmergencyStatus(ctx context.Context, id string, from, to model.EmergencyStatus,
+		requestedTms *time.Time) error
+	DeleteEmergencyAccess(ctx context.Context, id string) error
+	ApproveExpiredEmergencyAccess(ctx context.Context, now time.Time) (int64, error)
+
+	SaveAuditEvent(ctx context.Context, e model.AuditEvent) error
+	FindAuditEvents(ctx context.Context, userID string, before int64,
+		limit int) ([]model.AuditEvent, error)
+
 	InTransaction(ctx context.Context, transact func(context.Context) error) error
 }
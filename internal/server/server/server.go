@@ -2,149 +2,350 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
+	"flag"
 	"fmt"
 	"github.com/denis-oreshkevich/gophkeeper/internal/server/api"
+	"github.com/denis-oreshkevich/gophkeeper/internal/server/config"
+	"github.com/denis-oreshkevich/gophkeeper/internal/server/metrics"
 	"github.com/denis-oreshkevich/gophkeeper/internal/server/repo/postgres"
 	"github.com/denis-oreshkevich/gophkeeper/internal/server/service"
+	"github.com/denis-oreshkevich/gophkeeper/internal/server/throttle"
 	"github.com/denis-oreshkevich/gophkeeper/internal/shared/auth"
-	"github.com/denis-oreshkevich/gophkeeper/internal/shared/config"
 	"github.com/denis-oreshkevich/gophkeeper/internal/shared/logger"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/tracing"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"go.uber.org/zap"
-	"go.uber.org/zap/zapcore"
 	"net/http"
 	"os"
 	"os/signal"
 	"sync"
 	"syscall"
+	"time"
 )
 
+var buildVersion = "N/A"
+
+var buildDate = "N/A"
+
+var buildCommit = "N/A"
+
+// emergencyTimerInterval is how often ended emergency access waiting periods
+// are looked for.
+const emergencyTimerInterval = time.Minute
+
 func Run() error {
 	ctx := context.Background()
-	err := logger.Initialize(zapcore.DebugLevel.String())
+	// configuration errors are logged at the default level
+	err := logger.Initialize(config.Default().LogLevel)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "logger initialize", err)
-		os.Exit(1)
+		return fmt.Errorf("logger.Initialize: %w", err)
 	}
-	defer logger.Log.Sync()
-
-	conf, err := config.Parse()
+	conf, err := config.Load(os.Args[1:])
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return fmt.Errorf("config.Load: %w", err)
+	}
+	err = logger.Initialize(conf.LogLevel)
 	if err != nil {
-		logger.Log.Fatal("parse config", zap.Error(err))
+		return fmt.Errorf("logger.Initialize: %w", err)
 	}
+	defer logger.Log.Sync()
+
+	logger.Log.Info(fmt.Sprintf("Build version: %s\n", buildVersion))
+	logger.Log.Info(fmt.Sprintf("Build date: %s\n", buildDate))
+	logger.Log.Info(fmt.Sprintf("Build commit: %s\n", buildCommit))
+	logger.Log.Debug("config", zap.Stringer("config", conf))
+
+	auth.SetKeys(conf.Auth.JWTKeys)
 
 	ctx, stop := signal.NotifyContext(ctx, syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT)
 	defer stop()
 
-	pgRepo, err := postgres.NewRepository(ctx, conf.DataBaseURI)
+	flushTracing, err := tracing.Setup(ctx, "gophkeeper-server", tracing.Config{
+		Exporter: conf.Tracing.Exporter,
+		Endpoint: conf.Tracing.Endpoint,
+		Insecure: conf.Tracing.Insecure,
+		Writer:   os.Stdout,
+	})
+	if err != nil {
+		return fmt.Errorf("tracing.Setup: %w", err)
+	}
+	defer flushTracing()
+
+	pgRepo, err := postgres.NewRepository(ctx, postgres.Config{
+		DSN:             conf.DB.DSN,
+		MaxConns:        conf.DB.MaxConns,
+		MinConns:        conf.DB.MinConns,
+		MaxConnLifetime: conf.DB.MaxConnLifetime,
+		MaxConnIdleTime: conf.DB.MaxConnIdleTime,
+	})
 	if err != nil {
 		return fmt.Errorf("postgres.NewRepository: %w", err)
 	}
 	defer pgRepo.Close()
+	metrics.RegisterPool(pgRepo.Stat)
+	metrics.RegisterStoredBytes(pgRepo.StoredBytes)
 
-	serverService := service.NewServerService(pgRepo)
-	controller := api.NewController(serverService)
+	manager, err := auth.NewCertManager(auth.CertConfig{
+		CertPath:  conf.TLS.Cert,
+		KeyPath:   conf.TLS.Key,
+		CAPath:    conf.TLS.CA,
+		CAKeyPath: conf.TLS.CAKey,
+		CRLPath:   conf.TLS.CRL,
+		Hosts:     conf.TLS.Hosts,
+	})
+	if err != nil {
+		return fmt.Errorf("auth.NewCertManager: %w", err)
+	}
+	pin, err := manager.Pin()
+	if err != nil {
+		return fmt.Errorf("manager.Pin: %w", err)
+	}
+	logger.Log.Info("tls", zap.String("ca", manager.CAPath), zap.String("pin", pin))
 
-	router, err := SetUpRouter(ctx, controller)
+	var devices service.DeviceCA
+	if conf.TLS.MTLS {
+		devices = manager
+	}
+	serverService, err := service.NewServerService(pgRepo, conf.Auth.BcryptCost, devices)
+	if err != nil {
+		return fmt.Errorf("service.NewServerService: %w", err)
+	}
+	err = serverService.RefreshCRL(ctx)
+	if err != nil {
+		return fmt.Errorf("serverService.RefreshCRL: %w", err)
+	}
+	th, err := throttle.New(throttle.Config{
+		LoginAttempts: conf.Limits.LockoutAttempts,
+		IPAttempts:    conf.Limits.IPLockoutAttempts,
+		Lockout:       conf.Limits.LockoutDuration,
+		Backoff:       conf.Limits.LoginBackoff,
+	})
+	if err != nil {
+		return fmt.Errorf("throttle.New: %w", err)
+	}
+	controller := api.NewController(serverService, th)
+	health := api.NewHealth(pgRepo, api.BuildInfo{
+		Version: buildVersion,
+		Date:    buildDate,
+		Commit:  buildCommit,
+	})
+
+	router, err := SetUpRouter(ctx, controller, health, api.Limits{
+		Auth:    conf.Limits.AuthBody,
+		Default: conf.Limits.DefaultBody,
+		Sync:    conf.Limits.SyncBody,
+		Binary:  conf.Limits.BinaryBody,
+	})
 	if err != nil {
 		return fmt.Errorf("SetUpRouter: %w", err)
 	}
 
 	srv := &http.Server{
-		Addr:    conf.ServerAddress,
+		Addr:    conf.Address,
 		Handler: router,
 	}
+	if conf.TLS.MTLS {
+		// device certificates are optional, clients without one use tokens
+		srv.TLSConfig = &tls.Config{
+			ClientAuth: tls.VerifyClientCertIfGiven,
+			ClientCAs:  manager.ClientCAs(),
+		}
+	}
 
 	var wg sync.WaitGroup
 
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		<-ctx.Done()
-
-		if err := srv.Shutdown(context.Background()); err != nil {
-			logger.Log.Error("HTTP server Shutdown", zap.Error(err))
-		}
+		serverService.RunEmergencyTimer(ctx, emergencyTimerInterval)
 	}()
+	if conf.TLS.MTLS {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			serverService.RunCRLTimer(ctx, auth.CRLRefresh)
+		}()
+	}
 
-	err = os.Mkdir("certs", 0755)
-	if err != nil {
-		if !errors.Is(err, os.ErrExist) {
-			return fmt.Errorf("os.Mkdir: %w", err)
+	var admin *http.Server
+	if conf.AdminAddress != "" {
+		admin = &http.Server{
+			Addr:    conf.AdminAddress,
+			Handler: SetUpAdminRouter(),
 		}
+		go func() {
+			logger.Log.Info("admin server started", zap.String("address", conf.AdminAddress))
+			err := admin.ListenAndServe()
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Log.Error("admin server ListenAndServe", zap.Error(err))
+			}
+		}()
 	}
 
-	manager, errHTTPS := auth.NewCertManager("certs/cert.pem", "certs/key.pem")
-	if errHTTPS != nil {
-		return fmt.Errorf("auth.NewCertManager: %w", errHTTPS)
+	serveErr := make(chan error, 1)
+	go func() {
+		logger.Log.Info("server started", zap.String("address", conf.Address))
+		serveErr <- srv.ListenAndServeTLS(manager.CertPath, manager.KeyPath)
+	}()
+
+	select {
+	case err = <-serveErr:
+		stop()
+	case <-ctx.Done():
+		// a second signal kills the process
+		stop()
+		shutdown(srv, health, conf.ShutdownDelay, conf.ShutdownTimeout)
+		err = <-serveErr
+	}
+	if admin != nil {
+		// metrics stay available until the API has drained
+		closeServer(admin, "admin server", conf.ShutdownTimeout)
 	}
-	logger.Log.Info("server started")
-	err = srv.ListenAndServeTLS(manager.CertPath, manager.KeyPath)
+	wg.Wait()
 
-	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-		return fmt.Errorf("srv.ListenAndServe(): %s\n", err)
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("srv.ListenAndServeTLS: %w", err)
 	}
+	logger.Log.Info("Server closed")
+	return nil
+}
 
-	logger.Log.Info("server started")
+// shutdown fails readiness first and keeps serving for delay, so the load
+// balancer stops sending requests. Then in-flight requests get up to
+// timeout to finish.
+func shutdown(srv *http.Server, health *api.Health, delay, timeout time.Duration) {
+	health.SetShuttingDown()
+	logger.Log.Info("shutting down", zap.Duration("delay", delay), zap.Duration("timeout", timeout))
+	time.Sleep(delay)
+	closeServer(srv, "HTTP server", timeout)
+}
 
-	if err != nil {
-		if errors.Is(err, http.ErrServerClosed) {
-			logger.Log.Info("Server closed")
-		} else {
-			return fmt.Errorf("router run %w", err)
-		}
+// closeServer drains srv, connections still open after timeout are closed.
+func closeServer(srv *http.Server, name string, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err := srv.Shutdown(ctx)
+	if err == nil {
+		return
+	}
+	logger.Log.Error(name+" Shutdown", zap.Error(err))
+	if err := srv.Close(); err != nil {
+		logger.Log.Error(name+" Close", zap.Error(err))
 	}
-
-	wg.Wait()
-	return nil
 }
 
-func SetUpRouter(ctx context.Context, controller *api.Controller) (*chi.Mux, error) {
+func SetUpRouter(ctx context.Context, controller *api.Controller,
+	health *api.Health, limits api.Limits) (*chi.Mux, error) {
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID)
+	r.Use(api.Tracing)
+	r.Use(metrics.Middleware)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
-	r.Use(api.Auth)
-	r.Route("/api", func(r chi.Router) {
+	r.Get("/healthz", health.HandleHealthz)
+	r.Get("/readyz", health.HandleReadyz)
+	r.Get("/version", health.HandleVersion)
+	r.Get("/crl", controller.HandleGetCRL)
+	r.With(api.RequestInfo, api.BodyLimit(limits.Default), controller.Auth).Route("/api", func(r chi.Router) {
 		r.Route("/user", func(r chi.Router) {
-			r.Post("/register", controller.HandleRegisterUser)
-			r.Post("/login", controller.HandleLoginUser)
+			r.With(api.BodyLimit(limits.Auth)).Delete("/", controller.HandleDeleteUser)
+			r.With(api.BodyLimit(limits.Auth)).Post("/register", controller.HandleRegisterUser)
+			r.With(api.BodyLimit(limits.Auth)).Post("/prelogin", controller.HandlePostPrelogin)
+			r.With(api.BodyLimit(limits.Auth)).Post("/login", controller.HandleLoginUser)
+			r.With(api.BodyLimit(limits.Auth)).Put("/password", controller.HandlePutPassword)
+			r.With(api.BodyLimit(limits.Auth)).Post("/recovery", controller.HandlePostRecovery)
+			r.With(api.BodyLimit(limits.Auth)).Post("/recovery/reset", controller.HandlePostRecoveryReset)
+			r.Get("/audit", controller.HandleGetAudit)
 			r.Route("/client", func(r chi.Router) {
+				r.Get("/", controller.HandleGetClients)
 				r.Post("/", controller.HandlePostClient)
 				r.Put("/", controller.HandlePutClient)
+				r.Delete("/{id}", controller.HandleDeleteClient)
 			})
 			r.Route("/credentials", func(r chi.Router) {
 				r.Get("/", controller.HandleGetUserCredentials)
 				r.Get("/{id}", controller.HandleGetCredentialsByID)
 				r.Post("/", controller.HandlePostCredentials)
 				r.Delete("/{id}", controller.HandleDeleteCredentialsByID)
-				r.Post("/sync", controller.HandlePostSyncCredentials)
+				r.With(api.BodyLimit(limits.Sync)).Post("/sync", controller.HandlePostSyncCredentials)
 			})
 			r.Route("/cards", func(r chi.Router) {
 				r.Get("/", controller.HandleGetUserCards)
 				r.Get("/{id}", controller.HandleGetCardByID)
 				r.Post("/", controller.HandlePostCard)
 				r.Delete("/{id}", controller.HandleDeleteCardByID)
-				r.Post("/sync", controller.HandlePostSyncCard)
+				r.With(api.BodyLimit(limits.Sync)).Post("/sync", controller.HandlePostSyncCard)
 			})
 			r.Route("/texts", func(r chi.Router) {
 				r.Get("/", controller.HandleGetUserTexts)
 				r.Get("/{id}", controller.HandleGetTextByID)
 				r.Post("/", controller.HandlePostText)
 				r.Delete("/{id}", controller.HandleDeleteTextByID)
-				r.Post("/sync", controller.HandlePostSyncText)
+				r.With(api.BodyLimit(limits.Sync)).Post("/sync", controller.HandlePostSyncText)
 			})
 			r.Route("/binaries", func(r chi.Router) {
+				r.Use(api.BodyLimit(limits.Binary))
 				r.Get("/", controller.HandleGetUserBinaries)
 				r.Get("/{id}", controller.HandleGetBinaryByID)
 				r.Post("/", controller.HandlePostBinary)
 				r.Delete("/{id}", controller.HandleDeleteBinaryByID)
 				r.Post("/sync", controller.HandlePostSyncBinary)
 			})
+			r.Route("/keys", func(r chi.Router) {
+				r.Get("/", controller.HandleGetUserKey)
+				r.Put("/", controller.HandlePutUserKey)
+				r.Get("/{login}", controller.HandleGetPublicKey)
+			})
+			r.Route("/shares", func(r chi.Router) {
+				r.Use(api.BodyLimit(limits.Binary))
+				r.Get("/", controller.HandleGetShares)
+				r.Post("/", controller.HandlePostShare)
+				r.Put("/{id}", controller.HandlePutSharePayload)
+				r.Delete("/{id}/grants/{login}", controller.HandleDeleteShareGrant)
+			})
+			r.Route("/emergency", func(r chi.Router) {
+				r.Get("/", controller.HandleGetEmergencyAccess)
+				r.Post("/", controller.HandlePostEmergencyAccess)
+				r.Delete("/{id}", controller.HandleDeleteEmergencyAccess)
+				r.Post("/{id}/request", controller.HandlePostEmergencyRequest)
+				r.Post("/{id}/approve", controller.HandlePostEmergencyApprove)
+				r.Post("/{id}/decline", controller.HandlePostEmergencyDecline)
+				r.Get("/{id}/vault", controller.HandleGetEmergencyVault)
+			})
+			r.Route("/orgs", func(r chi.Router) {
+				r.Get("/", controller.HandleGetOrgs)
+				r.Post("/", controller.HandlePostOrg)
+				r.Route("/{org}", func(r chi.Router) {
+					r.Get("/members", controller.HandleGetOrgMembers)
+					r.Post("/members", controller.HandlePostOrgMember)
+					r.Delete("/members/{login}", controller.HandleDeleteOrgMember)
+					r.Get("/collections", controller.HandleGetCollections)
+					r.Post("/collections", controller.HandlePostCollection)
+					r.Route("/collections/{id}", func(r chi.Router) {
+						r.Use(api.BodyLimit(limits.Binary))
+						r.Put("/key", controller.HandlePutCollectionKey)
+						r.Get("/items", controller.HandleGetOrgItems)
+						r.Post("/items", controller.HandlePostOrgItem)
+					})
+				})
+			})
 
 		})
 	})
 	return r, nil
 }
+
+// SetUpAdminRouter serves the endpoints for operators, it is not exposed
+// with the API.
+func SetUpAdminRouter() *chi.Mux {
+	r := chi.NewRouter()
+	r.Use(middleware.Recoverer)
+	r.Method(http.MethodGet, "/metrics", metrics.Handler())
+	return r
+}
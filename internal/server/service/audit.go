@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/server/repo"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/auth"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/logger"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultAuditLimit = 50
+	MaxAuditLimit     = 500
+)
+
+type requestInfoKey struct{}
+
+// RequestInfo tells where a request came from, audit events carry it.
+type RequestInfo struct {
+	IP        string
+	ClientID  string
+	RequestID string
+}
+
+func WithRequestInfo(ctx context.Context, info RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, info)
+}
+
+// WithClientID sets the client of the request, an authenticated device
+// replaces the client the header claims.
+func WithClientID(ctx context.Context, clientID string) context.Context {
+	info := requestInfo(ctx)
+	info.ClientID = clientID
+	return WithRequestInfo(ctx, info)
+}
+
+func requestInfo(ctx context.Context) RequestInfo {
+	info, _ := ctx.Value(requestInfoKey{}).(RequestInfo)
+	return info
+}
+
+// AuditLogger appends security relevant events to the audit log. A failed
+// write is logged and does not fail the action.
+type AuditLogger struct {
+	repository repo.ServerRepository
+}
+
+func NewAuditLogger(repository repo.ServerRepository) AuditLogger {
+	return AuditLogger{repository: repository}
+}
+
+// Log records the event of the user, an empty userID records it without
+// one.
+func (a AuditLogger) Log(ctx context.Context, userID string, event model.AuditEventType,
+	details string) {
+	info := requestInfo(ctx)
+	e := model.AuditEvent{
+		UserID:    userID,
+		Event:     event,
+		Details:   details,
+		ClientID:  info.ClientID,
+		IP:        info.IP,
+		RequestID: info.RequestID,
+	}
+	err := a.repository.SaveAuditEvent(ctx, e)
+	if err != nil {
+		logger.Log.Error("audit", zap.String("event", string(event)),
+			zap.String("userID", userID), zap.String("requestID", info.RequestID), zap.Error(err))
+	}
+}
+
+// LogCurrent records the event of the user of the request.
+func (a AuditLogger) LogCurrent(ctx context.Context, event model.AuditEventType, details string) {
+	userID, _ := auth.GetUserID(ctx)
+	a.Log(ctx, userID, event, details)
+}
+
+// RecordLockout records that the login or the address is locked out. A
+// lockout of a login unknown to the server is recorded without a user.
+func (s *ServerService) RecordLockout(ctx context.Context, login, scope string) {
+	ctx, span := tracer.Start(ctx, "ServerService.RecordLockout")
+	defer span.End()
+	userID, err := auth.GetUserID(ctx)
+	details := "scope " + scope
+	if err != nil {
+		details += ", login " + login
+		us, errFind := s.repository.FindUserByLogin(ctx, login)
+		if errFind == nil {
+			userID = us.ID
+		}
+	}
+	s.audit.Log(ctx, userID, model.AuditLockout, details)
+}
+
+// FindAuditEvents returns a page of the events of the user, newest first.
+func (s *ServerService) FindAuditEvents(ctx context.Context, before int64,
+	limit int) (model.AuditPage, error) {
+	ctx, span := tracer.Start(ctx, "ServerService.FindAuditEvents")
+	defer span.End()
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		return model.AuditPage{}, fmt.Errorf("auth.GetUserID: %w", err)
+	}
+	if limit <= 0 {
+		limit = DefaultAuditLimit
+	}
+	limit = min(limit, MaxAuditLimit)
+	events, err := s.repository.FindAuditEvents(ctx, userID, before, limit)
+	if err != nil {
+		return model.AuditPage{}, fmt.Errorf("repository.FindAuditEvents: %w", err)
+	}
+	page := model.AuditPage{Events: events}
+	if len(events) == limit {
+		page.Next = events[len(events)-1].ID
+	}
+	return page, nil
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/server/repo"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/auth"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/logger"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"go.uber.org/zap"
+	"math/big"
+	"time"
+)
+
+var ErrDeviceRevoked = errors.New("device is revoked")
+
+// DeviceCA signs the certificates of client devices and keeps the CRL of
+// the revoked ones.
+type DeviceCA interface {
+	SignClient(csrPEM, clientID string) (auth.DeviceCert, error)
+	UpdateCRL(revoked []auth.RevokedCert) error
+	IsRevoked(serial *big.Int) bool
+	CRL() []byte
+}
+
+// signClient signs the CSR of the client, it is dropped when devices are
+// not enabled.
+func (s *ServerService) signClient(client model.Client) (model.Client, error) {
+	csr := client.CSR
+	client.CSR = ""
+	if csr == "" || s.devices == nil {
+		return client, nil
+	}
+	cert, err := s.devices.SignClient(csr, client.ID)
+	if err != nil {
+		return model.Client{}, fmt.Errorf("devices.SignClient: %w", err)
+	}
+	client.Certificate = cert.PEM
+	client.CertFingerprint = cert.Fingerprint
+	client.CertSerial = cert.Serial
+	return client, nil
+}
+
+// AuthenticateDevice finds the client of a verified device certificate.
+// Certificates on the CRL and of revoked clients are refused.
+func (s *ServerService) AuthenticateDevice(ctx context.Context, cert *x509.Certificate) (model.Client, error) {
+	ctx, span := tracer.Start(ctx, "ServerService.AuthenticateDevice")
+	defer span.End()
+	if s.devices == nil {
+		return model.Client{}, repo.ErrItemNotFound
+	}
+	if s.devices.IsRevoked(cert.SerialNumber) {
+		return model.Client{}, ErrDeviceRevoked
+	}
+	client, err := s.repository.FindClientByFingerprint(ctx, auth.Fingerprint(cert))
+	if err != nil {
+		return model.Client{}, fmt.Errorf("repository.FindClientByFingerprint: %w", err)
+	}
+	if client.RevokedTms != nil {
+		return model.Client{}, ErrDeviceRevoked
+	}
+	return client, nil
+}
+
+func (s *ServerService) FindClients(ctx context.Context) ([]model.Client, error) {
+	ctx, span := tracer.Start(ctx, "ServerService.FindClients")
+	defer span.End()
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("auth.GetUserID: %w", err)
+	}
+	clients, err := s.repository.FindClientsByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("repository.FindClientsByUserID: %w", err)
+	}
+	return clients, nil
+}
+
+// RevokeClient revokes a client of the user, its certificate goes to the
+// CRL.
+func (s *ServerService) RevokeClient(ctx context.Context, id string) error {
+	ctx, span := tracer.Start(ctx, "ServerService.RevokeClient")
+	defer span.End()
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		return fmt.Errorf("auth.GetUserID: %w", err)
+	}
+	client, err := s.repository.RevokeClient(ctx, id, userID)
+	if err != nil {
+		return fmt.Errorf("repository.RevokeClient: %w", err)
+	}
+	s.audit.Log(ctx, userID, model.AuditClientRevoke, "client "+client.ID)
+	if client.CertSerial == "" {
+		return nil
+	}
+	err = s.RefreshCRL(ctx)
+	if err != nil {
+		return fmt.Errorf("s.RefreshCRL: %w", err)
+	}
+	return nil
+}
+
+// RefreshCRL signs the CRL again from the revoked clients of all users.
+func (s *ServerService) RefreshCRL(ctx context.Context) error {
+	ctx, span := tracer.Start(ctx, "ServerService.RefreshCRL")
+	defer span.End()
+	if s.devices == nil {
+		return nil
+	}
+	clients, err := s.repository.FindRevokedClients(ctx)
+	if err != nil {
+		return fmt.Errorf("repository.FindRevokedClients: %w", err)
+	}
+	revoked := make([]auth.RevokedCert, 0, len(clients))
+	for _, c := range clients {
+		revoked = append(revoked, auth.RevokedCert{Serial: c.CertSerial, RevokedTms: *c.RevokedTms})
+	}
+	err = s.devices.UpdateCRL(revoked)
+	if err != nil {
+		return fmt.Errorf("devices.UpdateCRL: %w", err)
+	}
+	return nil
+}
+
+// RunCRLTimer signs the CRL again every interval until ctx is done, so it
+// does not run out while no device is revoked.
+func (s *ServerService) RunCRLTimer(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := s.RefreshCRL(ctx)
+			if err != nil {
+				logger.Log.Error("s.RefreshCRL", zap.Error(err))
+			}
+		}
+	}
+}
+
+// CRL returns the DER CRL, nil when devices are not enabled.
+func (s *ServerService) CRL() []byte {
+	if s.devices == nil {
+		return nil
+	}
+	return s.devices.CRL()
+}
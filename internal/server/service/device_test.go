@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"github.com/denis-oreshkevich/gophkeeper/internal/server/repo"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/auth"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// countingCA counts the CRLs signed, the other methods of DeviceCA are not
+// expected to be called.
+type countingCA struct {
+	DeviceCA
+	updates int
+	revoked []auth.RevokedCert
+}
+
+func (c *countingCA) UpdateCRL(revoked []auth.RevokedCert) error {
+	c.updates++
+	c.revoked = revoked
+	return nil
+}
+
+type revokedRepo struct {
+	repo.ServerRepository
+	clients []model.Client
+}
+
+func (r revokedRepo) FindRevokedClients(context.Context) ([]model.Client, error) {
+	return r.clients, nil
+}
+
+func TestRunCRLTimer(t *testing.T) {
+	revokedTms := time.Now().UTC()
+	r := revokedRepo{clients: []model.Client{{ID: "device", CertSerial: "42", RevokedTms: &revokedTms}}}
+	ca := &countingCA{}
+	s := &ServerService{repository: r, devices: ca}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	s.RunCRLTimer(ctx, 5*time.Millisecond)
+
+	assert.Greater(t, ca.updates, 1)
+	assert.Equal(t, []auth.RevokedCert{{Serial: "42", RevokedTms: revokedTms}}, ca.revoked)
+}
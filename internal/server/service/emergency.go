@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/server/repo"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/auth"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/logger"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+var ErrEmergencyState = errors.New("emergency access is not in a state that allows it")
+
+// GrantEmergencyAccess makes the contact a trusted contact of the user. An
+// existing access of the contact is reset with the new key and period. The
+// id is chosen here, the one of the client could name the access of
+// another pair.
+func (s *ServerService) GrantEmergencyAccess(ctx context.Context, e model.EmergencyAccess) error {
+	ctx, span := tracer.Start(ctx, "ServerService.GrantEmergencyAccess")
+	defer span.End()
+	if err := e.Validate(); err != nil {
+		return fmt.Errorf("e.Validate: %w", err)
+	}
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		return fmt.Errorf("auth.GetUserID: %w", err)
+	}
+	contact, err := s.repository.FindUserByLogin(ctx, e.ContactLogin)
+	if err != nil {
+		return fmt.Errorf("repository.FindUserByLogin: %w", err)
+	}
+	if contact.ID == userID {
+		return ErrAccessDenied
+	}
+	saved, err := s.repository.FindEmergencyAccessByOwnerAndContact(ctx, userID, contact.ID)
+	switch {
+	case err == nil:
+		e.ID = saved.ID
+	case errors.Is(err, repo.ErrItemNotFound):
+		e.ID = uuid.New().String()
+	default:
+		return fmt.Errorf("repository.FindEmergencyAccessByOwnerAndContact: %w", err)
+	}
+	e.OwnerID, e.ContactID, e.Status = userID, contact.ID, model.EmergencyGranted
+	err = s.repository.SaveEmergencyAccess(ctx, e)
+	if err != nil {
+		return fmt.Errorf("repository.SaveEmergencyAccess: %w", err)
+	}
+	s.auditEmergency(ctx, e, fmt.Sprintf("granted to %s, wait %dh", e.ContactLogin, e.WaitHours))
+	return nil
+}
+
+// FindEmergencyAccess returns the accesses the user granted or was granted.
+// The wrapped key is only returned to the contact of an approved access.
+func (s *ServerService) FindEmergencyAccess(ctx context.Context) ([]model.EmergencyAccess, error) {
+	ctx, span := tracer.Start(ctx, "ServerService.FindEmergencyAccess")
+	defer span.End()
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("auth.GetUserID: %w", err)
+	}
+	res, err := s.repository.FindEmergencyAccessByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("repository.FindEmergencyAccessByUserID: %w", err)
+	}
+	for i := range res {
+		if res[i].ContactID != userID || res[i].Status != model.EmergencyApproved {
+			res[i].WrappedKey = ""
+		}
+	}
+	return res, nil
+}
+
+// RequestEmergencyAccess starts the waiting period, only the contact may do
+// so.
+func (s *ServerService) RequestEmergencyAccess(ctx context.Context, id string) error {
+	ctx, span := tracer.Start(ctx, "ServerService.RequestEmergencyAccess")
+	defer span.End()
+	e, userID, err := s.emergencyAccess(ctx, id)
+	if err != nil {
+		return err
+	}
+	if e.ContactID != userID {
+		return ErrAccessDenied
+	}
+	now := time.Now().UTC()
+	return s.moveEmergencyAccess(ctx, e, model.EmergencyGranted, model.EmergencyRequested, &now)
+}
+
+// ApproveEmergencyAccess lets the owner approve a request before the
+// waiting period ends.
+func (s *ServerService) ApproveEmergencyAccess(ctx context.Context, id string) error {
+	ctx, span := tracer.Start(ctx, "ServerService.ApproveEmergencyAccess")
+	defer span.End()
+	e, userID, err := s.emergencyAccess(ctx, id)
+	if err != nil {
+		return err
+	}
+	if e.OwnerID != userID {
+		return ErrAccessDenied
+	}
+	return s.moveEmergencyAccess(ctx, e, model.EmergencyRequested, model.EmergencyApproved,
+		e.RequestedTms)
+}
+
+// DeclineEmergencyAccess returns a request or an approved access to the
+// granted state, only the owner may do so.
+func (s *ServerService) DeclineEmergencyAccess(ctx context.Context, id string) error {
+	ctx, span := tracer.Start(ctx, "ServerService.DeclineEmergencyAccess")
+	defer span.End()
+	e, userID, err := s.emergencyAccess(ctx, id)
+	if err != nil {
+		return err
+	}
+	if e.OwnerID != userID {
+		return ErrAccessDenied
+	}
+	if e.Status == model.EmergencyGranted {
+		return ErrEmergencyState
+	}
+	return s.moveEmergencyAccess(ctx, e, e.Status, model.EmergencyGranted, nil)
+}
+
+// RevokeEmergencyAccess removes the access, either side may do so.
+func (s *ServerService) RevokeEmergencyAccess(ctx context.Context, id string) error {
+	ctx, span := tracer.Start(ctx, "ServerService.RevokeEmergencyAccess")
+	defer span.End()
+	e, userID, err := s.emergencyAccess(ctx, id)
+	if err != nil {
+		return err
+	}
+	if e.OwnerID != userID && e.ContactID != userID {
+		return ErrAccessDenied
+	}
+	err = s.repository.DeleteEmergencyAccess(ctx, id)
+	if err != nil {
+		return fmt.Errorf("repository.DeleteEmergencyAccess: %w", err)
+	}
+	s.auditEmergency(ctx, e, "revoked")
+	return nil
+}
+
+// FindEmergencyVault returns the vault of the owner to the contact of an
+// approved access. The items stay encrypted with the vault key of the owner.
+func (s *ServerService) FindEmergencyVault(ctx context.Context, id string) (model.EmergencyVault, error) {
+	ctx, span := tracer.Start(ctx, "ServerService.FindEmergencyVault")
+	defer span.End()
+	e, userID, err := s.emergencyAccess(ctx, id)
+	if err != nil {
+		return model.EmergencyVault{}, err
+	}
+	if e.ContactID != userID {
+		return model.EmergencyVault{}, ErrAccessDenied
+	}
+	if e.Status != model.EmergencyApproved {
+		return model.EmergencyVault{}, ErrEmergencyState
+	}
+	var v model.EmergencyVault
+	v.Credentials, err = s.repository.FindCredentialsByUserID(ctx, e.OwnerID)
+	if err != nil {
+		return model.EmergencyVault{}, fmt.Errorf("repository.FindCredentialsByUserID: %w", err)
+	}
+	v.Cards, err = s.repository.FindCardsByUserID(ctx, e.OwnerID)
+	if err != nil {
+		return model.EmergencyVault{}, fmt.Errorf("repository.FindCardsByUserID: %w", err)
+	}
+	v.Texts, err = s.repository.FindTextsByUserID(ctx, e.OwnerID)
+	if err != nil {
+		return model.EmergencyVault{}, fmt.Errorf("repository.FindTextsByUserID: %w", err)
+	}
+	v.Binaries, err = s.repository.FindBinariesByUserID(ctx, e.OwnerID)
+	if err != nil {
+		return model.EmergencyVault{}, fmt.Errorf("repository.FindBinariesByUserID: %w", err)
+	}
+	s.auditEmergency(ctx, e, "vault opened")
+	return v, nil
+}
+
+// RunEmergencyTimer approves the requests whose waiting period ended every
+// interval until ctx is done.
+func (s *ServerService) RunEmergencyTimer(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.repository.ApproveExpiredEmergencyAccess(ctx, time.Now().UTC())
+			if err != nil {
+				logger.Log.Error("repository.ApproveExpiredEmergencyAccess", zap.Error(err))
+				continue
+			}
+			if n > 0 {
+				logger.Log.Info(fmt.Sprintf("approved %d emergency access request(s)", n))
+			}
+		}
+	}
+}
+
+func (s *ServerService) emergencyAccess(ctx context.Context,
+	id string) (model.EmergencyAccess, string, error) {
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		return model.EmergencyAccess{}, "", fmt.Errorf("auth.GetUserID: %w", err)
+	}
+	e, err := s.repository.FindEmergencyAccessByID(ctx, id)
+	if err != nil {
+		return model.EmergencyAccess{}, "", fmt.Errorf("repository.FindEmergencyAccessByID: %w", err)
+	}
+	return e, userID, nil
+}
+
+func (s *ServerService) moveEmergencyAccess(ctx context.Context, e model.EmergencyAccess,
+	from, to model.EmergencyStatus, requestedTms *time.Time) error {
+	if e.Status != from {
+		return ErrEmergencyState
+	}
+	err := s.repository.UpdateEmergencyStatus(ctx, e.ID, from, to, requestedTms)
+	if err != nil {
+		if errors.Is(err, repo.ErrItemNotFound) {
+			return ErrEmergencyState
+		}
+		return fmt.Errorf("repository.UpdateEmergencyStatus: %w", err)
+	}
+	logger.Log.Info("emergency access", zap.String("id", e.ID),
+		zap.String("from", string(from)), zap.String("to", string(to)))
+	s.auditEmergency(ctx, e, strings.ToLower(string(from))+" -> "+strings.ToLower(string(to)))
+	return nil
+}
+
+// auditEmergency records the event for both the owner and the contact.
+func (s *ServerService) auditEmergency(ctx context.Context, e model.EmergencyAccess, details string) {
+	details = fmt.Sprintf("access %s %s", e.ID, details)
+	s.audit.Log(ctx, e.OwnerID, model.AuditEmergency, details)
+	s.audit.Log(ctx, e.ContactID, model.AuditEmergency, details)
+}
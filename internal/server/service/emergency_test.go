@@ -0,0 +1,288 @@
+package service
+
+import (
+	"context"
+	"github.com/denis-oreshkevich/gophkeeper/internal/server/repo"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/auth"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// emergencyRepo keeps users and emergency accesses in memory, the other
+// methods of the repository are not expected to be called.
+type emergencyRepo struct {
+	repo.ServerRepository
+	users    map[string]model.User
+	accesses map[string]model.EmergencyAccess
+}
+
+func newEmergencyRepo(logins ...string) *emergencyRepo {
+	r := &emergencyRepo{
+		users:    make(map[string]model.User),
+		accesses: make(map[string]model.EmergencyAccess),
+	}
+	for _, l := range logins {
+		r.users[l] = model.User{ID: uuid.New().String(), Login: l}
+	}
+	return r
+}
+
+func (r *emergencyRepo) FindUserByLogin(_ context.Context, login string) (model.User, error) {
+	u, ok := r.users[login]
+	if !ok {
+		return model.User{}, repo.ErrItemNotFound
+	}
+	return u, nil
+}
+
+// SaveEmergencyAccess behaves like the upsert of postgres, a row of another
+// pair is left alone.
+func (r *emergencyRepo) SaveEmergencyAccess(_ context.Context, e model.EmergencyAccess) error {
+	saved, ok := r.accesses[e.ID]
+	if ok && (saved.OwnerID != e.OwnerID || saved.ContactID != e.ContactID) {
+		return repo.ErrItemNotFound
+	}
+	e.RequestedTms = nil
+	r.accesses[e.ID] = e
+	return nil
+}
+
+func (r *emergencyRepo) FindEmergencyAccessByID(_ context.Context, id string) (model.EmergencyAccess, error) {
+	e, ok := r.accesses[id]
+	if !ok {
+		return model.EmergencyAccess{}, repo.ErrItemNotFound
+	}
+	return e, nil
+}
+
+func (r *emergencyRepo) FindEmergencyAccessByOwnerAndContact(_ context.Context, ownerID,
+	contactID string) (model.EmergencyAccess, error) {
+	for _, e := range r.accesses {
+		if e.OwnerID == ownerID && e.ContactID == contactID {
+			return e, nil
+		}
+	}
+	return model.EmergencyAccess{}, repo.ErrItemNotFound
+}
+
+func (r *emergencyRepo) UpdateEmergencyStatus(_ context.Context, id string, from,
+	to model.EmergencyStatus, requestedTms *time.Time) error {
+	e, ok := r.accesses[id]
+	if !ok || e.Status != from {
+		return repo.ErrItemNotFound
+	}
+	e.Status, e.RequestedTms = to, requestedTms
+	r.accesses[id] = e
+	return nil
+}
+
+func (r *emergencyRepo) ApproveExpiredEmergencyAccess(_ context.Context, now time.Time) (int64, error) {
+	var n int64
+	for id, e := range r.accesses {
+		if e.Status == model.EmergencyRequested && !e.ApprovesAt().After(now) {
+			e.Status = model.EmergencyApproved
+			r.accesses[id] = e
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *emergencyRepo) SaveAuditEvent(context.Context, model.AuditEvent) error {
+	return nil
+}
+
+func (r *emergencyRepo) asUser(login string) context.Context {
+	return context.WithValue(context.Background(), auth.UserIDKey{}, r.users[login].ID)
+}
+
+func newEmergencyService(r *emergencyRepo) *ServerService {
+	return &ServerService{repository: r, audit: NewAuditLogger(r)}
+}
+
+func emergencyGrant(id, contact string) model.EmergencyAccess {
+	return model.EmergencyAccess{
+		ID:           id,
+		ContactLogin: contact,
+		WaitHours:    24,
+		WrappedKey:   "AAAAAAAAAAAAAAAAAAAAAA==",
+	}
+}
+
+func TestGrantEmergencyAccessTakeover(t *testing.T) {
+	r := newEmergencyRepo("alice", "bob", "mallory")
+	s := newEmergencyService(r)
+
+	require.NoError(t, s.GrantEmergencyAccess(r.asUser("alice"), emergencyGrant(uuid.New().String(), "bob")))
+	require.Len(t, r.accesses, 1)
+	var aliceID string
+	for id := range r.accesses {
+		aliceID = id
+	}
+
+	// mallory names the access of alice in another grant
+	err := s.GrantEmergencyAccess(r.asUser("mallory"), emergencyGrant(aliceID, "bob"))
+	require.NoError(t, err)
+
+	alice := r.accesses[aliceID]
+	assert.Equal(t, r.users["alice"].ID, alice.OwnerID)
+	assert.Equal(t, r.users["bob"].ID, alice.ContactID)
+	require.Len(t, r.accesses, 2)
+	for id, e := range r.accesses {
+		if id != aliceID {
+			assert.Equal(t, r.users["mallory"].ID, e.OwnerID)
+		}
+	}
+
+	// granting again keeps the id of the pair
+	require.NoError(t, s.GrantEmergencyAccess(r.asUser("alice"), emergencyGrant(uuid.New().String(), "bob")))
+	assert.Len(t, r.accesses, 2)
+	assert.Contains(t, r.accesses, aliceID)
+}
+
+// grantedAccess grants bob access to the vault of alice and returns its id.
+func grantedAccess(t *testing.T, r *emergencyRepo, s *ServerService) string {
+	t.Helper()
+	require.NoError(t, s.GrantEmergencyAccess(r.asUser("alice"), emergencyGrant(uuid.New().String(), "bob")))
+	saved, err := r.FindEmergencyAccessByOwnerAndContact(context.Background(),
+		r.users["alice"].ID, r.users["bob"].ID)
+	require.NoError(t, err)
+	return saved.ID
+}
+
+func TestEmergencyAccessTransitions(t *testing.T) {
+	type step struct {
+		login   string
+		action  string
+		wantErr error
+	}
+	tests := []struct {
+		name       string
+		steps      []step
+		wantStatus model.EmergencyStatus
+	}{
+		{
+			name: "request and approve",
+			steps: []step{
+				{login: "bob", action: "request"},
+				{login: "alice", action: "approve"},
+			},
+			wantStatus: model.EmergencyApproved,
+		},
+		{
+			name: "request and decline",
+			steps: []step{
+				{login: "bob", action: "request"},
+				{login: "alice", action: "decline"},
+			},
+			wantStatus: model.EmergencyGranted,
+		},
+		{
+			name: "approved access declined",
+			steps: []step{
+				{login: "bob", action: "request"},
+				{login: "alice", action: "approve"},
+				{login: "alice", action: "decline"},
+			},
+			wantStatus: model.EmergencyGranted,
+		},
+		{
+			name: "contact approves own request",
+			steps: []step{
+				{login: "bob", action: "request"},
+				{login: "bob", action: "approve", wantErr: ErrAccessDenied},
+			},
+			wantStatus: model.EmergencyRequested,
+		},
+		{
+			name: "contact declines",
+			steps: []step{
+				{login: "bob", action: "request"},
+				{login: "bob", action: "decline", wantErr: ErrAccessDenied},
+			},
+			wantStatus: model.EmergencyRequested,
+		},
+		{
+			name:       "owner requests",
+			steps:      []step{{login: "alice", action: "request", wantErr: ErrAccessDenied}},
+			wantStatus: model.EmergencyGranted,
+		},
+		{
+			name:       "stranger requests",
+			steps:      []step{{login: "mallory", action: "request", wantErr: ErrAccessDenied}},
+			wantStatus: model.EmergencyGranted,
+		},
+		{
+			name:       "approve without request",
+			steps:      []step{{login: "alice", action: "approve", wantErr: ErrEmergencyState}},
+			wantStatus: model.EmergencyGranted,
+		},
+		{
+			name:       "decline without request",
+			steps:      []step{{login: "alice", action: "decline", wantErr: ErrEmergencyState}},
+			wantStatus: model.EmergencyGranted,
+		},
+		{
+			name: "request twice",
+			steps: []step{
+				{login: "bob", action: "request"},
+				{login: "bob", action: "request", wantErr: ErrEmergencyState},
+			},
+			wantStatus: model.EmergencyRequested,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newEmergencyRepo("alice", "bob", "mallory")
+			s := newEmergencyService(r)
+			id := grantedAccess(t, r, s)
+
+			actions := map[string]func(context.Context, string) error{
+				"request": s.RequestEmergencyAccess,
+				"approve": s.ApproveEmergencyAccess,
+				"decline": s.DeclineEmergencyAccess,
+			}
+			for _, st := range tt.steps {
+				err := actions[st.action](r.asUser(st.login), id)
+				if st.wantErr != nil {
+					assert.ErrorIs(t, err, st.wantErr, "%s %s", st.login, st.action)
+				} else {
+					require.NoError(t, err, "%s %s", st.login, st.action)
+				}
+			}
+			assert.Equal(t, tt.wantStatus, r.accesses[id].Status)
+		})
+	}
+}
+
+func TestRunEmergencyTimer(t *testing.T) {
+	r := newEmergencyRepo("alice", "bob", "carol")
+	s := newEmergencyService(r)
+	expired := grantedAccess(t, r, s)
+	require.NoError(t, s.GrantEmergencyAccess(r.asUser("alice"), emergencyGrant(uuid.New().String(), "carol")))
+	waiting, err := r.FindEmergencyAccessByOwnerAndContact(context.Background(),
+		r.users["alice"].ID, r.users["carol"].ID)
+	require.NoError(t, err)
+
+	require.NoError(t, s.RequestEmergencyAccess(r.asUser("bob"), expired))
+	require.NoError(t, s.RequestEmergencyAccess(r.asUser("carol"), waiting.ID))
+	// the request of bob was made longer ago than the waiting period
+	e := r.accesses[expired]
+	past := time.Now().UTC().Add(-time.Duration(e.WaitHours)*time.Hour - time.Minute)
+	e.RequestedTms = &past
+	r.accesses[expired] = e
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	s.RunEmergencyTimer(ctx, time.Millisecond)
+
+	assert.Equal(t, model.EmergencyApproved, r.accesses[expired].Status)
+	assert.Equal(t, model.EmergencyRequested, r.accesses[waiting.ID].Status)
+
+	_, err = s.FindEmergencyVault(r.asUser("carol"), waiting.ID)
+	assert.ErrorIs(t, err, ErrEmergencyState)
+}
@@ -0,0 +1,435 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/server/repo"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/auth"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+)
+
+var ErrKeyVersion = errors.New("collection key was changed")
+
+// CreateOrg creates an organization owned by the user.
+func (s *ServerService) CreateOrg(ctx context.Context, org model.Org) error {
+	ctx, span := tracer.Start(ctx, "ServerService.CreateOrg")
+	defer span.End()
+	if err := org.Validate(); err != nil {
+		return fmt.Errorf("org.Validate: %w", err)
+	}
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		return fmt.Errorf("auth.GetUserID: %w", err)
+	}
+	err = s.repository.InTransaction(ctx, func(ctx context.Context) error {
+		if err := s.repository.SaveOrg(ctx, org); err != nil {
+			return fmt.Errorf("repository.SaveOrg: %w", err)
+		}
+		owner := model.OrgMember{UserID: userID, Role: model.RoleOwner}
+		if err := s.repository.SaveOrgMember(ctx, org.ID, owner); err != nil {
+			return fmt.Errorf("repository.SaveOrgMember: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("repository.InTransaction: %w", err)
+	}
+	s.audit.Log(ctx, userID, model.AuditOrg, fmt.Sprintf("org %s created", org.ID))
+	return nil
+}
+
+func (s *ServerService) FindOrgs(ctx context.Context) ([]model.Org, error) {
+	ctx, span := tracer.Start(ctx, "ServerService.FindOrgs")
+	defer span.End()
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("auth.GetUserID: %w", err)
+	}
+	orgs, err := s.repository.FindOrgsByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("repository.FindOrgsByUserID: %w", err)
+	}
+	return orgs, nil
+}
+
+func (s *ServerService) FindOrgMembers(ctx context.Context, orgID string) ([]model.OrgMember, error) {
+	ctx, span := tracer.Start(ctx, "ServerService.FindOrgMembers")
+	defer span.End()
+	if _, err := s.orgMember(ctx, orgID); err != nil {
+		return nil, err
+	}
+	members, err := s.repository.FindOrgMembers(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("repository.FindOrgMembers: %w", err)
+	}
+	return members, nil
+}
+
+// SaveOrgMember adds a member or changes the role of one. A new member
+// must get the current key of every collection. Only the owner may grant
+// or take the admin role.
+func (s *ServerService) SaveOrgMember(ctx context.Context, orgID string,
+	member model.OrgMember) error {
+	ctx, span := tracer.Start(ctx, "ServerService.SaveOrgMember")
+	defer span.End()
+	if err := member.Validate(); err != nil {
+		return fmt.Errorf("member.Validate: %w", err)
+	}
+	caller, err := s.orgMember(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	if !caller.Role.CanManage() {
+		return ErrAccessDenied
+	}
+	usr, err := s.repository.FindUserByLogin(ctx, member.Login)
+	if err != nil {
+		return fmt.Errorf("repository.FindUserByLogin: %w", err)
+	}
+	member.UserID = usr.ID
+
+	saved, err := s.repository.FindOrgMember(ctx, orgID, usr.ID)
+	isNew := errors.Is(err, repo.ErrItemNotFound)
+	if err != nil && !isNew {
+		return fmt.Errorf("repository.FindOrgMember: %w", err)
+	}
+	if saved.Role == model.RoleOwner {
+		return ErrAccessDenied
+	}
+	if caller.Role != model.RoleOwner &&
+		(member.Role == model.RoleAdmin || saved.Role == model.RoleAdmin) {
+		return ErrAccessDenied
+	}
+
+	if isNew {
+		collections, err := s.repository.FindCollectionsByOrgID(ctx, orgID, usr.ID)
+		if err != nil {
+			return fmt.Errorf("repository.FindCollectionsByOrgID: %w", err)
+		}
+		given := make(map[string]bool, len(member.Keys))
+		for _, k := range member.Keys {
+			given[k.CollectionID] = true
+		}
+		if len(given) != len(collections) || len(member.Keys) != len(collections) {
+			return keysError("keys must wrap the key of every collection")
+		}
+		for _, c := range collections {
+			if !given[c.ID] {
+				return keysError("keys must wrap the key of every collection")
+			}
+		}
+	}
+
+	err = s.repository.InTransaction(ctx, func(ctx context.Context) error {
+		if err := s.repository.SaveOrgMember(ctx, orgID, member); err != nil {
+			return fmt.Errorf("repository.SaveOrgMember: %w", err)
+		}
+		if !isNew {
+			return nil
+		}
+		for _, k := range member.Keys {
+			k.UserID = usr.ID
+			if err := s.repository.SaveCollectionKey(ctx, k.CollectionID, k); err != nil {
+				return fmt.Errorf("repository.SaveCollectionKey: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("repository.InTransaction: %w", err)
+	}
+	s.audit.Log(ctx, caller.UserID, model.AuditOrg,
+		fmt.Sprintf("org %s member %s %s", orgID, member.Login, member.Role))
+	return nil
+}
+
+// RemoveOrgMember takes the member out of the organization along with its
+// collection keys. The keys it already knows stay valid until the
+// collections are re-keyed. Members may leave on their own.
+func (s *ServerService) RemoveOrgMember(ctx context.Context, orgID, login string) error {
+	ctx, span := tracer.Start(ctx, "ServerService.RemoveOrgMember")
+	defer span.End()
+	caller, err := s.orgMember(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	usr, err := s.repository.FindUserByLogin(ctx, login)
+	if err != nil {
+		return fmt.Errorf("repository.FindUserByLogin: %w", err)
+	}
+	target, err := s.repository.FindOrgMember(ctx, orgID, usr.ID)
+	if err != nil {
+		return fmt.Errorf("repository.FindOrgMember: %w", err)
+	}
+	switch {
+	case target.Role == model.RoleOwner:
+		return ErrAccessDenied
+	case target.UserID == caller.UserID:
+	case !caller.Role.CanManage():
+		return ErrAccessDenied
+	case target.Role == model.RoleAdmin && caller.Role != model.RoleOwner:
+		return ErrAccessDenied
+	}
+	err = s.repository.InTransaction(ctx, func(ctx context.Context) error {
+		if err := s.repository.DeleteOrgMember(ctx, orgID, usr.ID); err != nil {
+			return fmt.Errorf("repository.DeleteOrgMember: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("repository.InTransaction: %w", err)
+	}
+	s.audit.Log(ctx, caller.UserID, model.AuditOrg, fmt.Sprintf("org %s member %s removed", orgID, login))
+	return nil
+}
+
+// CreateCollection creates a collection, its key must be wrapped for every
+// member.
+func (s *ServerService) CreateCollection(ctx context.Context, orgID string,
+	c model.Collection) error {
+	ctx, span := tracer.Start(ctx, "ServerService.CreateCollection")
+	defer span.End()
+	if err := c.Validate(); err != nil {
+		return fmt.Errorf("c.Validate: %w", err)
+	}
+	caller, err := s.orgMember(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	if !caller.Role.CanManage() {
+		return ErrAccessDenied
+	}
+	keys, err := s.memberKeys(ctx, orgID, c.Keys)
+	if err != nil {
+		return err
+	}
+	c.OrgID = orgID
+	c.KeyVersion = 1
+	err = s.repository.InTransaction(ctx, func(ctx context.Context) error {
+		if err := s.repository.SaveCollection(ctx, c); err != nil {
+			return fmt.Errorf("repository.SaveCollection: %w", err)
+		}
+		for _, k := range keys {
+			if err := s.repository.SaveCollectionKey(ctx, c.ID, k); err != nil {
+				return fmt.Errorf("repository.SaveCollectionKey: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("repository.InTransaction: %w", err)
+	}
+	s.audit.Log(ctx, caller.UserID, model.AuditOrg,
+		fmt.Sprintf("org %s collection %s created", orgID, c.ID))
+	return nil
+}
+
+// FindCollections returns the collections of the organization with the
+// key wrapped for the user.
+func (s *ServerService) FindCollections(ctx context.Context, orgID string) ([]model.Collection, error) {
+	ctx, span := tracer.Start(ctx, "ServerService.FindCollections")
+	defer span.End()
+	caller, err := s.orgMember(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	collections, err := s.repository.FindCollectionsByOrgID(ctx, orgID, caller.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("repository.FindCollectionsByOrgID: %w", err)
+	}
+	return collections, nil
+}
+
+// RekeyCollection replaces the key of a collection. The new key must be
+// wrapped for every member and every item of the collection sealed with it.
+func (s *ServerService) RekeyCollection(ctx context.Context, orgID, id string,
+	rekey model.CollectionRekey) error {
+	ctx, span := tracer.Start(ctx, "ServerService.RekeyCollection")
+	defer span.End()
+	if err := rekey.Validate(); err != nil {
+		return fmt.Errorf("rekey.Validate: %w", err)
+	}
+	caller, err := s.orgMember(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	if !caller.Role.CanManage() {
+		return ErrAccessDenied
+	}
+	c, err := s.collection(ctx, orgID, id)
+	if err != nil {
+		return err
+	}
+	if rekey.KeyVersion != c.KeyVersion+1 {
+		return ErrKeyVersion
+	}
+	keys, err := s.memberKeys(ctx, orgID, rekey.Keys)
+	if err != nil {
+		return err
+	}
+	saved, err := s.repository.FindOrgItemsByCollectionID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("repository.FindOrgItemsByCollectionID: %w", err)
+	}
+	given := make(map[string]bool, len(rekey.Items))
+	for _, it := range rekey.Items {
+		if it.KeyVersion != rekey.KeyVersion {
+			return ErrKeyVersion
+		}
+		given[it.ID] = true
+	}
+	if len(given) != len(saved) || len(rekey.Items) != len(saved) {
+		return ErrKeyVersion
+	}
+	for _, it := range saved {
+		if !given[it.ID] {
+			return ErrKeyVersion
+		}
+	}
+
+	err = s.repository.InTransaction(ctx, func(ctx context.Context) error {
+		if err := s.repository.UpdateCollectionKeyVersion(ctx, id, rekey.KeyVersion); err != nil {
+			return fmt.Errorf("repository.UpdateCollectionKeyVersion: %w", err)
+		}
+		if err := s.repository.DeleteCollectionKeys(ctx, id); err != nil {
+			return fmt.Errorf("repository.DeleteCollectionKeys: %w", err)
+		}
+		for _, k := range keys {
+			if err := s.repository.SaveCollectionKey(ctx, id, k); err != nil {
+				return fmt.Errorf("repository.SaveCollectionKey: %w", err)
+			}
+		}
+		for _, it := range rekey.Items {
+			it.CollectionID = id
+			if err := s.repository.SaveOrgItem(ctx, it); err != nil {
+				return fmt.Errorf("repository.SaveOrgItem: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("repository.InTransaction: %w", err)
+	}
+	s.audit.Log(ctx, caller.UserID, model.AuditOrg,
+		fmt.Sprintf("org %s collection %s rekeyed to version %d", orgID, id, rekey.KeyVersion))
+	return nil
+}
+
+func (s *ServerService) FindOrgItems(ctx context.Context, orgID, collectionID string) ([]model.OrgItem, error) {
+	ctx, span := tracer.Start(ctx, "ServerService.FindOrgItems")
+	defer span.End()
+	if _, err := s.orgMember(ctx, orgID); err != nil {
+		return nil, err
+	}
+	if _, err := s.collection(ctx, orgID, collectionID); err != nil {
+		return nil, err
+	}
+	items, err := s.repository.FindOrgItemsByCollectionID(ctx, collectionID)
+	if err != nil {
+		return nil, fmt.Errorf("repository.FindOrgItemsByCollectionID: %w", err)
+	}
+	return items, nil
+}
+
+// SaveOrgItem creates or updates an item of the collection. The item must
+// be sealed with the current collection key.
+func (s *ServerService) SaveOrgItem(ctx context.Context, orgID, collectionID string,
+	item model.OrgItem) error {
+	ctx, span := tracer.Start(ctx, "ServerService.SaveOrgItem")
+	defer span.End()
+	if err := item.Validate(); err != nil {
+		return fmt.Errorf("item.Validate: %w", err)
+	}
+	caller, err := s.orgMember(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	if !caller.Role.CanWrite() {
+		return ErrAccessDenied
+	}
+	c, err := s.collection(ctx, orgID, collectionID)
+	if err != nil {
+		return err
+	}
+	if item.KeyVersion != c.KeyVersion {
+		return ErrKeyVersion
+	}
+	saved, err := s.repository.FindOrgItemByID(ctx, item.ID)
+	if err == nil {
+		if saved.CollectionID != collectionID {
+			return ErrAccessDenied
+		}
+		if saved.ModifiedTms.After(item.ModifiedTms) {
+			return ErrStale
+		}
+	} else if !errors.Is(err, repo.ErrItemNotFound) {
+		return fmt.Errorf("repository.FindOrgItemByID: %w", err)
+	}
+	item.CollectionID = collectionID
+	err = s.repository.SaveOrgItem(ctx, item)
+	if err != nil {
+		return fmt.Errorf("repository.SaveOrgItem: %w", err)
+	}
+	return nil
+}
+
+// orgMember returns the membership of the user, non members are denied.
+func (s *ServerService) orgMember(ctx context.Context, orgID string) (model.OrgMember, error) {
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		return model.OrgMember{}, fmt.Errorf("auth.GetUserID: %w", err)
+	}
+	m, err := s.repository.FindOrgMember(ctx, orgID, userID)
+	if err != nil {
+		if errors.Is(err, repo.ErrItemNotFound) {
+			return model.OrgMember{}, ErrAccessDenied
+		}
+		return model.OrgMember{}, fmt.Errorf("repository.FindOrgMember: %w", err)
+	}
+	return m, nil
+}
+
+func (s *ServerService) collection(ctx context.Context, orgID, id string) (model.Collection, error) {
+	c, err := s.repository.FindCollectionByID(ctx, id)
+	if err != nil {
+		return model.Collection{}, fmt.Errorf("repository.FindCollectionByID: %w", err)
+	}
+	if c.OrgID != orgID {
+		return model.Collection{}, repo.ErrItemNotFound
+	}
+	return c, nil
+}
+
+// memberKeys resolves the logins of keys and checks there is exactly one
+// key for every member.
+func (s *ServerService) memberKeys(ctx context.Context, orgID string,
+	keys []model.CollectionKey) ([]model.CollectionKey, error) {
+	members, err := s.repository.FindOrgMembers(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("repository.FindOrgMembers: %w", err)
+	}
+	byLogin := make(map[string]string, len(members))
+	for _, m := range members {
+		byLogin[m.Login] = m.UserID
+	}
+	seen := make(map[string]bool, len(keys))
+	for i := range keys {
+		userID, ok := byLogin[keys[i].Login]
+		if !ok || seen[userID] {
+			return nil, keysError("keys must wrap the collection key once for every member")
+		}
+		seen[userID] = true
+		keys[i].UserID = userID
+	}
+	if len(seen) != len(members) {
+		return nil, keysError("keys must wrap the collection key once for every member")
+	}
+	return keys, nil
+}
+
+func keysError(msg string) error {
+	return &model.ValidationError{Entries: []model.ValidationErrEntry{
+		model.NewValidationErr("keys", []string{msg}),
+	}}
+}
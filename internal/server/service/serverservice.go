@@ -4,59 +4,137 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/server/metrics"
 	"github.com/denis-oreshkevich/gophkeeper/internal/server/repo"
 	"github.com/denis-oreshkevich/gophkeeper/internal/shared/auth"
 	"github.com/denis-oreshkevich/gophkeeper/internal/shared/logger"
 	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/tracing"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
+var tracer = tracing.Tracer("server/service")
+
 type ServerService struct {
-	repository repo.ServerRepository
+	repository   repo.ServerRepository
+	audit        AuditLogger
+	passwordCost int
+
+	// devices signs device certificates, nil when mTLS is not enabled.
+	devices DeviceCA
+
+	// dummyHash is compared against when the login does not exist, so the
+	// answer takes as long as for a wrong password.
+	dummyHash string
 }
 
-func NewServerService(repository repo.ServerRepository) ServerService {
-	return ServerService{
-		repository: repository,
+func NewServerService(repository repo.ServerRepository, passwordCost int,
+	devices DeviceCA) (ServerService, error) {
+	if err := auth.CheckPasswordCost(passwordCost); err != nil {
+		return ServerService{}, err
+	}
+	dummyHash, err := auth.EncryptPasswordCost(uuid.New().String(), passwordCost)
+	if err != nil {
+		return ServerService{}, fmt.Errorf("auth.EncryptPasswordCost: %w", err)
 	}
+	return ServerService{
+		repository:   repository,
+		audit:        NewAuditLogger(repository),
+		passwordCost: passwordCost,
+		devices:      devices,
+		dummyHash:    dummyHash,
+	}, nil
 }
 
-func (s *ServerService) Register(ctx context.Context, login, password string) (model.User, error) {
-	ePassword, err := auth.EncryptPassword(password)
+func (s *ServerService) Register(ctx context.Context, u model.AuthUser) (model.User, error) {
+	ctx, span := tracer.Start(ctx, "ServerService.Register")
+	defer span.End()
+	ePassword, err := auth.EncryptPasswordCost(u.Password, s.passwordCost)
 	if err != nil {
-		return model.User{}, fmt.Errorf("auth.EncryptPassword: %w", err)
+		return model.User{}, fmt.Errorf("auth.EncryptPasswordCost: %w", err)
+	}
+	newUser := model.NewUser(u.Login, ePassword)
+	newUser.Vault = u.Vault
+	if u.Recovery != nil {
+		newUser.RecoveryKey = u.Recovery.WrappedKey
+		newUser.RecoveryHash, err = auth.EncryptPasswordCost(u.Recovery.Auth, s.passwordCost)
+		if err != nil {
+			return model.User{}, fmt.Errorf("auth.EncryptPasswordCost: %w", err)
+		}
 	}
-	newUser := model.NewUser(login, ePassword)
 	user, err := s.repository.CreateUser(ctx, newUser)
 	if err != nil {
 		return model.User{}, fmt.Errorf("repository.CreateUser: %w", err)
 	}
+	s.audit.Log(ctx, user.ID, model.AuditRegister, "")
 	user.HashedPassword = ""
 	return user, nil
 }
 
+// Login checks the password. A hash made with another cost than the
+// configured one is replaced while the password is at hand.
 func (s *ServerService) Login(ctx context.Context, login, password string) (model.User, error) {
+	ctx, span := tracer.Start(ctx, "ServerService.Login")
+	defer span.End()
 	us, err := s.repository.FindUserByLogin(ctx, login)
 	if err != nil {
+		if errors.Is(err, repo.ErrItemNotFound) {
+			_ = auth.ComparePasswords(s.dummyHash, password)
+			s.audit.Log(ctx, "", model.AuditLoginFailed, "login "+login)
+		}
 		return model.User{}, fmt.Errorf("repository.FindUserByLogin: %w", err)
 	}
 	err = auth.ComparePasswords(us.HashedPassword, password)
 	if err != nil {
+		s.audit.Log(ctx, us.ID, model.AuditLoginFailed, "")
 		return model.User{}, err
 	}
+	s.audit.Log(ctx, us.ID, model.AuditLogin, "")
+	if auth.NeedsRehash(us.HashedPassword, s.passwordCost) {
+		s.rehash(ctx, us.ID, password)
+	}
 	us.HashedPassword = ""
 	return us, nil
 }
 
+func (s *ServerService) rehash(ctx context.Context, userID, password string) {
+	hash, err := auth.EncryptPasswordCost(password, s.passwordCost)
+	if err == nil {
+		err = s.repository.UpdateUserPasswordHash(ctx, userID, hash)
+	}
+	if err != nil {
+		logger.Log.Error("rehash password", zap.String("userID", userID), zap.Error(err))
+		return
+	}
+	logger.Log.Info("password rehashed", zap.String("userID", userID))
+}
+
 func (s *ServerService) RegisterClient(ctx context.Context, client model.Client) (model.Client, error) {
-	client, err := s.repository.CreateClient(ctx, client)
+	ctx, span := tracer.Start(ctx, "ServerService.RegisterClient")
+	defer span.End()
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		return model.Client{}, fmt.Errorf("auth.GetUserID: %w", err)
+	}
+	if client.UserID != userID {
+		return model.Client{}, ErrAccessDenied
+	}
+	client, err = s.signClient(client)
+	if err != nil {
+		return model.Client{}, fmt.Errorf("s.signClient: %w", err)
+	}
+	client, err = s.repository.CreateClient(ctx, client)
 	if err != nil {
 		return model.Client{}, fmt.Errorf("repository.CreateClient: %w", err)
 	}
+	s.audit.Log(ctx, client.UserID, model.AuditClientRegister, "client "+client.ID)
 	return client, nil
 }
 
 func (s *ServerService) CheckClient(ctx context.Context, id string) (model.Client, error) {
+	ctx, span := tracer.Start(ctx, "ServerService.CheckClient")
+	defer span.End()
 	client, err := s.repository.FindClientByID(ctx, id)
 	if err != nil {
 		return model.Client{}, fmt.Errorf("repository.FindClientByID: %w", err)
@@ -65,6 +143,11 @@ func (s *ServerService) CheckClient(ctx context.Context, id string) (model.Clien
 }
 
 func (s *ServerService) SaveCredentials(ctx context.Context, cred model.Credentials) error {
+	ctx, span := tracer.Start(ctx, "ServerService.SaveCredentials")
+	defer span.End()
+	if err := cred.Validate(); err != nil {
+		return fmt.Errorf("cred.Validate: %w", err)
+	}
 	err := s.repository.SaveCredentials(ctx, cred)
 	if err != nil {
 		return fmt.Errorf("repository.SaveCredentials: %w", err)
@@ -73,6 +156,8 @@ func (s *ServerService) SaveCredentials(ctx context.Context, cred model.Credenti
 }
 
 func (s *ServerService) FindCredentialsByID(ctx context.Context, id string) (model.Credentials, error) {
+	ctx, span := tracer.Start(ctx, "ServerService.FindCredentialsByID")
+	defer span.End()
 	cred, err := s.repository.FindCredentialsByID(ctx, id)
 	if err != nil {
 		return model.Credentials{}, fmt.Errorf("repository.FindCredentialsByID: %w", err)
@@ -82,6 +167,8 @@ func (s *ServerService) FindCredentialsByID(ctx context.Context, id string) (mod
 }
 
 func (s *ServerService) FindCredentialsByUserID(ctx context.Context) ([]model.Credentials, error) {
+	ctx, span := tracer.Start(ctx, "ServerService.FindCredentialsByUserID")
+	defer span.End()
 	userID, err := auth.GetUserID(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("auth.GetUserID: %w", err)
@@ -94,14 +181,22 @@ func (s *ServerService) FindCredentialsByUserID(ctx context.Context) ([]model.Cr
 }
 
 func (s *ServerService) DeleteCredentialsByID(ctx context.Context, id string) error {
+	ctx, span := tracer.Start(ctx, "ServerService.DeleteCredentialsByID")
+	defer span.End()
 	err := s.repository.DeleteCredentialsByID(ctx, id)
 	if err != nil {
 		return fmt.Errorf("repository.DeleteCredentialsByID: %w", err)
 	}
+	s.audit.LogCurrent(ctx, model.AuditDelete, "credentials "+id)
 	return nil
 }
 
 func (s *ServerService) SaveText(ctx context.Context, txt *model.Text) error {
+	ctx, span := tracer.Start(ctx, "ServerService.SaveText")
+	defer span.End()
+	if err := txt.Validate(); err != nil {
+		return fmt.Errorf("txt.Validate: %w", err)
+	}
 	err := s.repository.SaveText(ctx, txt)
 	if err != nil {
 		return fmt.Errorf("repository.SaveText: %w", err)
@@ -110,6 +205,8 @@ func (s *ServerService) SaveText(ctx context.Context, txt *model.Text) error {
 }
 
 func (s *ServerService) FindTextByID(ctx context.Context, id string) (*model.Text, error) {
+	ctx, span := tracer.Start(ctx, "ServerService.FindTextByID")
+	defer span.End()
 	text, err := s.repository.FindTextByID(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("repository.FindTextByID: %w", err)
@@ -118,6 +215,8 @@ func (s *ServerService) FindTextByID(ctx context.Context, id string) (*model.Tex
 }
 
 func (s *ServerService) FindTextsByUserID(ctx context.Context) ([]*model.Text, error) {
+	ctx, span := tracer.Start(ctx, "ServerService.FindTextsByUserID")
+	defer span.End()
 	userID, err := auth.GetUserID(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("auth.GetUserID: %w", err)
@@ -130,14 +229,22 @@ func (s *ServerService) FindTextsByUserID(ctx context.Context) ([]*model.Text, e
 }
 
 func (s *ServerService) DeleteTextByID(ctx context.Context, id string) error {
+	ctx, span := tracer.Start(ctx, "ServerService.DeleteTextByID")
+	defer span.End()
 	err := s.repository.DeleteTextByID(ctx, id)
 	if err != nil {
 		return fmt.Errorf("repository.DeleteTextByID: %w", err)
 	}
+	s.audit.LogCurrent(ctx, model.AuditDelete, "text "+id)
 	return nil
 }
 
 func (s *ServerService) SaveBinary(ctx context.Context, bin *model.Binary) error {
+	ctx, span := tracer.Start(ctx, "ServerService.SaveBinary")
+	defer span.End()
+	if err := bin.Validate(); err != nil {
+		return fmt.Errorf("bin.Validate: %w", err)
+	}
 	err := s.repository.SaveBinary(ctx, bin)
 	if err != nil {
 		return fmt.Errorf("repository.SaveBinary: %w", err)
@@ -146,6 +253,8 @@ func (s *ServerService) SaveBinary(ctx context.Context, bin *model.Binary) error
 }
 
 func (s *ServerService) FindBinaryByID(ctx context.Context, id string) (*model.Binary, error) {
+	ctx, span := tracer.Start(ctx, "ServerService.FindBinaryByID")
+	defer span.End()
 	b, err := s.repository.FindBinaryByID(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("repository.FindBinaryByID: %w", err)
@@ -154,6 +263,8 @@ func (s *ServerService) FindBinaryByID(ctx context.Context, id string) (*model.B
 }
 
 func (s *ServerService) FindBinariesByUserID(ctx context.Context) ([]*model.Binary, error) {
+	ctx, span := tracer.Start(ctx, "ServerService.FindBinariesByUserID")
+	defer span.End()
 	userID, err := auth.GetUserID(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("auth.GetUserID: %w", err)
@@ -166,14 +277,22 @@ func (s *ServerService) FindBinariesByUserID(ctx context.Context) ([]*model.Bina
 }
 
 func (s *ServerService) DeleteBinaryByID(ctx context.Context, id string) error {
+	ctx, span := tracer.Start(ctx, "ServerService.DeleteBinaryByID")
+	defer span.End()
 	err := s.repository.DeleteBinaryByID(ctx, id)
 	if err != nil {
 		return fmt.Errorf("repository.DeleteBinaryByID: %w", err)
 	}
+	s.audit.LogCurrent(ctx, model.AuditDelete, "binary "+id)
 	return nil
 }
 
 func (s *ServerService) SaveCard(ctx context.Context, card model.Card) error {
+	ctx, span := tracer.Start(ctx, "ServerService.SaveCard")
+	defer span.End()
+	if err := card.Validate(); err != nil {
+		return fmt.Errorf("card.Validate: %w", err)
+	}
 	err := s.repository.SaveCard(ctx, card)
 	if err != nil {
 		return fmt.Errorf("repository.SaveCard: %w", err)
@@ -182,6 +301,8 @@ func (s *ServerService) SaveCard(ctx context.Context, card model.Card) error {
 }
 
 func (s *ServerService) FindCardByID(ctx context.Context, id string) (model.Card, error) {
+	ctx, span := tracer.Start(ctx, "ServerService.FindCardByID")
+	defer span.End()
 	card, err := s.repository.FindCardByID(ctx, id)
 	if err != nil {
 		return model.Card{}, fmt.Errorf("repository.FindCardByID: %w", err)
@@ -190,6 +311,8 @@ func (s *ServerService) FindCardByID(ctx context.Context, id string) (model.Card
 }
 
 func (s *ServerService) FindCardsByUserID(ctx context.Context) ([]model.Card, error) {
+	ctx, span := tracer.Start(ctx, "ServerService.FindCardsByUserID")
+	defer span.End()
 	userID, err := auth.GetUserID(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("auth.GetUserID: %w", err)
@@ -202,15 +325,23 @@ func (s *ServerService) FindCardsByUserID(ctx context.Context) ([]model.Card, er
 }
 
 func (s *ServerService) DeleteCardByID(ctx context.Context, id string) error {
+	ctx, span := tracer.Start(ctx, "ServerService.DeleteCardByID")
+	defer span.End()
 	err := s.repository.DeleteCardByID(ctx, id)
 	if err != nil {
 		return fmt.Errorf("repository.DeleteCardByID: %w", err)
 	}
+	s.audit.LogCurrent(ctx, model.AuditDelete, "card "+id)
 	return nil
 }
 
 func (s *ServerService) SyncCredentials(ctx context.Context,
 	sync *model.CredSync) ([]model.Credentials, error) {
+	ctx, span := tracer.Start(ctx, "ServerService.SyncCredentials")
+	defer span.End()
+	if err := sync.Validate(); err != nil {
+		return nil, fmt.Errorf("sync.Validate: %w", err)
+	}
 	userID, err := auth.GetUserID(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("auth.GetUserID: %w", err)
@@ -219,6 +350,7 @@ func (s *ServerService) SyncCredentials(ctx context.Context,
 
 	creds := sync.Credentials
 	log.Debug(fmt.Sprintf("credentials length for sync = %d", len(creds)))
+	metrics.SyncBatch("credentials", len(creds))
 
 	modifiedAfter, err := s.repository.FindCredentialsModifiedAfter(ctx, userID,
 		sync.LastSyncTms)
@@ -226,6 +358,7 @@ func (s *ServerService) SyncCredentials(ctx context.Context,
 		return nil, fmt.Errorf("repository.FindCredentialsModifiedAfter: %w", err)
 	}
 
+	var deleted []string
 	err = s.repository.InTransaction(ctx, func(ctx context.Context) error {
 		for i := 0; i < len(creds); i++ {
 			credentials := creds[i]
@@ -241,6 +374,7 @@ func (s *ServerService) SyncCredentials(ctx context.Context,
 			if checkNeeded && saved.ModifiedTms.After(credentials.ModifiedTms) {
 				log.Debug(fmt.Sprintf("credentials with id = %s "+
 					"did not saved^ because newer version was saved", credentials.ID))
+				metrics.SyncConflict("credentials")
 				continue
 			}
 			errSave := s.repository.SaveCredentials(ctx, *credentials)
@@ -249,6 +383,10 @@ func (s *ServerService) SyncCredentials(ctx context.Context,
 					credentials.ID), zap.Error(errSave))
 				return errSave
 			}
+			if credentials.Status == model.StatusDeleted &&
+				(!checkNeeded || saved.Status != model.StatusDeleted) {
+				deleted = append(deleted, credentials.ID)
+			}
 		}
 
 		return nil
@@ -256,10 +394,18 @@ func (s *ServerService) SyncCredentials(ctx context.Context,
 	if err != nil {
 		return nil, fmt.Errorf("repository.InTransaction: %w", err)
 	}
+	for _, id := range deleted {
+		s.audit.Log(ctx, userID, model.AuditDelete, "credentials "+id)
+	}
 	return modifiedAfter, nil
 }
 func (s *ServerService) SyncCard(ctx context.Context,
 	sync *model.CardSync) ([]model.Card, error) {
+	ctx, span := tracer.Start(ctx, "ServerService.SyncCard")
+	defer span.End()
+	if err := sync.Validate(); err != nil {
+		return nil, fmt.Errorf("sync.Validate: %w", err)
+	}
 	userID, err := auth.GetUserID(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("auth.GetUserID: %w", err)
@@ -268,12 +414,14 @@ func (s *ServerService) SyncCard(ctx context.Context,
 
 	cards := sync.Cards
 	log.Debug(fmt.Sprintf("cards length for sync = %d", len(cards)))
+	metrics.SyncBatch("card", len(cards))
 
 	modifiedAfter, err := s.repository.FindCardsModifiedAfter(ctx, userID,
 		sync.LastSyncTms)
 	if err != nil {
 		return nil, fmt.Errorf("repository.FindCardsModifiedAfter: %w", err)
 	}
+	var deleted []string
 	err = s.repository.InTransaction(ctx, func(ctx context.Context) error {
 		for i := 0; i < len(cards); i++ {
 			card := cards[i]
@@ -289,6 +437,7 @@ func (s *ServerService) SyncCard(ctx context.Context,
 			if checkNeeded && saved.ModifiedTms.After(card.ModifiedTms) {
 				log.Debug(fmt.Sprintf("card with id = %s "+
 					"did not saved^ because newer version was saved", card.ID))
+				metrics.SyncConflict("card")
 				continue
 			}
 			errSave := s.repository.SaveCard(ctx, *card)
@@ -297,15 +446,27 @@ func (s *ServerService) SyncCard(ctx context.Context,
 					card.ID), zap.Error(errSave))
 				return errSave
 			}
+			if card.Status == model.StatusDeleted &&
+				(!checkNeeded || saved.Status != model.StatusDeleted) {
+				deleted = append(deleted, card.ID)
+			}
 		}
 		return nil
 	})
 	if err != nil {
 		return nil, fmt.Errorf("repository.InTransaction: %w", err)
 	}
+	for _, id := range deleted {
+		s.audit.Log(ctx, userID, model.AuditDelete, "card "+id)
+	}
 	return modifiedAfter, nil
 }
 func (s *ServerService) SyncText(ctx context.Context, sync *model.TextSync) ([]*model.Text, error) {
+	ctx, span := tracer.Start(ctx, "ServerService.SyncText")
+	defer span.End()
+	if err := sync.Validate(); err != nil {
+		return nil, fmt.Errorf("sync.Validate: %w", err)
+	}
 	userID, err := auth.GetUserID(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("auth.GetUserID: %w", err)
@@ -314,6 +475,7 @@ func (s *ServerService) SyncText(ctx context.Context, sync *model.TextSync) ([]*
 
 	texts := sync.Texts
 	log.Debug(fmt.Sprintf("texts length for sync = %d", len(texts)))
+	metrics.SyncBatch("text", len(texts))
 
 	textsAfter, err := s.repository.FindActiveTextsModifiedAfter(ctx, userID,
 		sync.LastSyncTms)
@@ -327,6 +489,7 @@ func (s *ServerService) SyncText(ctx context.Context, sync *model.TextSync) ([]*
 		return nil, fmt.Errorf("repository.FindDeletedTextsModifiedAfter: %w", err)
 	}
 	textsAfter = append(textsAfter, textsDeleted...)
+	var deleted []string
 	err = s.repository.InTransaction(ctx, func(ctx context.Context) error {
 		for i := 0; i < len(texts); i++ {
 			text := texts[i]
@@ -342,6 +505,7 @@ func (s *ServerService) SyncText(ctx context.Context, sync *model.TextSync) ([]*
 			if checkNeeded && saved.ModifiedTms.After(text.ModifiedTms) {
 				log.Debug(fmt.Sprintf("card with id = %s "+
 					"did not saved^ because newer version was saved", text.ID))
+				metrics.SyncConflict("text")
 				continue
 			}
 			errSave := s.repository.SaveText(ctx, text)
@@ -350,16 +514,28 @@ func (s *ServerService) SyncText(ctx context.Context, sync *model.TextSync) ([]*
 					text.ID), zap.Error(errSave))
 				return errSave
 			}
+			if text.Status == model.StatusDeleted &&
+				(!checkNeeded || saved.Status != model.StatusDeleted) {
+				deleted = append(deleted, text.ID)
+			}
 		}
 		return nil
 	})
 	if err != nil {
 		return nil, fmt.Errorf("repository.InTransaction: %w", err)
 	}
+	for _, id := range deleted {
+		s.audit.Log(ctx, userID, model.AuditDelete, "text "+id)
+	}
 	return textsAfter, nil
 }
 func (s *ServerService) SyncBinary(ctx context.Context,
 	sync *model.BinarySync) ([]*model.Binary, error) {
+	ctx, span := tracer.Start(ctx, "ServerService.SyncBinary")
+	defer span.End()
+	if err := sync.Validate(); err != nil {
+		return nil, fmt.Errorf("sync.Validate: %w", err)
+	}
 	userID, err := auth.GetUserID(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("auth.GetUserID: %w", err)
@@ -368,6 +544,7 @@ func (s *ServerService) SyncBinary(ctx context.Context,
 
 	binaries := sync.Binaries
 	log.Debug(fmt.Sprintf("binaries length for sync = %d", len(binaries)))
+	metrics.SyncBatch("binary", len(binaries))
 
 	binaryAfter, err := s.repository.FindActiveBinariesModifiedAfter(ctx, userID,
 		sync.LastSyncTms)
@@ -381,6 +558,7 @@ func (s *ServerService) SyncBinary(ctx context.Context,
 		return nil, fmt.Errorf("repository.FindDeletedBinariesModifiedAfter: %w", err)
 	}
 	binaryAfter = append(binaryAfter, binariesDeleted...)
+	var deleted []string
 	err = s.repository.InTransaction(ctx, func(ctx context.Context) error {
 		for i := 0; i < len(binaries); i++ {
 			binary := binaries[i]
@@ -396,6 +574,7 @@ func (s *ServerService) SyncBinary(ctx context.Context,
 			if checkNeeded && saved.ModifiedTms.After(binary.ModifiedTms) {
 				log.Debug(fmt.Sprintf("card with id = %s "+
 					"did not saved^ because newer version was saved", binary.ID))
+				metrics.SyncConflict("binary")
 				continue
 			}
 			errSave := s.repository.SaveBinary(ctx, binary)
@@ -404,19 +583,29 @@ func (s *ServerService) SyncBinary(ctx context.Context,
 					binary.ID), zap.Error(errSave))
 				return errSave
 			}
+			if binary.Status == model.StatusDeleted &&
+				(!checkNeeded || saved.Status != model.StatusDeleted) {
+				deleted = append(deleted, binary.ID)
+			}
 		}
 		return nil
 	})
 	if err != nil {
 		return nil, fmt.Errorf("repository.InTransaction: %w", err)
 	}
+	for _, id := range deleted {
+		s.audit.Log(ctx, userID, model.AuditDelete, "binary "+id)
+	}
 	return binaryAfter, nil
 }
 
 func (s *ServerService) UpdateClientLastSyncTms(ctx context.Context, client model.Client) error {
+	ctx, span := tracer.Start(ctx, "ServerService.UpdateClientLastSyncTms")
+	defer span.End()
 	err := s.repository.UpdateClientLastSyncTmsByID(ctx, client.ID, client.SyncTms)
 	if err != nil {
 		return fmt.Errorf("repository.UpdateClientLastSyncTmsByID: %w", err)
 	}
+	s.audit.LogCurrent(ctx, model.AuditSync, "client "+client.ID)
 	return nil
 }
@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/server/repo"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/auth"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+)
+
+var ErrAccessDenied = errors.New("access denied")
+
+var ErrKeyExists = errors.New("user key already exists")
+
+var ErrStale = errors.New("newer version is saved")
+
+// SaveUserKey publishes the keypair of the user. A published key can't be
+// replaced, items shared with the user are wrapped for it, only its
+// private key can be wrapped again.
+func (s *ServerService) SaveUserKey(ctx context.Context, key model.UserKey) error {
+	ctx, span := tracer.Start(ctx, "ServerService.SaveUserKey")
+	defer span.End()
+	if err := key.Validate(); err != nil {
+		return fmt.Errorf("key.Validate: %w", err)
+	}
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		return fmt.Errorf("auth.GetUserID: %w", err)
+	}
+	key.UserID = userID
+	saved, err := s.repository.FindUserKeyByUserID(ctx, userID)
+	if err == nil {
+		if saved.PublicKey != key.PublicKey {
+			return ErrKeyExists
+		}
+		if saved.WrappedPrivateKey == key.WrappedPrivateKey {
+			return nil
+		}
+	} else if !errors.Is(err, repo.ErrItemNotFound) {
+		return fmt.Errorf("repository.FindUserKeyByUserID: %w", err)
+	}
+	err = s.repository.SaveUserKey(ctx, key)
+	if err != nil {
+		return fmt.Errorf("repository.SaveUserKey: %w", err)
+	}
+	return nil
+}
+
+func (s *ServerService) FindUserKey(ctx context.Context) (model.UserKey, error) {
+	ctx, span := tracer.Start(ctx, "ServerService.FindUserKey")
+	defer span.End()
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		return model.UserKey{}, fmt.Errorf("auth.GetUserID: %w", err)
+	}
+	key, err := s.repository.FindUserKeyByUserID(ctx, userID)
+	if err != nil {
+		return model.UserKey{}, fmt.Errorf("repository.FindUserKeyByUserID: %w", err)
+	}
+	return key, nil
+}
+
+// FindPublicKey returns the public key of another user.
+func (s *ServerService) FindPublicKey(ctx context.Context, login string) (model.UserKey, error) {
+	ctx, span := tracer.Start(ctx, "ServerService.FindPublicKey")
+	defer span.End()
+	usr, err := s.repository.FindUserByLogin(ctx, login)
+	if err != nil {
+		return model.UserKey{}, fmt.Errorf("repository.FindUserByLogin: %w", err)
+	}
+	key, err := s.repository.FindUserKeyByUserID(ctx, usr.ID)
+	if err != nil {
+		return model.UserKey{}, fmt.Errorf("repository.FindUserKeyByUserID: %w", err)
+	}
+	key.WrappedPrivateKey = ""
+	return key, nil
+}
+
+// SaveShare creates a share or updates one of the user, the given grants
+// are added or replaced.
+func (s *ServerService) SaveShare(ctx context.Context, share model.Share) error {
+	ctx, span := tracer.Start(ctx, "ServerService.SaveShare")
+	defer span.End()
+	if err := share.Validate(); err != nil {
+		return fmt.Errorf("share.Validate: %w", err)
+	}
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		return fmt.Errorf("auth.GetUserID: %w", err)
+	}
+	saved, err := s.repository.FindShareByID(ctx, share.ID)
+	isNew := errors.Is(err, repo.ErrItemNotFound)
+	if err != nil && !isNew {
+		return fmt.Errorf("repository.FindShareByID: %w", err)
+	}
+	if !isNew && saved.OwnerID != userID {
+		return ErrAccessDenied
+	}
+	share.OwnerID = userID
+
+	var ownerGranted bool
+	for i := range share.Grants {
+		usr, err := s.repository.FindUserByLogin(ctx, share.Grants[i].Login)
+		if err != nil {
+			return fmt.Errorf("repository.FindUserByLogin %s: %w", share.Grants[i].Login, err)
+		}
+		share.Grants[i].UserID = usr.ID
+		ownerGranted = ownerGranted || usr.ID == userID
+	}
+	if isNew && !ownerGranted {
+		return &model.ValidationError{Entries: []model.ValidationErrEntry{
+			model.NewValidationErr("grants", []string{"the owner must be granted the item key"}),
+		}}
+	}
+
+	err = s.repository.InTransaction(ctx, func(ctx context.Context) error {
+		if err := s.repository.SaveShare(ctx, share); err != nil {
+			return fmt.Errorf("repository.SaveShare: %w", err)
+		}
+		for _, g := range share.Grants {
+			if err := s.repository.SaveShareGrant(ctx, share.ID, g); err != nil {
+				return fmt.Errorf("repository.SaveShareGrant: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("repository.InTransaction: %w", err)
+	}
+	for _, g := range share.Grants {
+		if g.UserID != userID {
+			s.audit.Log(ctx, userID, model.AuditShare,
+				fmt.Sprintf("share %s with %s, %s", share.ID, g.Login, g.Access))
+		}
+	}
+	return nil
+}
+
+// FindShares returns the shares the user owns or was granted. Item ids of
+// the owners are not disclosed to the grantees.
+func (s *ServerService) FindShares(ctx context.Context) ([]model.Share, error) {
+	ctx, span := tracer.Start(ctx, "ServerService.FindShares")
+	defer span.End()
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("auth.GetUserID: %w", err)
+	}
+	shares, err := s.repository.FindSharesByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("repository.FindSharesByUserID: %w", err)
+	}
+	for i := range shares {
+		if shares[i].OwnerID != userID {
+			shares[i].ItemID = ""
+		}
+	}
+	return shares, nil
+}
+
+// UpdateSharePayload saves a new version of the shared item, the owner and
+// the users with write access may do so.
+func (s *ServerService) UpdateSharePayload(ctx context.Context, id string,
+	payload model.SharePayload) error {
+	ctx, span := tracer.Start(ctx, "ServerService.UpdateSharePayload")
+	defer span.End()
+	if err := payload.Validate(); err != nil {
+		return fmt.Errorf("payload.Validate: %w", err)
+	}
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		return fmt.Errorf("auth.GetUserID: %w", err)
+	}
+	share, err := s.repository.FindShareByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("repository.FindShareByID: %w", err)
+	}
+	if share.OwnerID != userID {
+		grant, err := s.repository.FindShareGrant(ctx, id, userID)
+		if err != nil {
+			if errors.Is(err, repo.ErrItemNotFound) {
+				return ErrAccessDenied
+			}
+			return fmt.Errorf("repository.FindShareGrant: %w", err)
+		}
+		if grant.Access != model.AccessWrite {
+			return ErrAccessDenied
+		}
+	}
+	if share.ModifiedTms.After(payload.ModifiedTms) {
+		return ErrStale
+	}
+	err = s.repository.UpdateSharePayload(ctx, id, payload)
+	if err != nil {
+		return fmt.Errorf("repository.UpdateSharePayload: %w", err)
+	}
+	return nil
+}
+
+// RevokeShare removes the access of login, only the owner may do so.
+func (s *ServerService) RevokeShare(ctx context.Context, id, login string) error {
+	ctx, span := tracer.Start(ctx, "ServerService.RevokeShare")
+	defer span.End()
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		return fmt.Errorf("auth.GetUserID: %w", err)
+	}
+	share, err := s.repository.FindShareByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("repository.FindShareByID: %w", err)
+	}
+	if share.OwnerID != userID {
+		return ErrAccessDenied
+	}
+	usr, err := s.repository.FindUserByLogin(ctx, login)
+	if err != nil {
+		return fmt.Errorf("repository.FindUserByLogin: %w", err)
+	}
+	if usr.ID == userID {
+		return ErrAccessDenied
+	}
+	err = s.repository.DeleteShareGrant(ctx, id, usr.ID)
+	if err != nil {
+		return fmt.Errorf("repository.DeleteShareGrant: %w", err)
+	}
+	s.audit.Log(ctx, userID, model.AuditShareRevoke, fmt.Sprintf("share %s from %s", id, login))
+	return nil
+}
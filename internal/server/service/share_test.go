@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"github.com/denis-oreshkevich/gophkeeper/internal/server/repo"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/auth"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// shareRepo keeps users, shares and their grants in memory, the other
+// methods of the repository are not expected to be called.
+type shareRepo struct {
+	repo.ServerRepository
+	users  map[string]model.User
+	shares map[string]model.Share
+	grants map[string]map[string]model.ShareGrant
+}
+
+func newShareRepo(logins ...string) *shareRepo {
+	r := &shareRepo{
+		users:  make(map[string]model.User),
+		shares: make(map[string]model.Share),
+		grants: make(map[string]map[string]model.ShareGrant),
+	}
+	for _, l := range logins {
+		r.users[l] = model.User{ID: uuid.New().String(), Login: l}
+	}
+	return r
+}
+
+func (r *shareRepo) FindUserByLogin(_ context.Context, login string) (model.User, error) {
+	u, ok := r.users[login]
+	if !ok {
+		return model.User{}, repo.ErrItemNotFound
+	}
+	return u, nil
+}
+
+func (r *shareRepo) InTransaction(ctx context.Context, transact func(context.Context) error) error {
+	return transact(ctx)
+}
+
+func (r *shareRepo) SaveShare(_ context.Context, share model.Share) error {
+	share.Grants = nil
+	r.shares[share.ID] = share
+	return nil
+}
+
+func (r *shareRepo) FindShareByID(_ context.Context, id string) (model.Share, error) {
+	share, ok := r.shares[id]
+	if !ok {
+		return model.Share{}, repo.ErrItemNotFound
+	}
+	return share, nil
+}
+
+func (r *shareRepo) UpdateSharePayload(_ context.Context, id string, payload model.SharePayload) error {
+	share := r.shares[id]
+	share.Payload, share.ModifiedTms = payload.Payload, payload.ModifiedTms
+	r.shares[id] = share
+	return nil
+}
+
+func (r *shareRepo) SaveShareGrant(_ context.Context, shareID string, grant model.ShareGrant) error {
+	if r.grants[shareID] == nil {
+		r.grants[shareID] = make(map[string]model.ShareGrant)
+	}
+	r.grants[shareID][grant.UserID] = grant
+	return nil
+}
+
+func (r *shareRepo) FindShareGrant(_ context.Context, shareID, userID string) (model.ShareGrant, error) {
+	grant, ok := r.grants[shareID][userID]
+	if !ok {
+		return model.ShareGrant{}, repo.ErrItemNotFound
+	}
+	return grant, nil
+}
+
+func (r *shareRepo) DeleteShareGrant(_ context.Context, shareID, userID string) error {
+	if _, ok := r.grants[shareID][userID]; !ok {
+		return repo.ErrItemNotFound
+	}
+	delete(r.grants[shareID], userID)
+	return nil
+}
+
+func (r *shareRepo) SaveAuditEvent(context.Context, model.AuditEvent) error {
+	return nil
+}
+
+func (r *shareRepo) asUser(login string) context.Context {
+	return context.WithValue(context.Background(), auth.UserIDKey{}, r.users[login].ID)
+}
+
+func newShare(id string, modified time.Time, grants ...model.ShareGrant) model.Share {
+	return model.Share{
+		ID:          id,
+		ItemID:      uuid.New().String(),
+		Kind:        "text",
+		Payload:     "cGF5bG9hZA==",
+		ModifiedTms: modified,
+		Grants:      grants,
+	}
+}
+
+func shareGrant(login string, access model.Access) model.ShareGrant {
+	return model.ShareGrant{Login: login, WrappedKey: "AAAAAAAAAAAAAAAAAAAAAA==", Access: access}
+}
+
+func TestShareAccess(t *testing.T) {
+	tests := []struct {
+		name string
+		// change is made by alice after bob was granted write access
+		change  func(s *ServerService, r *shareRepo, id string) error
+		wantErr error
+	}{
+		{
+			name:   "write access kept",
+			change: func(*ServerService, *shareRepo, string) error { return nil },
+		},
+		{
+			name: "downgraded to read",
+			change: func(s *ServerService, r *shareRepo, id string) error {
+				return s.SaveShare(r.asUser("alice"), newShare(id, time.Now().UTC(),
+					shareGrant("bob", model.AccessRead)))
+			},
+			wantErr: ErrAccessDenied,
+		},
+		{
+			name: "revoked",
+			change: func(s *ServerService, r *shareRepo, id string) error {
+				return s.RevokeShare(r.asUser("alice"), id, "bob")
+			},
+			wantErr: ErrAccessDenied,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newShareRepo("alice", "bob")
+			s := &ServerService{repository: r, audit: NewAuditLogger(r)}
+			id := uuid.New().String()
+			created := time.Now().UTC().Add(-time.Hour)
+			require.NoError(t, s.SaveShare(r.asUser("alice"), newShare(id, created,
+				shareGrant("alice", model.AccessWrite), shareGrant("bob", model.AccessWrite))))
+			require.NoError(t, tt.change(s, r, id))
+
+			payload := model.SharePayload{Payload: "bmV3", ModifiedTms: time.Now().UTC().Add(time.Minute)}
+			err := s.UpdateSharePayload(r.asUser("bob"), id, payload)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.NotEqual(t, payload.Payload, r.shares[id].Payload)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, payload.Payload, r.shares[id].Payload)
+		})
+	}
+}
+
+func TestSaveShareNotOwner(t *testing.T) {
+	r := newShareRepo("alice", "bob", "mallory")
+	s := &ServerService{repository: r, audit: NewAuditLogger(r)}
+	id := uuid.New().String()
+	require.NoError(t, s.SaveShare(r.asUser("alice"), newShare(id, time.Now().UTC(),
+		shareGrant("alice", model.AccessWrite), shareGrant("bob", model.AccessRead))))
+
+	tests := []struct {
+		name  string
+		login string
+		grant model.ShareGrant
+	}{
+		{name: "grantee upgrades own access", login: "bob", grant: shareGrant("bob", model.AccessWrite)},
+		{name: "stranger grants self", login: "mallory", grant: shareGrant("mallory", model.AccessWrite)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.SaveShare(r.asUser(tt.login), newShare(id, time.Now().UTC(), tt.grant))
+			assert.ErrorIs(t, err, ErrAccessDenied)
+			assert.Equal(t, model.AccessRead, r.grants[id][r.users["bob"].ID].Access)
+			assert.NotContains(t, r.grants[id], r.users["mallory"].ID)
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/server/repo"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/auth"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/logger"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/model"
+	"go.uber.org/zap"
+)
+
+// ChangePassword replaces the password of the user after checking the old
+// one. The vault key itself does not change, only its wrapping.
+func (s *ServerService) ChangePassword(ctx context.Context, c model.PasswordChange) error {
+	ctx, span := tracer.Start(ctx, "ServerService.ChangePassword")
+	defer span.End()
+	if err := c.Validate(); err != nil {
+		return fmt.Errorf("c.Validate: %w", err)
+	}
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		return fmt.Errorf("auth.GetUserID: %w", err)
+	}
+	us, err := s.repository.FindUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("repository.FindUserByID: %w", err)
+	}
+	err = auth.ComparePasswords(us.HashedPassword, c.Password)
+	if err != nil {
+		s.audit.Log(ctx, userID, model.AuditPasswordFailed, "password change")
+		return err
+	}
+	us.RecoveryKey, us.RecoveryHash = "", ""
+	if c.Recovery != nil {
+		us.RecoveryKey = c.Recovery.WrappedKey
+		us.RecoveryHash, err = auth.EncryptPasswordCost(c.Recovery.Auth, s.passwordCost)
+		if err != nil {
+			return fmt.Errorf("auth.EncryptPasswordCost: %w", err)
+		}
+	}
+	err = s.updatePassword(ctx, us, c.NewPassword, c.Vault)
+	if err != nil {
+		return err
+	}
+	s.audit.Log(ctx, userID, model.AuditPasswordChange, "")
+	return nil
+}
+
+// DeleteUser erases the account after checking the password. Everything of
+// the user goes in one transaction: items, clients, keys, shares, emergency
+// access and the organizations the user owns.
+func (s *ServerService) DeleteUser(ctx context.Context, d model.UserDeletion) error {
+	ctx, span := tracer.Start(ctx, "ServerService.DeleteUser")
+	defer span.End()
+	if err := d.Validate(); err != nil {
+		return fmt.Errorf("d.Validate: %w", err)
+	}
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		return fmt.Errorf("auth.GetUserID: %w", err)
+	}
+	us, err := s.repository.FindUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("repository.FindUserByID: %w", err)
+	}
+	err = auth.ComparePasswords(us.HashedPassword, d.Password)
+	if err != nil {
+		s.audit.Log(ctx, userID, model.AuditPasswordFailed, "account deletion")
+		return err
+	}
+	err = s.repository.InTransaction(ctx, func(ctx context.Context) error {
+		err := s.repository.DeleteOrgsByOwnerID(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("repository.DeleteOrgsByOwnerID: %w", err)
+		}
+		err = s.repository.DeleteUser(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("repository.DeleteUser: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("repository.InTransaction: %w", err)
+	}
+	logger.Log.Info("user deleted", zap.String("userID", userID))
+	return nil
+}
+
+// Prelogin tells the client what proves the password of the login. Unknown
+// logins get the answer of new accounts.
+func (s *ServerService) Prelogin(ctx context.Context, r model.PreloginRequest) (model.Prelogin, error) {
+	ctx, span := tracer.Start(ctx, "ServerService.Prelogin")
+	defer span.End()
+	if err := r.Validate(); err != nil {
+		return model.Prelogin{}, fmt.Errorf("r.Validate: %w", err)
+	}
+	us, err := s.repository.FindUserByLogin(ctx, r.Login)
+	if errors.Is(err, repo.ErrItemNotFound) {
+		return model.Prelogin{LoginSecret: true}, nil
+	}
+	if err != nil {
+		return model.Prelogin{}, fmt.Errorf("repository.FindUserByLogin: %w", err)
+	}
+	return model.Prelogin{LoginSecret: us.Vault != nil}, nil
+}
+
+// FindRecoveryKit returns the vault key wrapped with the recovery key to
+// whoever proves knowing the recovery key.
+func (s *ServerService) FindRecoveryKit(ctx context.Context, r model.RecoveryRequest) (model.RecoveryKit, error) {
+	ctx, span := tracer.Start(ctx, "ServerService.FindRecoveryKit")
+	defer span.End()
+	if err := r.Validate(); err != nil {
+		return model.RecoveryKit{}, fmt.Errorf("r.Validate: %w", err)
+	}
+	us, err := s.recoveringUser(ctx, r.Login, r.Auth)
+	if err != nil {
+		return model.RecoveryKit{}, err
+	}
+	s.audit.Log(ctx, us.ID, model.AuditRecovery, "")
+	return model.RecoveryKit{WrappedKey: us.RecoveryKey}, nil
+}
+
+// ResetPassword sets a new password with the recovery key, the recovery
+// kit stays valid.
+func (s *ServerService) ResetPassword(ctx context.Context, r model.PasswordReset) (model.User, error) {
+	ctx, span := tracer.Start(ctx, "ServerService.ResetPassword")
+	defer span.End()
+	if err := r.Validate(); err != nil {
+		return model.User{}, fmt.Errorf("r.Validate: %w", err)
+	}
+	us, err := s.recoveringUser(ctx, r.Login, r.Auth)
+	if err != nil {
+		return model.User{}, err
+	}
+	us.RecoveryKey, us.RecoveryHash = "", ""
+	err = s.updatePassword(ctx, us, r.NewPassword, r.Vault)
+	if err != nil {
+		return model.User{}, err
+	}
+	s.audit.Log(ctx, us.ID, model.AuditPasswordReset, "")
+	us.HashedPassword = ""
+	us.Vault = &r.Vault
+	return us, nil
+}
+
+// recoveringUser finds the user by login and checks the recovery proof.
+// Users without a recovery kit can't recover, to the caller they look like
+// a wrong proof.
+func (s *ServerService) recoveringUser(ctx context.Context, login, proof string) (model.User, error) {
+	us, err := s.repository.FindUserByLogin(ctx, login)
+	if err != nil {
+		if errors.Is(err, repo.ErrItemNotFound) {
+			_ = auth.ComparePasswords(s.dummyHash, proof)
+		}
+		return model.User{}, fmt.Errorf("repository.FindUserByLogin: %w", err)
+	}
+	if us.RecoveryHash == "" {
+		_ = auth.ComparePasswords(s.dummyHash, proof)
+		s.audit.Log(ctx, us.ID, model.AuditRecoveryFailed, "no recovery kit")
+		return model.User{}, auth.ErrPasswordMismatch
+	}
+	err = auth.ComparePasswords(us.RecoveryHash, proof)
+	if err != nil {
+		s.audit.Log(ctx, us.ID, model.AuditRecoveryFailed, "")
+		return model.User{}, err
+	}
+	return us, nil
+}
+
+func (s *ServerService) updatePassword(ctx context.Context, us model.User,
+	password string, vault model.VaultKey) error {
+	hash, err := auth.EncryptPasswordCost(password, s.passwordCost)
+	if err != nil {
+		return fmt.Errorf("auth.EncryptPasswordCost: %w", err)
+	}
+	us.HashedPassword, us.Vault = hash, &vault
+	err = s.repository.UpdateUserPassword(ctx, us)
+	if err != nil {
+		return fmt.Errorf("repository.UpdateUserPassword: %w", err)
+	}
+	return nil
+}
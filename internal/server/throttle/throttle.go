@@ -0,0 +1,230 @@
+// Package throttle slows down password guessing. Failed attempts are counted
+// per login and per client address: every failure doubles the wait before
+// the next attempt, and enough of them lock the key out for a while.
+package throttle
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxEntries bounds the tracked keys of a scope. Expired ones are dropped
+// beyond it, then the oldest ones.
+const maxEntries = 10000
+
+// busyWait is the wait suggested while another attempt of a login is being
+// verified.
+const busyWait = time.Second
+
+type Config struct {
+	// LoginAttempts failures of a login lock it out, IPAttempts those of an
+	// address.
+	LoginAttempts int
+	IPAttempts    int
+
+	// Lockout is how long a key stays locked, failures older than that are
+	// forgotten.
+	Lockout time.Duration
+
+	// Backoff is the wait after the first failure, it doubles with every
+	// further one up to Lockout.
+	Backoff time.Duration
+}
+
+// Scope tells which key was locked out.
+type Scope string
+
+const (
+	ScopeLogin Scope = "login"
+
+	ScopeIP Scope = "ip"
+)
+
+type entry struct {
+	failures int
+	pending  int
+	last     time.Time
+	until    time.Time
+}
+
+type Throttle struct {
+	conf   Config
+	mx     sync.Mutex
+	logins map[string]*entry
+	ips    map[string]*entry
+	now    func() time.Time
+}
+
+func New(conf Config) (*Throttle, error) {
+	if conf.LoginAttempts < 1 || conf.IPAttempts < 1 {
+		return nil, errors.New("lockout attempts must be positive")
+	}
+	if conf.Lockout <= 0 || conf.Backoff < 0 {
+		return nil, errors.New("lockout must be positive and backoff not negative")
+	}
+	return &Throttle{
+		conf:   conf,
+		logins: make(map[string]*entry),
+		ips:    make(map[string]*entry),
+		now:    time.Now,
+	}, nil
+}
+
+// Attempt is a password attempt reserved by Begin. It is ended by Fail,
+// Succeed or Release, the first call counts.
+type Attempt struct {
+	t     *Throttle
+	ip    string
+	login string
+	done  bool
+}
+
+// Begin reserves an attempt of login from ip, or returns how long the
+// caller has to wait. The reservation is made before the password is
+// checked: a login has one attempt in flight at a time and those of an
+// address count against its limit, so parallel requests can't pass it.
+func (t *Throttle) Begin(ip, login string) (*Attempt, time.Duration) {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	now := t.now()
+	ipEntry, loginEntry := t.ips[ip], t.logins[login]
+	if w := max(wait(ipEntry, now), wait(loginEntry, now)); w > 0 {
+		return nil, w
+	}
+	if loginEntry != nil && loginEntry.pending > 0 {
+		return nil, busyWait
+	}
+	if ipEntry != nil && !t.expired(ipEntry, now) &&
+		ipEntry.failures+ipEntry.pending >= t.conf.IPAttempts {
+		return nil, busyWait
+	}
+	t.track(t.ips, ip, now).pending++
+	t.track(t.logins, login, now).pending++
+	return &Attempt{t: t, ip: ip, login: login}, 0
+}
+
+// Fail records a failed attempt and returns the scopes that got locked out
+// by it.
+func (a *Attempt) Fail() []Scope {
+	t := a.t
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	if !a.end() {
+		return nil
+	}
+	now := t.now()
+	var locked []Scope
+	if t.fail(t.ips, a.ip, t.conf.IPAttempts, now) {
+		locked = append(locked, ScopeIP)
+	}
+	if t.fail(t.logins, a.login, t.conf.LoginAttempts, now) {
+		locked = append(locked, ScopeLogin)
+	}
+	return locked
+}
+
+// Succeed forgets the failures of the login, those of the address stay.
+func (a *Attempt) Succeed() {
+	t := a.t
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	if a.end() {
+		delete(t.logins, a.login)
+	}
+}
+
+// Release ends an attempt that neither failed nor succeeded, e.g. on an
+// internal error.
+func (a *Attempt) Release() {
+	a.t.mx.Lock()
+	defer a.t.mx.Unlock()
+	a.end()
+}
+
+// end drops the reservation, it reports false when the attempt has ended
+// before.
+func (a *Attempt) end() bool {
+	if a.done {
+		return false
+	}
+	a.done = true
+	for _, e := range []*entry{a.t.ips[a.ip], a.t.logins[a.login]} {
+		if e != nil && e.pending > 0 {
+			e.pending--
+		}
+	}
+	return true
+}
+
+// track returns the entry of key, an expired one starts over.
+func (t *Throttle) track(entries map[string]*entry, key string, now time.Time) *entry {
+	e, ok := entries[key]
+	if ok && !t.expired(e, now) {
+		return e
+	}
+	if !ok && len(entries) >= maxEntries {
+		t.prune(entries, now)
+	}
+	e = &entry{}
+	entries[key] = e
+	return e
+}
+
+func (t *Throttle) fail(entries map[string]*entry, key string, attempts int,
+	now time.Time) bool {
+	e := t.track(entries, key, now)
+	e.failures++
+	e.last = now
+	if e.failures >= attempts {
+		e.failures = 0
+		e.until = now.Add(t.conf.Lockout)
+		return true
+	}
+	backoff := t.conf.Backoff << (e.failures - 1)
+	if backoff <= 0 || backoff > t.conf.Lockout {
+		backoff = t.conf.Lockout
+	}
+	e.until = now.Add(backoff)
+	return false
+}
+
+func (t *Throttle) expired(e *entry, now time.Time) bool {
+	return e.pending == 0 && now.After(e.until) && now.Sub(e.last) > t.conf.Lockout
+}
+
+// prune drops the expired entries. When that is not enough the oldest
+// ones without an attempt in flight go, down to nine tenths of the bound.
+func (t *Throttle) prune(entries map[string]*entry, now time.Time) {
+	for k, e := range entries {
+		if t.expired(e, now) {
+			delete(entries, k)
+		}
+	}
+	if len(entries) < maxEntries {
+		return
+	}
+	keys := make([]string, 0, len(entries))
+	for k, e := range entries {
+		if e.pending == 0 {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return entries[keys[i]].last.Before(entries[keys[j]].last)
+	})
+	for _, k := range keys {
+		if len(entries) < maxEntries*9/10 {
+			return
+		}
+		delete(entries, k)
+	}
+}
+
+func wait(e *entry, now time.Time) time.Duration {
+	if e == nil || !now.Before(e.until) {
+		return 0
+	}
+	return e.until.Sub(now)
+}
@@ -0,0 +1,213 @@
+package throttle
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func (c *clock) Add(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+var testConf = Config{
+	LoginAttempts: 3,
+	IPAttempts:    5,
+	Lockout:       time.Minute,
+	Backoff:       time.Second,
+}
+
+func newTestThrottle(t *testing.T, conf Config) (*Throttle, *clock) {
+	t.Helper()
+	th, err := New(conf)
+	require.NoError(t, err)
+	c := &clock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	th.now = c.Now
+	return th, c
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    Config
+		wantErr bool
+	}{
+		{name: "valid", conf: testConf},
+		{name: "no login attempts", conf: Config{IPAttempts: 1, Lockout: time.Minute}, wantErr: true},
+		{name: "no ip attempts", conf: Config{LoginAttempts: 1, Lockout: time.Minute}, wantErr: true},
+		{name: "no lockout", conf: Config{LoginAttempts: 1, IPAttempts: 1}, wantErr: true},
+		{name: "negative backoff", conf: Config{LoginAttempts: 1, IPAttempts: 1,
+			Lockout: time.Minute, Backoff: -time.Second}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.conf)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	th, c := newTestThrottle(t, testConf)
+	tests := []struct {
+		name     string
+		advance  time.Duration
+		wantWait time.Duration
+		fail     bool
+		locked   []Scope
+	}{
+		{name: "first attempt", fail: true},
+		{name: "during backoff", advance: 500 * time.Millisecond, wantWait: 500 * time.Millisecond},
+		{name: "after backoff", advance: 500 * time.Millisecond, fail: true},
+		{name: "backoff doubles", advance: time.Second, wantWait: time.Second},
+		{name: "lockout", advance: time.Second, fail: true, locked: []Scope{ScopeLogin}},
+		{name: "locked out", advance: 30 * time.Second, wantWait: 30 * time.Second},
+		{name: "lockout over", advance: 30 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c.Add(tt.advance)
+			a, wait := th.Begin("10.0.0.1", "alice")
+			assert.Equal(t, tt.wantWait, wait)
+			if tt.wantWait > 0 {
+				assert.Nil(t, a)
+				return
+			}
+			require.NotNil(t, a)
+			if tt.fail {
+				assert.Equal(t, tt.locked, a.Fail())
+				return
+			}
+			a.Release()
+		})
+	}
+}
+
+func TestSucceed(t *testing.T) {
+	th, c := newTestThrottle(t, testConf)
+	a, _ := th.Begin("10.0.0.1", "alice")
+	a.Fail()
+	c.Add(time.Second)
+
+	a, wait := th.Begin("10.0.0.1", "alice")
+	require.NotNil(t, a, "wait %s", wait)
+	a.Succeed()
+	// ending twice does nothing
+	a.Fail()
+
+	a, wait = th.Begin("10.0.0.1", "alice")
+	require.NotNil(t, a, "the failures of the login are forgotten")
+	assert.Zero(t, wait)
+	a.Release()
+	assert.Equal(t, 1, th.ips["10.0.0.1"].failures, "the failures of the address stay")
+}
+
+func TestInFlight(t *testing.T) {
+	tests := []struct {
+		name     string
+		first    [2]string
+		second   [2]string
+		wantBusy bool
+	}{
+		{name: "same login", first: [2]string{"10.0.0.1", "alice"},
+			second: [2]string{"10.0.0.2", "alice"}, wantBusy: true},
+		{name: "same address", first: [2]string{"10.0.0.1", "alice"},
+			second: [2]string{"10.0.0.1", "bob"}},
+		{name: "other login and address", first: [2]string{"10.0.0.1", "alice"},
+			second: [2]string{"10.0.0.2", "bob"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			th, _ := newTestThrottle(t, testConf)
+			first, _ := th.Begin(tt.first[0], tt.first[1])
+			require.NotNil(t, first)
+			second, wait := th.Begin(tt.second[0], tt.second[1])
+			if tt.wantBusy {
+				assert.Nil(t, second)
+				assert.Equal(t, busyWait, wait)
+			} else {
+				assert.NotNil(t, second)
+			}
+			first.Release()
+			third, _ := th.Begin(tt.first[0], tt.first[1])
+			assert.NotNil(t, third, "a released attempt frees the login")
+		})
+	}
+}
+
+func TestParallelAttempts(t *testing.T) {
+	conf := testConf
+	conf.Backoff = 0
+	th, _ := newTestThrottle(t, conf)
+
+	var (
+		wg       sync.WaitGroup
+		mx       sync.Mutex
+		attempts []*Attempt
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if a, _ := th.Begin("10.0.0.1", fmt.Sprintf("user%d", i)); a != nil {
+				mx.Lock()
+				attempts = append(attempts, a)
+				mx.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.Len(t, attempts, conf.IPAttempts, "attempts in flight are bound by the address limit")
+
+	locked := 0
+	for _, a := range attempts {
+		locked += len(a.Fail())
+	}
+	assert.Equal(t, 1, locked, "the address is locked out once")
+	a, wait := th.Begin("10.0.0.1", "user100")
+	assert.Nil(t, a)
+	assert.Equal(t, conf.Lockout, wait)
+}
+
+func TestBoundedEntries(t *testing.T) {
+	th, c := newTestThrottle(t, testConf)
+	for i := 0; i < maxEntries+100; i++ {
+		a, _ := th.Begin(fmt.Sprintf("ip%d", i), fmt.Sprintf("user%d", i))
+		require.NotNil(t, a)
+		a.Fail()
+		c.Add(time.Millisecond)
+	}
+	assert.LessOrEqual(t, len(th.logins), maxEntries)
+	assert.LessOrEqual(t, len(th.ips), maxEntries)
+	_, ok := th.logins[fmt.Sprintf("user%d", maxEntries+99)]
+	assert.True(t, ok, "the newest entry is kept")
+	_, ok = th.logins["user0"]
+	assert.False(t, ok, "the oldest entry is evicted")
+
+	// once the bound is reached again the expired entries go first
+	c.Add(2 * time.Minute)
+	for i := 0; len(th.logins) < maxEntries; i++ {
+		a, _ := th.Begin(fmt.Sprintf("new-ip%d", i), fmt.Sprintf("new-user%d", i))
+		require.NotNil(t, a)
+		a.Fail()
+	}
+	a, _ := th.Begin("ip-last", "user-last")
+	require.NotNil(t, a)
+	a.Fail()
+	assert.Less(t, len(th.logins), maxEntries/10)
+}
@@ -1,110 +1,372 @@
 package auth
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
-	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/hex"
 	"encoding/pem"
 	"errors"
 	"fmt"
 	"github.com/denis-oreshkevich/gophkeeper/internal/shared/logger"
-	"log"
+	"go.uber.org/zap"
 	"math/big"
 	"net"
 	"os"
+	"path/filepath"
+	"sync"
 	"time"
 )
 
+const (
+	caValidity   = 10 * 365 * 24 * time.Hour
+	certValidity = 365 * 24 * time.Hour
+
+	// certRenewBefore is how long before expiry a generated certificate is
+	// reissued.
+	certRenewBefore = 30 * 24 * time.Hour
+
+	// legacySerial is the fixed serial of the self-signed certificates older
+	// versions wrote with a world-readable key, they are replaced.
+	legacySerial = 1658
+)
+
+const (
+	certBlockType = "CERTIFICATE"
+	keyBlockType  = "PRIVATE KEY"
+	crlBlockType  = "X509 CRL"
+	csrBlockType  = "CERTIFICATE REQUEST"
+)
+
+// CertConfig says where the TLS files are and which DNS names and IPs the
+// generated server certificate is valid for.
+type CertConfig struct {
+	CertPath  string
+	KeyPath   string
+	CAPath    string
+	CAKeyPath string
+	CRLPath   string
+	Hosts     []string
+}
+
 // CertManager holds paths to cert and key
 type CertManager struct {
 	CertPath string
 	KeyPath  string
+	CAPath   string
+	CRLPath  string
+
+	ca    *x509.Certificate
+	caKey crypto.Signer
+
+	mu      sync.RWMutex
+	crl     []byte
+	revoked map[string]struct{}
 }
 
-// NewCertManager creates new CertManager
-func NewCertManager(certPath, keyPath string) (*CertManager, error) {
-	var create bool
-	if _, err := os.Stat(certPath); errors.Is(err, os.ErrNotExist) {
-		logger.Log.Info("certificate is not exist")
-		create = true
-	}
-	if _, err := os.Stat(keyPath); errors.Is(err, os.ErrNotExist) {
-		logger.Log.Info("key is not exist")
-		create = true
-	}
-	if !create {
-		return &CertManager{
-			CertPath: certPath,
-			KeyPath:  keyPath,
-		}, nil
-	}
-	// создаём шаблон сертификата
-	cert := &x509.Certificate{
-		// указываем уникальный номер сертификата
-		SerialNumber: big.NewInt(1658),
-		// заполняем базовую информацию о владельце сертификата
+// NewCertManager loads the local CA and the server certificate, generating
+// what is missing. A certificate of the local CA is reissued when it is
+// about to expire or doesn't cover the hosts, any other certificate is used
+// as it is.
+func NewCertManager(conf CertConfig) (*CertManager, error) {
+	if len(conf.Hosts) == 0 {
+		return nil, errors.New("no hosts for the server certificate")
+	}
+	ca, caKey, err := loadOrCreateCA(conf.CAPath, conf.CAKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("loadOrCreateCA: %w", err)
+	}
+	m := &CertManager{
+		CertPath: conf.CertPath,
+		KeyPath:  conf.KeyPath,
+		CAPath:   conf.CAPath,
+		CRLPath:  conf.CRLPath,
+		ca:       ca,
+		caKey:    caKey,
+	}
+
+	issue, reuseKey, err := m.needsIssue(conf.Hosts)
+	if err != nil {
+		return nil, fmt.Errorf("m.needsIssue: %w", err)
+	}
+	if issue {
+		err = m.issueServerCert(conf.Hosts, reuseKey)
+		if err != nil {
+			return nil, fmt.Errorf("m.issueServerCert: %w", err)
+		}
+	}
+	err = restrictKeyFile(m.KeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("restrictKeyFile: %w", err)
+	}
+	return m, nil
+}
+
+// Pin returns the pin of the served certificate clients can check.
+func (m *CertManager) Pin() (string, error) {
+	cert, err := readCert(m.CertPath)
+	if err != nil {
+		return "", fmt.Errorf("readCert: %w", err)
+	}
+	return Pin(cert), nil
+}
+
+// Pin is the hex SHA-256 of the public key of cert. It survives reissuing
+// the certificate with the same key.
+func Pin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return hex.EncodeToString(sum[:])
+}
+
+// needsIssue tells if the server certificate has to be issued and if the
+// present key can be kept for it.
+func (m *CertManager) needsIssue(hosts []string) (bool, bool, error) {
+	cert, err := readCert(m.CertPath)
+	if errors.Is(err, os.ErrNotExist) {
+		logger.Log.Info("certificate does not exist", zap.String("path", m.CertPath))
+		return true, false, nil
+	}
+	if err != nil {
+		return false, false, fmt.Errorf("readCert: %w", err)
+	}
+	if cert.SerialNumber.Cmp(big.NewInt(legacySerial)) == 0 && cert.CheckSignatureFrom(cert) == nil {
+		logger.Log.Warn("replacing the legacy self-signed certificate", zap.String("path", m.CertPath))
+		return true, false, nil
+	}
+	own := cert.CheckSignatureFrom(m.ca) == nil
+
+	_, err = tls.LoadX509KeyPair(m.CertPath, m.KeyPath)
+	if err != nil {
+		if own {
+			logger.Log.Warn("reissuing certificate", zap.String("path", m.CertPath), zap.Error(err))
+			return true, false, nil
+		}
+		return false, false, fmt.Errorf("tls.LoadX509KeyPair: %w", err)
+	}
+	if !own {
+		return false, false, nil
+	}
+	if time.Until(cert.NotAfter) < certRenewBefore {
+		logger.Log.Info("reissuing expiring certificate", zap.Time("notAfter", cert.NotAfter))
+		return true, true, nil
+	}
+	for _, h := range hosts {
+		if cert.VerifyHostname(h) != nil {
+			logger.Log.Info("reissuing certificate for new hosts", zap.Strings("hosts", hosts))
+			return true, true, nil
+		}
+	}
+	return false, false, nil
+}
+
+func (m *CertManager) issueServerCert(hosts []string, reuseKey bool) error {
+	var key crypto.Signer
+	var err error
+	if reuseKey {
+		key, err = readKey(m.KeyPath)
+	}
+	if !reuseKey || err != nil {
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return fmt.Errorf("ecdsa.GenerateKey: %w", err)
+		}
+	}
+	serial, err := newSerial()
+	if err != nil {
+		return fmt.Errorf("newSerial: %w", err)
+	}
+
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
 		Subject: pkix.Name{
-			Organization: []string{"Denis"},
-			Country:      []string{"BY"},
-		},
-		// разрешаем использование сертификата для 127.0.0.1 и ::1
-		IPAddresses: []net.IP{
-			net.IPv4(127, 0, 0, 1), net.IPv6loopback,
+			Organization: []string{"GophKeeper"},
+			CommonName:   hosts[0],
 		},
-		// сертификат верен, начиная со времени создания
-		NotBefore: time.Now(),
-		// время жизни сертификата — 10 лет
-		NotAfter:     time.Now().AddDate(10, 0, 0),
-		SubjectKeyId: []byte{1, 2, 3, 4, 6},
-		// устанавливаем использование ключа для цифровой подписи,
-		// а также клиентской и серверной авторизации
-		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		NotBefore:   now.Add(-time.Minute),
+		NotAfter:    now.Add(certValidity),
 		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
 	}
 
-	// создаём новый приватный RSA-ключ длиной 4096 бит
-	// обратите внимание, что для генерации ключа и сертификата
-	// используется rand.Reader в качестве источника случайных данных
-	privateKey, err := rsa.GenerateKey(rand.Reader, 4096)
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, m.ca, key.Public(), m.caKey)
+	if err != nil {
+		return fmt.Errorf("x509.CreateCertificate: %w", err)
+	}
+	err = writeKey(m.KeyPath, key)
 	if err != nil {
-		log.Fatal(err)
+		return fmt.Errorf("writeKey: %w", err)
 	}
+	err = writePEM(m.CertPath, certBlockType, der, 0644)
+	if err != nil {
+		return fmt.Errorf("writePEM: %w", err)
+	}
+	logger.Log.Info("issued server certificate", zap.String("path", m.CertPath),
+		zap.Strings("hosts", hosts), zap.Time("notAfter", tmpl.NotAfter))
+	return nil
+}
+
+// loadOrCreateCA reads the local CA, creating it when both files are
+// missing.
+func loadOrCreateCA(certPath, keyPath string) (*x509.Certificate, crypto.Signer, error) {
+	cert, certErr := readCert(certPath)
+	key, keyErr := readKey(keyPath)
+	if certErr == nil && keyErr == nil {
+		err := restrictKeyFile(keyPath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("restrictKeyFile: %w", err)
+		}
+		return cert, key, nil
+	}
+	if !errors.Is(certErr, os.ErrNotExist) || !errors.Is(keyErr, os.ErrNotExist) {
+		return nil, nil, fmt.Errorf("incomplete CA %s, %s: %w", certPath, keyPath,
+			errors.Join(certErr, keyErr))
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ecdsa.GenerateKey: %w", err)
+	}
+	serial, err := newSerial()
+	if err != nil {
+		return nil, nil, fmt.Errorf("newSerial: %w", err)
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			Organization: []string{"GophKeeper"},
+			CommonName:   "GophKeeper local CA",
+		},
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("x509.CreateCertificate: %w", err)
+	}
+	cert, err = x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, fmt.Errorf("x509.ParseCertificate: %w", err)
+	}
+	err = writeKey(keyPath, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("writeKey: %w", err)
+	}
+	err = writePEM(certPath, certBlockType, der, 0644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("writePEM: %w", err)
+	}
+	logger.Log.Info("created local CA", zap.String("path", certPath))
+	return cert, key, nil
+}
+
+func newSerial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
 
-	// создаём сертификат x.509
-	certBytes, err := x509.CreateCertificate(rand.Reader, cert, cert, &privateKey.PublicKey, privateKey)
+func readCert(path string) (*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != certBlockType {
+		return nil, fmt.Errorf("no certificate in %s", path)
 	}
+	return x509.ParseCertificate(block.Bytes)
+}
 
-	// кодируем сертификат и ключ в формате PEM, который
-	// используется для хранения и обмена криптографическими ключами
-	certFile, err := os.OpenFile(certPath, os.O_WRONLY|os.O_CREATE, 0644)
+func readKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != keyBlockType {
+		return nil, fmt.Errorf("no private key in %s", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
 	if err != nil {
-		return nil, fmt.Errorf("os.OpenFile %s: %w", certPath, err)
+		return nil, fmt.Errorf("x509.ParsePKCS8PrivateKey: %w", err)
 	}
-	err = pem.Encode(certFile, &pem.Block{
-		Type:  "CERTIFICATE",
-		Bytes: certBytes,
-	})
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported key in %s", path)
+	}
+	return signer, nil
+}
+
+func writeKey(path string, key crypto.Signer) error {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
 	if err != nil {
-		return nil, fmt.Errorf("pem.Encode certFil: %w", err)
+		return fmt.Errorf("x509.MarshalPKCS8PrivateKey: %w", err)
 	}
+	return writePEM(path, keyBlockType, der, 0600)
+}
 
-	keyFile, err := os.OpenFile(keyPath, os.O_WRONLY|os.O_CREATE, 0644)
+// writePEM replaces the file, so a key file keeps no broader mode it had.
+func writePEM(path, blockType string, der []byte, perm os.FileMode) error {
+	err := os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return fmt.Errorf("os.MkdirAll: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
 	if err != nil {
-		return nil, fmt.Errorf("os.OpenFile %s: %w", keyPath, err)
+		return fmt.Errorf("os.CreateTemp: %w", err)
 	}
-	err = pem.Encode(keyFile, &pem.Block{
-		Type:  "RSA PRIVATE KEY",
-		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
-	})
+	defer os.Remove(tmp.Name())
+	err = tmp.Chmod(perm)
 	if err != nil {
-		return nil, fmt.Errorf("pem.Encode keyFile: %w", err)
+		tmp.Close()
+		return fmt.Errorf("tmp.Chmod: %w", err)
 	}
+	err = pem.Encode(tmp, &pem.Block{Type: blockType, Bytes: der})
+	if err != nil {
+		tmp.Close()
+		return fmt.Errorf("pem.Encode: %w", err)
+	}
+	err = tmp.Close()
+	if err != nil {
+		return fmt.Errorf("tmp.Close: %w", err)
+	}
+	err = os.Rename(tmp.Name(), path)
+	if err != nil {
+		return fmt.Errorf("os.Rename: %w", err)
+	}
+	return nil
+}
 
-	return &CertManager{
-		CertPath: certPath,
-		KeyPath:  keyPath,
-	}, nil
+// restrictKeyFile makes a key file readable by the owner only.
+func restrictKeyFile(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("os.Stat: %w", err)
+	}
+	if info.Mode().Perm()&0077 == 0 {
+		return nil
+	}
+	logger.Log.Warn("restricting key file mode", zap.String("path", path),
+		zap.Stringer("mode", info.Mode().Perm()))
+	err = os.Chmod(path, 0600)
+	if err != nil {
+		return fmt.Errorf("os.Chmod: %w", err)
+	}
+	return nil
 }
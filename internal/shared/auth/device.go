@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+const (
+	deviceCertValidity = 5 * 365 * 24 * time.Hour
+
+	// crlValidity is how long relying parties may use a CRL, it is
+	// reissued on every revocation, start and CRLRefresh.
+	crlValidity = 7 * 24 * time.Hour
+
+	// CRLRefresh is how often the CRL is signed again, well before the
+	// one served runs out.
+	CRLRefresh = crlValidity / 7
+)
+
+var ErrCSR = errors.New("certificate request is not valid")
+
+// DeviceCert is a device certificate signed by the local CA.
+type DeviceCert struct {
+	PEM         string
+	Fingerprint string
+	Serial      string
+}
+
+// RevokedCert is an entry of the CRL.
+type RevokedCert struct {
+	Serial     string
+	RevokedTms time.Time
+}
+
+// Fingerprint is the hex SHA-256 of the DER certificate.
+func Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// SignClient signs the PEM request of the client device. Only the key of
+// the request is taken, the subject is the client id.
+func (m *CertManager) SignClient(csrPEM, clientID string) (DeviceCert, error) {
+	block, _ := pem.Decode([]byte(csrPEM))
+	if block == nil || block.Type != csrBlockType {
+		return DeviceCert{}, ErrCSR
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return DeviceCert{}, fmt.Errorf("%w: %w", ErrCSR, err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return DeviceCert{}, fmt.Errorf("%w: %w", ErrCSR, err)
+	}
+	serial, err := newSerial()
+	if err != nil {
+		return DeviceCert{}, fmt.Errorf("newSerial: %w", err)
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			Organization: []string{"GophKeeper"},
+			CommonName:   clientID,
+		},
+		NotBefore:   now.Add(-time.Minute),
+		NotAfter:    now.Add(deviceCertValidity),
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, m.ca, csr.PublicKey, m.caKey)
+	if err != nil {
+		return DeviceCert{}, fmt.Errorf("x509.CreateCertificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return DeviceCert{}, fmt.Errorf("x509.ParseCertificate: %w", err)
+	}
+	return DeviceCert{
+		PEM:         string(pem.EncodeToMemory(&pem.Block{Type: certBlockType, Bytes: der})),
+		Fingerprint: Fingerprint(cert),
+		Serial:      serial.String(),
+	}, nil
+}
+
+// ClientCAs is the pool verifying device certificates.
+func (m *CertManager) ClientCAs() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(m.ca)
+	return pool
+}
+
+// UpdateCRL signs a CRL of the revoked certificates and writes it to
+// CRLPath.
+func (m *CertManager) UpdateCRL(revoked []RevokedCert) error {
+	now := time.Now()
+	entries := make([]x509.RevocationListEntry, 0, len(revoked))
+	set := make(map[string]struct{}, len(revoked))
+	for _, r := range revoked {
+		serial, ok := new(big.Int).SetString(r.Serial, 10)
+		if !ok {
+			return fmt.Errorf("serial %s is not a number", r.Serial)
+		}
+		entries = append(entries, x509.RevocationListEntry{
+			SerialNumber:   serial,
+			RevocationTime: r.RevokedTms,
+		})
+		set[r.Serial] = struct{}{}
+	}
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:                    big.NewInt(now.UnixNano()),
+		ThisUpdate:                now,
+		NextUpdate:                now.Add(crlValidity),
+		RevokedCertificateEntries: entries,
+	}, m.ca, m.caKey)
+	if err != nil {
+		return fmt.Errorf("x509.CreateRevocationList: %w", err)
+	}
+	if m.CRLPath != "" {
+		err = writePEM(m.CRLPath, crlBlockType, der, 0644)
+		if err != nil {
+			return fmt.Errorf("writePEM: %w", err)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.crl = der
+	m.revoked = set
+	return nil
+}
+
+// CRL returns the DER CRL last signed.
+func (m *CertManager) CRL() []byte {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.crl
+}
+
+// IsRevoked tells if the certificate with the serial is on the CRL.
+func (m *CertManager) IsRevoked(serial *big.Int) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.revoked[serial.String()]
+	return ok
+}
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestCertManager(t *testing.T) *CertManager {
+	t.Helper()
+	dir := t.TempDir()
+	m, err := NewCertManager(CertConfig{
+		CertPath:  filepath.Join(dir, "server.pem"),
+		KeyPath:   filepath.Join(dir, "server-key.pem"),
+		CAPath:    filepath.Join(dir, "ca.pem"),
+		CAKeyPath: filepath.Join(dir, "ca-key.pem"),
+		CRLPath:   filepath.Join(dir, "crl.pem"),
+		Hosts:     []string{"localhost"},
+	})
+	require.NoError(t, err)
+	return m
+}
+
+func newCSR(t *testing.T) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: "device"},
+	}, key)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: csrBlockType, Bytes: der}))
+}
+
+func TestSignClient(t *testing.T) {
+	m := newTestCertManager(t)
+
+	cert, err := m.SignClient(newCSR(t), "client-id")
+	require.NoError(t, err)
+	block, _ := pem.Decode([]byte(cert.PEM))
+	require.NotNil(t, block)
+	parsed, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	assert.Equal(t, "client-id", parsed.Subject.CommonName, "the subject of the request is not taken")
+	assert.Equal(t, Fingerprint(parsed), cert.Fingerprint)
+	assert.Equal(t, parsed.SerialNumber.String(), cert.Serial)
+	_, err = parsed.Verify(x509.VerifyOptions{
+		Roots:     m.ClientCAs(),
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name string
+		csr  string
+	}{
+		{name: "empty", csr: ""},
+		{name: "not a request", csr: cert.PEM},
+		{name: "garbage", csr: string(pem.EncodeToMemory(&pem.Block{Type: csrBlockType, Bytes: []byte("x")}))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := m.SignClient(tt.csr, "client-id")
+			assert.ErrorIs(t, err, ErrCSR)
+		})
+	}
+}
+
+func TestUpdateCRL(t *testing.T) {
+	m := newTestCertManager(t)
+	revoked, err := m.SignClient(newCSR(t), "revoked")
+	require.NoError(t, err)
+	kept, err := m.SignClient(newCSR(t), "kept")
+	require.NoError(t, err)
+
+	revokedTms := time.Now().Add(-time.Hour).Truncate(time.Second).UTC()
+	require.NoError(t, m.UpdateCRL([]RevokedCert{{Serial: revoked.Serial, RevokedTms: revokedTms}}))
+
+	block, _ := pem.Decode([]byte(revoked.PEM))
+	revokedCert, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	block, _ = pem.Decode([]byte(kept.PEM))
+	keptCert, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	assert.True(t, m.IsRevoked(revokedCert.SerialNumber))
+	assert.False(t, m.IsRevoked(keptCert.SerialNumber))
+
+	crl, err := x509.ParseRevocationList(m.CRL())
+	require.NoError(t, err)
+	require.NoError(t, crl.CheckSignatureFrom(m.ca))
+	require.Len(t, crl.RevokedCertificateEntries, 1)
+	assert.Equal(t, revokedCert.SerialNumber, crl.RevokedCertificateEntries[0].SerialNumber)
+	assert.True(t, revokedTms.Equal(crl.RevokedCertificateEntries[0].RevocationTime))
+	// the timer signs a new one long before relying parties drop it
+	assert.True(t, crl.NextUpdate.After(time.Now().Add(2*CRLRefresh)))
+
+	data, err := os.ReadFile(m.CRLPath)
+	require.NoError(t, err)
+	block, _ = pem.Decode(data)
+	require.NotNil(t, block)
+	assert.Equal(t, m.CRL(), block.Bytes)
+
+	// a device taken off the list is accepted again
+	require.NoError(t, m.UpdateCRL(nil))
+	assert.False(t, m.IsRevoked(revokedCert.SerialNumber))
+
+	assert.Error(t, m.UpdateCRL([]RevokedCert{{Serial: "not a number"}}))
+}
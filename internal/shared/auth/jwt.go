@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"errors"
 	"fmt"
 	"github.com/denis-oreshkevich/gophkeeper/internal/shared/logger"
 	"github.com/golang-jwt/jwt/v4"
@@ -9,14 +10,28 @@ import (
 	"time"
 )
 
-const (
-	secretKey = "GopherSecretKey"
-	tokenExp  = time.Hour * 24
-)
+const tokenExp = time.Hour * 24
 
 var authLog = logger.Log.With(zap.String("cat", "AUTH"))
 
+var ErrNoKeys = errors.New("no jwt keys are set")
+
+// keys sign and verify tokens, the first one signs.
+var keys [][]byte
+
+// SetKeys replaces the token keys. The first one signs new tokens, the
+// others still verify tokens signed before a rotation.
+func SetKeys(newKeys []string) {
+	keys = make([][]byte, len(newKeys))
+	for i, k := range newKeys {
+		keys[i] = []byte(k)
+	}
+}
+
 func GenerateToken(userID string) (string, error) {
+	if len(keys) == 0 {
+		return "", ErrNoKeys
+	}
 	authLog.Debug(fmt.Sprintf("creating new token for sub = %s", userID))
 	claims := jwt.RegisteredClaims{
 		Subject:   userID,
@@ -24,7 +39,7 @@ func GenerateToken(userID string) (string, error) {
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
-	tokenString, err := token.SignedString([]byte(secretKey))
+	tokenString, err := token.SignedString(keys[0])
 	if err != nil {
 		return "", fmt.Errorf("signedString. %w", err)
 	}
@@ -33,17 +48,37 @@ func GenerateToken(userID string) (string, error) {
 
 func ValidateToken(tokenString string) (*jwt.RegisteredClaims, bool) {
 	tokenString = strings.Replace(tokenString, "Bearer ", "", 1)
-	claims := &jwt.RegisteredClaims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+	err := ErrNoKeys
+	for _, key := range keys {
+		claims := &jwt.RegisteredClaims{}
+		var token *jwt.Token
+		token, err = jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+			}
+			return key, nil
+		})
+		if err == nil {
+			return claims, token.Valid
+		}
+		if !errors.Is(err, jwt.ErrSignatureInvalid) {
+			break
 		}
-		return []byte(secretKey), nil
-	})
+	}
+	authLog.Debug("parsing jwt with claims", zap.Error(err))
+	return nil, false
+}
+
+// TokenExpiry reads the expiry of a token without verifying it, clients
+// use it to log in again before the server rejects the token.
+func TokenExpiry(tokenString string) (time.Time, error) {
+	claims := &jwt.RegisteredClaims{}
+	_, _, err := jwt.NewParser().ParseUnverified(tokenString, claims)
 	if err != nil {
-		authLog.Debug("parsing jwt with claims", zap.String("token", tokenString),
-			zap.Error(err))
-		return nil, false
+		return time.Time{}, fmt.Errorf("ParseUnverified: %w", err)
+	}
+	if claims.ExpiresAt == nil {
+		return time.Time{}, errors.New("token has no expiry")
 	}
-	return claims, token.Valid
+	return claims.ExpiresAt.Time, nil
 }
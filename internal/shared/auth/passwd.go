@@ -9,13 +9,33 @@ import (
 var ErrPasswordMismatch = errors.New("passwords mismatch")
 
 func EncryptPassword(passwd string) (string, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(passwd), bcrypt.MinCost)
+	return EncryptPasswordCost(passwd, bcrypt.DefaultCost)
+}
+
+// EncryptPasswordCost hashes the password with the given bcrypt cost.
+func EncryptPasswordCost(passwd string, cost int) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(passwd), cost)
 	if err != nil {
 		return "", fmt.Errorf("bcrypt.GenerateFromPassword: %w", err)
 	}
 	return string(hash), nil
 }
 
+// NeedsRehash reports whether the hash was made with a cost other than
+// cost, so it should be replaced on the next successful login.
+func NeedsRehash(hashedPwd string, cost int) bool {
+	c, err := bcrypt.Cost([]byte(hashedPwd))
+	return err != nil || c != cost
+}
+
+// CheckPasswordCost validates a configured bcrypt cost.
+func CheckPasswordCost(cost int) error {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	return nil
+}
+
 func ComparePasswords(hashedPwd, plainPwd string) error {
 	byteHash := []byte(hashedPwd)
 	bytePlain := []byte(plainPwd)
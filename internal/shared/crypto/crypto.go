@@ -6,6 +6,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"strings"
 )
 
 type Dealer struct {
@@ -15,7 +16,20 @@ type Dealer struct {
 
 func NewDealer(k string) (*Dealer, error) {
 	key := sha256.Sum256([]byte(k))
+	return newDealer(key)
+}
+
+// NewDealerFromKey returns a dealer for a vault key obtained with Key.
+func NewDealerFromKey(k []byte) (*Dealer, error) {
+	if len(k) != KeySize {
+		return nil, fmt.Errorf("vault key must be %d bytes", KeySize)
+	}
+	var key [32]byte
+	copy(key[:], k)
+	return newDealer(key)
+}
 
+func newDealer(key [32]byte) (*Dealer, error) {
 	aesblock, err := aes.NewCipher(key[:])
 	if err != nil {
 		return nil, fmt.Errorf("aes.NewCipher: %w", err)
@@ -30,24 +44,64 @@ func NewDealer(k string) (*Dealer, error) {
 	}, nil
 }
 
-func (d Dealer) Encrypt(msg string) (string, error) {
-	nonce := d.key[len(d.key)-d.aesgcm.NonceSize():]
+// Key returns the vault key, it can be handed over wrapped to open the
+// vault elsewhere.
+func (d Dealer) Key() []byte {
+	return append([]byte(nil), d.key[:]...)
+}
 
-	dst := d.aesgcm.Seal(nil, nonce, []byte(msg), nil) // зашифровываем
-	return hex.EncodeToString(dst), nil
+// Encrypt seals msg with a random nonce into the hex form vault values are
+// kept in, marked with SealedPrefix.
+func (d Dealer) Encrypt(msg string) (string, error) {
+	sealed, err := d.EncryptBytes([]byte(msg))
+	if err != nil {
+		return "", fmt.Errorf("d.EncryptBytes: %w", err)
+	}
+	return SealedPrefix + hex.EncodeToString(sealed), nil
 }
 
+// Decrypt opens a value of Encrypt. Values without SealedPrefix were
+// written by older clients with the static nonce.
 func (d Dealer) Decrypt(msg string) (string, error) {
-	nonce := d.key[len(d.key)-d.aesgcm.NonceSize():]
-
-	encrypted, err := hex.DecodeString(msg)
+	rest, sealed := strings.CutPrefix(msg, SealedPrefix)
+	encrypted, err := hex.DecodeString(rest)
 	if err != nil {
 		return "", fmt.Errorf("hex.DecodeString: %w", err)
 	}
 
-	decrypted, err := d.aesgcm.Open(nil, nonce, encrypted, nil)
+	var decrypted []byte
+	if sealed {
+		decrypted, err = d.DecryptBytes(encrypted)
+	} else {
+		decrypted, err = d.DecryptLegacy(encrypted)
+	}
 	if err != nil {
-		return "", fmt.Errorf("aesgcm.Open: %w", err)
+		return "", fmt.Errorf("decrypt: %w", err)
 	}
 	return string(decrypted), nil
 }
+
+// IsSealed reports whether v was written with a random nonce.
+func IsSealed(v string) bool {
+	return strings.HasPrefix(v, SealedPrefix)
+}
+
+func (d Dealer) EncryptBytes(msg []byte) ([]byte, error) {
+	return Seal(d.key[:], msg)
+}
+
+func (d Dealer) DecryptBytes(msg []byte) ([]byte, error) {
+	return Open(d.key[:], msg)
+}
+
+// DecryptLegacy opens values of older clients, which took the nonce from
+// the key. Nothing is encrypted that way any more.
+func (d Dealer) DecryptLegacy(msg []byte) ([]byte, error) {
+	nonce := d.key[len(d.key)-d.aesgcm.NonceSize():]
+
+	decrypted, err := d.aesgcm.Open(nil, nonce, msg, nil)
+	if err != nil {
+		return nil, fmt.Errorf("aesgcm.Open: %w", err)
+	}
+	return decrypted, nil
+}
@@ -0,0 +1,145 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// KeySize is the size of item keys.
+const KeySize = 32
+
+const wrapInfo = "gophkeeper share key"
+
+var ErrMalformed = errors.New("malformed ciphertext")
+
+// GenerateKeyPair returns a new X25519 keypair.
+func GenerateKeyPair() (pub, priv []byte, err error) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ecdh.GenerateKey: %w", err)
+	}
+	return key.PublicKey().Bytes(), key.Bytes(), nil
+}
+
+// Fingerprint identifies a public key, users compare it to make sure the
+// key the server hands out is the one of the user.
+func Fingerprint(pub []byte) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:])
+}
+
+// NewItemKey returns a random key for Seal.
+func NewItemKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("rand.Read: %w", err)
+	}
+	return key, nil
+}
+
+// WrapKey encrypts key for the owner of the X25519 public key. An ephemeral
+// keypair is used, the result is the ephemeral public key followed by the
+// sealed key.
+func WrapKey(recipient []byte, key []byte) ([]byte, error) {
+	pub, err := ecdh.X25519().NewPublicKey(recipient)
+	if err != nil {
+		return nil, fmt.Errorf("ecdh.NewPublicKey: %w", err)
+	}
+	eph, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("ecdh.GenerateKey: %w", err)
+	}
+	secret, err := eph.ECDH(pub)
+	if err != nil {
+		return nil, fmt.Errorf("eph.ECDH: %w", err)
+	}
+	ephPub := eph.PublicKey().Bytes()
+	sealed, err := Seal(wrappingKey(secret, ephPub, recipient), key)
+	if err != nil {
+		return nil, err
+	}
+	return append(ephPub, sealed...), nil
+}
+
+// UnwrapKey decrypts a key wrapped by WrapKey with the X25519 private key.
+func UnwrapKey(private []byte, wrapped []byte) ([]byte, error) {
+	priv, err := ecdh.X25519().NewPrivateKey(private)
+	if err != nil {
+		return nil, fmt.Errorf("ecdh.NewPrivateKey: %w", err)
+	}
+	size := len(priv.PublicKey().Bytes())
+	if len(wrapped) < size {
+		return nil, ErrMalformed
+	}
+	eph, err := ecdh.X25519().NewPublicKey(wrapped[:size])
+	if err != nil {
+		return nil, fmt.Errorf("ecdh.NewPublicKey: %w", err)
+	}
+	secret, err := priv.ECDH(eph)
+	if err != nil {
+		return nil, fmt.Errorf("priv.ECDH: %w", err)
+	}
+	return Open(wrappingKey(secret, wrapped[:size], priv.PublicKey().Bytes()), wrapped[size:])
+}
+
+func wrappingKey(secret, ephPub, recipient []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte(wrapInfo))
+	h.Write(secret)
+	h.Write(ephPub)
+	h.Write(recipient)
+	return h.Sum(nil)
+}
+
+// SealedPrefix marks the string form of values sealed with Seal, values
+// without it were encrypted by older clients with a static nonce.
+const SealedPrefix = "v1:"
+
+// Seal encrypts msg with AES-GCM under a random nonce, the nonce is
+// prepended to the result.
+func Seal(key, msg []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("rand.Read: %w", err)
+	}
+	return aead.Seal(nonce, nonce, msg, nil), nil
+}
+
+// Open decrypts the result of Seal.
+func Open(key, sealed []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrMalformed
+	}
+	nonce, msg := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	res, err := aead.Open(nil, nonce, msg, nil)
+	if err != nil {
+		return nil, fmt.Errorf("aead.Open: %w", err)
+	}
+	return res, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("aes.NewCipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("cipher.NewGCM: %w", err)
+	}
+	return aead, nil
+}
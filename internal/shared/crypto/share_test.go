@@ -0,0 +1,114 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestWrapKey(t *testing.T) {
+	pub, priv, err := GenerateKeyPair()
+	require.NoError(t, err)
+	_, otherPriv, err := GenerateKeyPair()
+	require.NoError(t, err)
+	key, err := NewItemKey()
+	require.NoError(t, err)
+
+	wrapped, err := WrapKey(pub, key)
+	require.NoError(t, err)
+	again, err := WrapKey(pub, key)
+	require.NoError(t, err)
+	assert.NotEqual(t, wrapped, again, "every wrap takes a new ephemeral key")
+
+	tampered := append([]byte(nil), wrapped...)
+	tampered[len(tampered)-1] ^= 1
+
+	tests := []struct {
+		name    string
+		priv    []byte
+		wrapped []byte
+		wantErr bool
+	}{
+		{name: "recipient", priv: priv, wrapped: wrapped},
+		{name: "recipient, second wrap", priv: priv, wrapped: again},
+		{name: "other recipient", priv: otherPriv, wrapped: wrapped, wantErr: true},
+		{name: "tampered", priv: priv, wrapped: tampered, wantErr: true},
+		{name: "ephemeral key only", priv: priv, wrapped: wrapped[:32], wantErr: true},
+		{name: "too short", priv: priv, wrapped: wrapped[:10], wantErr: true},
+		{name: "empty", priv: priv, wrapped: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := UnwrapKey(tt.priv, tt.wrapped)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, key, got)
+		})
+	}
+}
+
+func TestWrapKeyBadRecipient(t *testing.T) {
+	key, err := NewItemKey()
+	require.NoError(t, err)
+	_, err = WrapKey([]byte("short"), key)
+	assert.Error(t, err)
+}
+
+func TestFingerprint(t *testing.T) {
+	a, _, err := GenerateKeyPair()
+	require.NoError(t, err)
+	b, _, err := GenerateKeyPair()
+	require.NoError(t, err)
+	assert.Equal(t, Fingerprint(a), Fingerprint(append([]byte(nil), a...)))
+	assert.NotEqual(t, Fingerprint(a), Fingerprint(b))
+}
+
+func TestDealer(t *testing.T) {
+	key, err := NewItemKey()
+	require.NoError(t, err)
+	dealer, err := NewDealerFromKey(key)
+	require.NoError(t, err)
+	other, err := NewDealer("other")
+	require.NoError(t, err)
+
+	sealed, err := dealer.Encrypt("secret")
+	require.NoError(t, err)
+	again, err := dealer.Encrypt("secret")
+	require.NoError(t, err)
+	assert.True(t, IsSealed(sealed))
+	assert.NotEqual(t, sealed, again, "every value takes a new nonce")
+
+	// values of older clients took the nonce from the key
+	nonce := dealer.key[len(dealer.key)-dealer.aesgcm.NonceSize():]
+	legacy := hex.EncodeToString(dealer.aesgcm.Seal(nil, nonce, []byte("secret"), nil))
+	assert.False(t, IsSealed(legacy))
+
+	tests := []struct {
+		name    string
+		dealer  *Dealer
+		value   string
+		wantErr bool
+	}{
+		{name: "sealed", dealer: dealer, value: sealed},
+		{name: "legacy", dealer: dealer, value: legacy},
+		{name: "sealed, other key", dealer: other, value: sealed, wantErr: true},
+		{name: "legacy, other key", dealer: other, value: legacy, wantErr: true},
+		{name: "not hex", dealer: dealer, value: SealedPrefix + "zz", wantErr: true},
+		{name: "too short", dealer: dealer, value: SealedPrefix + "00", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.dealer.Decrypt(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "secret", got)
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"strings"
+)
+
+const (
+	saltSize = 16
+
+	// recoveryKeySize bytes are printed as groups of recoveryGroup base32
+	// characters.
+	recoveryKeySize = 20
+	recoveryGroup   = 4
+
+	recoveryWrapInfo = "gophkeeper recovery wrap"
+	recoveryAuthInfo = "gophkeeper recovery auth"
+
+	loginSaltInfo = "gophkeeper login salt"
+	loginInfo     = "gophkeeper login"
+)
+
+var ErrRecoveryKey = errors.New("recovery key is not valid")
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewSalt returns a random salt for DeriveKey.
+func NewSalt() ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("rand.Read: %w", err)
+	}
+	return salt, nil
+}
+
+// DeriveKey turns a password into a key that wraps the vault key.
+func DeriveKey(password string, salt []byte) []byte {
+	return argon2.IDKey([]byte(password), salt, 1, 64*1024, 4, KeySize)
+}
+
+// LoginSecret is sent to the server in place of the password. It is
+// derived with a salt of its own, so the server gets neither the password
+// nor the key DeriveKey makes from it for the vault key.
+func LoginSecret(login, password string) string {
+	salt := sha256.Sum256([]byte(loginSaltInfo + login))
+	master := argon2.IDKey([]byte(password), salt[:saltSize], 1, 64*1024, 4, KeySize)
+	secret := sha256.Sum256(append([]byte(loginInfo), master...))
+	return hex.EncodeToString(secret[:])
+}
+
+// NewRecoveryKey returns a random recovery key formatted for printing.
+func NewRecoveryKey() (string, error) {
+	k := make([]byte, recoveryKeySize)
+	if _, err := rand.Read(k); err != nil {
+		return "", fmt.Errorf("rand.Read: %w", err)
+	}
+	s := recoveryEncoding.EncodeToString(k)
+	groups := make([]string, 0, len(s)/recoveryGroup)
+	for i := 0; i < len(s); i += recoveryGroup {
+		groups = append(groups, s[i:min(i+recoveryGroup, len(s))])
+	}
+	return strings.Join(groups, "-"), nil
+}
+
+// RecoveryKeys splits a printed recovery key into the key that wraps the
+// vault key and the proof of knowing it the server checks. The server can't
+// get one from the other.
+func RecoveryKeys(recoveryKey string) (wrap, auth []byte, err error) {
+	s := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(recoveryKey))
+	k, err := recoveryEncoding.DecodeString(s)
+	if err != nil || len(k) != recoveryKeySize {
+		return nil, nil, ErrRecoveryKey
+	}
+	w := sha256.Sum256(append([]byte(recoveryWrapInfo), k...))
+	a := sha256.Sum256(append([]byte(recoveryAuthInfo), k...))
+	return w[:], a[:], nil
+}
@@ -0,0 +1,152 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestDeriveKey(t *testing.T) {
+	salt := []byte("0123456789abcdef")
+	key := DeriveKey("password", salt)
+	require.Len(t, key, KeySize)
+
+	tests := []struct {
+		name     string
+		password string
+		salt     []byte
+		wantSame bool
+	}{
+		{name: "same password and salt", password: "password", salt: salt, wantSame: true},
+		{name: "other password", password: "Password", salt: salt},
+		{name: "other salt", password: "password", salt: []byte("fedcba9876543210")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DeriveKey(tt.password, tt.salt)
+			if tt.wantSame {
+				assert.Equal(t, key, got)
+			} else {
+				assert.NotEqual(t, key, got)
+			}
+		})
+	}
+}
+
+func TestNewSalt(t *testing.T) {
+	a, err := NewSalt()
+	require.NoError(t, err)
+	b, err := NewSalt()
+	require.NoError(t, err)
+	assert.Len(t, a, saltSize)
+	assert.NotEqual(t, a, b)
+}
+
+func TestWrapVaultKey(t *testing.T) {
+	salt, err := NewSalt()
+	require.NoError(t, err)
+	vaultKey, err := NewItemKey()
+	require.NoError(t, err)
+
+	wrapped, err := Seal(DeriveKey("password", salt), vaultKey)
+	require.NoError(t, err)
+	got, err := Open(DeriveKey("password", salt), wrapped)
+	require.NoError(t, err)
+	assert.Equal(t, vaultKey, got)
+
+	_, err = Open(DeriveKey("wrong password", salt), wrapped)
+	assert.Error(t, err)
+}
+
+func TestNewRecoveryKey(t *testing.T) {
+	format := regexp.MustCompile(`^[A-Z2-7]{4}(-[A-Z2-7]{4}){7}$`)
+	seen := make(map[string]bool)
+	for i := 0; i < 10; i++ {
+		k, err := NewRecoveryKey()
+		require.NoError(t, err)
+		assert.Regexp(t, format, k)
+		assert.False(t, seen[k], "recovery key repeated")
+		seen[k] = true
+
+		_, _, err = RecoveryKeys(k)
+		assert.NoError(t, err)
+	}
+}
+
+func TestRecoveryKeys(t *testing.T) {
+	printed, err := NewRecoveryKey()
+	require.NoError(t, err)
+	wrap, auth, err := RecoveryKeys(printed)
+	require.NoError(t, err)
+	assert.Len(t, wrap, KeySize)
+	assert.Len(t, auth, KeySize)
+	assert.NotEqual(t, wrap, auth, "the server must not get the wrapping key")
+
+	tests := []struct {
+		name     string
+		input    string
+		wantSame bool
+		wantErr  bool
+	}{
+		{name: "as printed", input: printed, wantSame: true},
+		{name: "lower case", input: strings.ToLower(printed), wantSame: true},
+		{name: "spaces instead of dashes", input: strings.ReplaceAll(printed, "-", " "), wantSame: true},
+		{name: "no separators", input: strings.ReplaceAll(printed, "-", ""), wantSame: true},
+		{name: "other key", input: "AAAA-AAAA-AAAA-AAAA-AAAA-AAAA-AAAA-AAAA"},
+		{name: "group missing", input: printed[:len(printed)-5], wantErr: true},
+		{name: "group added", input: printed + "-AAAA", wantErr: true},
+		{name: "not base32", input: "1111-1111-1111-1111-1111-1111-1111-1111", wantErr: true},
+		{name: "empty", input: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, a, err := RecoveryKeys(tt.input)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrRecoveryKey)
+				return
+			}
+			require.NoError(t, err)
+			if tt.wantSame {
+				assert.Equal(t, wrap, w)
+				assert.Equal(t, auth, a)
+			} else {
+				assert.NotEqual(t, wrap, w)
+				assert.NotEqual(t, auth, a)
+			}
+		})
+	}
+}
+
+func TestLoginSecret(t *testing.T) {
+	secret := LoginSecret("alice", "password")
+	assert.NotContains(t, secret, "password")
+
+	salt := sha256.Sum256([]byte(loginSaltInfo + "alice"))
+	assert.NotEqual(t, hex.EncodeToString(DeriveKey("password", salt[:saltSize])), secret,
+		"the server must not get the key derived from the password")
+
+	tests := []struct {
+		name     string
+		login    string
+		password string
+		wantSame bool
+	}{
+		{name: "same login and password", login: "alice", password: "password", wantSame: true},
+		{name: "other password", login: "alice", password: "Password"},
+		{name: "other login", login: "bob", password: "password"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := LoginSecret(tt.login, tt.password)
+			if tt.wantSame {
+				assert.Equal(t, secret, got)
+			} else {
+				assert.NotEqual(t, secret, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package model
+
+import "time"
+
+type AuditEventType string
+
+const (
+	AuditRegister       AuditEventType = "register"
+	AuditLogin          AuditEventType = "login"
+	AuditLoginFailed    AuditEventType = "login_failed"
+	AuditLockout        AuditEventType = "lockout"
+	AuditClientRegister AuditEventType = "client_register"
+	AuditClientRevoke   AuditEventType = "client_revoke"
+	AuditSync           AuditEventType = "sync"
+	AuditDelete         AuditEventType = "delete"
+	AuditShare          AuditEventType = "share"
+	AuditShareRevoke    AuditEventType = "share_revoke"
+	AuditPasswordChange AuditEventType = "password_change"
+	AuditPasswordFailed AuditEventType = "password_failed"
+	AuditRecovery       AuditEventType = "recovery"
+	AuditRecoveryFailed AuditEventType = "recovery_failed"
+	AuditPasswordReset  AuditEventType = "password_reset"
+	AuditOrg            AuditEventType = "org"
+	AuditEmergency      AuditEventType = "emergency"
+)
+
+// AuditEvent is a security relevant event of a user and where the request
+// came from.
+type AuditEvent struct {
+	ID         int64          `json:"id"`
+	UserID     string         `json:"-"`
+	Event      AuditEventType `json:"event"`
+	Details    string         `json:"details,omitempty"`
+	ClientID   string         `json:"client_id,omitempty"`
+	IP         string         `json:"ip,omitempty"`
+	RequestID  string         `json:"request_id,omitempty"`
+	CreatedTms time.Time      `json:"created_tms"`
+}
+
+// AuditPage is a page of events, newest first. Next is passed as before to
+// get the following page, it is empty on the last one.
+type AuditPage struct {
+	Events []AuditEvent `json:"events"`
+	Next   int64        `json:"next,omitempty"`
+}
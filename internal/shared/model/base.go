@@ -4,8 +4,10 @@ import "time"
 
 type Base interface {
 	GetID() string
+	GetUserID() string
 	IsNew() bool
 	GetModifiedTms() time.Time
+	SetModifiedTms(tms time.Time)
 	GetStatus() Status
 	SetStatus(status Status)
 }
@@ -27,6 +27,10 @@ func (b *Binary) GetID() string {
 	return b.ID
 }
 
+func (b *Binary) GetUserID() string {
+	return b.UserID
+}
+
 func (b *Binary) IsNew() bool {
 	return b.New
 }
@@ -35,6 +39,10 @@ func (b *Binary) GetModifiedTms() time.Time {
 	return b.ModifiedTms
 }
 
+func (b *Binary) SetModifiedTms(tms time.Time) {
+	b.ModifiedTms = tms
+}
+
 func (b *Binary) GetStatus() Status {
 	return b.Status
 }
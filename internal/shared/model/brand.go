@@ -0,0 +1,89 @@
+package model
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Brand is a card payment system detected from the issuer identification number.
+type Brand string
+
+const (
+	BrandVisa       Brand = "VISA"
+	BrandMastercard Brand = "MASTERCARD"
+	BrandAmex       Brand = "AMEX"
+	BrandDiscover   Brand = "DISCOVER"
+	BrandJCB        Brand = "JCB"
+	BrandDiners     Brand = "DINERS"
+	BrandUnionPay   Brand = "UNIONPAY"
+	BrandMaestro    Brand = "MAESTRO"
+	BrandMir        Brand = "MIR"
+	BrandUnknown    Brand = "UNKNOWN"
+)
+
+type iinRange struct {
+	from  int
+	to    int
+	brand Brand
+}
+
+// iinRanges are checked in order, so more specific prefixes go first.
+var iinRanges = []iinRange{
+	{from: 2200, to: 2204, brand: BrandMir},
+	{from: 2221, to: 2720, brand: BrandMastercard},
+	{from: 5100, to: 5599, brand: BrandMastercard},
+	{from: 3400, to: 3499, brand: BrandAmex},
+	{from: 3700, to: 3799, brand: BrandAmex},
+	{from: 3528, to: 3589, brand: BrandJCB},
+	{from: 3000, to: 3059, brand: BrandDiners},
+	{from: 3600, to: 3699, brand: BrandDiners},
+	{from: 3800, to: 3999, brand: BrandDiners},
+	{from: 6011, to: 6011, brand: BrandDiscover},
+	{from: 6440, to: 6599, brand: BrandDiscover},
+	{from: 6200, to: 6299, brand: BrandUnionPay},
+	{from: 5000, to: 5099, brand: BrandMaestro},
+	{from: 5600, to: 5899, brand: BrandMaestro},
+	{from: 6300, to: 6399, brand: BrandMaestro},
+	{from: 6700, to: 6799, brand: BrandMaestro},
+	{from: 4000, to: 4999, brand: BrandVisa},
+}
+
+// DetectBrand returns the card brand by the first digits of the number.
+func DetectBrand(num string) Brand {
+	n := NormalizeCardNum(num)
+	if len(n) < 4 || !isDigits(n) {
+		return BrandUnknown
+	}
+	prefix, err := strconv.Atoi(n[:4])
+	if err != nil {
+		return BrandUnknown
+	}
+	for _, r := range iinRanges {
+		if prefix >= r.from && prefix <= r.to {
+			return r.brand
+		}
+	}
+	return BrandUnknown
+}
+
+// FormatCardNum splits the card number into groups of four digits.
+func FormatCardNum(num string) string {
+	n := NormalizeCardNum(num)
+	var sb strings.Builder
+	for i, c := range n {
+		if i > 0 && i%4 == 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteRune(c)
+	}
+	return sb.String()
+}
+
+// MaskCardNum hides all digits of the card number except the last four.
+func MaskCardNum(num string) string {
+	n := NormalizeCardNum(num)
+	if len(n) <= 4 {
+		return FormatCardNum(n)
+	}
+	return FormatCardNum(strings.Repeat("*", len(n)-4) + n[len(n)-4:])
+}
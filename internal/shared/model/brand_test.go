@@ -0,0 +1,71 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDetectBrand(t *testing.T) {
+	tests := []struct {
+		name string
+		num  string
+		want Brand
+	}{
+		{name: "visa", num: "4111 1111 1111 1111", want: BrandVisa},
+		{name: "mastercard", num: "5555555555554444", want: BrandMastercard},
+		{name: "mastercard 2-series", num: "2223003122003222", want: BrandMastercard},
+		{name: "amex", num: "378282246310005", want: BrandAmex},
+		{name: "discover", num: "6011111111111117", want: BrandDiscover},
+		{name: "discover 65", num: "6500000000000002", want: BrandDiscover},
+		{name: "jcb", num: "3530111333300000", want: BrandJCB},
+		{name: "diners", num: "30569309025904", want: BrandDiners},
+		{name: "unionpay", num: "6200000000000005", want: BrandUnionPay},
+		{name: "maestro", num: "6304000000000000", want: BrandMaestro},
+		{name: "mir", num: "2200 0000 0000 0004", want: BrandMir},
+		{name: "dashes", num: "4111-1111-1111-1111", want: BrandVisa},
+		{name: "unknown prefix", num: "9999999999999995", want: BrandUnknown},
+		{name: "too short", num: "411", want: BrandUnknown},
+		{name: "not digits", num: "41x11111", want: BrandUnknown},
+		{name: "empty", num: "", want: BrandUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, DetectBrand(tt.num))
+		})
+	}
+}
+
+func TestFormatCardNum(t *testing.T) {
+	tests := []struct {
+		num  string
+		want string
+	}{
+		{num: "4111111111111111", want: "4111 1111 1111 1111"},
+		{num: "378282246310005", want: "3782 8224 6310 005"},
+		{num: " 4111-1111 1111-1111 ", want: "4111 1111 1111 1111"},
+		{num: "4111", want: "4111"},
+		{num: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.num, func(t *testing.T) {
+			assert.Equal(t, tt.want, FormatCardNum(tt.num))
+		})
+	}
+}
+
+func TestMaskCardNum(t *testing.T) {
+	tests := []struct {
+		num  string
+		want string
+	}{
+		{num: "4111111111111111", want: "**** **** **** 1111"},
+		{num: "3782 8224 6310 005", want: "**** **** ***0 005"},
+		{num: "1234", want: "1234"},
+		{num: "12", want: "12"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.num, func(t *testing.T) {
+			assert.Equal(t, tt.want, MaskCardNum(tt.num))
+		})
+	}
+}
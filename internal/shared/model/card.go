@@ -2,15 +2,47 @@ package model
 
 import "time"
 
+// Card holds a payment card. All string values except ID, UserID and
+// Status are encrypted by the client.
 type Card struct {
-	ID          string    `json:"id"`
-	Num         string    `json:"num"`
-	CVC         string    `json:"cvc"`
-	HolderName  string    `json:"holder_name"`
-	New         bool      `json:"-"`
-	UserID      string    `json:"user_id"`
-	Status      Status    `json:"status"`
-	ModifiedTms time.Time `json:"modified_tms"`
+	ID             string         `json:"id"`
+	Num            string         `json:"num"`
+	CVC            string         `json:"cvc"`
+	HolderName     string         `json:"holder_name"`
+	ExpMonth       string         `json:"exp_month"`
+	ExpYear        string         `json:"exp_year"`
+	PIN            string         `json:"pin"`
+	Brand          string         `json:"brand"`
+	BillingAddress BillingAddress `json:"billing_address"`
+	New            bool           `json:"-"`
+	UserID         string         `json:"user_id"`
+	Status         Status         `json:"status"`
+	ModifiedTms    time.Time      `json:"modified_tms"`
+}
+
+// BillingAddress of the card holder, every field is optional.
+type BillingAddress struct {
+	Line1      string `json:"line1"`
+	Line2      string `json:"line2"`
+	City       string `json:"city"`
+	Region     string `json:"region"`
+	PostalCode string `json:"postal_code"`
+	Country    string `json:"country"`
+}
+
+func (a BillingAddress) IsEmpty() bool {
+	return a == BillingAddress{}
+}
+
+// CardInput holds plain card values entered by the user.
+type CardInput struct {
+	Num            string
+	CVC            string
+	HolderName     string
+	ExpMonth       string
+	ExpYear        string
+	PIN            string
+	BillingAddress BillingAddress
 }
 
 func NewCard(num string, cvc string, holderName string, userID string,
@@ -29,6 +61,10 @@ func (c *Card) GetID() string {
 	return c.ID
 }
 
+func (c *Card) GetUserID() string {
+	return c.UserID
+}
+
 func (c *Card) IsNew() bool {
 	return c.New
 }
@@ -37,6 +73,10 @@ func (c *Card) GetModifiedTms() time.Time {
 	return c.ModifiedTms
 }
 
+func (c *Card) SetModifiedTms(tms time.Time) {
+	c.ModifiedTms = tms
+}
+
 func (c *Card) GetStatus() Status {
 	return c.Status
 }
@@ -6,6 +6,14 @@ type Client struct {
 	ID      string    `json:"id"`
 	UserID  string    `json:"user_id"`
 	SyncTms time.Time `json:"sync_tms"`
+
+	// CSR asks the server to sign a device certificate at registration,
+	// the PEM certificate comes back in Certificate.
+	CSR             string     `json:"csr,omitempty"`
+	Certificate     string     `json:"certificate,omitempty"`
+	CertFingerprint string     `json:"cert_fingerprint,omitempty"`
+	CertSerial      string     `json:"-"`
+	RevokedTms      *time.Time `json:"revoked_tms,omitempty"`
 }
 
 func NewClient(userID string, syncTms time.Time) Client {
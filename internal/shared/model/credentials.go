@@ -27,6 +27,10 @@ func (c *Credentials) GetID() string {
 	return c.ID
 }
 
+func (c *Credentials) GetUserID() string {
+	return c.UserID
+}
+
 func (c *Credentials) IsNew() bool {
 	return c.New
 }
@@ -35,6 +39,10 @@ func (c *Credentials) GetModifiedTms() time.Time {
 	return c.ModifiedTms
 }
 
+func (c *Credentials) SetModifiedTms(tms time.Time) {
+	c.ModifiedTms = tms
+}
+
 func (c *Credentials) GetStatus() Status {
 	return c.Status
 }
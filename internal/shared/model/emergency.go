@@ -0,0 +1,47 @@
+package model
+
+import "time"
+
+type EmergencyStatus string
+
+const (
+	// EmergencyGranted is the idle state, the contact may request access.
+	EmergencyGranted EmergencyStatus = "GRANTED"
+
+	// EmergencyRequested waits for the owner, the access is approved when
+	// the waiting period ends.
+	EmergencyRequested EmergencyStatus = "REQUESTED"
+
+	EmergencyApproved EmergencyStatus = "APPROVED"
+)
+
+// EmergencyAccess lets a trusted contact open the vault of the owner. The
+// vault key is wrapped for the contact and only handed out once the access
+// is approved.
+type EmergencyAccess struct {
+	ID           string          `json:"id"`
+	OwnerLogin   string          `json:"owner_login,omitempty"`
+	OwnerID      string          `json:"-"`
+	ContactLogin string          `json:"contact_login"`
+	ContactID    string          `json:"-"`
+	WaitHours    int             `json:"wait_hours"`
+	Status       EmergencyStatus `json:"status,omitempty"`
+	RequestedTms *time.Time      `json:"requested_tms,omitempty"`
+	WrappedKey   string          `json:"wrapped_key,omitempty"`
+}
+
+// ApprovesAt returns when a pending request is approved automatically.
+func (e EmergencyAccess) ApprovesAt() time.Time {
+	if e.RequestedTms == nil {
+		return time.Time{}
+	}
+	return e.RequestedTms.Add(time.Duration(e.WaitHours) * time.Hour)
+}
+
+// EmergencyVault is the vault of the owner as the contact receives it.
+type EmergencyVault struct {
+	Credentials []Credentials `json:"credentials"`
+	Cards       []Card        `json:"cards"`
+	Texts       []*Text       `json:"texts"`
+	Binaries    []*Binary     `json:"binaries"`
+}
@@ -0,0 +1,82 @@
+package model
+
+import "time"
+
+type Role string
+
+const (
+	RoleOwner Role = "OWNER"
+
+	RoleAdmin Role = "ADMIN"
+
+	RoleMember Role = "MEMBER"
+
+	RoleReadOnly Role = "READ_ONLY"
+)
+
+// CanManage reports whether the role may change members and collections.
+func (r Role) CanManage() bool {
+	return r == RoleOwner || r == RoleAdmin
+}
+
+// CanWrite reports whether the role may change collection items.
+func (r Role) CanWrite() bool {
+	return r.CanManage() || r == RoleMember
+}
+
+// Org is an organization, Role is the one of the requesting user.
+type Org struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Role Role   `json:"role,omitempty"`
+}
+
+// OrgMember is a user of an organization. Keys wrap the current key of
+// every collection for a new member.
+type OrgMember struct {
+	Login     string          `json:"login"`
+	UserID    string          `json:"-"`
+	Role      Role            `json:"role"`
+	PublicKey string          `json:"public_key,omitempty"`
+	Keys      []CollectionKey `json:"keys,omitempty"`
+}
+
+// Collection groups organization items under one symmetric key. The key is
+// wrapped for every member, WrappedKey is the one of the requesting user.
+type Collection struct {
+	ID         string          `json:"id"`
+	OrgID      string          `json:"org_id,omitempty"`
+	Name       string          `json:"name"`
+	KeyVersion int             `json:"key_version"`
+	WrappedKey string          `json:"wrapped_key,omitempty"`
+	Keys       []CollectionKey `json:"keys,omitempty"`
+}
+
+// CollectionKey is a collection key wrapped for a member. Login is set when
+// keys of a collection are sent, CollectionID when keys of a member are.
+type CollectionKey struct {
+	CollectionID string `json:"collection_id,omitempty"`
+	Login        string `json:"login,omitempty"`
+	UserID       string `json:"-"`
+	WrappedKey   string `json:"wrapped_key"`
+}
+
+// OrgItem is a vault item of a collection sealed with the collection key
+// of KeyVersion.
+type OrgItem struct {
+	ID           string    `json:"id"`
+	CollectionID string    `json:"collection_id,omitempty"`
+	Kind         string    `json:"kind"`
+	Payload      string    `json:"payload"`
+	KeyVersion   int       `json:"key_version"`
+	Status       Status    `json:"status"`
+	ModifiedTms  time.Time `json:"modified_tms"`
+}
+
+// CollectionRekey replaces the key of a collection: the new key is wrapped
+// for every remaining member and every item is sealed again with it.
+type CollectionRekey struct {
+	KeyVersion int             `json:"key_version"`
+	Keys       []CollectionKey `json:"keys"`
+	Items      []OrgItem       `json:"items"`
+}
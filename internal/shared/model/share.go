@@ -0,0 +1,51 @@
+package model
+
+import "time"
+
+type Access string
+
+const (
+	AccessRead Access = "READ"
+
+	AccessWrite Access = "WRITE"
+)
+
+// UserKey is the X25519 keypair a user receives shared items with. The
+// private key is wrapped under the vault key and only returned to its owner.
+type UserKey struct {
+	UserID            string `json:"user_id"`
+	PublicKey         string `json:"public_key"`
+	WrappedPrivateKey string `json:"wrapped_private_key,omitempty"`
+}
+
+// Share is a copy of a vault item sealed with its own item key. The item
+// key is wrapped for every user the item is shared with, the owner
+// included.
+type Share struct {
+	ID          string    `json:"id"`
+	ItemID      string    `json:"item_id,omitempty"`
+	OwnerID     string    `json:"owner_id,omitempty"`
+	OwnerLogin  string    `json:"owner_login,omitempty"`
+	Kind        string    `json:"kind"`
+	Payload     string    `json:"payload"`
+	ModifiedTms time.Time `json:"modified_tms"`
+
+	Grants []ShareGrant `json:"grants,omitempty"`
+
+	// Access and WrappedKey of the requesting user, set in listings.
+	Access     Access `json:"access,omitempty"`
+	WrappedKey string `json:"wrapped_key,omitempty"`
+}
+
+type ShareGrant struct {
+	Login      string `json:"login"`
+	UserID     string `json:"-"`
+	WrappedKey string `json:"wrapped_key"`
+	Access     Access `json:"access"`
+}
+
+// SharePayload updates the sealed item of a share.
+type SharePayload struct {
+	Payload     string    `json:"payload"`
+	ModifiedTms time.Time `json:"modified_tms"`
+}
@@ -24,6 +24,10 @@ func (t *Text) GetID() string {
 	return t.ID
 }
 
+func (t *Text) GetUserID() string {
+	return t.UserID
+}
+
 func (t *Text) IsNew() bool {
 	return t.New
 }
@@ -32,6 +36,10 @@ func (t *Text) GetModifiedTms() time.Time {
 	return t.ModifiedTms
 }
 
+func (t *Text) SetModifiedTms(tms time.Time) {
+	t.ModifiedTms = tms
+}
+
 func (t *Text) GetStatus() Status {
 	return t.Status
 }
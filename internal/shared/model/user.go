@@ -1,9 +1,12 @@
 package model
 
 type User struct {
-	ID             string `json:"id"`
-	Login          string `json:"login"`
-	HashedPassword string `json:"password"`
+	ID             string    `json:"id"`
+	Login          string    `json:"login"`
+	HashedPassword string    `json:"password"`
+	Vault          *VaultKey `json:"vault,omitempty"`
+	RecoveryKey    string    `json:"-"`
+	RecoveryHash   string    `json:"-"`
 }
 
 func NewUser(login, hashedPassword string) User {
@@ -13,7 +16,67 @@ func NewUser(login, hashedPassword string) User {
 	}
 }
 
+// AuthUser logs a user in. On registration it also carries the wrapped vault
+// key and the recovery kit.
 type AuthUser struct {
-	Login    string `json:"login"`
+	Login    string       `json:"login"`
+	Password string       `json:"password"`
+	Vault    *VaultKey    `json:"vault,omitempty"`
+	Recovery *RecoveryKit `json:"recovery,omitempty"`
+}
+
+// VaultKey is the vault key wrapped with a key derived from the password
+// and Salt. Users without one use the legacy key derived from id and login.
+type VaultKey struct {
+	WrappedKey string `json:"wrapped_key"`
+	Salt       string `json:"salt"`
+}
+
+// RecoveryKit is the vault key wrapped with the recovery key. Auth proves
+// knowing the recovery key, the server keeps only its hash.
+type RecoveryKit struct {
+	WrappedKey string `json:"wrapped_key"`
+	Auth       string `json:"auth,omitempty"`
+}
+
+// PasswordChange replaces the password of the logged in user. The vault key
+// is wrapped again for the new password, a recovery kit is set when the user
+// has none yet.
+type PasswordChange struct {
+	Password    string       `json:"password"`
+	NewPassword string       `json:"new_password"`
+	Vault       VaultKey     `json:"vault"`
+	Recovery    *RecoveryKit `json:"recovery,omitempty"`
+}
+
+// RecoveryRequest asks for the recovery kit of a user.
+type RecoveryRequest struct {
+	Login string `json:"login"`
+	Auth  string `json:"auth"`
+}
+
+// PasswordReset sets a new password with the recovery key instead of the
+// old password.
+type PasswordReset struct {
+	Login       string   `json:"login"`
+	Auth        string   `json:"auth"`
+	NewPassword string   `json:"new_password"`
+	Vault       VaultKey `json:"vault"`
+}
+
+// PreloginRequest asks how the password of login is proven.
+type PreloginRequest struct {
+	Login string `json:"login"`
+}
+
+// Prelogin tells whether the account takes the login secret the client
+// derives from the password. Accounts without a vault key take the
+// password itself until it is changed.
+type Prelogin struct {
+	LoginSecret bool `json:"login_secret"`
+}
+
+// UserDeletion confirms deleting the account with the password.
+type UserDeletion struct {
 	Password string `json:"password"`
 }
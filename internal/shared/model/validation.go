@@ -0,0 +1,560 @@
+package model
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/crypto"
+	"github.com/google/uuid"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	maxCredLoginLen    = 128
+	maxCredPasswordLen = 256
+	maxCardFieldLen    = 128
+	maxAddressLineLen  = 512
+	maxAddressFieldLen = 256
+	maxBinaryNameLen   = 1024
+
+	// sealedOverhead is the room the prefix and the nonce of a value sealed
+	// with a random nonce take on top of the ciphertext, rounded up.
+	sealedOverhead = 32
+)
+
+// ValidationErrEntry holds validation errors of a single field.
+type ValidationErrEntry struct {
+	Field  string   `json:"field"`
+	Errors []string `json:"errors"`
+}
+
+func NewValidationErr(field string, errs []string) ValidationErrEntry {
+	return ValidationErrEntry{
+		Field:  field,
+		Errors: errs,
+	}
+}
+
+// ValidationError is returned when a model does not pass validation.
+type ValidationError struct {
+	Entries []ValidationErrEntry
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Entries))
+	for _, entry := range e.Entries {
+		parts = append(parts, fmt.Sprintf("%s: %s", entry.Field, strings.Join(entry.Errors, ", ")))
+	}
+	return "validation failed: " + strings.Join(parts, "; ")
+}
+
+type validator struct {
+	entries []ValidationErrEntry
+}
+
+func (v *validator) check(ok bool, field, msg string) {
+	if ok {
+		return
+	}
+	for i := range v.entries {
+		if v.entries[i].Field == field {
+			v.entries[i].Errors = append(v.entries[i].Errors, msg)
+			return
+		}
+	}
+	v.entries = append(v.entries, NewValidationErr(field, []string{msg}))
+}
+
+func (v *validator) merge(prefix string, isNil bool, validate func() error) {
+	if isNil {
+		v.check(false, prefix, "must not be null")
+		return
+	}
+	err := validate()
+	if err == nil {
+		return
+	}
+	var valErr *ValidationError
+	if !errors.As(err, &valErr) {
+		v.check(false, prefix, err.Error())
+		return
+	}
+	for _, entry := range valErr.Entries {
+		for _, msg := range entry.Errors {
+			v.check(false, prefix+"."+entry.Field, msg)
+		}
+	}
+}
+
+func (v *validator) err() error {
+	if len(v.entries) == 0 {
+		return nil
+	}
+	return &ValidationError{Entries: v.entries}
+}
+
+// NormalizeCardNum removes spaces and dashes from the card number.
+func NormalizeCardNum(num string) string {
+	return strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(num))
+}
+
+// LuhnValid reports whether the digits string passes the Luhn checksum.
+func LuhnValid(digits string) bool {
+	if digits == "" {
+		return false
+	}
+	var sum int
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		c := digits[i]
+		if c < '0' || c > '9' {
+			return false
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateCardInput checks plain card values before they are encrypted.
+func ValidateCardInput(in CardInput, now time.Time) error {
+	var v validator
+	v.checkCard(in)
+	v.check(in.CVC != "", "cvc", "cvc is required")
+	v.check(strings.TrimSpace(in.HolderName) != "", "holder_name", "holder name is required")
+	month, errM := strconv.Atoi(in.ExpMonth)
+	v.check(errM == nil, "exp_month", "expiry month is required")
+	year, errY := strconv.Atoi(NormalizeExpYear(in.ExpYear))
+	v.check(errY == nil, "exp_year", "expiry year is required")
+	if errM == nil && errY == nil {
+		for _, msg := range ValidateCardExpiry(month, year, now) {
+			v.check(false, "exp", msg)
+		}
+	}
+	return v.err()
+}
+
+// ValidateCardStructure checks that the number of an imported card is valid
+// and that the other values are well formed when present. Missing values and
+// expired cards pass, the card is kept as it was exported.
+func ValidateCardStructure(in CardInput) error {
+	var v validator
+	v.checkCard(in)
+	if in.ExpMonth != "" {
+		month, err := strconv.Atoi(in.ExpMonth)
+		v.check(err == nil && month >= 1 && month <= 12, "exp_month",
+			"month must be between 1 and 12")
+	}
+	if in.ExpYear != "" {
+		year, err := strconv.Atoi(NormalizeExpYear(in.ExpYear))
+		v.check(err == nil && year >= 2000 && year <= 2100, "exp_year",
+			"year must be a four digit year")
+	}
+	return v.err()
+}
+
+// checkCard checks the number and the values that are well formed whenever
+// they are present.
+func (v *validator) checkCard(in CardInput) {
+	n := NormalizeCardNum(in.Num)
+	v.check(n != "", "num", "number is required")
+	if n != "" {
+		v.check(isDigits(n), "num", "number must contain only digits, spaces or dashes")
+		v.check(len(n) >= 12 && len(n) <= 19, "num", "number must be 12 to 19 digits long")
+		v.check(LuhnValid(n), "num", "number does not pass Luhn check")
+	}
+	if in.CVC != "" {
+		v.check(isDigits(in.CVC) && len(in.CVC) >= 3 && len(in.CVC) <= 4, "cvc",
+			"cvc must be 3 or 4 digits")
+	}
+	if in.PIN != "" {
+		v.check(isDigits(in.PIN) && len(in.PIN) >= 4 && len(in.PIN) <= 12, "pin",
+			"pin must be 4 to 12 digits")
+	}
+	addr := in.BillingAddress
+	if !addr.IsEmpty() {
+		v.check(strings.TrimSpace(addr.Line1) != "", "billing_address.line1",
+			"address line is required")
+		v.check(strings.TrimSpace(addr.City) != "", "billing_address.city", "city is required")
+		v.check(len(addr.Country) == 2, "billing_address.country",
+			"country must be an ISO 3166-1 alpha-2 code")
+	}
+}
+
+// NormalizeExpYear converts a two digit expiry year to four digits.
+func NormalizeExpYear(year string) string {
+	year = strings.TrimSpace(year)
+	if len(year) == 2 && isDigits(year) {
+		return "20" + year
+	}
+	return year
+}
+
+// ValidateCardExpiry checks that month and year form a not yet expired date.
+func ValidateCardExpiry(month, year int, now time.Time) []string {
+	var errs []string
+	if month < 1 || month > 12 {
+		errs = append(errs, "month must be between 1 and 12")
+	}
+	if year < 2000 || year > 2100 {
+		errs = append(errs, "year must be a four digit year")
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	// card is valid through the last day of the expiry month
+	expires := time.Date(year, time.Month(month)+1, 1, 0, 0, 0, 0, time.UTC)
+	if !now.Before(expires) {
+		errs = append(errs, "card is expired")
+	}
+	return errs
+}
+
+// ValidateCredentialsInput checks plain credentials before they are encrypted.
+func ValidateCredentialsInput(login, password string) error {
+	var v validator
+	v.check(strings.TrimSpace(login) != "", "login", "login is required")
+	v.check(password != "", "password", "password is required")
+	return v.err()
+}
+
+// ValidateTextInput checks plain text before it is saved.
+func ValidateTextInput(txt string) error {
+	var v validator
+	v.check(strings.TrimSpace(txt) != "", "txt", "text is required")
+	return v.err()
+}
+
+func (v *validator) checkBase(id string, status Status, modifiedTms time.Time) {
+	_, err := uuid.Parse(id)
+	v.check(err == nil, "id", "id must be a UUID")
+	v.check(status == StatusActive || status == StatusDeleted, "status", "status is not valid")
+	v.check(!modifiedTms.IsZero(), "modified_tms", "modified_tms is required")
+}
+
+// checkOptionalCipher checks an encrypted field that may be left empty.
+func (v *validator) checkOptionalCipher(field, value string, maxLen int) {
+	if value != "" {
+		v.checkCipher(field, value, maxLen)
+	}
+}
+
+// checkCipher checks the structure of an encrypted field: the server never
+// sees plain values, so only presence, encoding and size can be verified.
+func (v *validator) checkCipher(field, value string, maxLen int) {
+	if value == "" {
+		v.check(false, field, field+" is required")
+		return
+	}
+	_, err := hex.DecodeString(strings.TrimPrefix(value, crypto.SealedPrefix))
+	v.check(err == nil, field, field+" must be hex encoded ciphertext")
+	maxLen += sealedOverhead
+	v.check(len(value) <= maxLen, field, fmt.Sprintf("%s must not be longer than %d", field, maxLen))
+}
+
+func (c *Credentials) Validate() error {
+	var v validator
+	v.checkBase(c.ID, c.Status, c.ModifiedTms)
+	if c.Status != StatusDeleted {
+		v.checkCipher("login", c.Login, maxCredLoginLen)
+		v.checkCipher("password", c.Password, maxCredPasswordLen)
+	}
+	return v.err()
+}
+
+func (c *Card) Validate() error {
+	var v validator
+	v.checkBase(c.ID, c.Status, c.ModifiedTms)
+	if c.Status != StatusDeleted {
+		v.checkCipher("num", c.Num, maxCardFieldLen)
+		// imported cards may lack them
+		v.checkOptionalCipher("cvc", c.CVC, maxCardFieldLen)
+		v.checkOptionalCipher("holder_name", c.HolderName, maxCardFieldLen)
+		v.checkOptionalCipher("exp_month", c.ExpMonth, maxCardFieldLen)
+		v.checkOptionalCipher("exp_year", c.ExpYear, maxCardFieldLen)
+		v.checkOptionalCipher("pin", c.PIN, maxCardFieldLen)
+		v.checkOptionalCipher("brand", c.Brand, maxCardFieldLen)
+		addr := c.BillingAddress
+		v.checkOptionalCipher("billing_address.line1", addr.Line1, maxAddressLineLen)
+		v.checkOptionalCipher("billing_address.line2", addr.Line2, maxAddressLineLen)
+		v.checkOptionalCipher("billing_address.city", addr.City, maxAddressFieldLen)
+		v.checkOptionalCipher("billing_address.region", addr.Region, maxAddressFieldLen)
+		v.checkOptionalCipher("billing_address.postal_code", addr.PostalCode, maxCardFieldLen)
+		v.checkOptionalCipher("billing_address.country", addr.Country, maxCardFieldLen)
+	}
+	return v.err()
+}
+
+func (t *Text) Validate() error {
+	var v validator
+	v.checkBase(t.ID, t.Status, t.ModifiedTms)
+	if t.Status != StatusDeleted {
+		v.check(t.Txt != "", "txt", "txt is required")
+	}
+	return v.err()
+}
+
+func (b *Binary) Validate() error {
+	var v validator
+	v.checkBase(b.ID, b.Status, b.ModifiedTms)
+	if b.Status != StatusDeleted {
+		v.check(b.Name != "", "name", "name is required")
+		v.check(len(b.Name) <= maxBinaryNameLen, "name",
+			fmt.Sprintf("name must not be longer than %d", maxBinaryNameLen))
+		_, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(b.Data, crypto.SealedPrefix))
+		v.check(err == nil, "data", "data must be base64 encoded")
+	}
+	return v.err()
+}
+
+func (s *CredSync) Validate() error {
+	var v validator
+	for i, c := range s.Credentials {
+		v.merge(fmt.Sprintf("credentials[%d]", i), c == nil, c.Validate)
+	}
+	return v.err()
+}
+
+func (s *CardSync) Validate() error {
+	var v validator
+	for i, c := range s.Cards {
+		v.merge(fmt.Sprintf("cards[%d]", i), c == nil, c.Validate)
+	}
+	return v.err()
+}
+
+func (s *TextSync) Validate() error {
+	var v validator
+	for i, t := range s.Texts {
+		v.merge(fmt.Sprintf("texts[%d]", i), t == nil, t.Validate)
+	}
+	return v.err()
+}
+
+func (s *BinarySync) Validate() error {
+	var v validator
+	for i, b := range s.Binaries {
+		v.merge(fmt.Sprintf("binaries[%d]", i), b == nil, b.Validate)
+	}
+	return v.err()
+}
+
+// shareKinds are the item kinds a share can hold.
+var shareKinds = map[string]bool{"cred": true, "card": true, "text": true, "file": true}
+
+// x25519KeyLen is the size of an X25519 public key.
+const x25519KeyLen = 32
+
+func (k *UserKey) Validate() error {
+	var v validator
+	pub, err := base64.StdEncoding.DecodeString(k.PublicKey)
+	v.check(err == nil && len(pub) == x25519KeyLen, "public_key",
+		"public_key must be a base64 encoded X25519 key")
+	wrapped := strings.TrimPrefix(k.WrappedPrivateKey, crypto.SealedPrefix)
+	_, err = base64.StdEncoding.DecodeString(wrapped)
+	v.check(wrapped != "" && err == nil, "wrapped_private_key",
+		"wrapped_private_key must be base64 encoded")
+	return v.err()
+}
+
+func (s *Share) Validate() error {
+	var v validator
+	_, err := uuid.Parse(s.ID)
+	v.check(err == nil, "id", "id must be a UUID")
+	_, err = uuid.Parse(s.ItemID)
+	v.check(err == nil, "item_id", "item_id must be a UUID")
+	v.check(shareKinds[s.Kind], "kind", "kind must be cred, card, text or file")
+	v.checkPayload(s.Payload, s.ModifiedTms)
+	v.check(len(s.Grants) > 0, "grants", "grants are required")
+	for i, g := range s.Grants {
+		v.merge(fmt.Sprintf("grants[%d]", i), false, g.Validate)
+	}
+	return v.err()
+}
+
+func (g ShareGrant) Validate() error {
+	var v validator
+	v.check(strings.TrimSpace(g.Login) != "", "login", "login is required")
+	_, err := base64.StdEncoding.DecodeString(g.WrappedKey)
+	v.check(g.WrappedKey != "" && err == nil, "wrapped_key", "wrapped_key must be base64 encoded")
+	v.check(g.Access == AccessRead || g.Access == AccessWrite, "access", "access must be READ or WRITE")
+	return v.err()
+}
+
+func (p *SharePayload) Validate() error {
+	var v validator
+	v.checkPayload(p.Payload, p.ModifiedTms)
+	return v.err()
+}
+
+func (v *validator) checkPayload(payload string, modifiedTms time.Time) {
+	_, err := base64.StdEncoding.DecodeString(payload)
+	v.check(payload != "" && err == nil, "payload", "payload must be base64 encoded")
+	v.check(!modifiedTms.IsZero(), "modified_tms", "modified_tms is required")
+}
+
+const maxOrgNameLen = 128
+
+var roles = map[Role]bool{RoleOwner: true, RoleAdmin: true, RoleMember: true, RoleReadOnly: true}
+
+func (o *Org) Validate() error {
+	var v validator
+	_, err := uuid.Parse(o.ID)
+	v.check(err == nil, "id", "id must be a UUID")
+	v.checkName(o.Name)
+	return v.err()
+}
+
+func (m *OrgMember) Validate() error {
+	var v validator
+	v.check(strings.TrimSpace(m.Login) != "", "login", "login is required")
+	v.check(roles[m.Role] && m.Role != RoleOwner, "role", "role must be ADMIN, MEMBER or READ_ONLY")
+	for i, k := range m.Keys {
+		_, err := uuid.Parse(k.CollectionID)
+		v.check(err == nil, fmt.Sprintf("keys[%d].collection_id", i), "collection_id must be a UUID")
+		v.checkWrappedKey(fmt.Sprintf("keys[%d].wrapped_key", i), k.WrappedKey)
+	}
+	return v.err()
+}
+
+func (c *Collection) Validate() error {
+	var v validator
+	_, err := uuid.Parse(c.ID)
+	v.check(err == nil, "id", "id must be a UUID")
+	v.checkName(c.Name)
+	v.checkMemberKeys(c.Keys)
+	return v.err()
+}
+
+func (i *OrgItem) Validate() error {
+	var v validator
+	_, err := uuid.Parse(i.ID)
+	v.check(err == nil, "id", "id must be a UUID")
+	v.check(shareKinds[i.Kind], "kind", "kind must be cred, card, text or file")
+	v.check(i.KeyVersion > 0, "key_version", "key_version must be positive")
+	v.check(i.Status == StatusActive || i.Status == StatusDeleted, "status", "status is not valid")
+	v.checkPayload(i.Payload, i.ModifiedTms)
+	return v.err()
+}
+
+func (r *CollectionRekey) Validate() error {
+	var v validator
+	v.check(r.KeyVersion > 1, "key_version", "key_version must follow the current one")
+	v.checkMemberKeys(r.Keys)
+	for i := range r.Items {
+		v.merge(fmt.Sprintf("items[%d]", i), false, r.Items[i].Validate)
+	}
+	return v.err()
+}
+
+func (v *validator) checkName(name string) {
+	v.check(strings.TrimSpace(name) != "", "name", "name is required")
+	v.check(len(name) <= maxOrgNameLen, "name", fmt.Sprintf("name must be at most %d bytes", maxOrgNameLen))
+}
+
+func (v *validator) checkMemberKeys(keys []CollectionKey) {
+	v.check(len(keys) > 0, "keys", "keys are required")
+	for i, k := range keys {
+		v.check(strings.TrimSpace(k.Login) != "", fmt.Sprintf("keys[%d].login", i), "login is required")
+		v.checkWrappedKey(fmt.Sprintf("keys[%d].wrapped_key", i), k.WrappedKey)
+	}
+}
+
+func (v *validator) checkWrappedKey(field, key string) {
+	_, err := base64.StdEncoding.DecodeString(key)
+	v.check(key != "" && err == nil, field, "wrapped_key must be base64 encoded")
+}
+
+// maxWaitHours bounds the waiting period of emergency access to a year.
+const maxWaitHours = 24 * 365
+
+func (e *EmergencyAccess) Validate() error {
+	var v validator
+	_, err := uuid.Parse(e.ID)
+	v.check(err == nil, "id", "id must be a UUID")
+	v.check(strings.TrimSpace(e.ContactLogin) != "", "contact_login", "contact_login is required")
+	v.check(e.WaitHours >= 0 && e.WaitHours <= maxWaitHours, "wait_hours",
+		fmt.Sprintf("wait_hours must be between 0 and %d", maxWaitHours))
+	v.checkWrappedKey("wrapped_key", e.WrappedKey)
+	return v.err()
+}
+
+func (k *VaultKey) Validate() error {
+	var v validator
+	v.checkWrappedKey("wrapped_key", k.WrappedKey)
+	v.checkBase64("salt", k.Salt)
+	return v.err()
+}
+
+func (k *RecoveryKit) Validate() error {
+	var v validator
+	v.checkWrappedKey("wrapped_key", k.WrappedKey)
+	v.checkBase64("auth", k.Auth)
+	return v.err()
+}
+
+func (c *PasswordChange) Validate() error {
+	var v validator
+	v.check(strings.TrimSpace(c.Password) != "", "password", "password is required")
+	v.check(strings.TrimSpace(c.NewPassword) != "", "new_password", "new_password is required")
+	v.merge("vault", false, c.Vault.Validate)
+	if c.Recovery != nil {
+		v.merge("recovery", false, c.Recovery.Validate)
+	}
+	return v.err()
+}
+
+func (v *validator) checkBase64(field, value string) {
+	_, err := base64.StdEncoding.DecodeString(value)
+	v.check(value != "" && err == nil, field, field+" must be base64 encoded")
+}
+
+func (r *RecoveryRequest) Validate() error {
+	var v validator
+	v.check(strings.TrimSpace(r.Login) != "", "login", "login is required")
+	v.checkBase64("auth", r.Auth)
+	return v.err()
+}
+
+func (r *PreloginRequest) Validate() error {
+	var v validator
+	v.check(strings.TrimSpace(r.Login) != "", "login", "login is required")
+	return v.err()
+}
+
+func (r *PasswordReset) Validate() error {
+	var v validator
+	v.check(strings.TrimSpace(r.Login) != "", "login", "login is required")
+	v.checkBase64("auth", r.Auth)
+	v.check(strings.TrimSpace(r.NewPassword) != "", "new_password", "new_password is required")
+	v.merge("vault", false, r.Vault.Validate)
+	return v.err()
+}
+
+func (d *UserDeletion) Validate() error {
+	var v validator
+	v.check(strings.TrimSpace(d.Password) != "", "password", "password is required")
+	return v.err()
+}
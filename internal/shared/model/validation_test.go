@@ -0,0 +1,163 @@
+package model
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sort"
+	"testing"
+	"time"
+)
+
+// failedFields returns the sorted fields of a validation error.
+func failedFields(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var valErr *ValidationError
+	require.True(t, errors.As(err, &valErr), "not a validation error: %v", err)
+	fields := make([]string, 0, len(valErr.Entries))
+	for _, e := range valErr.Entries {
+		fields = append(fields, e.Field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func TestLuhnValid(t *testing.T) {
+	tests := []struct {
+		digits string
+		want   bool
+	}{
+		{digits: "4111111111111111", want: true},
+		{digits: "4111111111111112", want: false},
+		{digits: "378282246310005", want: true},
+		{digits: "79927398713", want: true},
+		{digits: "79927398710", want: false},
+		{digits: "0", want: true},
+		{digits: "", want: false},
+		{digits: "4111 1111 1111 1111", want: false},
+		{digits: "41111111111111a1", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.digits, func(t *testing.T) {
+			assert.Equal(t, tt.want, LuhnValid(tt.digits))
+		})
+	}
+}
+
+func TestNormalizeCardNum(t *testing.T) {
+	assert.Equal(t, "4111111111111111", NormalizeCardNum(" 4111-1111 1111-1111\t"))
+}
+
+func TestNormalizeExpYear(t *testing.T) {
+	tests := []struct {
+		year string
+		want string
+	}{
+		{year: "30", want: "2030"},
+		{year: " 2030 ", want: "2030"},
+		{year: "3", want: "3"},
+		{year: "ab", want: "ab"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.year, func(t *testing.T) {
+			assert.Equal(t, tt.want, NormalizeExpYear(tt.year))
+		})
+	}
+}
+
+func TestValidateCardExpiry(t *testing.T) {
+	now := time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		month   int
+		year    int
+		wantErr bool
+	}{
+		{name: "future", month: 1, year: 2030},
+		{name: "this month", month: 5, year: 2024},
+		{name: "last month", month: 4, year: 2024, wantErr: true},
+		{name: "month zero", month: 0, year: 2030, wantErr: true},
+		{name: "month 13", month: 13, year: 2030, wantErr: true},
+		{name: "two digit year", month: 1, year: 30, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := ValidateCardExpiry(tt.month, tt.year, now)
+			assert.Equal(t, tt.wantErr, len(errs) > 0, "errors %v", errs)
+		})
+	}
+}
+
+func validCard() CardInput {
+	return CardInput{
+		Num:        "4111 1111 1111 1111",
+		CVC:        "123",
+		HolderName: "Denis",
+		ExpMonth:   "12",
+		ExpYear:    "30",
+	}
+}
+
+func TestValidateCardInput(t *testing.T) {
+	now := time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		edit       func(c *CardInput)
+		wantFields []string
+	}{
+		{name: "valid", edit: func(c *CardInput) {}},
+		{name: "amex with 4 digit cvc", edit: func(c *CardInput) {
+			c.Num, c.CVC = "378282246310005", "1234"
+		}},
+		{name: "luhn", edit: func(c *CardInput) { c.Num = "4111111111111112" }, wantFields: []string{"num"}},
+		{name: "too short", edit: func(c *CardInput) { c.Num = "42" }, wantFields: []string{"num"}},
+		{name: "letters", edit: func(c *CardInput) { c.Num = "4111x11111111111" }, wantFields: []string{"num"}},
+		{name: "no number", edit: func(c *CardInput) { c.Num = "" }, wantFields: []string{"num"}},
+		{name: "no cvc", edit: func(c *CardInput) { c.CVC = "" }, wantFields: []string{"cvc"}},
+		{name: "bad cvc", edit: func(c *CardInput) { c.CVC = "12" }, wantFields: []string{"cvc"}},
+		{name: "no holder", edit: func(c *CardInput) { c.HolderName = " " }, wantFields: []string{"holder_name"}},
+		{name: "no expiry", edit: func(c *CardInput) { c.ExpMonth, c.ExpYear = "", "" },
+			wantFields: []string{"exp_month", "exp_year"}},
+		{name: "expired", edit: func(c *CardInput) { c.ExpYear = "2023" }, wantFields: []string{"exp"}},
+		{name: "bad pin", edit: func(c *CardInput) { c.PIN = "12" }, wantFields: []string{"pin"}},
+		{name: "address", edit: func(c *CardInput) {
+			c.BillingAddress = BillingAddress{Line1: "Main st 1", City: "Moscow", Country: "RU"}
+		}},
+		{name: "partial address", edit: func(c *CardInput) {
+			c.BillingAddress = BillingAddress{PostalCode: "101000", Country: "Russia"}
+		}, wantFields: []string{"billing_address.city", "billing_address.country", "billing_address.line1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validCard()
+			tt.edit(&c)
+			assert.Equal(t, tt.wantFields, failedFields(t, ValidateCardInput(c, now)))
+		})
+	}
+}
+
+func TestValidateCardStructure(t *testing.T) {
+	tests := []struct {
+		name       string
+		card       CardInput
+		wantFields []string
+	}{
+		{name: "number only", card: CardInput{Num: "4111111111111111"}},
+		{name: "expired", card: CardInput{Num: "4111111111111111", ExpMonth: "1", ExpYear: "2001"}},
+		{name: "luhn", card: CardInput{Num: "4111111111111112"}, wantFields: []string{"num"}},
+		{name: "no number", card: CardInput{CVC: "123"}, wantFields: []string{"num"}},
+		{name: "bad cvc", card: CardInput{Num: "4111111111111111", CVC: "1"}, wantFields: []string{"cvc"}},
+		{name: "bad month", card: CardInput{Num: "4111111111111111", ExpMonth: "13"},
+			wantFields: []string{"exp_month"}},
+		{name: "bad year", card: CardInput{Num: "4111111111111111", ExpYear: "1999"},
+			wantFields: []string{"exp_year"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantFields, failedFields(t, ValidateCardStructure(tt.card)))
+		})
+	}
+}
@@ -0,0 +1,103 @@
+// Package tracing sets up OpenTelemetry for the client and the server. The
+// trace context goes from the client to the server in the W3C headers.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"github.com/denis-oreshkevich/gophkeeper/internal/shared/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"io"
+	"time"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// Config selects where spans go.
+type Config struct {
+	// Exporter is none, stdout or otlp.
+	Exporter string
+	// Endpoint is the host:port of the OTLP/HTTP receiver.
+	Endpoint string
+	// Insecure sends to the OTLP receiver without TLS, as a local
+	// collector expects.
+	Insecure bool
+	// Writer receives the spans of the stdout exporter.
+	Writer io.Writer
+}
+
+// flushTimeout bounds the export of the spans left on exit, so an
+// unreachable collector doesn't hold it.
+const flushTimeout = 5 * time.Second
+
+// Setup installs the tracer provider and the propagator for the service.
+// The returned function exports the spans left, it has to be called before
+// exiting. With the none exporter spans are not recorded but the trace
+// context is still passed on.
+func Setup(ctx context.Context, service string, conf Config) (func(), error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch conf.Exporter {
+	case "", ExporterNone:
+		return func() {}, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(conf.Writer), stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, fmt.Errorf("stdouttrace.New: %w", err)
+		}
+	case ExporterOTLP:
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(conf.Endpoint)}
+		if conf.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("otlptracehttp.New: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %s", conf.Exporter)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", service))),
+	)
+	otel.SetTracerProvider(provider)
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+		defer cancel()
+		if err := provider.Shutdown(ctx); err != nil {
+			logger.Log.Error("tracing shutdown", zap.Error(err))
+		}
+	}, nil
+}
+
+// Tracer returns the tracer of an instrumented package.
+func Tracer(name string) trace.Tracer {
+	return otel.Tracer("github.com/denis-oreshkevich/gophkeeper/" + name)
+}
+
+// End ends the span, recording err if there is one.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
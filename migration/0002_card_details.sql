@@ -0,0 +1,25 @@
+-- +goose Up
+alter table keeper.card
+    add column if not exists exp_month varchar(128) not null default '',
+    add column if not exists exp_year varchar(128) not null default '',
+    add column if not exists pin varchar(128) not null default '',
+    add column if not exists brand varchar(128) not null default '',
+    add column if not exists billing_line1 varchar(512) not null default '',
+    add column if not exists billing_line2 varchar(512) not null default '',
+    add column if not exists billing_city varchar(256) not null default '',
+    add column if not exists billing_region varchar(256) not null default '',
+    add column if not exists billing_postal_code varchar(128) not null default '',
+    add column if not exists billing_country varchar(128) not null default '';
+
+-- +goose Down
+alter table keeper.card
+    drop column if exists exp_month,
+    drop column if exists exp_year,
+    drop column if exists pin,
+    drop column if exists brand,
+    drop column if exists billing_line1,
+    drop column if exists billing_line2,
+    drop column if exists billing_city,
+    drop column if exists billing_region,
+    drop column if exists billing_postal_code,
+    drop column if exists billing_country;
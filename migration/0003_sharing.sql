@@ -0,0 +1,38 @@
+-- +goose Up
+create table if not exists keeper.usr_key(
+    user_id uuid not null,
+    public_key varchar(64) not null,
+    wrapped_private_key varchar(256) not null,
+    constraint usr_key_pkey primary key (user_id),
+    constraint fk_usr_key_usr_id foreign key(user_id) references keeper.usr(id)
+);
+
+create table if not exists keeper.share(
+    id uuid not null,
+    item_id uuid not null,
+    owner_id uuid not null,
+    kind varchar(8) not null,
+    payload text not null,
+    modified_tms timestamp not null,
+    constraint share_pkey primary key (id),
+    constraint share_owner_item_uk unique (owner_id, item_id),
+    constraint fk_share_usr_id foreign key(owner_id) references keeper.usr(id)
+);
+
+create table if not exists keeper.share_grant(
+    share_id uuid not null,
+    user_id uuid not null,
+    wrapped_key varchar(256) not null,
+    access varchar(8) not null,
+    constraint share_grant_pkey primary key (share_id, user_id),
+    constraint fk_share_grant_share_id foreign key(share_id)
+        references keeper.share(id) on delete cascade,
+    constraint fk_share_grant_usr_id foreign key(user_id) references keeper.usr(id)
+);
+
+create index if not exists share_grant_user_id_idx on keeper.share_grant(user_id);
+
+-- +goose Down
+drop table if exists keeper.share_grant;
+drop table if exists keeper.share;
+drop table if exists keeper.usr_key;
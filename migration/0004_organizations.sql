@@ -0,0 +1,59 @@
+-- +goose Up
+create table if not exists keeper.org(
+    id uuid not null,
+    name varchar(128) not null,
+    constraint org_pkey primary key (id)
+);
+
+create table if not exists keeper.org_member(
+    org_id uuid not null,
+    user_id uuid not null,
+    role varchar(16) not null,
+    constraint org_member_pkey primary key (org_id, user_id),
+    constraint fk_org_member_org_id foreign key(org_id) references keeper.org(id) on delete cascade,
+    constraint fk_org_member_usr_id foreign key(user_id) references keeper.usr(id)
+);
+
+create index if not exists org_member_user_id_idx on keeper.org_member(user_id);
+
+create table if not exists keeper.collection(
+    id uuid not null,
+    org_id uuid not null,
+    name varchar(128) not null,
+    key_version integer not null,
+    constraint collection_pkey primary key (id),
+    constraint collection_org_name_uk unique (org_id, name),
+    constraint fk_collection_org_id foreign key(org_id) references keeper.org(id) on delete cascade
+);
+
+create table if not exists keeper.collection_key(
+    collection_id uuid not null,
+    user_id uuid not null,
+    wrapped_key varchar(256) not null,
+    constraint collection_key_pkey primary key (collection_id, user_id),
+    constraint fk_collection_key_collection_id foreign key(collection_id)
+        references keeper.collection(id) on delete cascade,
+    constraint fk_collection_key_usr_id foreign key(user_id) references keeper.usr(id)
+);
+
+create table if not exists keeper.org_item(
+    id uuid not null,
+    collection_id uuid not null,
+    kind varchar(8) not null,
+    payload text not null,
+    key_version integer not null,
+    status varchar(8) not null,
+    modified_tms timestamp not null,
+    constraint org_item_pkey primary key (id),
+    constraint fk_org_item_collection_id foreign key(collection_id)
+        references keeper.collection(id) on delete cascade
+);
+
+create index if not exists org_item_collection_id_idx on keeper.org_item(collection_id);
+
+-- +goose Down
+drop table if exists keeper.org_item;
+drop table if exists keeper.collection_key;
+drop table if exists keeper.collection;
+drop table if exists keeper.org_member;
+drop table if exists keeper.org;
@@ -0,0 +1,20 @@
+-- +goose Up
+create table if not exists keeper.emergency_access(
+    id uuid not null,
+    owner_id uuid not null,
+    contact_id uuid not null,
+    wait_hours integer not null,
+    status varchar(16) not null,
+    requested_tms timestamp,
+    wrapped_key varchar(256) not null,
+    constraint emergency_access_pkey primary key (id),
+    constraint emergency_access_owner_contact_uk unique (owner_id, contact_id),
+    constraint fk_emergency_access_owner_id foreign key(owner_id) references keeper.usr(id),
+    constraint fk_emergency_access_contact_id foreign key(contact_id) references keeper.usr(id)
+);
+
+create index if not exists emergency_access_contact_id_idx on keeper.emergency_access(contact_id);
+create index if not exists emergency_access_requested_idx on keeper.emergency_access(status, requested_tms);
+
+-- +goose Down
+drop table if exists keeper.emergency_access;
@@ -0,0 +1,11 @@
+-- +goose Up
+alter table keeper.usr add column if not exists vault_key varchar(128);
+alter table keeper.usr add column if not exists kdf_salt varchar(64);
+alter table keeper.usr add column if not exists recovery_key varchar(128);
+alter table keeper.usr add column if not exists recovery_hash varchar(128);
+
+-- +goose Down
+alter table keeper.usr drop column if exists recovery_hash;
+alter table keeper.usr drop column if exists recovery_key;
+alter table keeper.usr drop column if exists kdf_salt;
+alter table keeper.usr drop column if exists vault_key;
@@ -0,0 +1,74 @@
+-- +goose Up
+delete from keeper.cred where user_id not in (select id from keeper.usr);
+delete from keeper.txt where user_id not in (select id from keeper.usr);
+delete from keeper.binary where user_id not in (select id from keeper.usr);
+delete from keeper.card where user_id not in (select id from keeper.usr);
+
+create index if not exists cred_user_id_idx on keeper.cred(user_id);
+create index if not exists txt_user_id_idx on keeper.txt(user_id);
+create index if not exists binary_user_id_idx on keeper.binary(user_id);
+create index if not exists card_user_id_idx on keeper.card(user_id);
+create index if not exists client_user_id_idx on keeper.client(user_id);
+
+alter table keeper.cred add constraint fk_cred_usr_id
+    foreign key(user_id) references keeper.usr(id) on delete cascade;
+alter table keeper.txt add constraint fk_txt_usr_id
+    foreign key(user_id) references keeper.usr(id) on delete cascade;
+alter table keeper.binary add constraint fk_binary_usr_id
+    foreign key(user_id) references keeper.usr(id) on delete cascade;
+alter table keeper.card add constraint fk_card_usr_id
+    foreign key(user_id) references keeper.usr(id) on delete cascade;
+
+alter table keeper.client drop constraint fk_client_usr_id,
+    add constraint fk_client_usr_id
+    foreign key(user_id) references keeper.usr(id) on delete cascade;
+alter table keeper.usr_key drop constraint fk_usr_key_usr_id,
+    add constraint fk_usr_key_usr_id
+    foreign key(user_id) references keeper.usr(id) on delete cascade;
+alter table keeper.share drop constraint fk_share_usr_id,
+    add constraint fk_share_usr_id
+    foreign key(owner_id) references keeper.usr(id) on delete cascade;
+alter table keeper.share_grant drop constraint fk_share_grant_usr_id,
+    add constraint fk_share_grant_usr_id
+    foreign key(user_id) references keeper.usr(id) on delete cascade;
+alter table keeper.org_member drop constraint fk_org_member_usr_id,
+    add constraint fk_org_member_usr_id
+    foreign key(user_id) references keeper.usr(id) on delete cascade;
+alter table keeper.collection_key drop constraint fk_collection_key_usr_id,
+    add constraint fk_collection_key_usr_id
+    foreign key(user_id) references keeper.usr(id) on delete cascade;
+alter table keeper.emergency_access drop constraint fk_emergency_access_owner_id,
+    add constraint fk_emergency_access_owner_id
+    foreign key(owner_id) references keeper.usr(id) on delete cascade;
+alter table keeper.emergency_access drop constraint fk_emergency_access_contact_id,
+    add constraint fk_emergency_access_contact_id
+    foreign key(contact_id) references keeper.usr(id) on delete cascade;
+
+-- +goose Down
+alter table keeper.emergency_access drop constraint fk_emergency_access_contact_id,
+    add constraint fk_emergency_access_contact_id foreign key(contact_id) references keeper.usr(id);
+alter table keeper.emergency_access drop constraint fk_emergency_access_owner_id,
+    add constraint fk_emergency_access_owner_id foreign key(owner_id) references keeper.usr(id);
+alter table keeper.collection_key drop constraint fk_collection_key_usr_id,
+    add constraint fk_collection_key_usr_id foreign key(user_id) references keeper.usr(id);
+alter table keeper.org_member drop constraint fk_org_member_usr_id,
+    add constraint fk_org_member_usr_id foreign key(user_id) references keeper.usr(id);
+alter table keeper.share_grant drop constraint fk_share_grant_usr_id,
+    add constraint fk_share_grant_usr_id foreign key(user_id) references keeper.usr(id);
+alter table keeper.share drop constraint fk_share_usr_id,
+    add constraint fk_share_usr_id foreign key(owner_id) references keeper.usr(id);
+alter table keeper.usr_key drop constraint fk_usr_key_usr_id,
+    add constraint fk_usr_key_usr_id foreign key(user_id) references keeper.usr(id);
+alter table keeper.client drop constraint fk_client_usr_id,
+    add constraint fk_client_usr_id foreign key(user_id) references keeper.usr(id);
+
+alter table keeper.card drop constraint if exists fk_card_usr_id;
+alter table keeper.binary drop constraint if exists fk_binary_usr_id;
+alter table keeper.txt drop constraint if exists fk_txt_usr_id;
+alter table keeper.cred drop constraint if exists fk_cred_usr_id;
+
+drop index if exists keeper.client_user_id_idx;
+drop index if exists keeper.card_user_id_idx;
+drop index if exists keeper.binary_user_id_idx;
+drop index if exists keeper.txt_user_id_idx;
+drop index if exists keeper.cred_user_id_idx;
@@ -0,0 +1,32 @@
+-- +goose Up
+-- keeper.audit is append-only: rows are never updated, they only go with
+-- the account they belong to.
+create table if not exists keeper.audit(
+    id bigserial not null,
+    user_id uuid,
+    event varchar(32) not null,
+    details varchar(512) not null default '',
+    client_id varchar(64) not null default '',
+    ip varchar(64) not null default '',
+    request_id varchar(128) not null default '',
+    created_tms timestamp not null default (now() at time zone 'utc'),
+    constraint audit_pkey primary key (id),
+    constraint fk_audit_usr_id foreign key(user_id) references keeper.usr(id) on delete cascade
+);
+
+create index if not exists audit_user_id_idx on keeper.audit(user_id, id);
+
+-- +goose StatementBegin
+create or replace function keeper.audit_no_update() returns trigger as $$
+begin
+    raise exception 'keeper.audit is append-only';
+end;
+$$ language plpgsql;
+-- +goose StatementEnd
+
+create trigger audit_no_update before update on keeper.audit
+    for each row execute function keeper.audit_no_update();
+
+-- +goose Down
+drop table if exists keeper.audit;
+drop function if exists keeper.audit_no_update();
@@ -0,0 +1,15 @@
+-- +goose Up
+-- a client registered with a CSR is a device with a certificate of the
+-- local CA, revoked devices go to the CRL.
+alter table keeper.client add column if not exists cert_fingerprint varchar(64),
+    add column if not exists cert_serial varchar(40),
+    add column if not exists revoked_tms timestamp;
+
+create unique index if not exists client_cert_fingerprint_uk on keeper.client(cert_fingerprint);
+
+-- +goose Down
+drop index if exists keeper.client_cert_fingerprint_uk;
+
+alter table keeper.client drop column if exists cert_fingerprint,
+    drop column if exists cert_serial,
+    drop column if exists revoked_tms;
@@ -0,0 +1,40 @@
+-- +goose Up
+-- values sealed with a random nonce carry a version prefix and the nonce
+alter table keeper.cred
+    alter column login type varchar(160),
+    alter column password type varchar(288);
+
+alter table keeper.card
+    alter column num type varchar(160),
+    alter column cvc type varchar(160),
+    alter column holder_name type varchar(160),
+    alter column exp_month type varchar(160),
+    alter column exp_year type varchar(160),
+    alter column pin type varchar(160),
+    alter column brand type varchar(160),
+    alter column billing_line1 type varchar(544),
+    alter column billing_line2 type varchar(544),
+    alter column billing_city type varchar(288),
+    alter column billing_region type varchar(288),
+    alter column billing_postal_code type varchar(160),
+    alter column billing_country type varchar(160);
+
+-- +goose Down
+alter table keeper.cred
+    alter column login type varchar(128),
+    alter column password type varchar(256);
+
+alter table keeper.card
+    alter column num type varchar(128),
+    alter column cvc type varchar(128),
+    alter column holder_name type varchar(128),
+    alter column exp_month type varchar(128),
+    alter column exp_year type varchar(128),
+    alter column pin type varchar(128),
+    alter column brand type varchar(128),
+    alter column billing_line1 type varchar(512),
+    alter column billing_line2 type varchar(512),
+    alter column billing_city type varchar(256),
+    alter column billing_region type varchar(256),
+    alter column billing_postal_code type varchar(128),
+    alter column billing_country type varchar(128);